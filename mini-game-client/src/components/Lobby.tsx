@@ -17,6 +17,10 @@ interface LobbyProps {
 
 const backendUrl = process.env.NEXT_PUBLIC_HTTP_BACKEND_URL;
 
+const authHeaders = () => ({
+  headers: { Authorization: `Bearer ${localStorage.getItem("authToken") ?? ""}` },
+});
+
 export default function Lobby({ gameType, playerId, onRoomReady }: LobbyProps) {
   const [isLoadingNewGame, setIsLoadingNewGame] = useState(false);
   const [isLoadingJoinGame, setIsLoadingJoinGame] = useState(false);
@@ -39,7 +43,7 @@ export default function Lobby({ gameType, playerId, onRoomReady }: LobbyProps) {
         game_type: gameType,
         player_id: playerId,
         ai_level: aiLevel,
-      });
+      }, authHeaders());
 
       const newRoomId = data?.data?.room?.room_id;
       const playerMark = data?.data?.player_mark;
@@ -71,7 +75,7 @@ export default function Lobby({ gameType, playerId, onRoomReady }: LobbyProps) {
       const { data } = await axios.post(`${backendUrl}/room/create`, {
         game_type: gameType,
         player_id: playerId,
-      });
+      }, authHeaders());
 
       const newRoomId = data?.data?.room?.room_id;
       const playerMark = data?.data?.player_mark;
@@ -104,7 +108,7 @@ export default function Lobby({ gameType, playerId, onRoomReady }: LobbyProps) {
         room_id: roomId,
         player_id: playerId,
         game_type: gameType,
-      });
+      }, authHeaders());
 
       const newRoomId = data?.data?.room?.room_id;
       const playerMark = data?.data?.player_mark;
@@ -128,9 +132,13 @@ export default function Lobby({ gameType, playerId, onRoomReady }: LobbyProps) {
 
     switch (msg) {
       case "Player not found":
+      case "Authentication required":
+      case "Invalid session token":
+      case "Session token expired":
         // showErrorAlert("Player not found. Please register first.");
 
         localStorage.removeItem("playerId");
+        localStorage.removeItem("authToken");
 
         window.location.reload();
 
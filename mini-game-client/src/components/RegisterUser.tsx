@@ -36,6 +36,7 @@ export default function RegisterUser({ onRegister }: RegisterUserProps) {
       const playerId = data?.data?.player_id;
 
       localStorage.setItem("playerId", playerId);
+      localStorage.setItem("authToken", data?.data?.token);
 
       await showSuccessAlert("Success Create Player");
 
@@ -9,6 +9,7 @@ const FATAL_CLOSE_CODES = new Set([
   4002, // room full
   4003, // invalid room
   4004, // player not found
+  4006, // player does not match session
 ]);
 
 export const useGameWebSocket = (
@@ -20,7 +21,7 @@ export const useGameWebSocket = (
   const [hasReconnectStopped, setHasReconnectStopped] = useState(false);
 
   const { sendMessage, lastMessage, readyState } = useWebSocket(
-    `${backendUrl}/ws?room_id=${roomId}&player_id=${playerId}`,
+    `${backendUrl}/ws?room_id=${roomId}&player_id=${playerId}&access_token=${localStorage.getItem("authToken") ?? ""}`,
     {
       onOpen: () => {
         if (process.env.NODE_ENV === "development") {
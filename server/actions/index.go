@@ -2,25 +2,43 @@ package actions
 
 const (
 	// chess
-	CHESS_GAME_STATE    = "CHESS_GAME_STATE"
-	CHESS_MOVE          = "CHESS_MOVE"
-	CHESS_UNDO_REQUEST  = "CHESS_UNDO_REQUEST"
-	CHESS_MOVE_REJECTED = "chess_move_rejected"
-	START_GAME          = "START_GAME"
+	CHESS_GAME_STATE     = "CHESS_GAME_STATE"
+	CHESS_MOVE           = "CHESS_MOVE"
+	CHESS_UNDO_REQUEST   = "CHESS_UNDO_REQUEST"
+	CHESS_PREMOVE        = "CHESS_PREMOVE"
+	CHESS_PREMOVE_CANCEL = "CHESS_PREMOVE_CANCEL"
+	CHESS_MOVE_REJECTED  = "chess_move_rejected"
+	START_GAME           = "START_GAME"
 
 	// chat
 	CHAT_SEND    = "CHAT_SEND"
+	CHAT_MUTE    = "CHAT_MUTE"
+	CHAT_UNMUTE  = "CHAT_UNMUTE"
+	CHAT_REPORT  = "CHAT_REPORT"
 	CHAT_MESSAGE = "chat_message"
 	CHAT_HISTORY = "chat_history"
 
+	// reactions and typing
+	REACTION_SEND = "REACTION_SEND"
+	TYPING_START  = "TYPING_START"
+	TYPING_STOP   = "TYPING_STOP"
+
 	// room
 	CONNECTED_ON_SERVER = "CONNECTED_ON_SERVER"
 	CREATE_ROOM_WITH_AI = "CREATE_ROOM_WITH_AI"
 	ROOM_CREATED        = "ROOM_CREATED"
+	RESYNC_REQUEST      = "RESYNC_REQUEST"
+	RESUME              = "RESUME"
+	ROOM_INVITE_CREATE  = "ROOM_INVITE_CREATE"
+	ROOM_KICK           = "ROOM_KICK"
 
 	// common game (updating mark)
 	MARK_UPDATE = "MARK_UPDATE"
 
+	// takeback negotiation (chess and tictactoe)
+	TAKEBACK_REQUEST = "TAKEBACK_REQUEST"
+	TAKEBACK_RESPOND = "TAKEBACK_RESPOND"
+
 	// tictactoe
 	TICTACTOE_GAME_STATE = "TICTACTOE_GAME_STATE"
 	TICTACTOE_MOVE       = "TICTACTOE_MOVE"
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/config"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+	"github.com/tsaqiffatih/mini-game/middleware"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+// EventServerNotice carries an operator message to every client, such as a
+// maintenance warning.
+const EventServerNotice = "server_notice"
+
+const maxBroadcastChars = 500
+
+// RegisterAdminRouter serves the operator API under /admin. Every route needs
+// cfg's admin token; rooms are those held by the node answering.
+func RegisterAdminRouter(r *mux.Router, clients *ClientRegistry, gameService *service.GameService, cfg config.Config) {
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.AdminAuth(cfg.Auth.AdminToken))
+
+	admin.HandleFunc("/rooms", func(w http.ResponseWriter, r *http.Request) {
+		getAdminRooms(w, r, clients, gameService)
+	}).Methods("GET")
+
+	admin.HandleFunc("/rooms/{room_id}", func(w http.ResponseWriter, r *http.Request) {
+		getAdminRoom(w, r, gameService)
+	}).Methods("GET")
+
+	admin.HandleFunc("/rooms/{room_id}", func(w http.ResponseWriter, r *http.Request) {
+		deleteAdminRoom(w, r, clients, gameService)
+	}).Methods("DELETE")
+
+	admin.HandleFunc("/rooms/{room_id}/finish", func(w http.ResponseWriter, r *http.Request) {
+		finishAdminRoom(w, r, clients, gameService)
+	}).Methods("POST")
+
+	admin.HandleFunc("/rooms/{room_id}/kick", func(w http.ResponseWriter, r *http.Request) {
+		kickAdminPlayer(w, r, clients, gameService)
+	}).Methods("POST")
+
+	admin.HandleFunc("/rooms/{room_id}/audit", func(w http.ResponseWriter, r *http.Request) {
+		getAdminAudit(w, r, gameService)
+	}).Methods("GET")
+
+	admin.HandleFunc("/broadcast", func(w http.ResponseWriter, r *http.Request) {
+		broadcastAdminNotice(w, r, clients)
+	}).Methods("POST")
+
+	admin.HandleFunc("/stockfish", func(w http.ResponseWriter, r *http.Request) {
+		getAdminStockfish(w, r, gameService)
+	}).Methods("GET")
+
+	admin.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		writeSuccessResponse(w, http.StatusOK, dto.AdminConfigDTO{
+			File:     cfg.File,
+			Settings: cfg.Redacted(),
+		})
+	}).Methods("GET")
+}
+
+func getAdminRooms(w http.ResponseWriter, r *http.Request, clients *ClientRegistry, gameService *service.GameService) {
+	statuses, err := gameService.RoomStatusesWithContext(r.Context())
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	connections := make(map[string]int)
+	for _, client := range clients.AllClients() {
+		connections[client.RoomID]++
+	}
+	rooms := dto.AdminRoomsDTO{Rooms: make([]dto.AdminRoomDTO, 0, len(statuses))}
+	for _, status := range statuses {
+		rooms.Rooms = append(rooms.Rooms, dto.FromAdminRoom(status, connections[status.Snapshot.RoomID]))
+	}
+	writeSuccessResponse(w, http.StatusOK, rooms)
+}
+
+func getAdminRoom(w http.ResponseWriter, r *http.Request, gameService *service.GameService) {
+	snapshot, err := gameService.RoomSnapshotWithContext(r.Context(), mux.Vars(r)["room_id"])
+	if err != nil {
+		writeErrorResponse(w, http.StatusNotFound, service.ErrRoomNotFound.Error())
+		return
+	}
+	writeSuccessResponse(w, http.StatusOK, dto.FromRoomSnapshot(snapshot))
+}
+
+func finishAdminRoom(w http.ResponseWriter, r *http.Request, clients *ClientRegistry, gameService *service.GameService) {
+	ctx := r.Context()
+	snapshot, err := gameService.ForceFinishRoomWithContext(ctx, mux.Vars(r)["room_id"])
+	if err != nil {
+		writeErrorResponse(w, adminStatus(err), err.Error())
+		return
+	}
+
+	NotifyGameUpdateToClients(ctx, clients, snapshot)
+	writeSuccessResponse(w, http.StatusOK, dto.FromRoomSnapshot(snapshot))
+}
+
+// deleteAdminRoom drops the room and closes its players' connections as if
+// it had expired.
+func deleteAdminRoom(w http.ResponseWriter, r *http.Request, clients *ClientRegistry, gameService *service.GameService) {
+	ctx := r.Context()
+	roomID := mux.Vars(r)["room_id"]
+	snapshot, err := gameService.DeleteRoomWithContext(ctx, roomID)
+	if err != nil {
+		writeErrorResponse(w, adminStatus(err), err.Error())
+		return
+	}
+
+	CloseRoomConnections(ctx, clients, snapshot, "room closed by operator")
+	writeSuccessResponse(w, http.StatusOK, dto.FromRoomSnapshot(snapshot))
+}
+
+func kickAdminPlayer(w http.ResponseWriter, r *http.Request, clients *ClientRegistry, gameService *service.GameService) {
+	var request dto.AdminKickPayload
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.PlayerID == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "player_id is required")
+		return
+	}
+
+	ctx := r.Context()
+	roomID := mux.Vars(r)["room_id"]
+	if err := gameService.RemovePlayerWithContext(ctx, roomID, request.PlayerID); err != nil {
+		writeErrorResponse(w, adminStatus(err), err.Error())
+		return
+	}
+
+	NotifyToClientsInRoom(ctx, clients, gameService, roomID, EventPlayerKicked, EventPayload{
+		Message:   "Player " + request.PlayerID + " was removed by an operator",
+		Player:    &dto.PlayerDTO{ID: request.PlayerID, PlayerID: request.PlayerID},
+		Timestamp: time.Now(),
+	})
+	closePlayerConnections(ctx, clients, roomID, request.PlayerID, CloseCodePlayerKicked, "removed by operator")
+	writeSuccessResponse(w, http.StatusOK, map[string]interface{}{})
+}
+
+func broadcastAdminNotice(w http.ResponseWriter, r *http.Request, clients *ClientRegistry) {
+	var request dto.AdminBroadcastPayload
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	message := strings.TrimSpace(request.Message)
+	if message == "" || len([]rune(message)) > maxBroadcastChars {
+		writeErrorResponse(w, http.StatusBadRequest, "message must be 1 to 500 characters")
+		return
+	}
+
+	ctx := r.Context()
+	recipients := clients.Broadcast(ctx, EventServerNotice, EventPayload{
+		Message:   message,
+		Timestamp: time.Now(),
+	})
+	observability.Logger().WarnContext(ctx, "operator notice broadcast",
+		"room_id", "",
+		"player_id", "",
+		"event_type", "server_notice",
+		"recipients", recipients,
+	)
+	writeSuccessResponse(w, http.StatusOK, dto.AdminBroadcastDTO{Message: message, Recipients: recipients})
+}
+
+// getAdminAudit answers with the room's audit log, also once the room is
+// gone. With format=jsonl it answers with the bare events, one per line, as
+// the audit log file holds them.
+func getAdminAudit(w http.ResponseWriter, r *http.Request, gameService *service.GameService) {
+	roomID := mux.Vars(r)["room_id"]
+	events, err := gameService.RoomAuditWithContext(r.Context(), roomID)
+	if err != nil {
+		writeErrorResponse(w, adminStatus(err), err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("format") == "jsonl" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(w)
+		for _, event := range events {
+			_ = encoder.Encode(event)
+		}
+		return
+	}
+	writeSuccessResponse(w, http.StatusOK, dto.AdminAuditDTO{RoomID: roomID, Events: events})
+}
+
+func getAdminStockfish(w http.ResponseWriter, r *http.Request, gameService *service.GameService) {
+	engines, err := gameService.StockfishStatusesWithContext(r.Context())
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	binary, binaryErr := game.StockfishBinary()
+	writeSuccessResponse(w, http.StatusOK, dto.FromAdminStockfish(binary, binaryErr, engines))
+}
+
+func adminStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrRoomNotFound), errors.Is(err, game.ErrPlayerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, game.ErrRoomNotPlaying):
+		return http.StatusConflict
+	case errors.Is(err, game.ErrCannotKickPlayer):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrAuditLogDisabled):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
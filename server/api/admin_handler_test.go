@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/tsaqiffatih/mini-game/config"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/infrastructure"
+	"github.com/tsaqiffatih/mini-game/middleware"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+func newAdminTestRouter(clients *ClientRegistry, gameService *service.GameService) *mux.Router {
+	router := mux.NewRouter()
+	cfg := config.Default()
+	cfg.Auth.AdminToken = "secret"
+	RegisterAdminRouter(router, clients, gameService, cfg)
+	return router
+}
+
+func serveAdminRequest(router http.Handler, method string, path string, body string, token string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		request.Header.Set(middleware.AdminTokenHeader, token)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestAdminRouter_RequiresToken(t *testing.T) {
+	gameService, _ := newResumeTestRoom(t)
+	router := newAdminTestRouter(NewClientRegistry(), gameService)
+
+	for _, token := range []string{"", "wrong"} {
+		if recorder := serveAdminRequest(router, http.MethodGet, "/admin/rooms", "", token); recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("GET /admin/rooms with token %q status = %d, want %d", token, recorder.Code, http.StatusUnauthorized)
+		}
+	}
+
+	recorder := serveAdminRequest(router, http.MethodGet, "/admin/rooms", "", "secret")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET /admin/rooms status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var response struct {
+		Data struct {
+			Rooms []struct {
+				RoomState string `json:"room_state"`
+				Players   []struct {
+					ID string `json:"id"`
+				} `json:"players"`
+			} `json:"rooms"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode rooms: %v", err)
+	}
+	if len(response.Data.Rooms) != 1 || response.Data.Rooms[0].RoomState != "PLAYING" || len(response.Data.Rooms[0].Players) != 2 {
+		t.Fatalf("rooms = %+v, want one playing room with two players", response.Data.Rooms)
+	}
+}
+
+func TestAdminRouter_BroadcastAndDeleteRoom(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	clients := NewClientRegistry()
+	server := newWebSocketTestServer(t, clients, gameService)
+	router := newAdminTestRouter(clients, gameService)
+
+	conn := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	defer conn.Close()
+	readTestWebSocketEvent(t, conn, EventChatHistory)
+
+	recorder := serveAdminRequest(router, http.MethodPost, "/admin/broadcast", `{"message":"Restarting in 5 minutes"}`, "secret")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("POST /admin/broadcast status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var notice EventPayload
+	if err := json.Unmarshal(readTestWebSocketEvent(t, conn, EventServerNotice).Payload, &notice); err != nil {
+		t.Fatalf("decode server_notice: %v", err)
+	}
+	if notice.Message != "Restarting in 5 minutes" {
+		t.Fatalf("notice message = %q, want %q", notice.Message, "Restarting in 5 minutes")
+	}
+
+	if recorder := serveAdminRequest(router, http.MethodDelete, "/admin/rooms/"+roomID, "", "secret"); recorder.Code != http.StatusOK {
+		t.Fatalf("DELETE /admin/rooms status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	assertWebSocketCloseCode(t, conn, CloseCodeRoomExpired)
+	if recorder := serveAdminRequest(router, http.MethodGet, "/admin/rooms/"+roomID, "", "secret"); recorder.Code != http.StatusNotFound {
+		t.Fatalf("GET deleted room status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestAdminRouter_AuditDumpsRoomEvents(t *testing.T) {
+	gameService := service.NewGameService(infrastructure.NewMemoryRoomRepository(), game.NewPlayerManager())
+	router := newAdminTestRouter(NewClientRegistry(), gameService)
+	if recorder := serveAdminRequest(router, http.MethodGet, "/admin/rooms/ROOM/audit", "", "secret"); recorder.Code != http.StatusNotFound {
+		t.Fatalf("GET audit without a log status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+
+	gameService.SetAuditLog(infrastructure.NewMemoryAuditLog(0))
+	if _, err := gameService.AddPlayer("p1"); err != nil {
+		t.Fatalf("AddPlayer() error = %v", err)
+	}
+	res, err := gameService.CreateRoomWithContext(context.Background(), "tictactoe", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	path := "/admin/rooms/" + res.Room.RoomID + "/audit"
+
+	recorder := serveAdminRequest(router, http.MethodGet, path, "", "secret")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET %s status = %d, want %d", path, recorder.Code, http.StatusOK)
+	}
+	var response struct {
+		Data struct {
+			RoomID string `json:"room_id"`
+			Events []struct {
+				Action   string `json:"action"`
+				PlayerID string `json:"player_id"`
+				Accepted bool   `json:"accepted"`
+			} `json:"events"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode audit: %v", err)
+	}
+	events := response.Data.Events
+	if response.Data.RoomID != res.Room.RoomID || len(events) != 2 || events[1].Action != "join" || events[1].PlayerID != "p1" {
+		t.Fatalf("audit = %+v, want the room opened and p1 joining", response.Data)
+	}
+
+	recorder = serveAdminRequest(router, http.MethodGet, path+"?format=jsonl", "", "secret")
+	if lines := strings.Count(recorder.Body.String(), "\n"); recorder.Code != http.StatusOK || lines != 2 {
+		t.Fatalf("GET %s?format=jsonl = %d with %d lines, want 200 with 2", path, recorder.Code, lines)
+	}
+}
+
+func TestAdminRouter_ConfigHidesSecrets(t *testing.T) {
+	gameService, _ := newResumeTestRoom(t)
+	router := newAdminTestRouter(NewClientRegistry(), gameService)
+
+	recorder := serveAdminRequest(router, http.MethodGet, "/admin/config", "", "secret")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET /admin/config status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	if strings.Contains(recorder.Body.String(), `"secret"`) {
+		t.Fatalf("GET /admin/config exposed the admin token: %s", recorder.Body.String())
+	}
+	var response struct {
+		Data struct {
+			Settings map[string]string `json:"settings"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode config: %v", err)
+	}
+	if got := response.Data.Settings["auth.admin_token"]; got != "[redacted]" {
+		t.Fatalf("auth.admin_token = %q, want [redacted]", got)
+	}
+	if got := response.Data.Settings["server.port"]; got != "8080" {
+		t.Fatalf("server.port = %q, want 8080", got)
+	}
+}
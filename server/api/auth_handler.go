@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/auth"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+func RegisterAuthRouter(r *mux.Router, authService *service.AuthService) {
+	r.HandleFunc("/auth/register", func(w http.ResponseWriter, r *http.Request) {
+		registerAccount(w, r, authService)
+	}).Methods("POST")
+
+	r.HandleFunc("/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		login(w, r, authService)
+	}).Methods("POST")
+
+	r.HandleFunc("/auth/guest", func(w http.ResponseWriter, r *http.Request) {
+		signInGuest(w, r, authService)
+	}).Methods("POST")
+}
+
+// IsPublicRoute reports whether r may be served without a session token:
+// sign-in routes and read-only requests. The websocket upgrade, room event
+// streams and a player's own rooms, private ones included, always need a
+// session because they act for a player. Admin routes need no session: they
+// check the operator token instead.
+func IsPublicRoute(r *http.Request) bool {
+	if r.URL.Path == "/admin" || strings.HasPrefix(r.URL.Path, "/admin/") {
+		return true
+	}
+	if strings.HasPrefix(r.URL.Path, "/players/") && strings.HasSuffix(r.URL.Path, "/rooms") {
+		return false
+	}
+	if strings.HasPrefix(r.URL.Path, "/room/") &&
+		(strings.HasSuffix(r.URL.Path, "/events") || strings.HasSuffix(r.URL.Path, "/chat")) {
+		return false
+	}
+	switch r.URL.Path {
+	case "/ws", "/chat/search":
+		return false
+	case "/create/user", "/auth/register", "/auth/login", "/auth/guest":
+		return true
+	}
+	return r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions
+}
+
+// IsPlayerLimitedRoute reports whether r is limited per player and
+// connection by its handler, like a websocket message, and so should skip
+// the per-IP limit: posting actions for an event stream.
+func IsPlayerLimitedRoute(r *http.Request) bool {
+	return r.Method == http.MethodPost &&
+		strings.HasPrefix(r.URL.Path, "/room/") && strings.HasSuffix(r.URL.Path, "/actions")
+}
+
+// requestPlayerID returns the player a request acts for. An authenticated
+// request acts for its session player and may only repeat that ID; without
+// the auth middleware the declared ID is used as is.
+func requestPlayerID(w http.ResponseWriter, r *http.Request, declared string) (string, bool) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		return declared, true
+	}
+	if declared != "" && declared != claims.PlayerID {
+		writeErrorResponse(w, http.StatusForbidden, "Player does not match session")
+		return "", false
+	}
+	return claims.PlayerID, true
+}
+
+func registerAccount(w http.ResponseWriter, r *http.Request, authService *service.AuthService) {
+	var request dto.CredentialsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	session, err := authService.RegisterWithContext(r.Context(), request.Username, request.Password)
+	if err != nil {
+		writeErrorResponse(w, authStatus(err), err.Error())
+		return
+	}
+
+	writeSuccessResponse(w, http.StatusCreated, dto.FromSession(session.Token, session.Claims, session.Player))
+}
+
+func login(w http.ResponseWriter, r *http.Request, authService *service.AuthService) {
+	var request dto.CredentialsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	session, err := authService.LoginWithContext(r.Context(), request.Username, request.Password)
+	if err != nil {
+		writeErrorResponse(w, authStatus(err), err.Error())
+		return
+	}
+
+	writeSuccessResponse(w, http.StatusOK, dto.FromSession(session.Token, session.Claims, session.Player))
+}
+
+func signInGuest(w http.ResponseWriter, r *http.Request, authService *service.AuthService) {
+	var request dto.GuestRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	session, err := authService.GuestWithContext(r.Context(), request.PlayerID)
+	if err != nil {
+		writeErrorResponse(w, authStatus(err), err.Error())
+		return
+	}
+
+	writeSuccessResponse(w, http.StatusCreated, dto.FromSession(session.Token, session.Claims, session.Player))
+}
+
+func authStatus(err error) int {
+	switch {
+	case errors.Is(err, auth.ErrInvalidPassword):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrUsernameTaken):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+// getRoomChat pages back through a room's chat as the requesting player saw
+// it, the room being live or not.
+func getRoomChat(w http.ResponseWriter, r *http.Request, gameService *service.GameService) {
+	roomID := mux.Vars(r)["room_id"]
+	query := r.URL.Query()
+	playerID, ok := requestPlayerID(w, r, query.Get("player_id"))
+	if !ok || !validateRoomAndPlayerIDs(w, roomID, playerID) {
+		return
+	}
+	limit, ok := chatLimitFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := gameService.ChatPageWithContext(r.Context(), roomID, playerID, query.Get("before"), limit)
+	if err != nil {
+		writeErrorResponse(w, chatErrorStatus(err), err.Error())
+		return
+	}
+	writeSuccessResponse(w, http.StatusOK, dto.FromChatPage(page))
+}
+
+// searchChat finds the requesting player's messages and the ones they were
+// shown, in every room.
+func searchChat(w http.ResponseWriter, r *http.Request, gameService *service.GameService) {
+	query := r.URL.Query()
+	playerID, ok := requestPlayerID(w, r, query.Get("player_id"))
+	if !ok {
+		return
+	}
+	if playerID == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "playerID is required")
+		return
+	}
+	limit, ok := chatLimitFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	messages, err := gameService.SearchChatWithContext(r.Context(), playerID, query.Get("q"), limit)
+	if err != nil {
+		writeErrorResponse(w, chatErrorStatus(err), err.Error())
+		return
+	}
+	writeSuccessResponse(w, http.StatusOK, dto.FromChatSearch(messages))
+}
+
+func chatLimitFromQuery(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return 0, true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 0 {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid limit")
+		return 0, false
+	}
+	return limit, true
+}
+
+func chatErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, game.ErrChatMessageNotFound), errors.Is(err, service.ErrChatQueryEmpty):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrChatStoreDisabled):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/infrastructure"
+	"github.com/tsaqiffatih/mini-game/middleware"
+)
+
+func TestChatAPI_PagesRoomHistoryForSignedInPlayers(t *testing.T) {
+	server := newAPITestServer()
+	server.service.SetChatStore(infrastructure.NewMemoryChatStore(infrastructure.DefaultChatRetention))
+	server.router.Use(middleware.Authenticate(server.auth, IsPublicRoute))
+	session, err := server.auth.GuestWithContext(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("GuestWithContext() error = %v", err)
+	}
+	res, err := server.service.CreateRoomWithContext(context.Background(), "tictactoe", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	roomID := res.Room.RoomID
+	for _, message := range []string{"one", "two", "three"} {
+		if _, err := server.service.HandleChatMessageWithContext(context.Background(), roomID, "p1", message, nil); err != nil {
+			t.Fatalf("HandleChatMessageWithContext(%q) error = %v", message, err)
+		}
+	}
+
+	if recorder := doJSONRequest(t, server.router, http.MethodGet, "/room/"+roomID+"/chat?player_id=p1", nil); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("chat without token status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+
+	recorder := doAuthorizedJSONRequest(t, server.router, session.Token, http.MethodGet, "/room/"+roomID+"/chat?limit=2", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("chat status = %d, want %d; body=%s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	var response apiResponse
+	decodeJSONResponse(t, recorder, &response)
+	var page dto.ChatPageDTO
+	if err := json.Unmarshal(response.Data, &page); err != nil {
+		t.Fatalf("decode chat page: %v", err)
+	}
+	if len(page.Messages) != 2 || page.Messages[0].Message != "two" || !page.HasMore || page.NextBefore != page.Messages[0].ID {
+		t.Fatalf("chat page = %+v, want two and three with one more before", page)
+	}
+
+	recorder = doAuthorizedJSONRequest(t, server.router, session.Token, http.MethodGet, "/room/"+roomID+"/chat?before="+page.NextBefore, nil)
+	decodeJSONResponse(t, recorder, &response)
+	if err := json.Unmarshal(response.Data, &page); err != nil {
+		t.Fatalf("decode chat page: %v", err)
+	}
+	if len(page.Messages) != 1 || page.Messages[0].Message != "one" || page.HasMore {
+		t.Fatalf("earlier chat page = %+v, want only one", page)
+	}
+}
@@ -1,6 +1,11 @@
 package api
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
 	"sync"
 	"time"
 
@@ -9,144 +14,412 @@ import (
 )
 
 type Client struct {
-	PlayerID   string
-	Generation uint64
-	Conn       *websocket.Conn
-	Send       chan []byte
-	done       chan struct{}
-	close      sync.Once
+	PlayerID     string
+	RoomID       string
+	ConnectionID string
+	Generation   uint64
+	Conn         *websocket.Conn
+	Send         chan []byte
+	encoder      *eventEncoder
+	// clients is the registry the client was attached to; relay marks a
+	// stand-in for a connection on another node, see newRelayClient.
+	clients *ClientRegistry
+	relay   bool
+	done    chan struct{}
+	close   sync.Once
+	// closeCode and closeReason are what CloseAfterQueued closes with once
+	// the write pump reaches its nil message.
+	closeCode   int
+	closeReason string
+	// abuse rate limits the connection and counts its rejected messages.
+	abuse connectionAbuse
 }
 
+// maxConnectionsPerRoom bounds the tabs and devices one player can have
+// open on one room. The oldest connection makes way for a new one.
+const maxConnectionsPerRoom = 4
+
+// clientKey identifies one player's presence in one room.
+type clientKey struct {
+	roomID   string
+	playerID string
+}
+
+// ClientRegistry tracks connections, websockets and event streams alike, by
+// player, room and connection ID. A player may be connected to several rooms, and to one room
+// from several tabs; events for a player in a room fan out to all of them.
+//
+// Generations count the connections of a player to a room. A delayed removal
+// only proceeds while no connection is open and no newer one was made.
 type ClientRegistry struct {
-	clients     map[string]*Client
-	generations map[string]uint64
-	mu          sync.RWMutex
+	clients     map[clientKey]map[string]*Client
+	remote      map[clientKey]map[string]struct{}
+	generations map[clientKey]uint64
+	logs        map[string]*roomEventLog
+	commands    map[string]*commandCache
+	connections int
+	cluster     *Cluster
+	// messageLimits and chatLimits rate limit what each player sends.
+	messageLimits *messageLimiter
+	chatLimits    *messageLimiter
+	// connectionLimit and abusePolicy apply to each connection on its own.
+	connectionLimit MessageRateLimit
+	abusePolicy     AbusePolicy
+	mu              sync.RWMutex
 }
 
 func NewClientRegistry() *ClientRegistry {
 	return &ClientRegistry{
-		clients:     make(map[string]*Client),
-		generations: make(map[string]uint64),
+		clients:         make(map[clientKey]map[string]*Client),
+		remote:          make(map[clientKey]map[string]struct{}),
+		generations:     make(map[clientKey]uint64),
+		logs:            make(map[string]*roomEventLog),
+		commands:        make(map[string]*commandCache),
+		messageLimits:   newMessageLimiter(DefaultMessageRateLimit),
+		chatLimits:      newMessageLimiter(DefaultChatRateLimit),
+		connectionLimit: DefaultConnectionRateLimit,
+		abusePolicy:     DefaultAbusePolicy,
 	}
 }
 
-func (r *ClientRegistry) Attach(playerID string, conn *websocket.Conn, pongWait time.Duration) *Client {
-	return r.AttachWithGeneration(playerID, 0, conn, pongWait)
+func (r *ClientRegistry) Attach(roomID string, playerID string, conn *websocket.Conn, pongWait time.Duration) *Client {
+	return r.AttachWithGeneration(roomID, playerID, 0, conn, pongWait)
 }
 
-func (r *ClientRegistry) AttachWithGeneration(playerID string, generation uint64, conn *websocket.Conn, pongWait time.Duration) *Client {
+func (r *ClientRegistry) AttachWithGeneration(roomID string, playerID string, generation uint64, conn *websocket.Conn, pongWait time.Duration) *Client {
 	if generation == 0 {
-		generation = r.NextGeneration(playerID)
+		generation = r.NextGeneration(roomID, playerID)
 	}
 
-	client := &Client{
-		PlayerID:   playerID,
-		Generation: generation,
-		Conn:       conn,
-		Send:       make(chan []byte, 256),
-		done:       make(chan struct{}),
-	}
+	client := newClient(roomID, playerID, generation, conn.Subprotocol())
+	client.Conn = conn
 
+	conn.SetReadLimit(maxMessageSize)
 	conn.SetReadDeadline(time.Now().Add(pongWait))
 	conn.SetPongHandler(func(string) error {
 		conn.SetReadDeadline(time.Now().Add(pongWait))
 		return nil
 	})
 
-	var existing *Client
-	r.mu.Lock()
-	if generation > r.generations[playerID] {
-		r.generations[playerID] = generation
+	r.attach(client)
+	return client
+}
+
+// AttachStream registers a connection without a websocket, such as an event
+// stream. Its events are read from Send by the stream's handler.
+func (r *ClientRegistry) AttachStream(roomID string, playerID string, generation uint64) *Client {
+	if generation == 0 {
+		generation = r.NextGeneration(roomID, playerID)
 	}
-	existing = r.clients[playerID]
-	r.clients[playerID] = client
-	r.mu.Unlock()
 
-	if existing != nil {
-		observability.Logger().Info("websocket duplicate connection replaced",
-			"room_id", "",
-			"player_id", playerID,
-			"event_type", "websocket_duplicate_replaced",
+	client := newClient(roomID, playerID, generation, SubprotocolJSON)
+	r.attach(client)
+	return client
+}
+
+func newClient(roomID string, playerID string, generation uint64, protocol string) *Client {
+	return &Client{
+		PlayerID:     playerID,
+		RoomID:       roomID,
+		ConnectionID: newConnectionID(),
+		Generation:   generation,
+		Send:         make(chan []byte, 256),
+		encoder:      newEventEncoder(protocol),
+		done:         make(chan struct{}),
+	}
+}
+
+func (r *ClientRegistry) attach(client *Client) {
+	if oldest := r.add(client); oldest != nil {
+		observability.Logger().Info("websocket connection limit reached, oldest connection closed",
+			"room_id", client.RoomID,
+			"player_id", client.PlayerID,
+			"event_type", "websocket_connection_replaced",
 			"close_code", CloseCodeDuplicateConnection,
+			"connection_id", oldest.ConnectionID,
 		)
-		existing.CloseWithCode(CloseCodeDuplicateConnection, "duplicate connection")
+		oldest.CloseWithCode(CloseCodeDuplicateConnection, "too many connections")
 	}
+}
 
-	return client
+// add registers client and returns the connection it displaced, if the
+// player already had maxConnectionsPerRoom open on the room.
+func (r *ClientRegistry) add(client *Client) *Client {
+	key := clientKey{roomID: client.RoomID, playerID: client.PlayerID}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	client.clients = r
+	if client.Generation > r.generations[key] {
+		r.generations[key] = client.Generation
+	}
+	connections := r.clients[key]
+	if connections == nil {
+		connections = make(map[string]*Client)
+		r.clients[key] = connections
+	}
+
+	var oldest *Client
+	if len(connections) >= maxConnectionsPerRoom {
+		for _, existing := range connections {
+			if oldest == nil || existing.Generation < oldest.Generation {
+				oldest = existing
+			}
+		}
+		delete(connections, oldest.ConnectionID)
+		r.connections--
+	}
+	connections[client.ConnectionID] = client
+	r.connections++
+	observability.WebSocketClients.Set(float64(r.connections))
+	return oldest
 }
 
-func (r *ClientRegistry) NextGeneration(playerID string) uint64 {
+func (r *ClientRegistry) NextGeneration(roomID string, playerID string) uint64 {
+	key := clientKey{roomID: roomID, playerID: playerID}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.generations[playerID]++
-	return r.generations[playerID]
+	r.generations[key]++
+	return r.generations[key]
 }
 
-func (r *ClientRegistry) Get(playerID string) (*Client, bool) {
+// Generation returns the latest connection generation of the player in the
+// room.
+func (r *ClientRegistry) Generation(roomID string, playerID string) uint64 {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	client, exists := r.clients[playerID]
-	return client, exists
+	return r.generations[clientKey{roomID: roomID, playerID: playerID}]
 }
 
-func (r *ClientRegistry) IsConnected(playerID string) bool {
-	_, exists := r.Get(playerID)
-	return exists
+// RoomClients returns the player's local connections to the room.
+func (r *ClientRegistry) RoomClients(roomID string, playerID string) []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	connections := r.clients[clientKey{roomID: roomID, playerID: playerID}]
+	clients := make([]*Client, 0, len(connections))
+	for _, client := range connections {
+		clients = append(clients, client)
+	}
+	return clients
 }
 
-func (r *ClientRegistry) IsCurrentDisconnectedGeneration(playerID string, generation uint64) bool {
+// Connection returns the player's local connection to the room with the
+// given ID, or nil.
+func (r *ClientRegistry) Connection(roomID string, playerID string, connectionID string) *Client {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	if _, exists := r.clients[playerID]; exists {
+	return r.clients[clientKey{roomID: roomID, playerID: playerID}][connectionID]
+}
+
+// PlayerClients returns the player's local connections to every room.
+func (r *ClientRegistry) PlayerClients(playerID string) []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var clients []*Client
+	for key, connections := range r.clients {
+		if key.playerID != playerID {
+			continue
+		}
+		for _, client := range connections {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// ConnectionCount returns how many connections this node holds.
+func (r *ClientRegistry) ConnectionCount() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.connections
+}
+
+// AllClients returns every local connection, in any room.
+func (r *ClientRegistry) AllClients() []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clients := make([]*Client, 0, r.connections)
+	for _, connections := range r.clients {
+		for _, client := range connections {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// Broadcast sends an event to every local connection, reporting how many it
+// reached. Connections held by other nodes of the cluster are not reached.
+func (r *ClientRegistry) Broadcast(ctx context.Context, eventType string, payload interface{}) int {
+	messageBytes, err := json.Marshal(Event{
+		Type:    eventType,
+		Payload: marshalPayload(payload),
+		TraceID: observability.TraceID(ctx),
+	})
+	if err != nil {
+		observability.Logger().Warn("websocket event marshal failed",
+			"room_id", "",
+			"player_id", "",
+			"event_type", eventType,
+			"error", err,
+		)
+		return 0
+	}
+
+	sent := 0
+	for _, client := range r.AllClients() {
+		if enqueueEvent(client, eventType, messageBytes) {
+			sent++
+			continue
+		}
+		r.RemoveClient(client)
+	}
+	return sent
+}
+
+// IsConnected reports whether the player has any connection to the room,
+// on this node or, as far as this node owns the room, on another one.
+func (r *ClientRegistry) IsConnected(roomID string, playerID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.connectedLocked(clientKey{roomID: roomID, playerID: playerID})
+}
+
+func (r *ClientRegistry) connectedLocked(key clientKey) bool {
+	return len(r.clients[key]) > 0 || len(r.remote[key]) > 0
+}
+
+func (r *ClientRegistry) IsCurrentDisconnectedGeneration(roomID string, playerID string, generation uint64) bool {
+	key := clientKey{roomID: roomID, playerID: playerID}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.connectedLocked(key) {
 		return false
 	}
-	return r.generations[playerID] == generation
+	return r.generations[key] == generation
+}
+
+// hasRemote reports whether the player has connections to the room on
+// other nodes.
+func (r *ClientRegistry) hasRemote(roomID string, playerID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.remote[clientKey{roomID: roomID, playerID: playerID}]) > 0
 }
 
-func (r *ClientRegistry) Remove(playerID string) {
+// attachRemote records a connection to a room owned here that is held by
+// another node.
+func (r *ClientRegistry) attachRemote(roomID string, playerID string, connectionID string) {
+	key := clientKey{roomID: roomID, playerID: playerID}
+
 	r.mu.Lock()
-	client, exists := r.clients[playerID]
-	if exists {
-		delete(r.clients, playerID)
+	defer r.mu.Unlock()
+
+	if r.remote[key] == nil {
+		r.remote[key] = make(map[string]struct{})
 	}
-	r.mu.Unlock()
+	r.remote[key][connectionID] = struct{}{}
+}
 
-	if exists {
-		client.Close()
+func (r *ClientRegistry) detachRemote(roomID string, playerID string, connectionID string) {
+	key := clientKey{roomID: roomID, playerID: playerID}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.remote[key], connectionID)
+	if len(r.remote[key]) == 0 {
+		delete(r.remote, key)
 	}
 }
 
+// Cluster returns the cluster the registry relays events through, or nil
+// on a single node.
+func (r *ClientRegistry) Cluster() *Cluster {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cluster
+}
+
+func (r *ClientRegistry) setCluster(cluster *Cluster) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cluster = cluster
+}
+
+// RemoveClient unregisters client and closes it. It reports false when the
+// client was already gone, for example displaced by a newer connection.
 func (r *ClientRegistry) RemoveClient(client *Client) bool {
+	if !r.remove(client) {
+		return false
+	}
+	client.Close()
+	return true
+}
+
+func (r *ClientRegistry) remove(client *Client) bool {
+	key := clientKey{roomID: client.RoomID, playerID: client.PlayerID}
+
 	r.mu.Lock()
-	current, exists := r.clients[client.PlayerID]
-	if exists && current == client {
-		delete(r.clients, client.PlayerID)
+	defer r.mu.Unlock()
+
+	connections := r.clients[key]
+	if connections[client.ConnectionID] != client {
+		return false
+	}
+	delete(connections, client.ConnectionID)
+	if len(connections) == 0 {
+		delete(r.clients, key)
 	}
-	r.mu.Unlock()
+	r.connections--
+	observability.WebSocketClients.Set(float64(r.connections))
+	return true
+}
 
-	if exists && current == client {
+func (r *ClientRegistry) CloseAll() {
+	for _, client := range r.detachAll() {
 		client.Close()
-		return true
 	}
-
-	return false
 }
 
-func (r *ClientRegistry) CloseAll() {
+// detachAll empties the registry without marking anyone disconnected, and
+// returns the connections it held.
+func (r *ClientRegistry) detachAll() []*Client {
 	r.mu.Lock()
-	clients := make([]*Client, 0, len(r.clients))
-	for playerID, client := range r.clients {
-		delete(r.clients, playerID)
-		clients = append(clients, client)
+	defer r.mu.Unlock()
+
+	clients := make([]*Client, 0, r.connections)
+	for key, connections := range r.clients {
+		for _, client := range connections {
+			clients = append(clients, client)
+		}
+		delete(r.clients, key)
 	}
-	r.mu.Unlock()
+	r.connections = 0
+	observability.WebSocketClients.Set(0)
+	return clients
+}
 
-	for _, client := range clients {
-		client.Close()
+func newConnectionID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
 	}
+	return hex.EncodeToString(id)
 }
 
 func (c *Client) Enqueue(message []byte) bool {
@@ -162,6 +435,7 @@ func (c *Client) Enqueue(message []byte) bool {
 	case <-c.done:
 		return false
 	default:
+		observability.WebSocketEnqueueDrops.Inc()
 		c.Close()
 		return false
 	}
@@ -183,6 +457,43 @@ func (c *Client) CloseWithCode(code int, reason string) {
 	})
 }
 
+// CloseAfterQueued closes the connection with code once the events queued
+// before it are written. With the queue full it closes at once.
+func (c *Client) CloseAfterQueued(code int, reason string) {
+	c.closeCode = code
+	c.closeReason = reason
+	if !c.Enqueue(nil) {
+		c.CloseWithCode(code, reason)
+	}
+}
+
+// waitClosed waits up to timeout for the connection to close, as it does
+// once the events queued before CloseAfterQueued are written.
+func (c *Client) waitClosed(timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-c.done:
+	case <-timer.C:
+	}
+}
+
+// RequestResync makes the next snapshot sent to the client a full one,
+// after the client saw a gap in state versions.
+func (c *Client) RequestResync() {
+	if c.encoder != nil {
+		c.encoder.requestResync()
+	}
+}
+
+func (c *Client) encode(message []byte) (int, []byte, error) {
+	if c.encoder == nil {
+		return websocket.TextMessage, message, nil
+	}
+	return c.encoder.encode(message)
+}
+
 func (c *Client) WritePump(writeWait time.Duration, pingPeriod time.Duration) {
 	ticker := time.NewTicker(pingPeriod)
 
@@ -205,6 +516,10 @@ func (c *Client) WritePump(writeWait time.Duration, pingPeriod time.Duration) {
 		case <-c.done:
 			return
 		case message := <-c.Send:
+			if message == nil {
+				c.CloseWithCode(c.closeCode, c.closeReason)
+				return
+			}
 			if err := c.Conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
 				observability.Logger().Warn("websocket write deadline failed",
 					"room_id", "",
@@ -215,7 +530,17 @@ func (c *Client) WritePump(writeWait time.Duration, pingPeriod time.Duration) {
 				return
 			}
 
-			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			messageType, data, err := c.encode(message)
+			if err != nil {
+				observability.Logger().Warn("websocket event encode failed",
+					"room_id", c.RoomID,
+					"player_id", c.PlayerID,
+					"event_type", "websocket_encode_error",
+					"error", err,
+				)
+				continue
+			}
+			if err := c.Conn.WriteMessage(messageType, data); err != nil {
 				observability.Logger().Warn("websocket write failed",
 					"room_id", "",
 					"player_id", c.PlayerID,
@@ -0,0 +1,674 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tsaqiffatih/mini-game/actions"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/backplane"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+const (
+	clusterEventsChannel  = "events"
+	clusterRequestTimeout = 5 * time.Second
+	// clusterLobbyRefresh is how often a node announces its open rooms again.
+	// Another node's rooms leave the lobby after three periods unannounced.
+	clusterLobbyRefresh = 10 * time.Second
+)
+
+// Kinds of cluster messages. Deliver, lobby and lobby sync go to every node
+// on the events channel; the rest are addressed to one node's channel.
+const (
+	clusterDeliver    = "deliver"
+	clusterLobby      = "lobby"
+	clusterLobbySync  = "lobby_sync"
+	clusterConnect    = "connect"
+	clusterConnected  = "connected"
+	clusterMessage    = "message"
+	clusterDisconnect = "disconnect"
+	clusterJoin       = "join"
+	clusterReply      = "reply"
+)
+
+var ErrRoomOwnerUnavailable = errors.New("Room owner did not respond")
+
+type clusterEnvelope struct {
+	Kind         string            `json:"kind"`
+	Origin       string            `json:"origin"`
+	RequestID    uint64            `json:"request_id,omitempty"`
+	RoomID       string            `json:"room_id,omitempty"`
+	PlayerID     string            `json:"player_id,omitempty"`
+	ConnectionID string            `json:"connection_id,omitempty"`
+	GameType     string            `json:"game_type,omitempty"`
+	SessionNonce string            `json:"session_nonce,omitempty"`
+	Generation   uint64            `json:"generation,omitempty"`
+	EventType    string            `json:"event_type,omitempty"`
+	Data         json.RawMessage   `json:"data,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	Status       int               `json:"status,omitempty"`
+	CloseCode    int               `json:"close_code,omitempty"`
+	CloseReason  string            `json:"close_reason,omitempty"`
+	LastSeq      *uint64           `json:"last_seq,omitempty"`
+	Trace        map[string]string `json:"trace,omitempty"`
+}
+
+// Cluster joins this node's ClientRegistry to the other replicas through a
+// backplane. A room lives on the node holding its lease: websockets and joins
+// for the room on any other node are routed there, and the events the owner
+// produces are relayed back to the nodes holding each player's connections.
+type Cluster struct {
+	nodeID      string
+	backplane   backplane.Backplane
+	clients     *ClientRegistry
+	gameService *service.GameService
+	lobby       *service.LobbyService
+	ctx         context.Context
+	pending     map[uint64]chan clusterEnvelope
+	nextRequest uint64
+	unsubscribe []func()
+	mu          sync.Mutex
+}
+
+func NewCluster(nodeID string, bp backplane.Backplane, clients *ClientRegistry, gameService *service.GameService) *Cluster {
+	cluster := &Cluster{
+		nodeID:      nodeID,
+		backplane:   bp,
+		clients:     clients,
+		gameService: gameService,
+		ctx:         context.Background(),
+		pending:     make(map[uint64]chan clusterEnvelope),
+	}
+	clients.setCluster(cluster)
+	return cluster
+}
+
+func (c *Cluster) NodeID() string {
+	return c.nodeID
+}
+
+// SetLobby shares the lobby with the other nodes: this node's open rooms are
+// announced to them and theirs are listed here. Call it before Start.
+func (c *Cluster) SetLobby(lobby *service.LobbyService) {
+	c.lobby = lobby
+	lobby.SetLocalChangeNotifier(c.announceLobbyUpdate)
+}
+
+// Start subscribes to the cluster channels. Routed commands run with ctx.
+func (c *Cluster) Start(ctx context.Context) error {
+	c.ctx = ctx
+
+	stopEvents, err := c.backplane.Subscribe(ctx, clusterEventsChannel, c.handleEvent)
+	if err != nil {
+		return err
+	}
+	stopCommands, err := c.backplane.Subscribe(ctx, backplane.NodeChannel(c.nodeID), c.handleCommand)
+	if err != nil {
+		stopEvents()
+		return err
+	}
+
+	c.mu.Lock()
+	c.unsubscribe = append(c.unsubscribe, stopEvents, stopCommands)
+	c.mu.Unlock()
+
+	if c.lobby != nil {
+		lobbyCtx, stopLobby := context.WithCancel(ctx)
+		c.mu.Lock()
+		c.unsubscribe = append(c.unsubscribe, stopLobby)
+		c.mu.Unlock()
+		// The other nodes answer with their open rooms, so the lobby here
+		// is complete without waiting for their next refresh.
+		_ = c.publish(ctx, clusterEventsChannel, clusterEnvelope{Kind: clusterLobbySync})
+		go c.refreshLobby(lobbyCtx)
+	}
+	return nil
+}
+
+func (c *Cluster) Stop() {
+	c.mu.Lock()
+	unsubscribe := c.unsubscribe
+	c.unsubscribe = nil
+	c.mu.Unlock()
+
+	for _, stop := range unsubscribe {
+		stop()
+	}
+}
+
+// remoteOwner reports the node owning roomID when it is not this one.
+func (c *Cluster) remoteOwner(ctx context.Context, roomID string) (string, bool) {
+	owner, err := c.backplane.LeaseOwner(ctx, backplane.RoomLease(roomID))
+	if err != nil {
+		observability.Logger().WarnContext(ctx, "room owner lookup failed",
+			"room_id", roomID,
+			"player_id", "",
+			"event_type", "cluster_owner_error",
+			"error", err,
+		)
+		return "", false
+	}
+	return owner, owner != "" && owner != c.nodeID
+}
+
+// relay hands an event to the other nodes, which deliver it to the player's
+// connections to roomID, or to all of the player's connections when roomID
+// is empty.
+func (c *Cluster) relay(ctx context.Context, roomID string, playerID string, eventType string, messageBytes []byte) {
+	if err := c.publish(ctx, clusterEventsChannel, clusterEnvelope{
+		Kind:      clusterDeliver,
+		RoomID:    roomID,
+		PlayerID:  playerID,
+		EventType: eventType,
+		Data:      messageBytes,
+	}); err == nil {
+		observability.WebSocketMessagesSent.WithLabelValues(eventType).Inc()
+	}
+}
+
+// closeRemote closes the connections of the player to the room held by
+// other nodes.
+func (c *Cluster) closeRemote(ctx context.Context, roomID string, playerID string, code int, reason string) {
+	_ = c.publish(ctx, clusterEventsChannel, clusterEnvelope{
+		Kind:        clusterDeliver,
+		RoomID:      roomID,
+		PlayerID:    playerID,
+		CloseCode:   code,
+		CloseReason: reason,
+	})
+}
+
+// serveRemoteWebSocket runs a websocket whose room lives on owner. The
+// connection stays here; its messages are forwarded to the owner and the
+// owner's events come back through relay.
+func (c *Cluster) serveRemoteWebSocket(ctx context.Context, conn *websocket.Conn, owner string, roomID string, playerID string, lastSeq uint64, resume bool) {
+	client, code, reason := c.connectRemote(ctx, owner, roomID, playerID, lastSeq, resume, func(generation uint64) *Client {
+		client := c.clients.AttachWithGeneration(roomID, playerID, generation, conn, websocketTimeouts.PongWait)
+		go client.WritePump(websocketTimeouts.WriteWait, websocketTimeouts.PingPeriod)
+		return client
+	})
+	if client == nil {
+		closeWebsocketWithCode(conn, code, reason)
+		return
+	}
+
+	result := c.forwardMessages(ctx, conn, client, owner, roomID)
+	observability.Logger().InfoContext(ctx, "websocket disconnected",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", "websocket_disconnected",
+		"close_code", result.CloseCode,
+		"close_reason", result.CloseReason,
+		"generation", client.Generation,
+		"owner_node", owner,
+	)
+	c.disconnectRemote(ctx, owner, client)
+}
+
+// connectRemote registers a connection with the owner of its room and
+// attaches it here. Without a client it returns the close code the owner
+// rejected the connection with.
+func (c *Cluster) connectRemote(ctx context.Context, owner string, roomID string, playerID string, lastSeq uint64, resume bool, attach func(generation uint64) *Client) (*Client, int, string) {
+	reply, err := c.request(ctx, owner, clusterEnvelope{
+		Kind:     clusterConnect,
+		RoomID:   roomID,
+		PlayerID: playerID,
+	})
+	if err != nil {
+		observability.Logger().WarnContext(ctx, "websocket session rejected",
+			"room_id", roomID,
+			"player_id", playerID,
+			"event_type", "websocket_session_rejected",
+			"owner_node", owner,
+			"error", err,
+		)
+		return nil, websocket.CloseTryAgainLater, "room owner unavailable"
+	}
+	if reply.CloseCode != 0 {
+		observability.Logger().WarnContext(ctx, "websocket session rejected",
+			"room_id", roomID,
+			"player_id", playerID,
+			"event_type", "websocket_session_rejected",
+			"owner_node", owner,
+			"close_code", reply.CloseCode,
+			"close_reason", reply.CloseReason,
+		)
+		return nil, reply.CloseCode, reply.CloseReason
+	}
+
+	client := attach(reply.Generation)
+
+	observability.Logger().InfoContext(ctx, "websocket connected",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", "websocket_connected",
+		"generation", client.Generation,
+		"connection_id", client.ConnectionID,
+		"owner_node", owner,
+	)
+
+	// The owner sends the initial snapshot only now, so the relayed events
+	// find the client attached.
+	connected := clusterEnvelope{
+		Kind:         clusterConnected,
+		RoomID:       roomID,
+		PlayerID:     playerID,
+		ConnectionID: client.ConnectionID,
+		EventType:    reply.EventType,
+	}
+	if resume {
+		connected.LastSeq = &lastSeq
+	}
+	_ = c.publish(ctx, backplane.NodeChannel(owner), connected)
+	return client, 0, ""
+}
+
+// disconnectRemote detaches a connection to a room owned by owner. The
+// owner is told even when the connection was displaced, since it tracks
+// every remote connection by ID.
+func (c *Cluster) disconnectRemote(ctx context.Context, owner string, client *Client) {
+	c.clients.RemoveClient(client)
+	_ = c.publish(ctx, backplane.NodeChannel(owner), clusterEnvelope{
+		Kind:         clusterDisconnect,
+		RoomID:       client.RoomID,
+		PlayerID:     client.PlayerID,
+		ConnectionID: client.ConnectionID,
+	})
+}
+
+func (c *Cluster) forwardMessages(ctx context.Context, conn *websocket.Conn, client *Client, owner string, roomID string) websocketReadResult {
+	for {
+		messageType, msg, err := conn.ReadMessage()
+		if err != nil {
+			return websocketReadCloseResult(err)
+		}
+
+		message, err := decodeWebSocketMessage(messageType, msg)
+		if err != nil {
+			observability.WebSocketMessagesReceived.WithLabelValues("invalid").Inc()
+			switch c.clients.penalize(ctx, client, "invalid message") {
+			case penaltyWarn:
+				sendErrorMessage(ctx, client, "Invalid message format")
+			case penaltyClose:
+				client.waitClosed(closeFrameWait)
+				return websocketReadResult{CloseCode: CloseCodeAbuse, CloseReason: abuseCloseReason}
+			}
+			continue
+		}
+		// Limits apply where the connection is held, before the message
+		// travels to the owner.
+		allowed, closing := c.clients.allowMessage(ctx, roomID, client.PlayerID, client, message)
+		if closing {
+			client.waitClosed(closeFrameWait)
+			return websocketReadResult{CloseCode: CloseCodeAbuse, CloseReason: abuseCloseReason}
+		}
+		if !allowed {
+			continue
+		}
+		c.forwardMessage(ctx, owner, client, message)
+	}
+}
+
+// forwardMessage hands a message of a connection held here to the owner of
+// its room.
+func (c *Cluster) forwardMessage(ctx context.Context, owner string, client *Client, message WebSocketMessage) {
+	if message.Type == actions.RESYNC_REQUEST {
+		client.RequestResync()
+	}
+
+	_ = c.publish(ctx, backplane.NodeChannel(owner), clusterEnvelope{
+		Kind:         clusterMessage,
+		RoomID:       client.RoomID,
+		PlayerID:     client.PlayerID,
+		ConnectionID: client.ConnectionID,
+		Data:         marshalPayload(message),
+	})
+}
+
+// forwardJoin answers a join request for a room owned by another node with
+// the owner's response. sessionNonce is the nonce of the guest session the
+// player joined with, so the owner accepts that guest's tokens too.
+func (c *Cluster) forwardJoin(w http.ResponseWriter, ctx context.Context, owner string, roomID string, playerID string, sessionNonce string, gameType string, access service.RoomAccess) {
+	reply, err := c.request(ctx, owner, clusterEnvelope{
+		Kind:         clusterJoin,
+		RoomID:       roomID,
+		PlayerID:     playerID,
+		GameType:     gameType,
+		SessionNonce: sessionNonce,
+		Data:         marshalPayload(access),
+	})
+	if err != nil {
+		writeErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if reply.Error != "" {
+		writeErrorResponse(w, reply.Status, reply.Error)
+		return
+	}
+
+	writeSuccessResponse(w, http.StatusOK, reply.Data)
+}
+
+func (c *Cluster) handleEvent(data []byte) {
+	envelope, ok := c.decode(data)
+	if !ok || envelope.Origin == c.nodeID {
+		return
+	}
+
+	switch envelope.Kind {
+	case clusterDeliver:
+	case clusterLobby:
+		c.handleLobby(envelope)
+		return
+	case clusterLobbySync:
+		if c.lobby != nil {
+			// Publishing from here could block on this node's own
+			// subscription, which is busy running this handler.
+			go c.announceLobby(c.ctx)
+		}
+		return
+	default:
+		return
+	}
+
+	targets := c.clients.PlayerClients(envelope.PlayerID)
+	if envelope.RoomID != "" {
+		targets = c.clients.RoomClients(envelope.RoomID, envelope.PlayerID)
+	}
+	for _, client := range targets {
+		if envelope.ConnectionID != "" && client.ConnectionID != envelope.ConnectionID {
+			continue
+		}
+		if envelope.CloseCode != 0 {
+			client.CloseWithCode(envelope.CloseCode, envelope.CloseReason)
+			continue
+		}
+		if !client.Enqueue(envelope.Data) {
+			c.clients.RemoveClient(client)
+		}
+	}
+}
+
+// handleLobby lists or unlists a room another node announced.
+func (c *Cluster) handleLobby(envelope clusterEnvelope) {
+	if c.lobby == nil {
+		return
+	}
+
+	update := service.LobbyUpdate{RoomID: envelope.RoomID}
+	if len(envelope.Data) > 0 {
+		var room service.LobbyRoom
+		if err := json.Unmarshal(envelope.Data, &room); err != nil {
+			return
+		}
+		update.Room = &room
+	}
+	c.lobby.HandleRemoteUpdate(envelope.Origin, update)
+}
+
+// announceLobbyUpdate tells the other nodes that a room of this node
+// entered, changed in or left the lobby.
+func (c *Cluster) announceLobbyUpdate(ctx context.Context, update service.LobbyUpdate) {
+	envelope := clusterEnvelope{Kind: clusterLobby, RoomID: update.RoomID}
+	if update.Room != nil {
+		envelope.Data = marshalPayload(update.Room)
+	}
+	_ = c.publish(ctx, clusterEventsChannel, envelope)
+}
+
+// announceLobby tells the other nodes about every open room of this node.
+func (c *Cluster) announceLobby(ctx context.Context) {
+	rooms, err := c.lobby.LocalRoomsWithContext(ctx)
+	if err != nil {
+		return
+	}
+	for i := range rooms {
+		c.announceLobbyUpdate(ctx, service.LobbyUpdate{RoomID: rooms[i].RoomID, Room: &rooms[i]})
+	}
+}
+
+// refreshLobby announces this node's open rooms each clusterLobbyRefresh
+// until ctx is done, and drops the rooms of nodes that went quiet.
+func (c *Cluster) refreshLobby(ctx context.Context) {
+	ticker := time.NewTicker(clusterLobbyRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.announceLobby(ctx)
+			c.lobby.ExpireRemote(time.Now().Add(-3 * clusterLobbyRefresh))
+		}
+	}
+}
+
+func (c *Cluster) handleCommand(data []byte) {
+	envelope, ok := c.decode(data)
+	if !ok {
+		return
+	}
+	ctx := observability.ExtractTrace(c.ctx, envelope.Trace)
+
+	switch envelope.Kind {
+	case clusterReply:
+		c.mu.Lock()
+		replies := c.pending[envelope.RequestID]
+		delete(c.pending, envelope.RequestID)
+		c.mu.Unlock()
+		if replies != nil {
+			replies <- envelope
+		}
+	case clusterConnect:
+		c.handleConnect(ctx, envelope)
+	case clusterConnected:
+		c.handleConnected(ctx, envelope)
+	case clusterMessage:
+		c.handleMessage(ctx, envelope)
+	case clusterDisconnect:
+		c.handleDisconnect(ctx, envelope)
+	case clusterJoin:
+		c.handleJoin(ctx, envelope)
+	}
+}
+
+func (c *Cluster) handleConnect(ctx context.Context, request clusterEnvelope) {
+	player, err := c.gameService.GetPlayerInRoomWithContext(ctx, request.RoomID, request.PlayerID)
+	if err != nil {
+		code, reason := websocketCloseForValidationError(err)
+		c.reply(ctx, request, clusterEnvelope{CloseCode: code, CloseReason: reason})
+		return
+	}
+
+	generation := c.clients.NextGeneration(request.RoomID, request.PlayerID)
+	wasConnected := c.clients.IsConnected(request.RoomID, request.PlayerID)
+	wasDisconnected := player.Session == game.PlayerSessionDisconnected
+	if err := c.gameService.MarkPlayerConnectedWithContext(ctx, request.RoomID, request.PlayerID); err != nil {
+		code, reason := websocketCloseForValidationError(err)
+		c.reply(ctx, request, clusterEnvelope{CloseCode: code, CloseReason: reason})
+		return
+	}
+
+	c.reply(ctx, request, clusterEnvelope{
+		Generation: generation,
+		EventType:  connectionEventType(wasConnected, wasDisconnected),
+	})
+}
+
+func (c *Cluster) handleConnected(ctx context.Context, envelope clusterEnvelope) {
+	player, err := c.gameService.GetPlayerInRoomWithContext(ctx, envelope.RoomID, envelope.PlayerID)
+	if err != nil {
+		return
+	}
+
+	attach := func() *Client {
+		c.clients.attachRemote(envelope.RoomID, envelope.PlayerID, envelope.ConnectionID)
+		return newRelayClient(c.clients, envelope.RoomID, envelope.PlayerID, envelope.ConnectionID)
+	}
+	var client *Client
+	if envelope.LastSeq != nil {
+		client = resumeClient(ctx, c.clients, c.gameService, envelope.RoomID, *envelope.LastSeq, attach)
+	} else {
+		client = attach()
+	}
+	notifyRoomOnConnection(ctx, c.clients, c.gameService, envelope.RoomID, player, client, envelope.EventType, envelope.LastSeq != nil)
+	c.flush(ctx, client)
+}
+
+func (c *Cluster) handleMessage(ctx context.Context, envelope clusterEnvelope) {
+	var message WebSocketMessage
+	if err := json.Unmarshal(envelope.Data, &message); err != nil {
+		return
+	}
+
+	player, err := c.gameService.GetPlayerInRoomWithContext(ctx, envelope.RoomID, envelope.PlayerID)
+	if err != nil {
+		sendEventToPlayer(ctx, c.clients, envelope.PlayerID, "error", ErrorMessage{Message: err.Error()})
+		return
+	}
+
+	c.gameService.UpdatePlayerActivityWithContext(ctx, envelope.RoomID, player.ID)
+	client := newRelayClient(c.clients, envelope.RoomID, player.ID, envelope.ConnectionID)
+	handleMessageAction(ctx, c.clients, c.gameService, envelope.RoomID, player, client, message)
+	c.flush(ctx, client)
+}
+
+func (c *Cluster) handleDisconnect(ctx context.Context, envelope clusterEnvelope) {
+	c.clients.detachRemote(envelope.RoomID, envelope.PlayerID, envelope.ConnectionID)
+	if c.clients.IsConnected(envelope.RoomID, envelope.PlayerID) {
+		return
+	}
+
+	player, err := c.gameService.GetPlayerInRoomWithContext(ctx, envelope.RoomID, envelope.PlayerID)
+	if err != nil {
+		return
+	}
+	playerDisconnected(ctx, c.clients, c.gameService, envelope.RoomID, envelope.PlayerID, player, c.clients.Generation(envelope.RoomID, envelope.PlayerID))
+}
+
+func (c *Cluster) handleJoin(ctx context.Context, request clusterEnvelope) {
+	// The player signed in on another node, whose session was already
+	// verified there.
+	_, _ = c.gameService.AddPlayerWithSession(request.PlayerID, request.SessionNonce)
+
+	var access service.RoomAccess
+	if len(request.Data) > 0 {
+		_ = json.Unmarshal(request.Data, &access)
+	}
+	res, err := c.gameService.JoinRoomWithAccessWithContext(ctx, request.RoomID, request.PlayerID, request.GameType, access)
+	if err != nil {
+		c.reply(ctx, request, clusterEnvelope{Error: err.Error(), Status: joinRoomStatus(err)})
+		return
+	}
+	c.reply(ctx, request, clusterEnvelope{Data: marshalPayload(dto.FromJoinRoomResponse(res))})
+}
+
+func (c *Cluster) request(ctx context.Context, nodeID string, envelope clusterEnvelope) (clusterEnvelope, error) {
+	ctx, cancel := context.WithTimeout(ctx, clusterRequestTimeout)
+	defer cancel()
+
+	replies := make(chan clusterEnvelope, 1)
+	c.mu.Lock()
+	c.nextRequest++
+	envelope.RequestID = c.nextRequest
+	c.pending[envelope.RequestID] = replies
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, envelope.RequestID)
+		c.mu.Unlock()
+	}()
+
+	if err := c.publish(ctx, backplane.NodeChannel(nodeID), envelope); err != nil {
+		return clusterEnvelope{}, err
+	}
+
+	select {
+	case reply := <-replies:
+		return reply, nil
+	case <-ctx.Done():
+		return clusterEnvelope{}, ErrRoomOwnerUnavailable
+	}
+}
+
+func (c *Cluster) reply(ctx context.Context, request clusterEnvelope, reply clusterEnvelope) {
+	reply.Kind = clusterReply
+	reply.RequestID = request.RequestID
+	_ = c.publish(ctx, backplane.NodeChannel(request.Origin), reply)
+}
+
+// flush relays the events a routed command queued for its relay client.
+func (c *Cluster) flush(ctx context.Context, client *Client) {
+	for {
+		select {
+		case messageBytes := <-client.Send:
+			_ = c.publish(ctx, clusterEventsChannel, clusterEnvelope{
+				Kind:         clusterDeliver,
+				RoomID:       client.RoomID,
+				PlayerID:     client.PlayerID,
+				ConnectionID: client.ConnectionID,
+				Data:         messageBytes,
+			})
+		default:
+			return
+		}
+	}
+}
+
+func (c *Cluster) publish(ctx context.Context, channel string, envelope clusterEnvelope) error {
+	envelope.Origin = c.nodeID
+	envelope.Trace = observability.InjectTrace(ctx)
+
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	if err := c.backplane.Publish(ctx, channel, data); err != nil {
+		observability.Logger().WarnContext(ctx, "cluster publish failed",
+			"room_id", envelope.RoomID,
+			"player_id", envelope.PlayerID,
+			"event_type", "cluster_publish_error",
+			"kind", envelope.Kind,
+			"error", err,
+		)
+		return err
+	}
+	return nil
+}
+
+func (c *Cluster) decode(data []byte) (clusterEnvelope, bool) {
+	var envelope clusterEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		observability.Logger().Warn("cluster message decode failed",
+			"room_id", "",
+			"player_id", "",
+			"event_type", "cluster_message_invalid",
+			"error", err,
+		)
+		return clusterEnvelope{}, false
+	}
+	return envelope, true
+}
+
+// newRelayClient stands in for a websocket on another node while the owner
+// runs one of its commands; flush sends whatever it queued.
+func newRelayClient(clients *ClientRegistry, roomID string, playerID string, connectionID string) *Client {
+	return &Client{
+		PlayerID:     playerID,
+		RoomID:       roomID,
+		ConnectionID: connectionID,
+		Send:         make(chan []byte, 256),
+		clients:      clients,
+		relay:        true,
+		done:         make(chan struct{}),
+	}
+}
@@ -0,0 +1,413 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/tsaqiffatih/mini-game/actions"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/auth"
+	"github.com/tsaqiffatih/mini-game/backplane"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/infrastructure"
+	"github.com/tsaqiffatih/mini-game/service"
+	"github.com/tsaqiffatih/mini-game/stats"
+)
+
+type clusterTestNode struct {
+	service *service.GameService
+	router  *mux.Router
+	server  *httptest.Server
+}
+
+func newClusterTestNode(t *testing.T, bp backplane.Backplane, nodeID string) clusterTestNode {
+	t.Helper()
+
+	rooms := infrastructure.NewLeasedRoomRepository(infrastructure.NewMemoryRoomRepository(), bp, nodeID, time.Minute)
+	gameService := service.NewGameService(rooms, game.NewPlayerManager())
+	clients := NewClientRegistry()
+	cluster := NewCluster(nodeID, bp, clients, gameService)
+	if err := cluster.Start(context.Background()); err != nil {
+		t.Fatalf("Start(%s) error = %v", nodeID, err)
+	}
+	t.Cleanup(cluster.Stop)
+	gameService.SetRoomNotifier(func(ctx context.Context, snapshot game.RoomSnapshot) {
+		NotifyGameUpdateToClients(ctx, clients, snapshot)
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/room/join", func(w http.ResponseWriter, r *http.Request) {
+		joinRoom(w, r, clients, gameService)
+	}).Methods(http.MethodPost)
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		HandleWebSocket(w, r, clients, gameService)
+	})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return clusterTestNode{
+		service: gameService,
+		router:  router,
+		server:  server,
+	}
+}
+
+func TestCluster_RoutesCommandsToOwnerAndRelaysEvents(t *testing.T) {
+	bp := backplane.NewMemory()
+	defer bp.Close()
+	nodeA := newClusterTestNode(t, bp, "node-a")
+	nodeB := newClusterTestNode(t, bp, "node-b")
+
+	if _, err := nodeA.service.AddPlayer("p1"); err != nil {
+		t.Fatalf("AddPlayer() error = %v", err)
+	}
+	res, err := nodeA.service.CreateRoomWithContext(context.Background(), "tictactoe", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	roomID := res.Room.RoomID
+
+	recorder := doJSONRequest(t, nodeB.router, http.MethodPost, "/room/join", map[string]string{
+		"room_id":   roomID,
+		"player_id": "p2",
+		"game_type": "tictactoe",
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("join through node-b status = %d, want %d; body=%s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	if _, err := nodeA.service.GetPlayerInRoom(roomID, "p2"); err != nil {
+		t.Fatalf("p2 not in the room on its owner: %v", err)
+	}
+
+	p1 := dialTestWebSocket(t, nodeA.server, "room_id="+roomID+"&player_id=p1")
+	defer p1.Close()
+	p2 := dialTestWebSocket(t, nodeB.server, "room_id="+roomID+"&player_id=p2")
+	defer p2.Close()
+	readClusterTestSnapshot(t, p1, func(room dto.RoomSnapshotDTO) bool { return room.RoomID == roomID })
+	readClusterTestSnapshot(t, p2, func(room dto.RoomSnapshotDTO) bool { return room.RoomID == roomID })
+
+	sendClusterTestMove(t, p1, 0, 0)
+	readClusterTestSnapshot(t, p2, boardHasMarks(1))
+
+	sendClusterTestMove(t, p2, 1, 1)
+	room := readClusterTestSnapshot(t, p1, boardHasMarks(2))
+	if got := room.TicTacToe.Board[1][1]; got == "" {
+		t.Fatalf("board[1][1] is empty, want the move routed from node-b")
+	}
+}
+
+func TestCluster_RemoteWebSocketForUnknownPlayerIsRejectedByOwner(t *testing.T) {
+	bp := backplane.NewMemory()
+	defer bp.Close()
+	nodeA := newClusterTestNode(t, bp, "node-a")
+	nodeB := newClusterTestNode(t, bp, "node-b")
+
+	if _, err := nodeA.service.AddPlayer("p1"); err != nil {
+		t.Fatalf("AddPlayer() error = %v", err)
+	}
+	res, err := nodeA.service.CreateRoomWithContext(context.Background(), "tictactoe", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+
+	conn := dialTestWebSocket(t, nodeB.server, "room_id="+res.Room.RoomID+"&player_id=missing")
+	defer conn.Close()
+
+	assertWebSocketCloseCode(t, conn, CloseCodePlayerNotFound)
+}
+
+func TestCluster_RemoteResumeReplaysEventsFromOwner(t *testing.T) {
+	bp := backplane.NewMemory()
+	defer bp.Close()
+	nodeA := newClusterTestNode(t, bp, "node-a")
+	nodeB := newClusterTestNode(t, bp, "node-b")
+
+	for _, playerID := range []string{"p1", "p2"} {
+		if _, err := nodeA.service.AddPlayer(playerID); err != nil {
+			t.Fatalf("AddPlayer(%s) error = %v", playerID, err)
+		}
+	}
+	res, err := nodeA.service.CreateRoomWithContext(context.Background(), "tictactoe", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	roomID := res.Room.RoomID
+	if _, err := nodeA.service.JoinRoom(roomID, "p2", "tictactoe"); err != nil {
+		t.Fatalf("JoinRoom() error = %v", err)
+	}
+
+	p2 := dialTestWebSocket(t, nodeB.server, "room_id="+roomID+"&player_id=p2")
+	lastSeq := readTestWebSocketEvent(t, p2, EventChatHistory).Seq
+	p2.Close()
+
+	p1 := dialTestWebSocket(t, nodeA.server, "room_id="+roomID+"&player_id=p1")
+	defer p1.Close()
+	sendClusterTestMove(t, p1, 0, 0)
+	readClusterTestSnapshot(t, p1, boardHasMarks(1))
+
+	resumed := dialTestWebSocket(t, nodeB.server, "room_id="+roomID+"&player_id=p2&last_seq="+strconv.FormatUint(lastSeq, 10))
+	defer resumed.Close()
+	update := readNextTestWebSocketEvent(t, resumed)
+	for update.Type != EventGameUpdate {
+		if update.Type == EventRoomUpdate || update.Type == EventResumed {
+			t.Fatalf("got %s before the missed game_update", update.Type)
+		}
+		update = readNextTestWebSocketEvent(t, resumed)
+	}
+	if update.Seq <= lastSeq {
+		t.Fatalf("replayed game_update seq = %d, want above %d", update.Seq, lastSeq)
+	}
+	readTestWebSocketEvent(t, resumed, EventResumed)
+}
+
+func sendClusterTestMove(t *testing.T, conn *websocket.Conn, row int, col int) {
+	t.Helper()
+
+	payload, _ := json.Marshal(dto.TictactoeMovePayload{Row: row, Col: col})
+	if err := conn.WriteJSON(WebSocketMessage{Type: actions.TICTACTOE_MOVE, Payload: payload}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+}
+
+func boardHasMarks(marks int) func(dto.RoomSnapshotDTO) bool {
+	return func(room dto.RoomSnapshotDTO) bool {
+		if room.TicTacToe == nil {
+			return false
+		}
+		count := 0
+		for _, row := range room.TicTacToe.Board {
+			for _, cell := range row {
+				if cell != "" {
+					count++
+				}
+			}
+		}
+		return count == marks
+	}
+}
+
+// readClusterTestSnapshot reads events until a room_update or game_update
+// matches want.
+func readClusterTestSnapshot(t *testing.T, conn *websocket.Conn, want func(dto.RoomSnapshotDTO) bool) dto.RoomSnapshotDTO {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline() error = %v", err)
+	}
+	for {
+		var event Event
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("ReadJSON() error = %v", err)
+		}
+		if event.Type != EventRoomUpdate && event.Type != EventGameUpdate {
+			continue
+		}
+
+		var room dto.RoomSnapshotDTO
+		if err := json.Unmarshal(event.Payload, &room); err != nil {
+			t.Fatalf("decode %s: %v", event.Type, err)
+		}
+		if want(room) {
+			return room
+		}
+	}
+}
+
+func TestLeasedRooms_EvictRoomWhenLeaseIsTaken(t *testing.T) {
+	server := miniredis.RunT(t)
+	bp := backplane.NewRedis(redis.NewClient(&redis.Options{Addr: server.Addr()}), "test:")
+	defer bp.Close()
+
+	rooms := infrastructure.NewLeasedRoomRepository(infrastructure.NewMemoryRoomRepository(), bp, "node-a", time.Minute)
+	gameService := service.NewGameService(rooms, game.NewPlayerManager())
+	clients := NewClientRegistry()
+	rooms.SetLeaseLostNotifier(func(ctx context.Context, snapshot game.RoomSnapshot) {
+		CloseRoomConnections(ctx, clients, snapshot, "room moved to another node")
+	})
+
+	if _, err := gameService.AddPlayer("p1"); err != nil {
+		t.Fatalf("AddPlayer() error = %v", err)
+	}
+	res, err := gameService.CreateRoomWithContext(context.Background(), "tictactoe", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	roomID := res.Room.RoomID
+	client := newBufferedTestClient("p1")
+	addTestClient(clients, roomID, client)
+
+	// Another node takes the room over, as after a partition outlasting the TTL.
+	leaseKey := "test:" + backplane.RoomLease(roomID)
+	if err := server.Set(leaseKey, "node-b"); err != nil {
+		t.Fatalf("Set(%s) error = %v", leaseKey, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rooms.KeepLeases(ctx, 10*time.Millisecond)
+
+	select {
+	case <-client.done:
+	case <-time.After(time.Second):
+		t.Fatalf("client of room %s still open after its lease was taken", roomID)
+	}
+	if _, err := gameService.RoomSnapshotWithContext(context.Background(), roomID); err == nil {
+		t.Fatalf("RoomSnapshotWithContext(%s) error = nil, want the room evicted", roomID)
+	}
+	if owner, _ := server.Get(leaseKey); owner != "node-b" {
+		t.Fatalf("lease owner = %q, want node-b kept", owner)
+	}
+}
+
+func TestLeasedRooms_ReleasedRoomsCanBeRestoredByAnotherNode(t *testing.T) {
+	server := miniredis.RunT(t)
+	bp := backplane.NewRedis(redis.NewClient(&redis.Options{Addr: server.Addr()}), "test:")
+	defer bp.Close()
+
+	roomsA := infrastructure.NewLeasedRoomRepository(infrastructure.NewMemoryRoomRepository(), bp, "node-a", time.Minute)
+	serviceA := service.NewGameService(roomsA, game.NewPlayerManager())
+	for _, playerID := range []string{"p1", "p2"} {
+		if _, err := serviceA.AddPlayer(playerID); err != nil {
+			t.Fatalf("AddPlayer(%s) error = %v", playerID, err)
+		}
+	}
+	res, err := serviceA.CreateRoomWithContext(context.Background(), "tictactoe", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	records, err := serviceA.RoomRecordsWithContext(context.Background())
+	if err != nil {
+		t.Fatalf("RoomRecordsWithContext() error = %v", err)
+	}
+
+	roomsB := infrastructure.NewLeasedRoomRepository(infrastructure.NewMemoryRoomRepository(), bp, "node-b", time.Minute)
+	serviceB := service.NewGameService(roomsB, game.NewPlayerManager())
+	if restored, _ := serviceB.RestoreRoomsWithContext(context.Background(), records); restored != 0 {
+		t.Fatalf("restored while node-a holds the lease = %d, want 0", restored)
+	}
+
+	if err := roomsA.ReleaseLeases(context.Background()); err != nil {
+		t.Fatalf("ReleaseLeases() error = %v", err)
+	}
+	if restored, err := serviceB.RestoreRoomsWithContext(context.Background(), records); err != nil || restored != 1 {
+		t.Fatalf("RestoreRoomsWithContext() = %d, %v, want 1 room", restored, err)
+	}
+	if owner, _ := server.Get("test:" + backplane.RoomLease(res.Room.RoomID)); owner != "node-b" {
+		t.Fatalf("lease owner = %q, want node-b", owner)
+	}
+}
+
+func TestCluster_LobbyListsRoomsOfEveryNode(t *testing.T) {
+	bp := backplane.NewMemory()
+	defer bp.Close()
+
+	newLobbyNode := func(nodeID string) (*service.GameService, *service.LobbyService) {
+		rooms := infrastructure.NewLeasedRoomRepository(infrastructure.NewMemoryRoomRepository(), bp, nodeID, time.Minute)
+		gameService := service.NewGameService(rooms, game.NewPlayerManager())
+		lobbyService := service.NewLobbyService(gameService, stats.NewStore())
+		gameService.SetRoomChangeNotifier(lobbyService.HandleRoomChange)
+		cluster := NewCluster(nodeID, bp, NewClientRegistry(), gameService)
+		cluster.SetLobby(lobbyService)
+		if err := cluster.Start(context.Background()); err != nil {
+			t.Fatalf("Start(%s) error = %v", nodeID, err)
+		}
+		t.Cleanup(cluster.Stop)
+		return gameService, lobbyService
+	}
+	waitForLobby := func(lobbyService *service.LobbyService, want int) []service.LobbyRoom {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			rooms, err := lobbyService.ListWithContext(context.Background(), service.LobbyFilter{}, 0)
+			if err != nil {
+				t.Fatalf("ListWithContext() error = %v", err)
+			}
+			if len(rooms) == want || time.Now().After(deadline) {
+				return rooms
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	serviceA, _ := newLobbyNode("node-a")
+	_, lobbyB := newLobbyNode("node-b")
+	for _, playerID := range []string{"p1", "p2"} {
+		if _, err := serviceA.AddPlayer(playerID); err != nil {
+			t.Fatalf("AddPlayer(%s) error = %v", playerID, err)
+		}
+	}
+	res, err := serviceA.CreateRoomWithContext(context.Background(), "tictactoe", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	roomID := res.Room.RoomID
+
+	if rooms := waitForLobby(lobbyB, 1); len(rooms) != 1 || rooms[0].RoomID != roomID || rooms[0].HostID != "p1" {
+		t.Fatalf("lobby on node-b = %+v, want room %s of node-a", rooms, roomID)
+	}
+	// A node joining later asks for the rooms already open.
+	_, lobbyC := newLobbyNode("node-c")
+	if rooms := waitForLobby(lobbyC, 1); len(rooms) != 1 || rooms[0].RoomID != roomID {
+		t.Fatalf("lobby on node-c = %+v, want room %s of node-a", rooms, roomID)
+	}
+
+	if _, err := serviceA.JoinRoomWithContext(context.Background(), roomID, "p2", "tictactoe"); err != nil {
+		t.Fatalf("JoinRoomWithContext() error = %v", err)
+	}
+	if rooms := waitForLobby(lobbyB, 0); len(rooms) != 0 {
+		t.Fatalf("lobby on node-b after the room filled = %+v, want empty", rooms)
+	}
+}
+
+func TestCluster_RelayedJoinKeepsTheGuestSession(t *testing.T) {
+	bp := backplane.NewMemory()
+	defer bp.Close()
+	nodeA := newClusterTestNode(t, bp, "node-a")
+	nodeB := newClusterTestNode(t, bp, "node-b")
+
+	if _, err := nodeA.service.AddPlayer("p1"); err != nil {
+		t.Fatalf("AddPlayer() error = %v", err)
+	}
+	res, err := nodeA.service.CreateRoomWithContext(context.Background(), "tictactoe", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+
+	// p2 is a guest signed in on node-b; the owner must accept only that
+	// guest's tokens afterwards.
+	signer := auth.NewTokenSigner([]byte("test-secret"), 0)
+	token, claims, err := signer.Issue("p2", true, "p2-nonce")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	body, _ := json.Marshal(map[string]string{"room_id": res.Room.RoomID, "game_type": "tictactoe"})
+	request := httptest.NewRequest(http.MethodPost, "/room/join", bytes.NewReader(body))
+	request = request.WithContext(auth.WithClaims(request.Context(), claims))
+	recorder := httptest.NewRecorder()
+	nodeB.router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("join through node-b status = %d, want %d; body=%s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+
+	authA := service.NewAuthService(infrastructure.NewMemoryAccountRepository(), signer, nodeA.service)
+	if _, err := authA.AuthenticateWithContext(context.Background(), token); err != nil {
+		t.Fatalf("AuthenticateWithContext(p2) on the owner error = %v", err)
+	}
+	other, _, err := signer.Issue("p2", true, "other-nonce")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if _, err := authA.AuthenticateWithContext(context.Background(), other); err != auth.ErrSessionEnded {
+		t.Fatalf("AuthenticateWithContext(other p2) on the owner error = %v, want %v", err, auth.ErrSessionEnded)
+	}
+}
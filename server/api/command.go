@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/actions"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+const (
+	// commandCacheSize bounds the command results kept per room for
+	// answering retries.
+	commandCacheSize = 128
+	// commandWait is how long a retry waits for the first attempt, still
+	// running on another connection, before it is answered in_progress.
+	commandWait = 5 * time.Second
+)
+
+var (
+	errUnsupportedMessage      = errors.New("unsupported message type")
+	errStateVersionNotAccepted = errors.New("expected_state_version is not accepted for this message type")
+)
+
+type CommandAckPayload struct {
+	RequestID    string `json:"request_id"`
+	StateVersion uint64 `json:"state_version"`
+	Duplicate    bool   `json:"duplicate,omitempty"`
+}
+
+type CommandNackPayload struct {
+	RequestID    string `json:"request_id"`
+	Code         string `json:"code"`
+	Message      string `json:"message"`
+	StateVersion uint64 `json:"state_version"`
+	Duplicate    bool   `json:"duplicate,omitempty"`
+}
+
+type commandKey struct {
+	playerID  string
+	requestID string
+}
+
+// commandResult is the outcome of a command, ready once done is closed.
+// An empty code means the command was applied.
+type commandResult struct {
+	done         chan struct{}
+	code         string
+	message      string
+	stateVersion uint64
+}
+
+// commandCache remembers the latest commands of a room by player and
+// request ID, so a retried command is answered rather than applied again.
+type commandCache struct {
+	mu      sync.Mutex
+	results map[commandKey]*commandResult
+	order   []commandKey
+	used    atomic.Int64
+}
+
+// begin returns the result of the command, reporting true when this is the
+// first attempt and the caller must run it and close done.
+func (c *commandCache) begin(key commandKey) (*commandResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if result, ok := c.results[key]; ok {
+		return result, false
+	}
+	if c.results == nil {
+		c.results = make(map[commandKey]*commandResult)
+	}
+	result := &commandResult{done: make(chan struct{})}
+	c.results[key] = result
+	c.order = append(c.order, key)
+	if over := len(c.order) - commandCacheSize; over > 0 {
+		for _, evicted := range c.order[:over] {
+			delete(c.results, evicted)
+		}
+		c.order = c.order[over:]
+	}
+	return result, true
+}
+
+// roomCommands returns the command cache of the room.
+func (r *ClientRegistry) roomCommands(roomID string) *commandCache {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	commands := r.commands[roomID]
+	if commands == nil {
+		for id, idle := range r.commands {
+			if now.Sub(time.Unix(0, idle.used.Load())) > eventLogIdleTTL {
+				delete(r.commands, id)
+			}
+		}
+		commands = &commandCache{}
+		r.commands[roomID] = commands
+	}
+	commands.used.Store(now.UnixNano())
+	return commands
+}
+
+// runCommand handles a message with a request ID. The first attempt is
+// dispatched and answered with an ack or nack; a retry gets the same answer
+// marked duplicate without touching the room.
+func runCommand(
+	ctx context.Context,
+	clients *ClientRegistry,
+	gameService *service.GameService,
+	roomID string,
+	player game.PlayerSnapshot,
+	client *Client,
+	message WebSocketMessage,
+) error {
+	key := commandKey{playerID: player.ID, requestID: message.RequestID}
+	result, first := clients.roomCommands(roomID).begin(key)
+	if !first {
+		select {
+		case <-result.done:
+		case <-time.After(commandWait):
+			sendEvent(ctx, client, EventNack, CommandNackPayload{
+				RequestID: message.RequestID,
+				Code:      "in_progress",
+				Message:   "request is still being processed",
+				Duplicate: true,
+			})
+			return nil
+		}
+		observability.Logger().InfoContext(ctx, "websocket command retried",
+			"room_id", roomID,
+			"player_id", player.ID,
+			"event_type", "websocket_command_duplicate",
+			"request_id", message.RequestID,
+		)
+		sendCommandResult(ctx, client, message.RequestID, result, true)
+		return nil
+	}
+
+	err := dispatchMessage(ctx, clients, gameService, roomID, player, client, message)
+	if err != nil {
+		result.code = commandErrorCode(message.Type, err)
+		result.message = err.Error()
+	}
+	if snapshot, snapshotErr := gameService.RoomSnapshotWithContext(ctx, roomID); snapshotErr == nil {
+		result.stateVersion = snapshot.StateVersion
+	}
+	close(result.done)
+
+	sendCommandResult(ctx, client, message.RequestID, result, false)
+	return err
+}
+
+func sendCommandResult(ctx context.Context, client *Client, requestID string, result *commandResult, duplicate bool) {
+	if result.code == "" {
+		sendEvent(ctx, client, EventAck, CommandAckPayload{
+			RequestID:    requestID,
+			StateVersion: result.stateVersion,
+			Duplicate:    duplicate,
+		})
+		return
+	}
+	sendEvent(ctx, client, EventNack, CommandNackPayload{
+		RequestID:    requestID,
+		Code:         result.code,
+		Message:      result.message,
+		StateVersion: result.stateVersion,
+		Duplicate:    duplicate,
+	})
+}
+
+func commandErrorCode(messageType string, err error) string {
+	switch {
+	case errors.Is(err, game.ErrStateVersionMismatch):
+		return "state_version_mismatch"
+	case errors.Is(err, errUnsupportedMessage), errors.Is(err, errStateVersionNotAccepted):
+		return "unsupported"
+	case messageType == actions.CHESS_MOVE, messageType == actions.CHESS_PREMOVE:
+		return chessMoveRejectionCode(err)
+	default:
+		return "rejected"
+	}
+}
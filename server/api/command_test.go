@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/tsaqiffatih/mini-game/actions"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+)
+
+func TestCommandCache_EvictsOldestCommands(t *testing.T) {
+	cache := &commandCache{}
+	first := commandKey{playerID: "p1", requestID: "r0"}
+	cache.begin(first)
+	for i := 1; i <= commandCacheSize; i++ {
+		cache.begin(commandKey{playerID: "p1", requestID: "r" + strconv.Itoa(i)})
+	}
+
+	if _, isFirst := cache.begin(first); !isFirst {
+		t.Fatalf("begin(evicted command) first = false, want true")
+	}
+	if len(cache.results) != commandCacheSize {
+		t.Fatalf("cached commands = %d, want %d", len(cache.results), commandCacheSize)
+	}
+}
+
+func TestHandleWebSocket_RetriedCommandIsAnsweredNotReapplied(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	server := newWebSocketTestServer(t, NewClientRegistry(), gameService)
+
+	conn := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	defer conn.Close()
+	readTestWebSocketEvent(t, conn, EventChatHistory)
+
+	move := ticTacToeCommand(t, "move-1", 0, 0, nil)
+	writeTestCommand(t, conn, move)
+	ack := readTestAck(t, conn)
+	if ack.RequestID != "move-1" || ack.Duplicate {
+		t.Fatalf("ack = %+v, want the first answer to move-1", ack)
+	}
+
+	writeTestCommand(t, conn, move)
+	retried := readTestAck(t, conn)
+	if !retried.Duplicate || retried.StateVersion != ack.StateVersion {
+		t.Fatalf("retried ack = %+v, want a duplicate at state version %d", retried, ack.StateVersion)
+	}
+
+	snapshot, err := gameService.RoomSnapshot(roomID)
+	if err != nil {
+		t.Fatalf("RoomSnapshot() error = %v", err)
+	}
+	if snapshot.StateVersion != ack.StateVersion {
+		t.Fatalf("state version = %d, want %d after the retry", snapshot.StateVersion, ack.StateVersion)
+	}
+}
+
+func TestHandleWebSocket_StaleExpectedStateVersionIsNacked(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	server := newWebSocketTestServer(t, NewClientRegistry(), gameService)
+
+	conn := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	defer conn.Close()
+	readTestWebSocketEvent(t, conn, EventChatHistory)
+
+	snapshot, err := gameService.RoomSnapshot(roomID)
+	if err != nil {
+		t.Fatalf("RoomSnapshot() error = %v", err)
+	}
+	stale := snapshot.StateVersion - 1
+	writeTestCommand(t, conn, ticTacToeCommand(t, "move-1", 1, 1, &stale))
+
+	event := readTestWebSocketEvent(t, conn, EventNack)
+	var nack CommandNackPayload
+	if err := json.Unmarshal(event.Payload, &nack); err != nil {
+		t.Fatalf("decode nack: %v", err)
+	}
+	if nack.RequestID != "move-1" || nack.Code != "state_version_mismatch" || nack.StateVersion != snapshot.StateVersion {
+		t.Fatalf("nack = %+v, want state_version_mismatch at version %d", nack, snapshot.StateVersion)
+	}
+
+	after, err := gameService.RoomSnapshot(roomID)
+	if err != nil {
+		t.Fatalf("RoomSnapshot() error = %v", err)
+	}
+	if after.StateVersion != snapshot.StateVersion {
+		t.Fatalf("state version = %d, want the move rejected at %d", after.StateVersion, snapshot.StateVersion)
+	}
+}
+
+func TestHandleWebSocket_StaleExpectedStateVersionNacksEveryCommand(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	server := newWebSocketTestServer(t, NewClientRegistry(), gameService)
+
+	conn := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	defer conn.Close()
+	readTestWebSocketEvent(t, conn, EventChatHistory)
+	writeTestCommand(t, conn, ticTacToeCommand(t, "move-1", 0, 0, nil))
+	ack := readTestAck(t, conn)
+
+	stale := ack.StateVersion - 1
+	chat, err := json.Marshal(dto.ChatSendPayload{Message: "gg"})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	for _, messageType := range []string{actions.CHAT_SEND, actions.TAKEBACK_REQUEST} {
+		writeTestCommand(t, conn, WebSocketMessage{Type: messageType, Payload: chat, RequestID: messageType, ExpectedStateVersion: &stale})
+		if nack := readTestNack(t, conn); nack.RequestID != messageType || nack.Code != "state_version_mismatch" {
+			t.Fatalf("%s nack = %+v, want state_version_mismatch", messageType, nack)
+		}
+	}
+
+	reaction, err := json.Marshal(dto.ReactionSendPayload{ReactionID: "gg"})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	writeTestCommand(t, conn, WebSocketMessage{Type: actions.REACTION_SEND, Payload: reaction, RequestID: "reaction-1", ExpectedStateVersion: &ack.StateVersion})
+	if nack := readTestNack(t, conn); nack.Code != "unsupported" {
+		t.Fatalf("reaction nack = %+v, want expected_state_version refused", nack)
+	}
+
+	snapshot, err := gameService.RoomSnapshot(roomID)
+	if err != nil {
+		t.Fatalf("RoomSnapshot() error = %v", err)
+	}
+	history, err := gameService.ChatHistoryWithContext(context.Background(), roomID, "p1")
+	if err != nil {
+		t.Fatalf("ChatHistoryWithContext() error = %v", err)
+	}
+	if snapshot.StateVersion != ack.StateVersion || snapshot.Takeback.Pending || len(history) != 0 {
+		t.Fatalf("room = version %d, takeback %+v, %d chat messages, want it as the move left it at %d",
+			snapshot.StateVersion, snapshot.Takeback, len(history), ack.StateVersion)
+	}
+}
+
+func ticTacToeCommand(t *testing.T, requestID string, row int, col int, expected *uint64) WebSocketMessage {
+	t.Helper()
+
+	payload, err := json.Marshal(dto.TictactoeMovePayload{Row: row, Col: col})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	return WebSocketMessage{
+		Type:                 actions.TICTACTOE_MOVE,
+		Payload:              payload,
+		RequestID:            requestID,
+		ExpectedStateVersion: expected,
+	}
+}
+
+func writeTestCommand(t *testing.T, conn *websocket.Conn, message WebSocketMessage) {
+	t.Helper()
+
+	if err := conn.WriteJSON(message); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+}
+
+func readTestNack(t *testing.T, conn *websocket.Conn) CommandNackPayload {
+	t.Helper()
+
+	event := readTestWebSocketEvent(t, conn, EventNack)
+	var nack CommandNackPayload
+	if err := json.Unmarshal(event.Payload, &nack); err != nil {
+		t.Fatalf("decode nack: %v", err)
+	}
+	return nack
+}
+
+func readTestAck(t *testing.T, conn *websocket.Conn) CommandAckPayload {
+	t.Helper()
+
+	event := readTestWebSocketEvent(t, conn, EventAck)
+	var ack CommandAckPayload
+	if err := json.Unmarshal(event.Payload, &ack); err != nil {
+		t.Fatalf("decode ack: %v", err)
+	}
+	return ack
+}
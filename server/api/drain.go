@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+)
+
+// EventServerRestarting warns clients the server is going down. Clients
+// reconnect to the same room after the hinted delay.
+const EventServerRestarting = "server_restarting"
+
+type ServerRestartingPayload struct {
+	Message          string    `json:"message"`
+	ReconnectAfterMS int64     `json:"reconnect_after_ms"`
+	Timestamp        time.Time `json:"timestamp"`
+}
+
+// DrainClients sends server_restarting to every local connection and closes
+// each with 1012 (service restart) once the notice is written, waiting up to
+// wait for them. Players are not marked disconnected, so their rooms can be
+// saved as they are.
+func DrainClients(ctx context.Context, clients *ClientRegistry, reconnectAfter time.Duration, wait time.Duration) {
+	notified := clients.Broadcast(ctx, EventServerRestarting, ServerRestartingPayload{
+		Message:          "The server is restarting, reconnect shortly",
+		ReconnectAfterMS: reconnectAfter.Milliseconds(),
+		Timestamp:        time.Now(),
+	})
+
+	drained := clients.detachAll()
+	for _, client := range drained {
+		client.CloseAfterQueued(websocket.CloseServiceRestart, "server restarting")
+	}
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+waiting:
+	for _, client := range drained {
+		select {
+		case <-client.done:
+		case <-timeout.C:
+			for _, client := range drained {
+				client.CloseWithCode(websocket.CloseServiceRestart, "server restarting")
+			}
+			break waiting
+		}
+	}
+
+	observability.Logger().InfoContext(ctx, "clients drained",
+		"room_id", "",
+		"player_id", "",
+		"event_type", "shutdown",
+		"clients", notified,
+	)
+}
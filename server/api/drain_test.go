@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tsaqiffatih/mini-game/game"
+)
+
+func TestDrainClients_WarnsAndClosesWithoutDisconnectingPlayers(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	clients := NewClientRegistry()
+	server := newWebSocketTestServer(t, clients, gameService)
+
+	conn := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	defer conn.Close()
+	readTestWebSocketEvent(t, conn, EventChatHistory)
+
+	DrainClients(context.Background(), clients, 5*time.Second, time.Second)
+
+	var restarting ServerRestartingPayload
+	if err := json.Unmarshal(readTestWebSocketEvent(t, conn, EventServerRestarting).Payload, &restarting); err != nil {
+		t.Fatalf("decode server_restarting: %v", err)
+	}
+	if restarting.ReconnectAfterMS != 5000 {
+		t.Fatalf("reconnect_after_ms = %d, want 5000", restarting.ReconnectAfterMS)
+	}
+	assertWebSocketCloseCode(t, conn, websocket.CloseServiceRestart)
+
+	// The handler noticing the close must not mark the player gone, or the
+	// saved room would come back without them.
+	time.Sleep(50 * time.Millisecond)
+	player, err := gameService.GetPlayerInRoomWithContext(context.Background(), roomID, "p1")
+	if err != nil {
+		t.Fatalf("GetPlayerInRoomWithContext() error = %v", err)
+	}
+	if player.Session != game.PlayerSessionConnected {
+		t.Fatalf("player session after drain = %s, want %s", player.Session, game.PlayerSessionConnected)
+	}
+}
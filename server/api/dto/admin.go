@@ -0,0 +1,151 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+type AdminRoomDTO struct {
+	RoomID       string      `json:"room_id"`
+	GameType     string      `json:"game_type"`
+	RoomState    string      `json:"room_state"`
+	StateVersion uint64      `json:"state_version"`
+	IsAIEnabled  bool        `json:"is_ai_enabled"`
+	AILevel      int         `json:"ai_level"`
+	Players      []PlayerDTO `json:"players"`
+	Connections  int         `json:"connections"`
+	CreatedAt    time.Time   `json:"created_at"`
+	LastActive   time.Time   `json:"last_active"`
+}
+
+type AdminRoomsDTO struct {
+	Rooms []AdminRoomDTO `json:"rooms"`
+}
+
+type AdminKickPayload struct {
+	PlayerID string `json:"player_id"`
+}
+
+type AdminBroadcastPayload struct {
+	Message string `json:"message"`
+}
+
+type AdminBroadcastDTO struct {
+	Message    string `json:"message"`
+	Recipients int    `json:"recipients"`
+}
+
+type StockfishStatusDTO struct {
+	RoomID       string     `json:"room_id,omitempty"`
+	Path         string     `json:"path"`
+	Level        int        `json:"level"`
+	PID          int        `json:"pid,omitempty"`
+	Running      bool       `json:"running"`
+	Searching    bool       `json:"searching"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	Searches     int        `json:"searches"`
+	LastSearchAt *time.Time `json:"last_search_at,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+type AdminStockfishDTO struct {
+	Binary    string               `json:"binary"`
+	Available bool                 `json:"available"`
+	Error     string               `json:"error,omitempty"`
+	Engines   []StockfishStatusDTO `json:"engines"`
+}
+
+// AdminConfigDTO is the config the server runs with, secrets hidden.
+// Settings are keyed as in the config file, valued as a flag would take them.
+type AdminConfigDTO struct {
+	File     string            `json:"file,omitempty"`
+	Settings map[string]string `json:"settings"`
+}
+
+// FromAdminRoom describes a room for operators; connections counts the
+// room's connections on the node answering.
+func FromAdminRoom(status service.RoomStatus, connections int) AdminRoomDTO {
+	snapshot := status.Snapshot
+	players := make([]PlayerDTO, 0, len(snapshot.Players))
+	for _, player := range snapshot.Players {
+		players = append(players, FromPlayerSnapshot(player))
+	}
+	return AdminRoomDTO{
+		RoomID:       snapshot.RoomID,
+		GameType:     snapshot.GameType,
+		RoomState:    string(snapshot.RoomState),
+		StateVersion: snapshot.StateVersion,
+		IsAIEnabled:  snapshot.IsAIEnabled,
+		AILevel:      snapshot.AILevel,
+		Players:      players,
+		Connections:  connections,
+		CreatedAt:    status.CreatedAt,
+		LastActive:   status.LastActive,
+	}
+}
+
+func FromStockfishStatus(roomID string, status game.StockfishStatus) StockfishStatusDTO {
+	return StockfishStatusDTO{
+		RoomID:       roomID,
+		Path:         status.Path,
+		Level:        status.Level,
+		PID:          status.PID,
+		Running:      status.Running,
+		Searching:    status.Searching,
+		StartedAt:    optionalTime(status.StartedAt),
+		Searches:     status.Searches,
+		LastSearchAt: optionalTime(status.LastSearchAt),
+		LastError:    status.LastError,
+	}
+}
+
+func FromAdminStockfish(binary string, binaryErr error, engines []service.RoomStockfish) AdminStockfishDTO {
+	result := AdminStockfishDTO{
+		Binary:    binary,
+		Available: binaryErr == nil,
+		Engines:   make([]StockfishStatusDTO, 0, len(engines)),
+	}
+	if binaryErr != nil {
+		result.Error = binaryErr.Error()
+	}
+	for _, engine := range engines {
+		result.Engines = append(result.Engines, FromStockfishStatus(engine.RoomID, engine.Status))
+	}
+	return result
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// AdminAuditDTO is the audit log of one room, oldest event first.
+type AdminAuditDTO struct {
+	RoomID string            `json:"room_id"`
+	Events []game.AuditEvent `json:"events"`
+}
+
+// ReplayDTO is a room rebuilt from its audit log by `mini-game replay`.
+type ReplayDTO struct {
+	RoomID     string                 `json:"room_id"`
+	Replayed   int                    `json:"replayed"`
+	Skipped    int                    `json:"skipped"`
+	RestoredAt int                    `json:"restored_at,omitempty"`
+	Snapshot   RoomSnapshotDTO        `json:"snapshot"`
+	Violations []game.ReplayViolation `json:"violations"`
+}
+
+func FromReplayReport(report game.ReplayReport) ReplayDTO {
+	return ReplayDTO{
+		RoomID:     report.RoomID,
+		Replayed:   report.Replayed,
+		Skipped:    report.Skipped,
+		RestoredAt: report.RestoredAt,
+		Snapshot:   FromRoomSnapshot(report.Snapshot),
+		Violations: append(make([]game.ReplayViolation, 0, len(report.Violations)), report.Violations...),
+	}
+}
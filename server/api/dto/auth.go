@@ -0,0 +1,37 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/auth"
+	"github.com/tsaqiffatih/mini-game/game"
+)
+
+type CredentialsRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type GuestRequest struct {
+	PlayerID string `json:"player_id"`
+}
+
+// SessionDTO is the signed-in player with its session token. The player
+// fields sit at the top level so /create/user keeps its previous shape.
+type SessionDTO struct {
+	PlayerDTO
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Guest     bool      `json:"guest"`
+}
+
+func FromSession(token string, claims auth.Claims, player game.PlayerSnapshot) SessionDTO {
+	return SessionDTO{
+		PlayerDTO: FromPlayerSnapshot(player),
+		Token:     token,
+		TokenType: "Bearer",
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0).UTC(),
+		Guest:     claims.Guest,
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	chessdomain "github.com/tsaqiffatih/mini-game/chess"
 	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/service"
 	"github.com/tsaqiffatih/mini-game/tictactoe"
 )
 
@@ -32,6 +33,20 @@ type ChessUndoPayload struct {
 	Mode string `json:"mode,omitempty"`
 }
 
+type TakebackRespondPayload struct {
+	Accept bool `json:"accept"`
+}
+
+type RoomKickPayload struct {
+	PlayerID string `json:"player_id"`
+}
+
+type RoomInviteDTO struct {
+	RoomID    string    `json:"room_id"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 type ChessMoveRejectedDTO struct {
 	RoomID        string           `json:"room_id"`
 	PlayerID      string           `json:"player_id"`
@@ -45,6 +60,15 @@ type ChatSendPayload struct {
 	Message string `json:"message"`
 }
 
+type ChatMutePayload struct {
+	PlayerID string `json:"player_id"`
+}
+
+type ChatReportPayload struct {
+	MessageID string `json:"message_id"`
+	Reason    string `json:"reason"`
+}
+
 type ChatMessageDTO struct {
 	ID         string    `json:"id"`
 	RoomID     string    `json:"room_id,omitempty"`
@@ -52,12 +76,21 @@ type ChatMessageDTO struct {
 	PlayerMark string    `json:"player_mark"`
 	Message    string    `json:"message"`
 	CreatedAt  time.Time `json:"created_at"`
+	System     bool      `json:"system,omitempty"`
 }
 
 type ChatHistoryDTO struct {
 	Messages []ChatMessageDTO `json:"messages"`
 }
 
+// ChatPageDTO is a page of archived chat. NextBefore is the before to ask
+// for the page preceding it, set while HasMore.
+type ChatPageDTO struct {
+	Messages   []ChatMessageDTO `json:"messages"`
+	HasMore    bool             `json:"has_more"`
+	NextBefore string           `json:"next_before,omitempty"`
+}
+
 type PlayerDTO struct {
 	ID         string    `json:"id"`
 	PlayerID   string    `json:"player_id"`
@@ -90,6 +123,8 @@ type RoomSnapshotDTO struct {
 	IsAIEnabled  bool               `json:"is_ai_enabled"`
 	AILevel      int                `json:"ai_level"`
 	Players      []PlayerDTO        `json:"players"`
+	Takeback     TakebackDTO        `json:"takeback"`
+	Settings     RoomSettingsDTO    `json:"settings"`
 	Game         *GameStateDTO      `json:"game,omitempty"`
 	TicTacToe    *TicTacToeStateDTO `json:"tictactoe,omitempty"`
 	// Deprecated: chess clients should read the canonical state from
@@ -129,6 +164,9 @@ type ChessStateDTO struct {
 	LegalMoves     map[string][]string        `json:"legal_moves"`
 	AI             ChessAIDTO                 `json:"ai"`
 	Undo           ChessUndoDTO               `json:"undo"`
+	// Premove is only populated in snapshots addressed to the player who
+	// queued it.
+	Premove *ChessPremoveDTO `json:"premove,omitempty"`
 }
 
 type ChessAIDTO struct {
@@ -146,6 +184,28 @@ type ChessUndoDTO struct {
 	Pending         string `json:"pending,omitempty"`
 }
 
+type ChessPremoveDTO struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Promotion string    `json:"promotion,omitempty"`
+	QueuedAt  time.Time `json:"queued_at"`
+}
+
+type TakebackDTO struct {
+	Pending     bool           `json:"pending"`
+	RequestedBy string         `json:"requested_by,omitempty"`
+	ExpiresAt   *time.Time     `json:"expires_at,omitempty"`
+	Limit       int            `json:"limit"`
+	Used        map[string]int `json:"used"`
+}
+
+// RoomSettingsDTO never carries the room password, only whether one is set.
+type RoomSettingsDTO struct {
+	Visibility  string `json:"visibility"`
+	HasPassword bool   `json:"has_password"`
+	HostID      string `json:"host_id,omitempty"`
+}
+
 type MoveResponseDTO struct {
 	Room  RoomSnapshotDTO `json:"room"`
 	Ended bool            `json:"ended"`
@@ -182,6 +242,7 @@ func FromChatMessage(message game.ChatMessage) ChatMessageDTO {
 		PlayerMark: message.PlayerMark,
 		Message:    message.Message,
 		CreatedAt:  message.CreatedAt,
+		System:     message.System,
 	}
 }
 
@@ -203,6 +264,23 @@ func FromChatHistory(messages []game.ChatMessage) ChatHistoryDTO {
 	return ChatHistoryDTO{Messages: FromChatMessages(messages)}
 }
 
+func FromChatPage(page service.ChatPage) ChatPageDTO {
+	dto := ChatPageDTO{Messages: FromChatMessages(page.Messages), HasMore: page.More}
+	if page.More && len(page.Messages) > 0 {
+		dto.NextBefore = page.Messages[0].ID
+	}
+	return dto
+}
+
+// FromChatSearch keeps the room of every message, since results span rooms.
+func FromChatSearch(messages []game.ChatMessage) ChatHistoryDTO {
+	dtos := make([]ChatMessageDTO, 0, len(messages))
+	for _, message := range messages {
+		dtos = append(dtos, FromChatMessageEvent(message))
+	}
+	return ChatHistoryDTO{Messages: dtos}
+}
+
 func FromRoomSnapshot(snapshot game.RoomSnapshot) RoomSnapshotDTO {
 	players := make([]PlayerDTO, 0, len(snapshot.Players))
 	for _, player := range snapshot.Players {
@@ -220,6 +298,12 @@ func FromRoomSnapshot(snapshot game.RoomSnapshot) RoomSnapshotDTO {
 		IsAIEnabled:  snapshot.IsAIEnabled,
 		AILevel:      snapshot.AILevel,
 		Players:      players,
+		Takeback:     FromTakebackSnapshot(snapshot.Takeback),
+		Settings: RoomSettingsDTO{
+			Visibility:  string(snapshot.Settings.Visibility),
+			HasPassword: snapshot.Settings.HasPassword,
+			HostID:      snapshot.Settings.HostID,
+		},
 	}
 
 	gameState := &GameStateDTO{Type: snapshot.GameType}
@@ -275,3 +359,41 @@ func FromRoomSnapshot(snapshot game.RoomSnapshot) RoomSnapshotDTO {
 	dto.Game = gameState
 	return dto
 }
+
+func FromTakebackSnapshot(snapshot game.TakebackSnapshot) TakebackDTO {
+	dto := TakebackDTO{
+		Pending:     snapshot.Pending,
+		RequestedBy: snapshot.RequestedBy,
+		Limit:       snapshot.Limit,
+		Used:        make(map[string]int, len(snapshot.Used)),
+	}
+	for playerID, used := range snapshot.Used {
+		dto.Used[playerID] = used
+	}
+	if snapshot.Pending {
+		expiresAt := snapshot.ExpiresAt
+		dto.ExpiresAt = &expiresAt
+	}
+	return dto
+}
+
+// FromRoomSnapshotForPlayer builds the snapshot DTO as seen by one player,
+// including state that is private to that player such as a queued premove.
+func FromRoomSnapshotForPlayer(snapshot game.RoomSnapshot, playerID string) RoomSnapshotDTO {
+	dto := FromRoomSnapshot(snapshot)
+	if dto.Chess == nil || snapshot.Chess == nil {
+		return dto
+	}
+
+	dto.Chess.Undo.CanRequest = snapshot.Chess.Undo.RequestableBy[playerID]
+
+	if premove, ok := snapshot.Chess.Premoves[playerID]; ok {
+		dto.Chess.Premove = &ChessPremoveDTO{
+			From:      premove.From,
+			To:        premove.To,
+			Promotion: premove.Promotion,
+			QueuedAt:  premove.QueuedAt,
+		}
+	}
+	return dto
+}
@@ -0,0 +1,79 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+type ReadinessCheckDTO struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type ReadinessDTO struct {
+	Ready  bool                `json:"ready"`
+	Checks []ReadinessCheckDTO `json:"checks"`
+}
+
+type RoomCountDTO struct {
+	GameType string `json:"game_type"`
+	State    string `json:"state"`
+	Rooms    int    `json:"rooms"`
+}
+
+type EngineUsageDTO struct {
+	Engines   int `json:"engines"`
+	Running   int `json:"running"`
+	Searching int `json:"searching"`
+}
+
+// BuildDTO is what the binary knows about how it was built. Commit is empty
+// when the build carried no version control information.
+type BuildDTO struct {
+	GoVersion  string     `json:"go_version"`
+	Module     string     `json:"module"`
+	Version    string     `json:"version"`
+	Commit     string     `json:"commit,omitempty"`
+	CommitTime *time.Time `json:"commit_time,omitempty"`
+	Modified   bool       `json:"modified"`
+}
+
+type DebugStatusDTO struct {
+	NodeID      string         `json:"node_id"`
+	StartedAt   time.Time      `json:"started_at"`
+	UptimeSecs  int64          `json:"uptime_seconds"`
+	Draining    bool           `json:"draining"`
+	Goroutines  int            `json:"goroutines"`
+	Rooms       []RoomCountDTO `json:"rooms"`
+	Connections int            `json:"connections"`
+	Stockfish   EngineUsageDTO `json:"stockfish"`
+	Build       BuildDTO       `json:"build"`
+}
+
+func FromRoomCounts(counts []observability.RoomCount) []RoomCountDTO {
+	result := make([]RoomCountDTO, 0, len(counts))
+	for _, count := range counts {
+		result = append(result, RoomCountDTO{
+			GameType: count.GameType,
+			State:    count.State,
+			Rooms:    count.Rooms,
+		})
+	}
+	return result
+}
+
+func FromEngineUsage(engines []service.RoomStockfish) EngineUsageDTO {
+	usage := EngineUsageDTO{Engines: len(engines)}
+	for _, engine := range engines {
+		if engine.Status.Running {
+			usage.Running++
+		}
+		if engine.Status.Searching {
+			usage.Searching++
+		}
+	}
+	return usage
+}
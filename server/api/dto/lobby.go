@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+type LobbyRoomDTO struct {
+	RoomID      string    `json:"room_id"`
+	GameType    string    `json:"game_type"`
+	HostID      string    `json:"host_id"`
+	HostRecord  RecordDTO `json:"host_record"`
+	HasPassword bool      `json:"has_password"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type LobbyDTO struct {
+	Rooms []LobbyRoomDTO `json:"rooms"`
+}
+
+type LobbyRoomRemovedDTO struct {
+	RoomID string `json:"room_id"`
+}
+
+func FromLobbyRoom(room service.LobbyRoom) LobbyRoomDTO {
+	return LobbyRoomDTO{
+		RoomID:      room.RoomID,
+		GameType:    room.GameType,
+		HostID:      room.HostID,
+		HostRecord:  fromRecord(room.HostRecord),
+		HasPassword: room.HasPassword,
+		CreatedAt:   room.CreatedAt,
+	}
+}
+
+func FromLobby(rooms []service.LobbyRoom) LobbyDTO {
+	lobby := LobbyDTO{Rooms: make([]LobbyRoomDTO, 0, len(rooms))}
+	for _, room := range rooms {
+		lobby.Rooms = append(lobby.Rooms, FromLobbyRoom(room))
+	}
+	return lobby
+}
@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/game"
+)
+
+type ReactionSendPayload struct {
+	ReactionID string `json:"reaction_id"`
+}
+
+type ReactionDTO struct {
+	RoomID     string    `json:"room_id"`
+	PlayerID   string    `json:"player_id"`
+	PlayerMark string    `json:"player_mark"`
+	ReactionID string    `json:"reaction_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ReactionCatalogDTO struct {
+	Reactions           []string `json:"reactions"`
+	CooldownMillis      int64    `json:"cooldown_ms"`
+	TypingTimeoutMillis int64    `json:"typing_timeout_ms"`
+}
+
+type TypingDTO struct {
+	RoomID    string     `json:"room_id"`
+	PlayerID  string     `json:"player_id"`
+	Typing    bool       `json:"typing"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func FromReaction(reaction game.Reaction) ReactionDTO {
+	return ReactionDTO{
+		RoomID:     reaction.RoomID,
+		PlayerID:   reaction.PlayerID,
+		PlayerMark: reaction.PlayerMark,
+		ReactionID: reaction.ReactionID,
+		CreatedAt:  reaction.CreatedAt,
+	}
+}
+
+func FromReactionCatalog() ReactionCatalogDTO {
+	return ReactionCatalogDTO{
+		Reactions:           append([]string(nil), game.ReactionCatalog...),
+		CooldownMillis:      game.ReactionCooldown.Milliseconds(),
+		TypingTimeoutMillis: game.DefaultTypingTimeout.Milliseconds(),
+	}
+}
+
+func FromTypingState(state game.TypingState) TypingDTO {
+	dto := TypingDTO{
+		RoomID:   state.RoomID,
+		PlayerID: state.PlayerID,
+		Typing:   state.Typing,
+	}
+	if state.Typing {
+		expiresAt := state.ExpiresAt
+		dto.ExpiresAt = &expiresAt
+	}
+	return dto
+}
@@ -0,0 +1,137 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/stats"
+)
+
+type RecordDTO struct {
+	Games   int     `json:"games"`
+	Wins    int     `json:"wins"`
+	Losses  int     `json:"losses"`
+	Draws   int     `json:"draws"`
+	WinRate float64 `json:"win_rate"`
+}
+
+type LeaderboardEntryDTO struct {
+	Rank     int     `json:"rank"`
+	PlayerID string  `json:"player_id"`
+	Points   float64 `json:"points"`
+	RecordDTO
+}
+
+type LeaderboardDTO struct {
+	Game    string                `json:"game,omitempty"`
+	Entries []LeaderboardEntryDTO `json:"entries"`
+}
+
+type StreakDTO struct {
+	Result string `json:"result,omitempty"`
+	Length int    `json:"length"`
+}
+
+type AILevelStatsDTO struct {
+	Level int `json:"level"`
+	RecordDTO
+}
+
+type OpeningStatsDTO struct {
+	Opening string `json:"opening"`
+	RecordDTO
+}
+
+type GameRecordDTO struct {
+	RoomID          string    `json:"room_id"`
+	GameType        string    `json:"game_type"`
+	OpponentID      string    `json:"opponent_id"`
+	OpponentIsAI    bool      `json:"opponent_is_ai"`
+	AILevel         int       `json:"ai_level,omitempty"`
+	Result          string    `json:"result"`
+	Moves           int       `json:"moves"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Opening         string    `json:"opening,omitempty"`
+	FinishedAt      time.Time `json:"finished_at"`
+}
+
+type PlayerStatsDTO struct {
+	PlayerID               string               `json:"player_id"`
+	Overall                RecordDTO            `json:"overall"`
+	ByGame                 map[string]RecordDTO `json:"by_game"`
+	CurrentStreak          StreakDTO            `json:"current_streak"`
+	LongestWinStreak       int                  `json:"longest_win_streak"`
+	VsAI                   []AILevelStatsDTO    `json:"vs_ai"`
+	FavoriteOpenings       []OpeningStatsDTO    `json:"favorite_openings"`
+	AverageMoves           float64              `json:"average_moves"`
+	AverageDurationSeconds float64              `json:"average_duration_seconds"`
+	RecentGames            []GameRecordDTO      `json:"recent_games"`
+}
+
+func FromLeaderboard(game string, entries []stats.LeaderboardEntry) LeaderboardDTO {
+	dto := LeaderboardDTO{
+		Game:    game,
+		Entries: make([]LeaderboardEntryDTO, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		dto.Entries = append(dto.Entries, LeaderboardEntryDTO{
+			Rank:      entry.Rank,
+			PlayerID:  entry.PlayerID,
+			Points:    entry.Points,
+			RecordDTO: fromRecord(entry.Record),
+		})
+	}
+	return dto
+}
+
+func FromPlayerStats(playerStats stats.PlayerStats) PlayerStatsDTO {
+	dto := PlayerStatsDTO{
+		PlayerID: playerStats.PlayerID,
+		Overall:  fromRecord(playerStats.Overall),
+		ByGame:   make(map[string]RecordDTO, len(playerStats.ByGame)),
+		CurrentStreak: StreakDTO{
+			Result: string(playerStats.CurrentStreak.Result),
+			Length: playerStats.CurrentStreak.Length,
+		},
+		LongestWinStreak:       playerStats.LongestWinStreak,
+		VsAI:                   make([]AILevelStatsDTO, 0, len(playerStats.VsAI)),
+		FavoriteOpenings:       make([]OpeningStatsDTO, 0, len(playerStats.FavoriteOpenings)),
+		AverageMoves:           playerStats.AverageMoves,
+		AverageDurationSeconds: playerStats.AverageDuration.Seconds(),
+		RecentGames:            make([]GameRecordDTO, 0, len(playerStats.RecentGames)),
+	}
+
+	for gameType, record := range playerStats.ByGame {
+		dto.ByGame[gameType] = fromRecord(record)
+	}
+	for _, level := range playerStats.VsAI {
+		dto.VsAI = append(dto.VsAI, AILevelStatsDTO{Level: level.Level, RecordDTO: fromRecord(level.Record)})
+	}
+	for _, opening := range playerStats.FavoriteOpenings {
+		dto.FavoriteOpenings = append(dto.FavoriteOpenings, OpeningStatsDTO{Opening: opening.Opening, RecordDTO: fromRecord(opening.Record)})
+	}
+	for _, game := range playerStats.RecentGames {
+		dto.RecentGames = append(dto.RecentGames, GameRecordDTO{
+			RoomID:          game.RoomID,
+			GameType:        game.GameType,
+			OpponentID:      game.OpponentID,
+			OpponentIsAI:    game.OpponentIsAI,
+			AILevel:         game.AILevel,
+			Result:          string(game.Result),
+			Moves:           game.Moves,
+			DurationSeconds: game.Duration.Seconds(),
+			Opening:         game.Opening,
+			FinishedAt:      game.FinishedAt,
+		})
+	}
+	return dto
+}
+
+func fromRecord(record stats.Record) RecordDTO {
+	return RecordDTO{
+		Games:   record.Games,
+		Wins:    record.Wins,
+		Losses:  record.Losses,
+		Draws:   record.Draws,
+		WinRate: record.WinRate,
+	}
+}
@@ -0,0 +1,134 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/tournament"
+)
+
+type CreateTournamentRequest struct {
+	PlayerID string `json:"player_id,omitempty"`
+	Name     string `json:"name"`
+	Format   string `json:"format"`
+	GameType string `json:"game_type"`
+	Rounds   int    `json:"rounds,omitempty"`
+}
+
+type RegisterTournamentPlayerRequest struct {
+	PlayerID string `json:"player_id"`
+}
+
+type StartTournamentRequest struct {
+	PlayerID string `json:"player_id,omitempty"`
+}
+
+type RecordTournamentResultRequest struct {
+	PlayerID string `json:"player_id,omitempty"`
+	Round    int    `json:"round"`
+	Board    int    `json:"board"`
+	Result   string `json:"result"`
+}
+
+type TournamentDTO struct {
+	ID           string                   `json:"id"`
+	Name         string                   `json:"name"`
+	CreatorID    string                   `json:"creator_id"`
+	Format       string                   `json:"format"`
+	GameType     string                   `json:"game_type"`
+	Status       string                   `json:"status"`
+	Players      []string                 `json:"players"`
+	CurrentRound int                      `json:"current_round"`
+	TotalRounds  int                      `json:"total_rounds"`
+	Rounds       [][]TournamentPairingDTO `json:"rounds"`
+	Standings    []TournamentStandingDTO  `json:"standings"`
+	WinnerID     string                   `json:"winner_id,omitempty"`
+	CreatedAt    time.Time                `json:"created_at"`
+}
+
+type TournamentPairingDTO struct {
+	Round   int    `json:"round"`
+	Board   int    `json:"board"`
+	WhiteID string `json:"white_id"`
+	BlackID string `json:"black_id,omitempty"`
+	RoomID  string `json:"room_id,omitempty"`
+	Result  string `json:"result"`
+}
+
+type TournamentStandingDTO struct {
+	Rank            int     `json:"rank"`
+	PlayerID        string  `json:"player_id"`
+	Points          float64 `json:"points"`
+	Buchholz        float64 `json:"buchholz"`
+	SonnebornBerger float64 `json:"sonneborn_berger"`
+	Wins            int     `json:"wins"`
+	Draws           int     `json:"draws"`
+	Losses          int     `json:"losses"`
+	Byes            int     `json:"byes"`
+}
+
+type TournamentGameStartedDTO struct {
+	TournamentID string `json:"tournament_id"`
+	Round        int    `json:"round"`
+	Board        int    `json:"board"`
+	RoomID       string `json:"room_id"`
+	GameType     string `json:"game_type"`
+	OpponentID   string `json:"opponent_id"`
+	Color        string `json:"color"`
+}
+
+func FromTournamentSnapshot(snapshot tournament.Snapshot) TournamentDTO {
+	rounds := make([][]TournamentPairingDTO, 0, len(snapshot.Rounds))
+	for _, round := range snapshot.Rounds {
+		pairings := make([]TournamentPairingDTO, 0, len(round))
+		for _, pairing := range round {
+			result := string(pairing.Result)
+			if result == "" {
+				result = "pending"
+			}
+			pairings = append(pairings, TournamentPairingDTO{
+				Round:   pairing.Round,
+				Board:   pairing.Board,
+				WhiteID: pairing.WhiteID,
+				BlackID: pairing.BlackID,
+				RoomID:  pairing.RoomID,
+				Result:  result,
+			})
+		}
+		rounds = append(rounds, pairings)
+	}
+
+	players := append([]string{}, snapshot.Players...)
+	return TournamentDTO{
+		ID:           snapshot.ID,
+		Name:         snapshot.Name,
+		CreatorID:    snapshot.CreatorID,
+		Format:       string(snapshot.Format),
+		GameType:     snapshot.GameType,
+		Status:       string(snapshot.Status),
+		Players:      players,
+		CurrentRound: snapshot.CurrentRound,
+		TotalRounds:  snapshot.TotalRounds,
+		Rounds:       rounds,
+		Standings:    FromTournamentStandings(snapshot.Standings),
+		WinnerID:     snapshot.WinnerID,
+		CreatedAt:    snapshot.CreatedAt,
+	}
+}
+
+func FromTournamentStandings(standings []tournament.Standing) []TournamentStandingDTO {
+	dtos := make([]TournamentStandingDTO, 0, len(standings))
+	for _, standing := range standings {
+		dtos = append(dtos, TournamentStandingDTO{
+			Rank:            standing.Rank,
+			PlayerID:        standing.PlayerID,
+			Points:          standing.Points,
+			Buchholz:        standing.Buchholz,
+			SonnebornBerger: standing.SonnebornBerger,
+			Wins:            standing.Wins,
+			Draws:           standing.Draws,
+			Losses:          standing.Losses,
+			Byes:            standing.Byes,
+		})
+	}
+	return dtos
+}
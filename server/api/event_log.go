@@ -0,0 +1,257 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+const (
+	// eventLogSize bounds the deliveries kept per room for replay: one for
+	// each player a room event went to, and one for each reply to a single
+	// connection.
+	eventLogSize = 256
+	// eventLogIdleTTL is how long the log of a room without events is kept.
+	eventLogIdleTTL = 30 * time.Minute
+)
+
+type ResumePayload struct {
+	LastSeq uint64 `json:"last_seq"`
+}
+
+type ResumedPayload struct {
+	LastSeq  uint64 `json:"last_seq"`
+	Replayed int    `json:"replayed"`
+	Snapshot bool   `json:"snapshot"`
+}
+
+type loggedEvent struct {
+	seq      uint64
+	playerID string
+	// connectionID is set on a reply to one connection, such as an ack or an
+	// error, which only that connection may get again.
+	connectionID string
+	eventType    string
+	message      []byte
+}
+
+// roomEventLog numbers the events of one room and keeps the latest
+// deliveries, so a player who lost their connection can get what they
+// missed. Events are delivered while the log is held, which keeps every
+// connection's queue in sequence order.
+type roomEventLog struct {
+	mu  sync.Mutex
+	seq uint64
+	// trimmed is the highest sequence number with deliveries dropped from
+	// the log; replays must start after it.
+	trimmed uint64
+	events  []loggedEvent
+	used    atomic.Int64
+}
+
+func (l *roomEventLog) next() uint64 {
+	l.seq++
+	return l.seq
+}
+
+func (l *roomEventLog) record(seq uint64, playerID string, connectionID string, eventType string, message []byte) {
+	l.events = append(l.events, loggedEvent{
+		seq:          seq,
+		playerID:     playerID,
+		connectionID: connectionID,
+		eventType:    eventType,
+		message:      message,
+	})
+	if over := len(l.events) - eventLogSize; over > 0 {
+		l.trimmed = l.events[over-1].seq
+		l.events = l.events[over:]
+	}
+}
+
+// since returns the events after seq of the player on the connection
+// client: what went to the player, and the replies to that connection but
+// not to the player's other ones. It reports false when the log no longer
+// holds all of them, or when seq is from before the log was started, for
+// example on another node.
+func (l *roomEventLog) since(client *Client, seq uint64) ([]loggedEvent, bool) {
+	if seq < l.trimmed || seq > l.seq {
+		return nil, false
+	}
+
+	var events []loggedEvent
+	for _, event := range l.events {
+		if event.seq <= seq || event.playerID != client.PlayerID {
+			continue
+		}
+		if event.connectionID == "" || event.connectionID == client.ConnectionID {
+			events = append(events, event)
+		}
+	}
+	return events, true
+}
+
+// withRoomLog runs fn holding the event log of the room.
+func (r *ClientRegistry) withRoomLog(roomID string, fn func(log *roomEventLog)) {
+	now := time.Now()
+
+	r.mu.Lock()
+	log := r.logs[roomID]
+	if log == nil {
+		r.pruneEventLogsLocked(now)
+		log = &roomEventLog{}
+		r.logs[roomID] = log
+	}
+	log.used.Store(now.UnixNano())
+	r.mu.Unlock()
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	fn(log)
+}
+
+func (r *ClientRegistry) pruneEventLogsLocked(now time.Time) {
+	for roomID, log := range r.logs {
+		if now.Sub(time.Unix(0, log.used.Load())) > eventLogIdleTTL {
+			delete(r.logs, roomID)
+		}
+	}
+}
+
+// sendRoomEvent numbers an event sent to one connection and logs it for
+// that connection alone.
+func sendRoomEvent(ctx context.Context, client *Client, event Event) {
+	client.clients.withRoomLog(client.RoomID, func(log *roomEventLog) {
+		event.Seq = log.next()
+		messageBytes, ok := marshalEvent(event, client)
+		if !ok {
+			return
+		}
+		log.record(event.Seq, client.PlayerID, client.ConnectionID, event.Type, messageBytes)
+		enqueueClientEvent(client, event.Type, messageBytes)
+		flushRelayClient(ctx, client)
+	})
+}
+
+// sendStateEvent sends a connection the room's current state, stamped with
+// the last sequence number it covers. State is not logged: a replay always
+// ends with the events that changed it.
+func sendStateEvent(ctx context.Context, client *Client, eventType string, payload interface{}) {
+	if client.clients == nil || client.RoomID == "" {
+		sendEvent(ctx, client, eventType, payload)
+		return
+	}
+
+	client.clients.withRoomLog(client.RoomID, func(log *roomEventLog) {
+		sendStateEventLocked(ctx, log, client, eventType, payload)
+	})
+}
+
+func sendStateEventLocked(ctx context.Context, log *roomEventLog, client *Client, eventType string, payload interface{}) {
+	messageBytes, ok := marshalEvent(Event{
+		Type:    eventType,
+		Payload: marshalPayload(payload),
+		TraceID: observability.TraceID(ctx),
+		Seq:     log.seq,
+	}, client)
+	if !ok {
+		return
+	}
+	enqueueClientEvent(client, eventType, messageBytes)
+	flushRelayClient(ctx, client)
+}
+
+// resumeClient attaches a connection whose player saw the room's events up
+// to lastSeq and replays what they missed, or sends the room's state in full
+// when the log no longer reaches back that far. attach runs holding the log,
+// so no event is both replayed and delivered live.
+func resumeClient(ctx context.Context, clients *ClientRegistry, gameService *service.GameService, roomID string, lastSeq uint64, attach func() *Client) *Client {
+	var client *Client
+	clients.withRoomLog(roomID, func(log *roomEventLog) {
+		client = attach()
+		events, covered := log.since(client, lastSeq)
+		for _, event := range events {
+			enqueueClientEvent(client, event.eventType, event.message)
+		}
+		if !covered {
+			sendRoomStateLocked(ctx, log, client, gameService)
+		}
+
+		observability.Logger().InfoContext(ctx, "websocket session resumed",
+			"room_id", roomID,
+			"player_id", client.PlayerID,
+			"event_type", "websocket_resumed",
+			"last_seq", lastSeq,
+			"replayed", len(events),
+			"snapshot", !covered,
+		)
+		sendStateEventLocked(ctx, log, client, EventResumed, ResumedPayload{
+			LastSeq:  log.seq,
+			Replayed: len(events),
+			Snapshot: !covered,
+		})
+	})
+	return client
+}
+
+func sendRoomStateLocked(ctx context.Context, log *roomEventLog, client *Client, gameService *service.GameService) {
+	snapshot, err := gameService.RoomSnapshotWithContext(ctx, client.RoomID)
+	if err != nil {
+		observability.Logger().WarnContext(ctx, "room snapshot failed",
+			"room_id", client.RoomID,
+			"player_id", client.PlayerID,
+			"event_type", "room_snapshot_error",
+			"error", err,
+		)
+		return
+	}
+	sendStateEventLocked(ctx, log, client, EventRoomUpdate, dto.FromRoomSnapshotForPlayer(snapshot, client.PlayerID))
+
+	history, err := gameService.ChatHistoryWithContext(ctx, client.RoomID, client.PlayerID)
+	if err != nil {
+		return
+	}
+	sendStateEventLocked(ctx, log, client, EventChatHistory, dto.FromChatHistory(history))
+}
+
+func processResume(ctx context.Context, clients *ClientRegistry, gameService *service.GameService, roomID string, client *Client, message WebSocketMessage) error {
+	var payload ResumePayload
+	if err := json.Unmarshal(message.Payload, &payload); err != nil {
+		sendErrorMessage(ctx, client, "Invalid resume payload")
+		return err
+	}
+
+	resumeClient(ctx, clients, gameService, roomID, payload.LastSeq, func() *Client { return client })
+	return nil
+}
+
+// resumeFromQuery reads the last_seq a reconnecting client passes on /ws.
+func resumeFromQuery(r *http.Request) (uint64, bool) {
+	value := r.URL.Query().Get("last_seq")
+	if value == "" {
+		return 0, false
+	}
+	lastSeq, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return lastSeq, true
+}
+
+// flushRelayClient passes on at once what a relay client queued, so events
+// relayed to other nodes keep the order of the room's log.
+func flushRelayClient(ctx context.Context, client *Client) {
+	if !client.relay {
+		return
+	}
+	if cluster := client.clients.Cluster(); cluster != nil {
+		cluster.flush(ctx, client)
+	}
+}
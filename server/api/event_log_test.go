@@ -0,0 +1,194 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tsaqiffatih/mini-game/actions"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/infrastructure"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+func TestRoomEventLog_SinceReportsWhetherTheLogReachesBack(t *testing.T) {
+	log := &roomEventLog{}
+	for i := 0; i < eventLogSize; i++ {
+		seq := log.next()
+		log.record(seq, "p1", "", EventGameUpdate, []byte(`{}`))
+		log.record(seq, "p2", "", EventGameUpdate, []byte(`{}`))
+	}
+	p1 := &Client{PlayerID: "p1"}
+
+	events, covered := log.since(p1, log.seq-3)
+	if !covered || len(events) != 3 {
+		t.Fatalf("since(last 3) = %d events, covered %v, want 3, true", len(events), covered)
+	}
+	if _, covered := log.since(p1, 1); covered {
+		t.Fatalf("since(1) covered = true, want false after the log was trimmed")
+	}
+	if _, covered := log.since(p1, log.seq+1); covered {
+		t.Fatalf("since(future seq) covered = true, want false")
+	}
+	if events, covered := log.since(p1, log.seq); !covered || len(events) != 0 {
+		t.Fatalf("since(current seq) = %d events, covered %v, want 0, true", len(events), covered)
+	}
+}
+
+func TestHandleWebSocket_ResumeReplaysMissedEvents(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	server := newWebSocketTestServer(t, NewClientRegistry(), gameService)
+
+	first := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	lastSeq := readTestWebSocketEvent(t, first, EventChatHistory).Seq
+	first.Close()
+
+	p2 := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p2")
+	defer p2.Close()
+	payload, _ := json.Marshal(dto.ChatSendPayload{Message: "are you there?"})
+	if err := p2.WriteJSON(WebSocketMessage{Type: actions.CHAT_SEND, Payload: payload}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	readTestWebSocketEvent(t, p2, EventChatMessage)
+
+	resumed := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1&last_seq="+strconv.FormatUint(lastSeq, 10))
+	defer resumed.Close()
+
+	var replayed []Event
+	for {
+		event := readNextTestWebSocketEvent(t, resumed)
+		if event.Type == EventResumed {
+			var payload ResumedPayload
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				t.Fatalf("decode resumed: %v", err)
+			}
+			if payload.Snapshot || payload.Replayed != len(replayed) {
+				t.Fatalf("resumed = %+v, want %d replayed events and no snapshot", payload, len(replayed))
+			}
+			break
+		}
+		if event.Seq <= lastSeq {
+			t.Fatalf("replayed %s seq = %d, want above %d", event.Type, event.Seq, lastSeq)
+		}
+		replayed = append(replayed, event)
+	}
+
+	var chat bool
+	for _, event := range replayed {
+		if event.Type == EventRoomUpdate {
+			t.Fatalf("resume sent a room_update snapshot, want only the missed events")
+		}
+		chat = chat || event.Type == EventChatMessage
+	}
+	if !chat {
+		t.Fatalf("replayed %v, want the missed chat_message", replayed)
+	}
+}
+
+func TestHandleWebSocket_ResumeSkipsRepliesToOtherTabs(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	server := newWebSocketTestServer(t, NewClientRegistry(), gameService)
+
+	tab := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	defer tab.Close()
+	readTestWebSocketEvent(t, tab, EventChatHistory)
+	dropped := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	lastSeq := readTestWebSocketEvent(t, dropped, EventChatHistory).Seq
+	dropped.Close()
+
+	// The open tab gets an ack, a nack and an error of its own; then p2 says
+	// something the whole room hears.
+	writeTestCommand(t, tab, ticTacToeCommand(t, "move-1", 0, 0, nil))
+	readTestAck(t, tab)
+	writeTestCommand(t, tab, ticTacToeCommand(t, "move-2", 0, 0, nil))
+	readTestNack(t, tab)
+	p2 := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p2")
+	defer p2.Close()
+	payload, _ := json.Marshal(dto.ChatSendPayload{Message: "your move"})
+	if err := p2.WriteJSON(WebSocketMessage{Type: actions.CHAT_SEND, Payload: payload}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	readTestWebSocketEvent(t, tab, EventChatMessage)
+
+	resumed := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1&last_seq="+strconv.FormatUint(lastSeq, 10))
+	defer resumed.Close()
+
+	seen := map[uint64]bool{}
+	chat := 0
+	for {
+		event := readNextTestWebSocketEvent(t, resumed)
+		if event.Type == EventResumed {
+			break
+		}
+		switch event.Type {
+		case EventAck, EventNack, "error":
+			t.Fatalf("replayed %s of the other tab", event.Type)
+		case EventChatMessage:
+			chat++
+		}
+		if seen[event.Seq] {
+			t.Fatalf("replayed seq %d twice", event.Seq)
+		}
+		seen[event.Seq] = true
+	}
+	if chat != 1 {
+		t.Fatalf("replayed %d chat messages, want p2's once", chat)
+	}
+}
+
+func TestHandleWebSocket_ResumeFromUnknownSeqSendsSnapshot(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	server := newWebSocketTestServer(t, NewClientRegistry(), gameService)
+
+	conn := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1&last_seq=9999")
+	defer conn.Close()
+
+	snapshot := readNextTestWebSocketEvent(t, conn)
+	if snapshot.Type != EventRoomUpdate {
+		t.Fatalf("first event = %s, want %s", snapshot.Type, EventRoomUpdate)
+	}
+	resumed := readTestWebSocketEvent(t, conn, EventResumed)
+	var payload ResumedPayload
+	if err := json.Unmarshal(resumed.Payload, &payload); err != nil {
+		t.Fatalf("decode resumed: %v", err)
+	}
+	if !payload.Snapshot || payload.LastSeq != snapshot.Seq {
+		t.Fatalf("resumed = %+v, want a snapshot at seq %d", payload, snapshot.Seq)
+	}
+}
+
+func newResumeTestRoom(t *testing.T) (*service.GameService, string) {
+	t.Helper()
+
+	gameService := service.NewGameService(infrastructure.NewMemoryRoomRepository(), game.NewPlayerManager())
+	for _, playerID := range []string{"p1", "p2"} {
+		if _, err := gameService.AddPlayer(playerID); err != nil {
+			t.Fatalf("AddPlayer(%s) error = %v", playerID, err)
+		}
+	}
+	res, err := gameService.CreateRoomWithContext(context.Background(), "tictactoe", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	if _, err := gameService.JoinRoom(res.Room.RoomID, "p2", "tictactoe"); err != nil {
+		t.Fatalf("JoinRoom() error = %v", err)
+	}
+	return gameService, res.Room.RoomID
+}
+
+func readNextTestWebSocketEvent(t *testing.T, conn *websocket.Conn) Event {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline() error = %v", err)
+	}
+	var event Event
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	return event
+}
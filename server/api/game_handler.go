@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"strings"
 
 	"github.com/tsaqiffatih/mini-game/actions"
@@ -13,18 +14,18 @@ import (
 )
 
 // Shared utility functions
-func parsePayload(ctx context.Context, client *Client, roomID, eventType string, rawMessage json.RawMessage, payload interface{}) bool {
+func parsePayload(ctx context.Context, client *Client, roomID, eventType string, rawMessage json.RawMessage, payload interface{}) error {
 	if err := json.Unmarshal(rawMessage, payload); err != nil {
-		sendErrorMessage(client, "Failed to unmarshal JSON into payload struct")
+		sendErrorMessage(ctx, client, "Failed to unmarshal JSON into payload struct")
 		observability.Logger().WarnContext(ctx, "websocket payload unmarshal failed",
 			"room_id", roomID,
 			"player_id", client.PlayerID,
 			"event_type", eventType,
 			"error", err,
 		)
-		return false
+		return err
 	}
-	return true
+	return nil
 }
 
 // TicTacToe-related functions
@@ -36,12 +37,12 @@ func processTicTacToeMove(
 	gameService *service.GameService,
 	roomID string,
 	message WebSocketMessage,
-) {
+) error {
 	var payload dto.TictactoeMovePayload
-	if !parsePayload(ctx, client, roomID, message.Type, message.Payload, &payload) {
-		return
+	if err := parsePayload(ctx, client, roomID, message.Type, message.Payload, &payload); err != nil {
+		return err
 	}
-	handleMakeMoveTictactoe(
+	return handleMakeMoveTictactoe(
 		ctx,
 		client,
 		clients,
@@ -49,6 +50,7 @@ func processTicTacToeMove(
 		roomID,
 		player.ID,
 		payload,
+		message.ExpectedStateVersion,
 	)
 }
 
@@ -60,23 +62,26 @@ func handleMakeMoveTictactoe(
 	roomID string,
 	playerID string,
 	payload dto.TictactoeMovePayload,
-) {
+	expectedVersion *uint64,
+) error {
 	_, err := gameService.HandleTicTacToeMoveWithContext(
 		ctx,
 		roomID,
 		playerID,
 		payload.Row,
 		payload.Col,
+		expectedVersion,
 	)
 	if err != nil {
-		sendErrorMessage(client, err.Error())
-		return
+		sendErrorMessage(ctx, client, err.Error())
+		return err
 	}
 
-	NotifyTicTacToeClients(clients, gameService, roomID)
+	NotifyTicTacToeClients(ctx, clients, gameService, roomID)
+	return nil
 }
 
-func NotifyTicTacToeClients(clients *ClientRegistry, gameService *service.GameService, roomID string) {
+func NotifyTicTacToeClients(ctx context.Context, clients *ClientRegistry, gameService *service.GameService, roomID string) {
 	snapshot, err := gameService.RoomSnapshot(roomID)
 	if err != nil {
 		observability.Logger().Warn("room snapshot failed",
@@ -92,10 +97,7 @@ func NotifyTicTacToeClients(clients *ClientRegistry, gameService *service.GameSe
 		return
 	}
 
-	NotifySnapshotToClients(clients, snapshot, Event{
-		Type:    EventGameUpdate,
-		Payload: marshalPayload(dto.FromRoomSnapshot(snapshot)),
-	})
+	NotifyGameUpdateToClients(ctx, clients, snapshot)
 }
 
 // Chess-related functions
@@ -107,20 +109,20 @@ func processChessMove(
 	gameService *service.GameService,
 	roomID string,
 	playerID string,
-	message WebSocketMessage) {
+	message WebSocketMessage) error {
 
 	var payload dto.ChessMovePayload
 	if err := json.Unmarshal(message.Payload, &payload); err != nil {
-		sendChessMoveRejectedWithCode(client, roomID, playerID, payload, "invalid_payload", "Invalid chess move payload")
+		sendChessMoveRejectedWithCode(ctx, client, roomID, playerID, payload, "invalid_payload", "Invalid chess move payload")
 		observability.Logger().WarnContext(ctx, "websocket chess move payload unmarshal failed",
 			"room_id", roomID,
 			"player_id", client.PlayerID,
 			"event_type", message.Type,
 			"error", err,
 		)
-		return
+		return err
 	}
-	handleChessMove(
+	return handleChessMove(
 		ctx,
 		client,
 		clients,
@@ -128,6 +130,7 @@ func processChessMove(
 		roomID,
 		playerID,
 		payload,
+		message.ExpectedStateVersion,
 	)
 }
 
@@ -139,7 +142,8 @@ func handleChessMove(
 	roomID string,
 	playerID string,
 	payload dto.ChessMovePayload,
-) {
+	expectedVersion *uint64,
+) error {
 	if _, err := gameService.HandleChessMoveWithContext(
 		ctx,
 		roomID,
@@ -147,9 +151,10 @@ func handleChessMove(
 		payload.From,
 		payload.To,
 		payload.Promotion,
+		expectedVersion,
 	); err != nil {
-		sendChessMoveRejected(client, roomID, playerID, payload, err)
-		return
+		sendChessMoveRejected(ctx, client, roomID, playerID, payload, err)
+		return err
 	}
 
 	snapshot, err := gameService.RoomSnapshotWithContext(ctx, roomID)
@@ -160,13 +165,11 @@ func handleChessMove(
 			"event_type", "room_snapshot_error",
 			"error", err,
 		)
-		return
+		return nil
 	}
 
-	NotifySnapshotToClients(clients, snapshot, Event{
-		Type:    EventGameUpdate,
-		Payload: marshalPayload(dto.FromRoomSnapshot(snapshot)),
-	})
+	NotifyGameUpdateToClients(ctx, clients, snapshot)
+	return nil
 }
 
 func processChessUndo(
@@ -176,10 +179,11 @@ func processChessUndo(
 	clients *ClientRegistry,
 	gameService *service.GameService,
 	roomID string,
-) {
-	if err := gameService.HandleChessUndoWithContext(ctx, roomID, player.ID); err != nil {
-		sendErrorMessage(client, err.Error())
-		return
+	message WebSocketMessage,
+) error {
+	if err := gameService.HandleChessUndoWithContext(ctx, roomID, player.ID, message.ExpectedStateVersion); err != nil {
+		sendErrorMessage(ctx, client, err.Error())
+		return err
 	}
 
 	snapshot, err := gameService.RoomSnapshotWithContext(ctx, roomID)
@@ -190,21 +194,132 @@ func processChessUndo(
 			"event_type", "room_snapshot_error",
 			"error", err,
 		)
+		return nil
+	}
+
+	NotifyGameUpdateToClients(ctx, clients, snapshot)
+	return nil
+}
+
+func processChessPremove(
+	ctx context.Context,
+	player game.PlayerSnapshot,
+	client *Client,
+	clients *ClientRegistry,
+	gameService *service.GameService,
+	roomID string,
+	message WebSocketMessage,
+) error {
+	var payload dto.ChessMovePayload
+	if err := json.Unmarshal(message.Payload, &payload); err != nil {
+		sendChessMoveRejectedWithCode(ctx, client, roomID, player.ID, payload, "invalid_payload", "Invalid chess premove payload")
+		return err
+	}
+
+	result, err := gameService.HandleChessPremoveWithContext(
+		ctx,
+		roomID,
+		player.ID,
+		payload.From,
+		payload.To,
+		payload.Promotion,
+		message.ExpectedStateVersion,
+	)
+	if err != nil {
+		sendChessMoveRejected(ctx, client, roomID, player.ID, payload, err)
+		return err
+	}
+
+	// A premove played on the player's own turn is a move everyone sees.
+	if result.Applied {
+		notifyChessClients(ctx, clients, gameService, roomID, player)
+		return nil
+	}
+	notifyChessPremoveOwner(ctx, clients, gameService, roomID, player)
+	return nil
+}
+
+func processChessPremoveCancel(
+	ctx context.Context,
+	player game.PlayerSnapshot,
+	client *Client,
+	clients *ClientRegistry,
+	gameService *service.GameService,
+	roomID string,
+	message WebSocketMessage,
+) error {
+	if err := gameService.CancelChessPremoveWithContext(ctx, roomID, player.ID, message.ExpectedStateVersion); err != nil {
+		sendErrorMessage(ctx, client, err.Error())
+		return err
+	}
+
+	notifyChessPremoveOwner(ctx, clients, gameService, roomID, player)
+	return nil
+}
+
+func processTakebackRequest(
+	ctx context.Context,
+	player game.PlayerSnapshot,
+	client *Client,
+	clients *ClientRegistry,
+	gameService *service.GameService,
+	roomID string,
+	message WebSocketMessage,
+) error {
+	if _, err := gameService.RequestTakebackWithContext(ctx, roomID, player.ID, message.ExpectedStateVersion); err != nil {
+		sendErrorMessage(ctx, client, err.Error())
+		return err
+	}
+
+	notifyTakebackClients(ctx, clients, gameService, roomID, player)
+	return nil
+}
+
+func processTakebackRespond(
+	ctx context.Context,
+	player game.PlayerSnapshot,
+	client *Client,
+	clients *ClientRegistry,
+	gameService *service.GameService,
+	roomID string,
+	message WebSocketMessage,
+) error {
+	var payload dto.TakebackRespondPayload
+	if err := json.Unmarshal(message.Payload, &payload); err != nil {
+		sendErrorMessage(ctx, client, "Invalid takeback payload")
+		return err
+	}
+
+	if _, err := gameService.RespondTakebackWithContext(ctx, roomID, player.ID, payload.Accept, message.ExpectedStateVersion); err != nil {
+		sendErrorMessage(ctx, client, err.Error())
+		return err
+	}
+
+	notifyTakebackClients(ctx, clients, gameService, roomID, player)
+	return nil
+}
+
+func notifyTakebackClients(ctx context.Context, clients *ClientRegistry, gameService *service.GameService, roomID string, player game.PlayerSnapshot) {
+	snapshot, err := gameService.RoomSnapshotWithContext(ctx, roomID)
+	if err != nil {
+		observability.Logger().WarnContext(ctx, "room snapshot failed after takeback",
+			"room_id", roomID,
+			"player_id", player.ID,
+			"event_type", "room_snapshot_error",
+			"error", err,
+		)
 		return
 	}
 
-	NotifySnapshotToClients(clients, snapshot, Event{
-		Type:    EventGameUpdate,
-		Payload: marshalPayload(dto.FromRoomSnapshot(snapshot)),
-	})
+	NotifyGameUpdateToClients(ctx, clients, snapshot)
 }
 
-func sendChessMoveRejected(client *Client, roomID string, playerID string, payload dto.ChessMovePayload, err error) {
-	sendChessMoveRejectedWithCode(client, roomID, playerID, payload, chessMoveRejectionCode(err), err.Error())
+func sendChessMoveRejected(ctx context.Context, client *Client, roomID string, playerID string, payload dto.ChessMovePayload, err error) {
+	sendChessMoveRejectedWithCode(ctx, client, roomID, playerID, payload, chessMoveRejectionCode(err), err.Error())
 }
 
-func sendChessMoveRejectedWithCode(client *Client, roomID string, playerID string, payload dto.ChessMovePayload, code string, message string) {
-	sendEvent(client, actions.CHESS_MOVE_REJECTED, dto.ChessMoveRejectedDTO{
+func sendChessMoveRejectedWithCode(ctx context.Context, client *Client, roomID string, playerID string, payload dto.ChessMovePayload, code string, message string) {
+	sendEvent(ctx, client, actions.CHESS_MOVE_REJECTED, dto.ChessMoveRejectedDTO{
 		RoomID:        roomID,
 		PlayerID:      playerID,
 		AttemptedMove: payload,
@@ -218,6 +333,9 @@ func chessMoveRejectionCode(err error) string {
 	if err == nil {
 		return "unknown"
 	}
+	if errors.Is(err, game.ErrStateVersionMismatch) {
+		return "state_version_mismatch"
+	}
 	message := strings.ToLower(err.Error())
 	switch {
 	case strings.Contains(message, "not your turn"):
@@ -228,6 +346,8 @@ func chessMoveRejectionCode(err error) string {
 		return "game_not_active"
 	case strings.Contains(message, "player not found"):
 		return "player_not_in_room"
+	case strings.Contains(message, "premove"):
+		return "invalid_premove"
 	case strings.Contains(message, "illegal move"), strings.Contains(message, "invalid move"):
 		return "illegal_move"
 	default:
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/auth"
 	"github.com/tsaqiffatih/mini-game/game"
 	"github.com/tsaqiffatih/mini-game/service"
 )
@@ -17,13 +18,14 @@ type Response struct {
 	Message string      `json:"-"`
 }
 
-func RegisterRouter(r *mux.Router, clients *ClientRegistry, gameService *service.GameService) {
+func RegisterRouter(r *mux.Router, clients *ClientRegistry, gameService *service.GameService, authService *service.AuthService) {
+	// Kept for clients that predate /auth/guest; it signs in a guest.
 	r.HandleFunc("/create/user", func(w http.ResponseWriter, r *http.Request) {
-		addPlayer(w, r, gameService)
+		signInGuest(w, r, authService)
 	}).Methods("POST")
 
 	r.HandleFunc("/room/join", func(w http.ResponseWriter, r *http.Request) {
-		joinRoom(w, r, gameService)
+		joinRoom(w, r, clients, gameService)
 	}).Methods("POST")
 
 	r.HandleFunc("/room/create", func(w http.ResponseWriter, r *http.Request) {
@@ -34,17 +36,41 @@ func RegisterRouter(r *mux.Router, clients *ClientRegistry, gameService *service
 		createRoomWithAi(w, r, gameService)
 	}).Methods("POST")
 
+	r.HandleFunc("/players/{player_id}/rooms", func(w http.ResponseWriter, r *http.Request) {
+		listPlayerRooms(w, r, gameService)
+	}).Methods("GET")
+
 	r.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		HandleWebSocket(w, r, clients, gameService)
 	})
 
+	r.HandleFunc("/room/{room_id}/events", func(w http.ResponseWriter, r *http.Request) {
+		HandleRoomEvents(w, r, clients, gameService)
+	}).Methods("GET")
+
+	r.HandleFunc("/room/{room_id}/actions", func(w http.ResponseWriter, r *http.Request) {
+		HandleRoomActions(w, r, clients, gameService)
+	}).Methods("POST")
+
+	r.HandleFunc("/room/{room_id}/chat", func(w http.ResponseWriter, r *http.Request) {
+		getRoomChat(w, r, gameService)
+	}).Methods("GET")
+
+	r.HandleFunc("/chat/search", func(w http.ResponseWriter, r *http.Request) {
+		searchChat(w, r, gameService)
+	}).Methods("GET")
+
+	r.HandleFunc("/reactions", getReactions).Methods("GET")
+
 }
 
 func createRoom(w http.ResponseWriter, r *http.Request, gameService *service.GameService) {
 
 	var request struct {
-		GameType string `json:"game_type"`
-		PlayerID string `json:"player_id"`
+		GameType   string `json:"game_type"`
+		PlayerID   string `json:"player_id"`
+		Visibility string `json:"visibility,omitempty"`
+		Password   string `json:"password,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -52,7 +78,15 @@ func createRoom(w http.ResponseWriter, r *http.Request, gameService *service.Gam
 		return
 	}
 
-	res, err := gameService.CreateRoomWithContext(r.Context(), request.GameType, request.PlayerID)
+	playerID, ok := requestPlayerID(w, r, request.PlayerID)
+	if !ok {
+		return
+	}
+
+	res, err := gameService.CreateRoomWithOptionsWithContext(r.Context(), request.GameType, playerID, service.RoomOptions{
+		Visibility: game.RoomVisibility(request.Visibility),
+		Password:   request.Password,
+	})
 	if err != nil {
 		writeErrorResponse(w, createRoomStatus(err), err.Error())
 		return
@@ -74,7 +108,12 @@ func createRoomWithAi(w http.ResponseWriter, r *http.Request, gameService *servi
 		return
 	}
 
-	res, err := gameService.CreateRoomWithAILevelWithContext(r.Context(), request.GameType, request.PlayerID, request.AILevel)
+	playerID, ok := requestPlayerID(w, r, request.PlayerID)
+	if !ok {
+		return
+	}
+
+	res, err := gameService.CreateRoomWithAILevelWithContext(r.Context(), request.GameType, playerID, request.AILevel)
 	if err != nil {
 		writeErrorResponse(w, createRoomStatus(err), err.Error())
 		return
@@ -83,12 +122,14 @@ func createRoomWithAi(w http.ResponseWriter, r *http.Request, gameService *servi
 	writeSuccessResponse(w, http.StatusCreated, dto.FromJoinRoomResponse(res))
 }
 
-func joinRoom(w http.ResponseWriter, r *http.Request, gameService *service.GameService) {
+func joinRoom(w http.ResponseWriter, r *http.Request, clients *ClientRegistry, gameService *service.GameService) {
 
 	var request struct {
 		RoomID   string `json:"room_id"`
 		PlayerID string `json:"player_id"`
 		GameType string `json:"game_type"`
+		Password string `json:"password,omitempty"`
+		Invite   string `json:"invite,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -96,7 +137,21 @@ func joinRoom(w http.ResponseWriter, r *http.Request, gameService *service.GameS
 		return
 	}
 
-	res, err := gameService.JoinRoomWithContext(r.Context(), request.RoomID, request.PlayerID, request.GameType)
+	playerID, ok := requestPlayerID(w, r, request.PlayerID)
+	if !ok {
+		return
+	}
+
+	access := service.RoomAccess{Password: request.Password, Invite: request.Invite}
+	if cluster := clients.Cluster(); cluster != nil {
+		if owner, remote := cluster.remoteOwner(r.Context(), request.RoomID); remote {
+			claims, _ := auth.ClaimsFromContext(r.Context())
+			cluster.forwardJoin(w, r.Context(), owner, request.RoomID, playerID, claims.Nonce, request.GameType, access)
+			return
+		}
+	}
+
+	res, err := gameService.JoinRoomWithAccessWithContext(r.Context(), request.RoomID, playerID, request.GameType, access)
 	if err != nil {
 		writeErrorResponse(w, joinRoomStatus(err), err.Error())
 		return
@@ -105,24 +160,25 @@ func joinRoom(w http.ResponseWriter, r *http.Request, gameService *service.GameS
 	writeSuccessResponse(w, http.StatusOK, dto.FromJoinRoomResponse(res))
 }
 
-func addPlayer(w http.ResponseWriter, r *http.Request, gameService *service.GameService) {
-
-	var request struct {
-		PlayerID string `json:"player_id"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "Invalid request")
+// listPlayerRooms serves the rooms of the session player, for a dashboard of
+// their open games. Snapshots are the public view, without premoves.
+func listPlayerRooms(w http.ResponseWriter, r *http.Request, gameService *service.GameService) {
+	playerID, ok := requestPlayerID(w, r, mux.Vars(r)["player_id"])
+	if !ok {
 		return
 	}
 
-	player, err := gameService.AddPlayer(request.PlayerID)
+	snapshots, err := gameService.PlayerRoomsWithContext(r.Context(), playerID)
 	if err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	writeSuccessResponse(w, http.StatusCreated, dto.FromPlayerSnapshot(player))
+	rooms := make([]dto.RoomSnapshotDTO, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		rooms = append(rooms, dto.FromRoomSnapshot(snapshot))
+	}
+	writeSuccessResponse(w, http.StatusOK, rooms)
 }
 
 func writeSuccessResponse(w http.ResponseWriter, statusCode int, data interface{}) {
@@ -153,6 +209,10 @@ func createRoomStatus(err error) int {
 		return http.StatusNotFound
 	case service.ErrGameTypeRequired:
 		return http.StatusBadRequest
+	case game.ErrInvalidVisibility:
+		return http.StatusBadRequest
+	case service.ErrServerDraining:
+		return http.StatusServiceUnavailable
 	default:
 		return http.StatusBadRequest
 	}
@@ -166,6 +226,8 @@ func joinRoomStatus(err error) int {
 		return http.StatusNotFound
 	case game.ErrInvalidGameState:
 		return http.StatusBadRequest
+	case game.ErrRoomPrivate, game.ErrRoomPasswordInvalid, game.ErrPlayerKicked, game.ErrInviteUsed, service.ErrInviteInvalid:
+		return http.StatusForbidden
 	default:
 		return http.StatusNotFound
 	}
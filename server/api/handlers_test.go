@@ -8,8 +8,12 @@ import (
 	"testing"
 
 	"github.com/gorilla/mux"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/auth"
 	"github.com/tsaqiffatih/mini-game/game"
 	"github.com/tsaqiffatih/mini-game/infrastructure"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+	"github.com/tsaqiffatih/mini-game/middleware"
 	"github.com/tsaqiffatih/mini-game/service"
 	"github.com/tsaqiffatih/mini-game/tictactoe"
 )
@@ -17,6 +21,7 @@ import (
 type apiTestServer struct {
 	router  *mux.Router
 	service *service.GameService
+	auth    *service.AuthService
 }
 
 type apiResponse struct {
@@ -43,14 +48,21 @@ func newAPITestServer() apiTestServer {
 	roomRepository := infrastructure.NewMemoryRoomRepository()
 	playerManager := game.NewPlayerManager()
 	gameService := service.NewGameService(roomRepository, playerManager)
+	authService := service.NewAuthService(
+		infrastructure.NewMemoryAccountRepository(),
+		auth.NewTokenSigner([]byte("test-secret"), 0),
+		gameService,
+	)
 
 	router := mux.NewRouter()
-	RegisterRouter(router, NewClientRegistry(), gameService)
+	RegisterRouter(router, NewClientRegistry(), gameService, authService)
+	RegisterAuthRouter(router, authService)
 	registerAPITestAliases(router, gameService)
 
 	return apiTestServer{
 		router:  router,
 		service: gameService,
+		auth:    authService,
 	}
 }
 
@@ -317,3 +329,210 @@ func TestFullGameFlowAPI(t *testing.T) {
 		t.Fatalf("winner is empty")
 	}
 }
+
+func TestTournamentAPI_CreateRegisterAndStart(t *testing.T) {
+	server := newAPITestServer()
+	RegisterTournamentRouter(server.router, service.NewTournamentService(infrastructure.NewMemoryTournamentRepository(), server.service))
+	registerPlayerViaAPI(t, server, "p1")
+	registerPlayerViaAPI(t, server, "p2")
+
+	recorder := doJSONRequest(t, server.router, http.MethodPost, "/tournaments", map[string]interface{}{
+		"player_id": "p1",
+		"name":      "Friday",
+		"format":    "swiss",
+		"game_type": "chess",
+		"rounds":    1,
+	})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("create tournament status = %d, want %d; body=%s", recorder.Code, http.StatusCreated, recorder.Body.String())
+	}
+
+	var response apiResponse
+	decodeJSONResponse(t, recorder, &response)
+	var created dto.TournamentDTO
+	if err := json.Unmarshal(response.Data, &created); err != nil {
+		t.Fatalf("decode tournament data: %v", err)
+	}
+
+	for _, playerID := range []string{"p1", "p2"} {
+		recorder = doJSONRequest(t, server.router, http.MethodPost, "/tournaments/"+created.ID+"/players", map[string]string{
+			"player_id": playerID,
+		})
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("register %s status = %d, want %d; body=%s", playerID, recorder.Code, http.StatusOK, recorder.Body.String())
+		}
+	}
+
+	recorder = doJSONRequest(t, server.router, http.MethodPost, "/tournaments/"+created.ID+"/start", map[string]string{
+		"player_id": "p2",
+	})
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("start by p2 status = %d, want %d; body=%s", recorder.Code, http.StatusForbidden, recorder.Body.String())
+	}
+
+	recorder = doJSONRequest(t, server.router, http.MethodPost, "/tournaments/"+created.ID+"/start", map[string]string{
+		"player_id": "p1",
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("start status = %d, want %d; body=%s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+
+	decodeJSONResponse(t, recorder, &response)
+	var started dto.TournamentDTO
+	if err := json.Unmarshal(response.Data, &started); err != nil {
+		t.Fatalf("decode tournament data: %v", err)
+	}
+	if started.Status != "RUNNING" || len(started.Rounds) != 1 || started.Rounds[0][0].RoomID == "" {
+		t.Fatalf("tournament = %+v, want running with a room for round 1", started)
+	}
+
+	recorder = doJSONRequest(t, server.router, http.MethodPost, "/tournaments/"+created.ID+"/players", map[string]string{
+		"player_id": "p1",
+	})
+	if recorder.Code != http.StatusConflict {
+		t.Fatalf("register after start status = %d, want %d", recorder.Code, http.StatusConflict)
+	}
+}
+
+func TestAuthAPI_SessionTokenIdentifiesPlayer(t *testing.T) {
+	server := newAPITestServer()
+	server.router.Use(middleware.Authenticate(server.auth, IsPublicRoute))
+
+	recorder := doJSONRequest(t, server.router, http.MethodPost, "/create/user", map[string]string{
+		"player_id": "guest1",
+	})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("guest sign-in status = %d, want %d; body=%s", recorder.Code, http.StatusCreated, recorder.Body.String())
+	}
+	var response apiResponse
+	decodeJSONResponse(t, recorder, &response)
+	var guest dto.SessionDTO
+	if err := json.Unmarshal(response.Data, &guest); err != nil {
+		t.Fatalf("decode session data: %v", err)
+	}
+	if guest.PlayerID != "guest1" || !guest.Guest || guest.Token == "" {
+		t.Fatalf("guest session = %+v, want guest1 with a token", guest)
+	}
+
+	recorder = doJSONRequest(t, server.router, http.MethodPost, "/auth/register", dto.CredentialsRequest{
+		Username: "alice",
+		Password: "correct horse",
+	})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("register status = %d, want %d; body=%s", recorder.Code, http.StatusCreated, recorder.Body.String())
+	}
+	recorder = doJSONRequest(t, server.router, http.MethodPost, "/auth/login", dto.CredentialsRequest{
+		Username: "alice",
+		Password: "wrong password",
+	})
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("login with wrong password status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+
+	createRoom := map[string]string{"game_type": "tictactoe", "player_id": "alice"}
+	if recorder := doJSONRequest(t, server.router, http.MethodPost, "/room/create", createRoom); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("create room without token status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+	if recorder := doAuthorizedJSONRequest(t, server.router, guest.Token, http.MethodPost, "/room/create", createRoom); recorder.Code != http.StatusForbidden {
+		t.Fatalf("create room as someone else status = %d, want %d", recorder.Code, http.StatusForbidden)
+	}
+
+	recorder = doAuthorizedJSONRequest(t, server.router, guest.Token, http.MethodPost, "/room/create", map[string]string{
+		"game_type": "tictactoe",
+	})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("create room with token status = %d, want %d; body=%s", recorder.Code, http.StatusCreated, recorder.Body.String())
+	}
+	decodeJSONResponse(t, recorder, &response)
+	var created joinRoomAPIData
+	if err := json.Unmarshal(response.Data, &created); err != nil {
+		t.Fatalf("decode room data: %v", err)
+	}
+	if created.PlayerID != "guest1" {
+		t.Fatalf("room creator = %q, want session player guest1", created.PlayerID)
+	}
+
+	if recorder := doJSONRequest(t, server.router, http.MethodGet, "/ws?room_id="+created.Room.RoomID+"&player_id=guest1", nil); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("websocket upgrade without token status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAuthAPI_PlayerRoomsNeedTheirPlayersSession(t *testing.T) {
+	server := newAPITestServer()
+	server.router.Use(middleware.Authenticate(server.auth, IsPublicRoute))
+
+	tokens := map[string]string{}
+	for _, playerID := range []string{"guest1", "guest2"} {
+		recorder := doJSONRequest(t, server.router, http.MethodPost, "/create/user", map[string]string{"player_id": playerID})
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("sign-in %s status = %d, want %d", playerID, recorder.Code, http.StatusCreated)
+		}
+		var response apiResponse
+		decodeJSONResponse(t, recorder, &response)
+		var session dto.SessionDTO
+		if err := json.Unmarshal(response.Data, &session); err != nil {
+			t.Fatalf("decode session data: %v", err)
+		}
+		tokens[playerID] = session.Token
+	}
+	recorder := doAuthorizedJSONRequest(t, server.router, tokens["guest1"], http.MethodPost, "/room/create", map[string]string{
+		"game_type":  "tictactoe",
+		"visibility": "private",
+		"password":   "secret",
+	})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("create room status = %d, want %d; body=%s", recorder.Code, http.StatusCreated, recorder.Body.String())
+	}
+
+	if recorder := doJSONRequest(t, server.router, http.MethodGet, "/players/guest1/rooms", nil); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("player rooms without token status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+	if recorder := doAuthorizedJSONRequest(t, server.router, tokens["guest2"], http.MethodGet, "/players/guest1/rooms", nil); recorder.Code != http.StatusForbidden {
+		t.Fatalf("player rooms of someone else status = %d, want %d", recorder.Code, http.StatusForbidden)
+	}
+
+	recorder = doAuthorizedJSONRequest(t, server.router, tokens["guest1"], http.MethodGet, "/players/guest1/rooms", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("own rooms status = %d, want %d; body=%s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	var response apiResponse
+	decodeJSONResponse(t, recorder, &response)
+	var rooms []dto.RoomSnapshotDTO
+	if err := json.Unmarshal(response.Data, &rooms); err != nil {
+		t.Fatalf("decode rooms: %v", err)
+	}
+	if len(rooms) != 1 {
+		t.Fatalf("own rooms = %+v, want the private room", rooms)
+	}
+}
+
+func doAuthorizedJSONRequest(t *testing.T, handler http.Handler, token string, method string, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	encodedBody, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("marshal request body: %v", err)
+	}
+
+	request := httptest.NewRequest(method, path, bytes.NewReader(encodedBody))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func TestRequestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	server := newAPITestServer()
+	server.router.Use(observability.RequestMiddleware)
+
+	request := httptest.NewRequest(http.MethodPost, "/create/user", bytes.NewReader([]byte(`{"player_id":"p1"}`)))
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+
+	if got := recorder.Header().Get("X-Trace-ID"); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("X-Trace-ID = %q, want the incoming trace ID", got)
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/middleware"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+// BuildCommit is the git commit the binary was built from, set with
+// -ldflags "-X github.com/tsaqiffatih/mini-game/api.BuildCommit=...". It wins
+// over the commit the Go toolchain stamps, which is missing when the build
+// context has no .git directory.
+var BuildCommit string
+
+// readinessTimeout bounds each readiness check so a hung backplane fails the
+// probe instead of stalling it.
+const readinessTimeout = 2 * time.Second
+
+var errDraining = errors.New("server is draining")
+
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// RegisterHealthRouter serves /healthz, which answers while the process is
+// up, and /readyz, which answers 503 while this node should get no traffic.
+func RegisterHealthRouter(r *mux.Router, gameService *service.GameService) {
+	checks := []readinessCheck{
+		{name: "draining", check: func(context.Context) error {
+			if gameService.Draining() {
+				return errDraining
+			}
+			return nil
+		}},
+		{name: "rooms", check: gameService.CheckRoomsWithContext},
+		{name: "stockfish", check: func(context.Context) error {
+			_, err := game.StockfishBinary()
+			return err
+		}},
+	}
+
+	r.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeSuccessResponse(w, http.StatusOK, map[string]string{"status": "ok"})
+	}).Methods("GET", "HEAD")
+
+	r.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		getReadiness(w, r, checks)
+	}).Methods("GET", "HEAD")
+}
+
+// RegisterDebugRouter serves /debug/status, guarded by the admin token.
+func RegisterDebugRouter(r *mux.Router, clients *ClientRegistry, gameService *service.GameService, adminToken string, nodeID string) {
+	startedAt := time.Now().UTC()
+
+	debugRouter := r.PathPrefix("/debug").Subrouter()
+	debugRouter.Use(middleware.AdminAuth(adminToken))
+
+	debugRouter.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		getDebugStatus(w, r, clients, gameService, nodeID, startedAt)
+	}).Methods("GET")
+}
+
+func getReadiness(w http.ResponseWriter, r *http.Request, checks []readinessCheck) {
+	readiness := dto.ReadinessDTO{
+		Ready:  true,
+		Checks: make([]dto.ReadinessCheckDTO, 0, len(checks)),
+	}
+	for _, check := range checks {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		err := check.check(ctx)
+		cancel()
+
+		result := dto.ReadinessCheckDTO{Name: check.name, OK: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+			readiness.Ready = false
+		}
+		readiness.Checks = append(readiness.Checks, result)
+	}
+
+	if !readiness.Ready {
+		message := "not ready"
+		writeJSONResponse(w, http.StatusServiceUnavailable, Response{
+			Success: false,
+			Data:    readiness,
+			Error:   &message,
+		})
+		return
+	}
+	writeSuccessResponse(w, http.StatusOK, readiness)
+}
+
+func getDebugStatus(w http.ResponseWriter, r *http.Request, clients *ClientRegistry, gameService *service.GameService, nodeID string, startedAt time.Time) {
+	engines, err := gameService.StockfishStatusesWithContext(r.Context())
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccessResponse(w, http.StatusOK, dto.DebugStatusDTO{
+		NodeID:      nodeID,
+		StartedAt:   startedAt,
+		UptimeSecs:  int64(time.Since(startedAt).Seconds()),
+		Draining:    gameService.Draining(),
+		Goroutines:  runtime.NumGoroutine(),
+		Rooms:       dto.FromRoomCounts(gameService.RoomCounts()),
+		Connections: clients.ConnectionCount(),
+		Stockfish:   dto.FromEngineUsage(engines),
+		Build:       buildInfo(),
+	})
+}
+
+func buildInfo() dto.BuildDTO {
+	build := dto.BuildDTO{GoVersion: runtime.Version()}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		build.Module = info.Main.Path
+		build.Version = info.Main.Version
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				build.Commit = setting.Value
+			case "vcs.time":
+				if commitTime, err := time.Parse(time.RFC3339, setting.Value); err == nil {
+					build.CommitTime = &commitTime
+				}
+			case "vcs.modified":
+				build.Modified = setting.Value == "true"
+			}
+		}
+	}
+	if BuildCommit != "" {
+		build.Commit = BuildCommit
+	}
+	return build
+}
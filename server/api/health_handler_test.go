@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+type readinessResponse struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Ready  bool `json:"ready"`
+		Checks []struct {
+			Name  string `json:"name"`
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		} `json:"checks"`
+	} `json:"data"`
+}
+
+func newHealthTestRouter(t *testing.T, gameService *service.GameService) *mux.Router {
+	t.Helper()
+
+	// Any executable stands in for the engine binary.
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("os.Executable() error = %v", err)
+	}
+	t.Setenv("STOCKFISH_PATH", executable)
+
+	router := mux.NewRouter()
+	RegisterHealthRouter(router, gameService)
+	return router
+}
+
+func getReadinessResponse(t *testing.T, router http.Handler) (int, readinessResponse) {
+	t.Helper()
+
+	recorder := serveAdminRequest(router, http.MethodGet, "/readyz", "", "")
+	var response readinessResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode readiness: %v", err)
+	}
+	return recorder.Code, response
+}
+
+func TestHealthRouter_ReadyUntilDraining(t *testing.T) {
+	gameService, _ := newResumeTestRoom(t)
+	router := newHealthTestRouter(t, gameService)
+
+	if recorder := serveAdminRequest(router, http.MethodGet, "/healthz", "", ""); recorder.Code != http.StatusOK {
+		t.Fatalf("GET /healthz status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	status, response := getReadinessResponse(t, router)
+	if status != http.StatusOK || !response.Data.Ready {
+		t.Fatalf("GET /readyz = %d %+v, want 200 and ready", status, response.Data)
+	}
+
+	gameService.Drain()
+
+	if recorder := serveAdminRequest(router, http.MethodGet, "/healthz", "", ""); recorder.Code != http.StatusOK {
+		t.Fatalf("GET /healthz while draining status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	status, response = getReadinessResponse(t, router)
+	if status != http.StatusServiceUnavailable || response.Success || response.Data.Ready {
+		t.Fatalf("GET /readyz while draining = %d %+v, want 503 and not ready", status, response.Data)
+	}
+	for _, check := range response.Data.Checks {
+		if check.OK != (check.Name != "draining") {
+			t.Fatalf("check %s ok = %v, want only draining to fail", check.Name, check.OK)
+		}
+	}
+}
+
+func TestHealthRouter_NotReadyWithoutStockfish(t *testing.T) {
+	gameService, _ := newResumeTestRoom(t)
+	router := newHealthTestRouter(t, gameService)
+	t.Setenv("STOCKFISH_PATH", "/nonexistent/stockfish")
+
+	status, response := getReadinessResponse(t, router)
+	if status != http.StatusServiceUnavailable {
+		t.Fatalf("GET /readyz status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+	for _, check := range response.Data.Checks {
+		if check.Name == "stockfish" && (check.OK || check.Error == "") {
+			t.Fatalf("stockfish check = %+v, want a failure with its error", check)
+		}
+	}
+}
+
+func TestDebugRouter_StatusRequiresTokenAndCountsRooms(t *testing.T) {
+	gameService, _ := newResumeTestRoom(t)
+	router := mux.NewRouter()
+	RegisterDebugRouter(router, NewClientRegistry(), gameService, "secret", "node-a")
+
+	if recorder := serveAdminRequest(router, http.MethodGet, "/debug/status", "", ""); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("GET /debug/status without token status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+
+	recorder := serveAdminRequest(router, http.MethodGet, "/debug/status", "", "secret")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET /debug/status status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var response struct {
+		Data struct {
+			NodeID     string `json:"node_id"`
+			Goroutines int    `json:"goroutines"`
+			Rooms      []struct {
+				GameType string `json:"game_type"`
+				State    string `json:"state"`
+				Rooms    int    `json:"rooms"`
+			} `json:"rooms"`
+			Build struct {
+				GoVersion string `json:"go_version"`
+			} `json:"build"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if response.Data.NodeID != "node-a" || response.Data.Goroutines == 0 || response.Data.Build.GoVersion == "" {
+		t.Fatalf("status = %+v, want node, goroutines and go version", response.Data)
+	}
+	rooms := 0
+	for _, count := range response.Data.Rooms {
+		rooms += count.Rooms
+	}
+	if rooms != 1 {
+		t.Fatalf("rooms = %+v, want one room counted", response.Data.Rooms)
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+const (
+	EventLobbySnapshot    = "lobby_snapshot"
+	EventLobbyRoomUpdated = "lobby_room_updated"
+	EventLobbyRoomRemoved = "lobby_room_removed"
+)
+
+func RegisterLobbyRouter(r *mux.Router, lobbyService *service.LobbyService) {
+	r.HandleFunc("/lobby", func(w http.ResponseWriter, r *http.Request) {
+		getLobby(w, r, lobbyService)
+	}).Methods("GET")
+
+	r.HandleFunc("/lobby/ws", func(w http.ResponseWriter, r *http.Request) {
+		HandleLobbyWebSocket(w, r, lobbyService)
+	}).Methods("GET")
+}
+
+func getLobby(w http.ResponseWriter, r *http.Request, lobbyService *service.LobbyService) {
+	filter, limit, ok := lobbyFilterFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	rooms, err := lobbyService.ListWithContext(r.Context(), filter, limit)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeSuccessResponse(w, http.StatusOK, dto.FromLobby(rooms))
+}
+
+// HandleLobbyWebSocket streams the lobby: the open rooms matching the query's
+// filters first, then every room that opens, changes or goes away. The
+// connection only listens; anything the client sends is ignored.
+func HandleLobbyWebSocket(w http.ResponseWriter, r *http.Request, lobbyService *service.LobbyService) {
+	filter, limit, ok := lobbyFilterFromQuery(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	rooms, err := lobbyService.ListWithContext(ctx, filter, limit)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		observability.Logger().WarnContext(ctx, "lobby websocket upgrade failed",
+			"room_id", "",
+			"player_id", "",
+			"event_type", "lobby_websocket_upgrade_failed",
+			"error", err,
+		)
+		return
+	}
+
+	client := newClient("", "", 0, conn.Subprotocol())
+	client.Conn = conn
+	timeouts := websocketTimeouts
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(timeouts.PongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(timeouts.PongWait))
+		return nil
+	})
+	go client.WritePump(timeouts.WriteWait, timeouts.PingPeriod)
+	defer client.Close()
+
+	unsubscribe := lobbyService.Subscribe(func(update service.LobbyUpdate) {
+		if update.Room == nil {
+			sendEvent(ctx, client, EventLobbyRoomRemoved, dto.LobbyRoomRemovedDTO{RoomID: update.RoomID})
+			return
+		}
+		if filter.Matches(*update.Room) {
+			sendEvent(ctx, client, EventLobbyRoomUpdated, dto.FromLobbyRoom(*update.Room))
+		}
+	})
+	defer unsubscribe()
+	sendEvent(ctx, client, EventLobbySnapshot, dto.FromLobby(rooms))
+
+	observability.Logger().InfoContext(ctx, "lobby websocket connected",
+		"room_id", "",
+		"player_id", "",
+		"event_type", "lobby_websocket_connected",
+		"game_type", filter.GameType,
+	)
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+}
+
+// lobbyFilterFromQuery reads game_type, has_password, min_host_games and
+// limit, answering 400 for a malformed one.
+func lobbyFilterFromQuery(w http.ResponseWriter, r *http.Request) (service.LobbyFilter, int, bool) {
+	query := r.URL.Query()
+	filter := service.LobbyFilter{GameType: query.Get("game_type")}
+
+	if raw := query.Get("has_password"); raw != "" {
+		hasPassword, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid has_password")
+			return service.LobbyFilter{}, 0, false
+		}
+		filter.HasPassword = &hasPassword
+	}
+	if raw := query.Get("min_host_games"); raw != "" {
+		minGames, err := strconv.Atoi(raw)
+		if err != nil || minGames < 0 {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid min_host_games")
+			return service.LobbyFilter{}, 0, false
+		}
+		filter.MinHostGames = minGames
+	}
+
+	limit := 0
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid limit")
+			return service.LobbyFilter{}, 0, false
+		}
+		limit = parsed
+	}
+	return filter, limit, true
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/infrastructure"
+	"github.com/tsaqiffatih/mini-game/service"
+	"github.com/tsaqiffatih/mini-game/stats"
+)
+
+func TestHandleLobbyWebSocket_StreamsRoomsAsTheyOpen(t *testing.T) {
+	gameService := service.NewGameService(infrastructure.NewMemoryRoomRepository(), game.NewPlayerManager())
+	if _, err := gameService.AddPlayer("p1"); err != nil {
+		t.Fatalf("AddPlayer() error = %v", err)
+	}
+	lobbyService := service.NewLobbyService(gameService, stats.NewStore())
+	gameService.SetRoomChangeNotifier(lobbyService.HandleRoomChange)
+
+	router := mux.NewRouter()
+	RegisterLobbyRouter(router, lobbyService)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/lobby/ws?game_type=chess"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("websocket dial error = %v", err)
+	}
+	defer conn.Close()
+
+	var lobby dto.LobbyDTO
+	if err := json.Unmarshal(readTestWebSocketEvent(t, conn, EventLobbySnapshot).Payload, &lobby); err != nil {
+		t.Fatalf("decode lobby_snapshot: %v", err)
+	}
+	if len(lobby.Rooms) != 0 {
+		t.Fatalf("lobby rooms = %+v, want none", lobby.Rooms)
+	}
+
+	res, err := gameService.CreateRoomWithContext(context.Background(), "chess", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	var room dto.LobbyRoomDTO
+	if err := json.Unmarshal(readTestWebSocketEvent(t, conn, EventLobbyRoomUpdated).Payload, &room); err != nil {
+		t.Fatalf("decode lobby_room_updated: %v", err)
+	}
+	if room.RoomID != res.Room.RoomID || room.HostID != "p1" || room.GameType != "chess" {
+		t.Fatalf("lobby room = %+v, want room %s hosted by p1", room, res.Room.RoomID)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net"
 	"time"
 
@@ -38,7 +39,7 @@ func readMessages(
 		done <- result
 	}()
 	for {
-		_, msg, err := conn.ReadMessage()
+		messageType, msg, err := conn.ReadMessage()
 		if err != nil {
 			result = websocketReadCloseResult(err)
 			observability.Logger().InfoContext(ctx, "websocket read closed",
@@ -52,24 +53,48 @@ func readMessages(
 			break
 		}
 
-		var message WebSocketMessage
-		if err := json.Unmarshal(msg, &message); err != nil {
-			sendErrorMessage(client, "Invalid message format")
+		message, err := decodeWebSocketMessage(messageType, msg)
+		if err != nil {
+			observability.WebSocketMessagesReceived.WithLabelValues("invalid").Inc()
 			observability.Logger().WarnContext(ctx, "websocket message unmarshal failed",
 				"room_id", roomID,
 				"player_id", player.ID,
 				"event_type", "websocket_message_invalid",
 				"error", err,
 			)
+			switch clients.penalize(ctx, client, "invalid message") {
+			case penaltyWarn:
+				sendErrorMessage(ctx, client, "Invalid message format")
+			case penaltyClose:
+				client.waitClosed(closeFrameWait)
+				result = websocketReadResult{CloseCode: CloseCodeAbuse, CloseReason: abuseCloseReason}
+				return
+			}
 			continue
 		}
 
+		// The delta base is dropped here rather than by the handler, which
+		// may run on the room's owner in a cluster.
+		if message.Type == actions.RESYNC_REQUEST {
+			client.RequestResync()
+		}
+
 		observability.Logger().InfoContext(ctx, "websocket event received",
 			"room_id", roomID,
 			"player_id", player.ID,
 			"event_type", message.Type,
 		)
 
+		allowed, closing := clients.allowMessage(ctx, roomID, player.ID, client, message)
+		if closing {
+			client.waitClosed(closeFrameWait)
+			result = websocketReadResult{CloseCode: CloseCodeAbuse, CloseReason: abuseCloseReason}
+			return
+		}
+		if !allowed {
+			continue
+		}
+
 		gameService.UpdatePlayerActivityWithContext(ctx, roomID, player.ID)
 		handleMessageAction(ctx, clients, gameService, roomID, player, client, message)
 	}
@@ -80,6 +105,13 @@ func websocketReadCloseResult(err error) websocketReadResult {
 		CloseCode: websocket.CloseAbnormalClosure,
 	}
 
+	if errors.Is(err, websocket.ErrReadLimit) {
+		// The websocket library has already closed the connection with 1009.
+		result.CloseCode = websocket.CloseMessageTooBig
+		result.CloseReason = "message too big"
+		return result
+	}
+
 	var closeErr *websocket.CloseError
 	if errors.As(err, &closeErr) {
 		result.CloseCode = closeErr.Code
@@ -105,41 +137,130 @@ func handleMessageAction(
 	client *Client,
 	message WebSocketMessage,
 ) {
+	// Every message gets its own trace, linked to the connection's span.
+	ctx, endSpan := observability.StartRootSpan(ctx, "websocket.message",
+		slog.String("type", message.Type),
+		slog.String("room_id", roomID),
+		slog.String("player_id", player.ID),
+	)
+	defer endSpan(nil)
+
+	// Unsupported types share one label so clients cannot grow the metric.
+	metricType := message.Type
+	defer func() { observability.WebSocketMessagesReceived.WithLabelValues(metricType).Inc() }()
+
+	var err error
+	if message.RequestID != "" {
+		err = runCommand(ctx, clients, gameService, roomID, player, client, message)
+	} else {
+		err = dispatchMessage(ctx, clients, gameService, roomID, player, client, message)
+	}
+	if errors.Is(err, errUnsupportedMessage) {
+		metricType = "unsupported"
+	}
+}
+
+// stateVersionedMessages are the message types applied only at the
+// expected_state_version they carry. The others do not change the state
+// clients reconcile by version, so they refuse the field rather than ignore it.
+var stateVersionedMessages = map[string]bool{
+	actions.TICTACTOE_MOVE:       true,
+	actions.CHESS_MOVE:           true,
+	actions.CHESS_UNDO_REQUEST:   true,
+	actions.CHESS_PREMOVE:        true,
+	actions.CHESS_PREMOVE_CANCEL: true,
+	actions.TAKEBACK_REQUEST:     true,
+	actions.TAKEBACK_RESPOND:     true,
+	actions.CHAT_SEND:            true,
+	actions.ROOM_KICK:            true,
+}
+
+// dispatchMessage runs the action of a message. Failures are reported to the
+// client as they happen; the error is returned for the command's nack.
+func dispatchMessage(
+	ctx context.Context,
+	clients *ClientRegistry,
+	gameService *service.GameService,
+	roomID string,
+	player game.PlayerSnapshot,
+	client *Client,
+	message WebSocketMessage,
+) error {
+	if message.ExpectedStateVersion != nil && !stateVersionedMessages[message.Type] {
+		sendErrorMessage(ctx, client, errStateVersionNotAccepted.Error())
+		return errStateVersionNotAccepted
+	}
+
 	switch message.Type {
 	case actions.TICTACTOE_MOVE:
-		processTicTacToeMove(ctx, player, client, clients, gameService, roomID, message)
+		return processTicTacToeMove(ctx, player, client, clients, gameService, roomID, message)
 	case actions.CHESS_MOVE:
-		processChessMove(ctx, player, client, clients, gameService, roomID, player.ID, message)
+		return processChessMove(ctx, player, client, clients, gameService, roomID, player.ID, message)
 	case actions.CHESS_UNDO_REQUEST:
-		processChessUndo(ctx, player, client, clients, gameService, roomID)
+		return processChessUndo(ctx, player, client, clients, gameService, roomID, message)
+	case actions.CHESS_PREMOVE:
+		return processChessPremove(ctx, player, client, clients, gameService, roomID, message)
+	case actions.CHESS_PREMOVE_CANCEL:
+		return processChessPremoveCancel(ctx, player, client, clients, gameService, roomID, message)
+	case actions.TAKEBACK_REQUEST:
+		return processTakebackRequest(ctx, player, client, clients, gameService, roomID, message)
+	case actions.TAKEBACK_RESPOND:
+		return processTakebackRespond(ctx, player, client, clients, gameService, roomID, message)
 	case actions.CHAT_SEND:
-		processChatSend(ctx, player, client, clients, gameService, roomID, message)
+		return processChatSend(ctx, player, client, clients, gameService, roomID, message)
+	case actions.CHAT_MUTE:
+		return processChatMute(ctx, player, client, clients, gameService, roomID, message, true)
+	case actions.CHAT_UNMUTE:
+		return processChatMute(ctx, player, client, clients, gameService, roomID, message, false)
+	case actions.CHAT_REPORT:
+		return processChatReport(ctx, player, client, clients, gameService, roomID, message)
+	case actions.REACTION_SEND:
+		return processReactionSend(ctx, player, client, clients, gameService, roomID, message)
+	case actions.TYPING_START:
+		return processTyping(ctx, player, client, clients, gameService, roomID, true)
+	case actions.TYPING_STOP:
+		return processTyping(ctx, player, client, clients, gameService, roomID, false)
+	case actions.RESUME:
+		return processResume(ctx, clients, gameService, roomID, client, message)
+	case actions.RESYNC_REQUEST:
+		return processResyncRequest(ctx, player, client, gameService, roomID)
+	case actions.ROOM_INVITE_CREATE:
+		return processRoomInviteCreate(ctx, player, client, gameService, roomID)
+	case actions.ROOM_KICK:
+		return processRoomKick(ctx, player, client, clients, gameService, roomID, message)
 	case actions.CREATE_ROOM_WITH_AI:
 		var requestedRoomID string
 		if err := json.Unmarshal(message.Payload, &requestedRoomID); err != nil {
-			sendErrorMessage(client, "Invalid create room payload")
-			return
+			sendErrorMessage(ctx, client, "Invalid create room payload")
+			return err
 		}
 		event, err := gameService.CreateRoomWithAIByIDWithContext(ctx, requestedRoomID, "tictactoe")
 		if err != nil {
-			sendErrorMessage(client, err.Error())
-			return
+			sendErrorMessage(ctx, client, err.Error())
+			return err
 		}
-		NotifyToClientsInRoom(clients, gameService, event.RoomID, EventRoomUpdate, nil)
+		NotifyToClientsInRoom(ctx, clients, gameService, event.RoomID, EventRoomUpdate, nil)
+		return nil
 	default:
-		sendErrorMessage(client, "Unsupported message type")
+		sendErrorMessage(ctx, client, "Unsupported message type")
 		log.Println(message.Type, "<<<<<<<<<<<")
+		return errUnsupportedMessage
 	}
 }
 
-func notifyRoomOnConnection(ctx context.Context, clients *ClientRegistry, gameService *service.GameService, roomID string, player game.PlayerSnapshot, eventType string) {
+// notifyRoomOnConnection announces a new connection to the room and sends
+// it the room's state, unless it resumed and had its missed events replayed.
+func notifyRoomOnConnection(ctx context.Context, clients *ClientRegistry, gameService *service.GameService, roomID string, player game.PlayerSnapshot, client *Client, eventType string, resumed bool) {
 	if eventType != "" {
-		NotifyToClientsInRoom(clients, gameService, roomID, eventType, EventPayload{
+		NotifyToClientsInRoom(ctx, clients, gameService, roomID, eventType, EventPayload{
 			Message:   connectionEventMessage(eventType, player.ID, roomID),
 			Player:    playerEventDTO(player),
 			Timestamp: time.Now(),
 		})
 	}
+	if resumed {
+		return
+	}
 
 	snapshot, err := gameService.RoomSnapshotWithContext(ctx, roomID)
 	if err != nil {
@@ -152,8 +273,28 @@ func notifyRoomOnConnection(ctx context.Context, clients *ClientRegistry, gameSe
 		return
 	}
 
-	sendRoomSnapshotToClient(clientForPlayer(clients, player.ID), snapshot)
-	sendChatHistoryToClient(ctx, clients, gameService, roomID, player.ID)
+	sendRoomSnapshotToClient(ctx, client, snapshot)
+	sendChatHistoryToClient(ctx, client, gameService, roomID)
+}
+
+// processResyncRequest sends the client a full snapshot after it saw a gap
+// in state versions. The connection already dropped its delta base when it
+// read the request, see readMessages.
+func processResyncRequest(ctx context.Context, player game.PlayerSnapshot, client *Client, gameService *service.GameService, roomID string) error {
+	snapshot, err := gameService.RoomSnapshotWithContext(ctx, roomID)
+	if err != nil {
+		sendErrorMessage(ctx, client, err.Error())
+		return err
+	}
+
+	observability.Logger().InfoContext(ctx, "room resync requested",
+		"room_id", roomID,
+		"player_id", player.ID,
+		"event_type", "room_resync",
+		"state_version", snapshot.StateVersion,
+	)
+	sendRoomSnapshotToClient(ctx, client, snapshot)
+	return nil
 }
 
 func connectionEventMessage(eventType string, playerID string, roomID string) string {
@@ -165,7 +306,7 @@ func connectionEventMessage(eventType string, playerID string, roomID string) st
 	}
 }
 
-func notifyChessClients(clients *ClientRegistry, gameService *service.GameService, roomID string, player game.PlayerSnapshot) {
+func notifyChessClients(ctx context.Context, clients *ClientRegistry, gameService *service.GameService, roomID string, player game.PlayerSnapshot) {
 	snapshot, err := gameService.RoomSnapshot(roomID)
 	if err != nil {
 		observability.Logger().Warn("room snapshot failed",
@@ -177,14 +318,28 @@ func notifyChessClients(clients *ClientRegistry, gameService *service.GameServic
 		return
 	}
 
-	NotifySnapshotToClients(clients, snapshot, Event{
-		Type:    EventGameUpdate,
-		Payload: marshalPayload(dto.FromRoomSnapshot(snapshot)),
-	})
+	NotifyGameUpdateToClients(ctx, clients, snapshot)
+}
+
+// notifyChessPremoveOwner sends a queued or cancelled premove to the player
+// who owns it. The opponent gets nothing, so the premove stays hidden.
+func notifyChessPremoveOwner(ctx context.Context, clients *ClientRegistry, gameService *service.GameService, roomID string, player game.PlayerSnapshot) {
+	snapshot, err := gameService.RoomSnapshotWithContext(ctx, roomID)
+	if err != nil {
+		observability.Logger().WarnContext(ctx, "room snapshot failed after chess premove",
+			"room_id", roomID,
+			"player_id", player.ID,
+			"event_type", "room_snapshot_error",
+			"error", err,
+		)
+		return
+	}
+
+	notifyPlayerSnapshotToClients(ctx, clients, snapshot, player.ID, EventGameUpdate)
 }
 
-func sendErrorMessage(client *Client, message string) {
-	sendEvent(client, "error", ErrorMessage{Message: message})
+func sendErrorMessage(ctx context.Context, client *Client, message string) {
+	sendEvent(ctx, client, "error", ErrorMessage{Message: message})
 }
 
 func processChatSend(
@@ -195,57 +350,105 @@ func processChatSend(
 	gameService *service.GameService,
 	roomID string,
 	message WebSocketMessage,
-) {
+) error {
 	var payload dto.ChatSendPayload
 	if err := json.Unmarshal(message.Payload, &payload); err != nil {
-		sendErrorMessage(client, "Invalid chat payload")
-		return
+		sendErrorMessage(ctx, client, "Invalid chat payload")
+		return err
 	}
 
-	chatMessage, err := gameService.HandleChatMessageWithContext(ctx, roomID, player.ID, payload.Message)
+	post, err := gameService.HandleChatMessageWithContext(ctx, roomID, player.ID, payload.Message, message.ExpectedStateVersion)
 	if err != nil {
-		sendErrorMessage(client, err.Error())
-		return
+		sendErrorMessage(ctx, client, err.Error())
+		return err
 	}
 
-	snapshot, err := gameService.RoomSnapshotWithContext(ctx, roomID)
+	notifyChatMessage(ctx, clients, roomID, post.Message, post.Audience)
+	if post.Notice != nil {
+		notifyChatMessage(ctx, clients, roomID, *post.Notice, []string{player.ID})
+	}
+	return nil
+}
+
+func processChatMute(
+	ctx context.Context,
+	player game.PlayerSnapshot,
+	client *Client,
+	clients *ClientRegistry,
+	gameService *service.GameService,
+	roomID string,
+	message WebSocketMessage,
+	mute bool,
+) error {
+	var payload dto.ChatMutePayload
+	if err := parsePayload(ctx, client, roomID, message.Type, message.Payload, &payload); err != nil {
+		return err
+	}
+
+	notice, err := gameService.MutePlayerWithContext(ctx, roomID, player.ID, payload.PlayerID, mute)
 	if err != nil {
-		observability.Logger().WarnContext(ctx, "room snapshot failed after chat message",
-			"room_id", roomID,
-			"player_id", player.ID,
-			"event_type", "room_snapshot_error",
-			"error", err,
-		)
-		return
+		sendErrorMessage(ctx, client, err.Error())
+		return err
 	}
 
-	NotifySnapshotToClients(clients, snapshot, Event{
+	notifyChatMessage(ctx, clients, roomID, notice, []string{player.ID})
+	return nil
+}
+
+func processChatReport(
+	ctx context.Context,
+	player game.PlayerSnapshot,
+	client *Client,
+	clients *ClientRegistry,
+	gameService *service.GameService,
+	roomID string,
+	message WebSocketMessage,
+) error {
+	var payload dto.ChatReportPayload
+	if err := parsePayload(ctx, client, roomID, message.Type, message.Payload, &payload); err != nil {
+		return err
+	}
+
+	_, notice, err := gameService.ReportChatMessageWithContext(ctx, roomID, player.ID, payload.MessageID, payload.Reason)
+	if err != nil {
+		sendErrorMessage(ctx, client, err.Error())
+		return err
+	}
+
+	notifyChatMessage(ctx, clients, roomID, notice, []string{player.ID})
+	return nil
+}
+
+// notifyChatMessage sends a chat message to the players of its audience
+// only, so a muted player's messages never reach the players who muted them.
+func notifyChatMessage(ctx context.Context, clients *ClientRegistry, roomID string, chatMessage game.ChatMessage, audience []string) {
+	notifyEventToPlayers(ctx, clients, roomID, audience, Event{
 		Type:    EventChatMessage,
 		Payload: marshalPayload(dto.FromChatMessageEvent(chatMessage)),
 	})
 }
 
-func sendChatHistoryToClient(ctx context.Context, clients *ClientRegistry, gameService *service.GameService, roomID string, playerID string) {
-	client := clientForPlayer(clients, playerID)
+func sendChatHistoryToClient(ctx context.Context, client *Client, gameService *service.GameService, roomID string) {
 	if client == nil {
 		return
 	}
 
-	history, err := gameService.ChatHistoryWithContext(ctx, roomID)
+	history, err := gameService.ChatHistoryWithContext(ctx, roomID, client.PlayerID)
 	if err != nil {
 		observability.Logger().WarnContext(ctx, "chat history failed",
 			"room_id", roomID,
-			"player_id", playerID,
+			"player_id", client.PlayerID,
 			"event_type", "chat_history_error",
 			"error", err,
 		)
 		return
 	}
 
-	sendEvent(client, EventChatHistory, dto.FromChatHistory(history))
+	sendStateEvent(ctx, client, EventChatHistory, dto.FromChatHistory(history))
 }
 
 func NotifyToClientsInRoom(
+	ctx context.Context,
 	clients *ClientRegistry,
 	gameService *service.GameService,
 	roomID string,
@@ -264,36 +467,57 @@ func NotifyToClientsInRoom(
 	}
 
 	if eventType == EventRoomUpdate {
-		NotifyRoomUpdateToClients(clients, snapshot)
+		NotifyRoomUpdateToClients(ctx, clients, snapshot)
 		return
 	}
 
-	NotifySnapshotToClients(clients, snapshot, Event{
-		Type: eventType,
-		Payload: marshalPayload(RoomEventPayload{
-			Room: dto.FromRoomSnapshot(snapshot),
-			Data: marshalPayload(payload),
-		}),
+	// Each player gets the room as they see it, under one seq.
+	data := marshalPayload(payload)
+	clients.withRoomLog(snapshot.RoomID, func(log *roomEventLog) {
+		seq := log.next()
+		for _, player := range snapshot.Players {
+			roomPayload := RoomEventPayload{
+				Room: dto.FromRoomSnapshotForPlayer(snapshot, player.ID),
+				Data: data,
+			}
+			if !notifyPlayerEventLocked(ctx, log, seq, clients, snapshot.RoomID, player.ID, eventType, roomPayload) {
+				return
+			}
+		}
 	})
 }
 
-func NotifyRoomUpdateToClients(clients *ClientRegistry, snapshot game.RoomSnapshot) {
-	NotifySnapshotToClients(clients, snapshot, Event{
-		Type:    EventRoomUpdate,
-		Payload: marshalPayload(dto.FromRoomSnapshot(snapshot)),
-	})
+func NotifyRoomUpdateToClients(ctx context.Context, clients *ClientRegistry, snapshot game.RoomSnapshot) {
+	notifyPlayerSnapshotsToClients(ctx, clients, snapshot, EventRoomUpdate)
 }
 
-func NotifySnapshotToClients(clients *ClientRegistry, snapshot game.RoomSnapshot, events ...Event) {
+func NotifySnapshotToClients(ctx context.Context, clients *ClientRegistry, snapshot game.RoomSnapshot, events ...Event) {
 	if len(events) == 0 {
-		events = []Event{{Type: EventRoomUpdate, Payload: marshalPayload(dto.FromRoomSnapshot(snapshot))}}
+		notifyPlayerSnapshotsToClients(ctx, clients, snapshot, EventRoomUpdate)
+		return
 	}
 
+	playerIDs := make([]string, 0, len(snapshot.Players))
+	for _, player := range snapshot.Players {
+		playerIDs = append(playerIDs, player.ID)
+	}
 	for _, event := range events {
+		notifyEventToPlayers(ctx, clients, snapshot.RoomID, playerIDs, event)
+	}
+}
+
+// notifyEventToPlayers numbers event in the room's log and sends it to the
+// given players.
+func notifyEventToPlayers(ctx context.Context, clients *ClientRegistry, roomID string, playerIDs []string, event Event) {
+	if event.TraceID == "" {
+		event.TraceID = observability.TraceID(ctx)
+	}
+	clients.withRoomLog(roomID, func(log *roomEventLog) {
+		event.Seq = log.next()
 		messageBytes, err := json.Marshal(event)
 		if err != nil {
 			observability.Logger().Warn("websocket event marshal failed",
-				"room_id", snapshot.RoomID,
+				"room_id", roomID,
 				"player_id", "",
 				"event_type", event.Type,
 				"error", err,
@@ -301,59 +525,184 @@ func NotifySnapshotToClients(clients *ClientRegistry, snapshot game.RoomSnapshot
 			return
 		}
 
+		for _, playerID := range playerIDs {
+			log.record(event.Seq, playerID, "", event.Type, messageBytes)
+			deliverToPlayer(ctx, clients, roomID, playerID, event.Type, messageBytes)
+		}
+	})
+}
+
+func NotifyGameUpdateToClients(ctx context.Context, clients *ClientRegistry, snapshot game.RoomSnapshot) {
+	notifyPlayerSnapshotsToClients(ctx, clients, snapshot, EventGameUpdate)
+}
+
+// notifyPlayerSnapshotsToClients sends a snapshot event built separately for
+// every player, so player-private state never reaches the other clients.
+func notifyPlayerSnapshotsToClients(ctx context.Context, clients *ClientRegistry, snapshot game.RoomSnapshot, eventType string) {
+	clients.withRoomLog(snapshot.RoomID, func(log *roomEventLog) {
+		seq := log.next()
 		for _, player := range snapshot.Players {
-			client, connected := clients.Get(player.ID)
-			if !connected {
-				continue
-			}
-			if !client.Enqueue(messageBytes) {
-				clients.RemoveClient(client)
+			if !notifyPlayerSnapshotLocked(ctx, log, seq, clients, snapshot, player.ID, eventType) {
+				return
 			}
 		}
-	}
+	})
+}
+
+// notifyPlayerSnapshotToClients sends the snapshot as seen by playerID to that
+// player's connections only, for changes to state nobody else can see.
+func notifyPlayerSnapshotToClients(ctx context.Context, clients *ClientRegistry, snapshot game.RoomSnapshot, playerID string, eventType string) {
+	clients.withRoomLog(snapshot.RoomID, func(log *roomEventLog) {
+		notifyPlayerSnapshotLocked(ctx, log, log.next(), clients, snapshot, playerID, eventType)
+	})
+}
+
+func notifyPlayerSnapshotLocked(
+	ctx context.Context,
+	log *roomEventLog,
+	seq uint64,
+	clients *ClientRegistry,
+	snapshot game.RoomSnapshot,
+	playerID string,
+	eventType string,
+) bool {
+	return notifyPlayerEventLocked(ctx, log, seq, clients, snapshot.RoomID, playerID, eventType, dto.FromRoomSnapshotForPlayer(snapshot, playerID))
 }
 
-func NotifyGameUpdateToClients(clients *ClientRegistry, snapshot game.RoomSnapshot) {
-	NotifySnapshotToClients(clients, snapshot, Event{
-		Type:    EventGameUpdate,
-		Payload: marshalPayload(dto.FromRoomSnapshot(snapshot)),
+// notifyPlayerEventLocked logs and sends an event built for one player under
+// seq, which the room's other players get their own version of.
+func notifyPlayerEventLocked(
+	ctx context.Context,
+	log *roomEventLog,
+	seq uint64,
+	clients *ClientRegistry,
+	roomID string,
+	playerID string,
+	eventType string,
+	payload interface{},
+) bool {
+	messageBytes, err := json.Marshal(Event{
+		Type:    eventType,
+		Payload: marshalPayload(payload),
+		TraceID: observability.TraceID(ctx),
+		Seq:     seq,
 	})
+	if err != nil {
+		observability.Logger().Warn("websocket event marshal failed",
+			"room_id", roomID,
+			"player_id", playerID,
+			"event_type", eventType,
+			"error", err,
+		)
+		return false
+	}
+
+	log.record(seq, playerID, "", eventType, messageBytes)
+	deliverToPlayer(ctx, clients, roomID, playerID, eventType, messageBytes)
+	return true
 }
 
-func sendRoomSnapshotToClient(client *Client, snapshot game.RoomSnapshot) {
+func sendRoomSnapshotToClient(ctx context.Context, client *Client, snapshot game.RoomSnapshot) {
 	if client == nil {
 		return
 	}
 
-	sendEvent(client, EventRoomUpdate, dto.FromRoomSnapshot(snapshot))
+	sendStateEvent(ctx, client, EventRoomUpdate, dto.FromRoomSnapshotForPlayer(snapshot, client.PlayerID))
 }
 
-func sendEvent(client *Client, eventType string, payload interface{}) {
+// sendEvent sends an event to one connection. Events of a connection to a
+// room are numbered and logged like the room's broadcasts.
+func sendEvent(ctx context.Context, client *Client, eventType string, payload interface{}) {
 	if client == nil {
 		return
 	}
 
+	event := Event{
+		Type:    eventType,
+		Payload: marshalPayload(payload),
+		TraceID: observability.TraceID(ctx),
+	}
+	if client.clients != nil && client.RoomID != "" {
+		sendRoomEvent(ctx, client, event)
+		return
+	}
+
+	if messageBytes, ok := marshalEvent(event, client); ok {
+		enqueueClientEvent(client, eventType, messageBytes)
+	}
+}
+
+func marshalEvent(event Event, client *Client) ([]byte, bool) {
+	messageBytes, err := json.Marshal(event)
+	if err != nil {
+		observability.Logger().Warn("websocket event marshal failed",
+			"room_id", client.RoomID,
+			"player_id", client.PlayerID,
+			"event_type", event.Type,
+			"error", err,
+		)
+		return nil, false
+	}
+	return messageBytes, true
+}
+
+func enqueueClientEvent(client *Client, eventType string, messageBytes []byte) {
+	if !enqueueEvent(client, eventType, messageBytes) {
+		observability.Logger().Warn("websocket client send queue full",
+			"room_id", client.RoomID,
+			"player_id", client.PlayerID,
+			"event_type", "websocket_slow_client",
+		)
+	}
+}
+
+// sendEventToPlayer is sendEvent for every connection of the player, in any
+// room and on any node of the cluster.
+func sendEventToPlayer(ctx context.Context, clients *ClientRegistry, playerID string, eventType string, payload interface{}) {
 	messageBytes, err := json.Marshal(Event{
 		Type:    eventType,
 		Payload: marshalPayload(payload),
+		TraceID: observability.TraceID(ctx),
 	})
 	if err != nil {
 		observability.Logger().Warn("websocket event marshal failed",
 			"room_id", "",
-			"player_id", client.PlayerID,
+			"player_id", playerID,
 			"event_type", eventType,
 			"error", err,
 		)
 		return
 	}
 
+	enqueueToClients(clients, clients.PlayerClients(playerID), eventType, messageBytes)
+	if cluster := clients.Cluster(); cluster != nil {
+		cluster.relay(ctx, "", playerID, eventType, messageBytes)
+	}
+}
+
+// deliverToPlayer queues an event for every connection of the player to the
+// room. Connections held by other nodes of the cluster get it relayed.
+func deliverToPlayer(ctx context.Context, clients *ClientRegistry, roomID string, playerID string, eventType string, messageBytes []byte) {
+	enqueueToClients(clients, clients.RoomClients(roomID, playerID), eventType, messageBytes)
+	if cluster := clients.Cluster(); cluster != nil && clients.hasRemote(roomID, playerID) {
+		cluster.relay(ctx, roomID, playerID, eventType, messageBytes)
+	}
+}
+
+func enqueueToClients(clients *ClientRegistry, targets []*Client, eventType string, messageBytes []byte) {
+	for _, client := range targets {
+		if !enqueueEvent(client, eventType, messageBytes) {
+			clients.RemoveClient(client)
+		}
+	}
+}
+
+func enqueueEvent(client *Client, eventType string, messageBytes []byte) bool {
 	if !client.Enqueue(messageBytes) {
-		observability.Logger().Warn("websocket client send queue full",
-			"room_id", "",
-			"player_id", client.PlayerID,
-			"event_type", "websocket_slow_client",
-		)
+		return false
 	}
+	observability.WebSocketMessagesSent.WithLabelValues(eventType).Inc()
+	return true
 }
 
 func marshalPayload(payload interface{}) json.RawMessage {
@@ -369,11 +718,3 @@ func marshalPayload(payload interface{}) json.RawMessage {
 	}
 	return payloadBytes
 }
-
-func clientForPlayer(clients *ClientRegistry, playerID string) *Client {
-	client, connected := clients.Get(playerID)
-	if !connected {
-		return nil
-	}
-	return client
-}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/tsaqiffatih/mini-game/actions"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+)
+
+func TestHandleWebSocket_MutedPlayersMessagesSkipTheMuter(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	server := newWebSocketTestServer(t, NewClientRegistry(), gameService)
+
+	p1 := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	defer p1.Close()
+	readTestWebSocketEvent(t, p1, EventChatHistory)
+	p2 := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p2")
+	defer p2.Close()
+	readTestWebSocketEvent(t, p2, EventChatHistory)
+
+	mute, _ := json.Marshal(dto.ChatMutePayload{PlayerID: "p2"})
+	writeTestCommand(t, p1, WebSocketMessage{Type: actions.CHAT_MUTE, Payload: mute})
+	if notice := readTestChatMessage(t, p1); !notice.System {
+		t.Fatalf("chat_message after mute = %+v, want a system notice", notice)
+	}
+
+	sendTestChat(t, p2, "can you hear me?")
+	if own := readTestChatMessage(t, p2); own.PlayerID != "p2" {
+		t.Fatalf("p2 chat_message = %+v, want their own message", own)
+	}
+	sendTestChat(t, p1, "quiet in here")
+	if next := readTestChatMessage(t, p1); next.PlayerID != "p1" {
+		t.Fatalf("p1 chat_message = %+v, want their own message, not the muted p2's", next)
+	}
+}
+
+func sendTestChat(t *testing.T, conn *websocket.Conn, message string) {
+	t.Helper()
+
+	payload, _ := json.Marshal(dto.ChatSendPayload{Message: message})
+	writeTestCommand(t, conn, WebSocketMessage{Type: actions.CHAT_SEND, Payload: payload})
+}
+
+func readTestChatMessage(t *testing.T, conn *websocket.Conn) dto.ChatMessageDTO {
+	t.Helper()
+
+	event := readTestWebSocketEvent(t, conn, EventChatMessage)
+	var message dto.ChatMessageDTO
+	if err := json.Unmarshal(event.Payload, &message); err != nil {
+		t.Fatalf("decode chat_message: %v", err)
+	}
+	return message
+}
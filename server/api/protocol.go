@@ -0,0 +1,221 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Websocket subprotocols a client can ask for in Sec-WebSocket-Protocol.
+// Without one the connection speaks SubprotocolJSON.
+//
+// The v2 protocols send room_update and game_update as patches against the
+// previous snapshot sent on the same connection, see eventEncoder.
+const (
+	SubprotocolJSON         = "mini-game.v1.json"
+	SubprotocolDeltaJSON    = "mini-game.v2.json"
+	SubprotocolDeltaMsgpack = "mini-game.v2.msgpack"
+)
+
+var supportedSubprotocols = []string{SubprotocolDeltaMsgpack, SubprotocolDeltaJSON, SubprotocolJSON}
+
+// patchAppendKey marks an array patch that only appends items, so a growing
+// move list costs the new moves rather than the whole list.
+const patchAppendKey = "$append"
+
+// encodedEvent is an Event on the wire of a v2 connection. A BaseVersion
+// means Payload is a patch against the snapshot with that state version.
+type encodedEvent struct {
+	Type        string      `json:"type" msgpack:"type"`
+	Payload     interface{} `json:"payload" msgpack:"payload"`
+	BaseVersion *uint64     `json:"base_version,omitempty" msgpack:"base_version,omitempty"`
+	TraceID     string      `json:"trace_id,omitempty" msgpack:"trace_id,omitempty"`
+	Seq         uint64      `json:"seq,omitempty" msgpack:"seq,omitempty"`
+}
+
+// eventEncoder turns the JSON events queued for a client into frames of the
+// connection's subprotocol. Only the client's write pump encodes, so the
+// last snapshot needs no lock; resync is set from the read loop.
+type eventEncoder struct {
+	protocol    string
+	base        map[string]interface{}
+	baseVersion uint64
+	resync      atomic.Bool
+}
+
+func newEventEncoder(protocol string) *eventEncoder {
+	if protocol == "" {
+		protocol = SubprotocolJSON
+	}
+	return &eventEncoder{protocol: protocol}
+}
+
+// requestResync makes the next snapshot go out in full.
+func (e *eventEncoder) requestResync() {
+	e.resync.Store(true)
+}
+
+func (e *eventEncoder) encode(message []byte) (int, []byte, error) {
+	if e.protocol == SubprotocolJSON {
+		return websocket.TextMessage, message, nil
+	}
+
+	var event struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+		TraceID string          `json:"trace_id"`
+		Seq     uint64          `json:"seq"`
+	}
+	if err := json.Unmarshal(message, &event); err != nil {
+		return 0, nil, err
+	}
+	payload, err := decodeJSONValue(event.Payload)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	encoded := encodedEvent{Type: event.Type, Payload: payload, TraceID: event.TraceID, Seq: event.Seq}
+	if snapshot, ok := payload.(map[string]interface{}); ok && isSnapshotEvent(event.Type) {
+		if e.base != nil && !e.resync.Swap(false) {
+			baseVersion := e.baseVersion
+			encoded.Payload = diffObjects(e.base, snapshot)
+			encoded.BaseVersion = &baseVersion
+		}
+		e.base = snapshot
+		e.baseVersion = snapshotStateVersion(snapshot)
+	}
+
+	if e.protocol == SubprotocolDeltaMsgpack {
+		encoded.Payload = msgpackValue(encoded.Payload)
+		data, err := msgpack.Marshal(encoded)
+		return websocket.BinaryMessage, data, err
+	}
+	data, err := json.Marshal(encoded)
+	return websocket.TextMessage, data, err
+}
+
+func isSnapshotEvent(eventType string) bool {
+	return eventType == EventRoomUpdate || eventType == EventGameUpdate
+}
+
+func snapshotStateVersion(snapshot map[string]interface{}) uint64 {
+	number, _ := snapshot["state_version"].(json.Number)
+	version, _ := number.Int64()
+	return uint64(version)
+}
+
+// diffObjects returns a JSON merge patch (RFC 7396) from old to current,
+// except that an array which only grew is sent as {"$append": [new items]}.
+func diffObjects(old map[string]interface{}, current map[string]interface{}) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for key := range old {
+		if _, ok := current[key]; !ok {
+			patch[key] = nil
+		}
+	}
+
+	for key, value := range current {
+		previous, ok := old[key]
+		if !ok {
+			patch[key] = value
+			continue
+		}
+		if reflect.DeepEqual(previous, value) {
+			continue
+		}
+
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if previous, isObject := previous.(map[string]interface{}); isObject {
+				patch[key] = diffObjects(previous, value)
+				continue
+			}
+		case []interface{}:
+			if previous, isArray := previous.([]interface{}); isArray && len(previous) > 0 &&
+				len(value) > len(previous) && reflect.DeepEqual(previous, value[:len(previous)]) {
+				patch[key] = map[string]interface{}{patchAppendKey: value[len(previous):]}
+				continue
+			}
+		}
+		patch[key] = value
+	}
+	return patch
+}
+
+// decodeWebSocketMessage reads a client message: JSON in text frames,
+// MessagePack in binary frames, whatever the negotiated subprotocol.
+func decodeWebSocketMessage(messageType int, data []byte) (WebSocketMessage, error) {
+	var message WebSocketMessage
+	if messageType != websocket.BinaryMessage {
+		err := json.Unmarshal(data, &message)
+		return message, err
+	}
+
+	var decoded struct {
+		Type                 string      `msgpack:"type"`
+		Payload              interface{} `msgpack:"payload"`
+		RequestID            string      `msgpack:"request_id"`
+		ExpectedStateVersion *uint64     `msgpack:"expected_state_version"`
+	}
+	if err := msgpack.Unmarshal(data, &decoded); err != nil {
+		return message, err
+	}
+	payload, err := json.Marshal(decoded.Payload)
+	if err != nil {
+		return message, err
+	}
+	message.Type = decoded.Type
+	message.Payload = payload
+	message.RequestID = decoded.RequestID
+	message.ExpectedStateVersion = decoded.ExpectedStateVersion
+	return message, nil
+}
+
+func decodeJSONValue(data json.RawMessage) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("trailing data after JSON value")
+	}
+	return value, nil
+}
+
+// msgpackValue replaces the json.Numbers of a decoded JSON value with
+// integers or floats, which MessagePack encodes natively.
+func msgpackValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case json.Number:
+		if integer, err := value.Int64(); err == nil {
+			return integer
+		}
+		float, _ := value.Float64()
+		return float
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			converted[key] = msgpackValue(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(value))
+		for i, item := range value {
+			converted[i] = msgpackValue(item)
+		}
+		return converted
+	default:
+		return value
+	}
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tsaqiffatih/mini-game/actions"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/infrastructure"
+	"github.com/tsaqiffatih/mini-game/service"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestEventEncoder_DeltaJSON_PatchesAgainstPreviousSnapshot(t *testing.T) {
+	room, err := game.NewRoom("chess-room", "chess")
+	if err != nil {
+		t.Fatalf("NewRoom() error = %v", err)
+	}
+	addPlayerToContractRoom(t, room, "p1")
+	addPlayerToContractRoom(t, room, "p2")
+	if _, err := room.HandleChessMove("p1", "e2", "e4", ""); err != nil {
+		t.Fatalf("HandleChessMove(e4) error = %v", err)
+	}
+
+	encoder := newEventEncoder(SubprotocolDeltaJSON)
+	first := encodeTestSnapshot(t, encoder, room)
+	if first.BaseVersion != nil {
+		t.Fatalf("first snapshot base_version = %d, want a full snapshot", *first.BaseVersion)
+	}
+	firstVersion := room.Snapshot().StateVersion
+
+	if _, err := room.HandleChessMove("p2", "e7", "e5", ""); err != nil {
+		t.Fatalf("HandleChessMove(e5) error = %v", err)
+	}
+	second := encodeTestSnapshot(t, encoder, room)
+	if second.BaseVersion == nil || *second.BaseVersion != firstVersion {
+		t.Fatalf("second snapshot base_version = %v, want %d", second.BaseVersion, firstVersion)
+	}
+
+	patched := applyTestPatch(first.Payload, second.Payload)
+	want := decodeTestValue(t, marshalPayload(dto.FromRoomSnapshotForPlayer(room.Snapshot(), "p1")))
+	if !reflect.DeepEqual(patched, want) {
+		t.Fatalf("patched snapshot = %v, want %v", patched, want)
+	}
+
+	chess := second.Payload.(map[string]interface{})["game"].(map[string]interface{})["chess"].(map[string]interface{})
+	moves, ok := chess["pgn_moves"].(map[string]interface{})
+	if !ok || !reflect.DeepEqual(moves[patchAppendKey], []interface{}{"e5"}) {
+		t.Fatalf("pgn_moves patch = %v, want an append of e5", chess["pgn_moves"])
+	}
+}
+
+func TestEventEncoder_ResyncSendsFullSnapshot(t *testing.T) {
+	room, err := game.NewRoom("tictactoe-room", "tictactoe")
+	if err != nil {
+		t.Fatalf("NewRoom() error = %v", err)
+	}
+	addPlayerToContractRoom(t, room, "p1")
+
+	encoder := newEventEncoder(SubprotocolDeltaJSON)
+	encodeTestSnapshot(t, encoder, room)
+	if event := encodeTestSnapshot(t, encoder, room); event.BaseVersion == nil {
+		t.Fatalf("repeated snapshot is full, want a patch")
+	}
+
+	encoder.requestResync()
+	event := encodeTestSnapshot(t, encoder, room)
+	if event.BaseVersion != nil {
+		t.Fatalf("snapshot after resync base_version = %d, want a full snapshot", *event.BaseVersion)
+	}
+	if event.Payload.(map[string]interface{})["room_id"] != "tictactoe-room" {
+		t.Fatalf("snapshot after resync = %v, want the full room", event.Payload)
+	}
+}
+
+func TestHandleWebSocket_MsgpackSubprotocolSendsBinaryDeltas(t *testing.T) {
+	gameService := service.NewGameService(infrastructure.NewMemoryRoomRepository(), game.NewPlayerManager())
+	clients := NewClientRegistry()
+	gameService.SetRoomNotifier(func(ctx context.Context, snapshot game.RoomSnapshot) {
+		NotifyGameUpdateToClients(ctx, clients, snapshot)
+	})
+	for _, playerID := range []string{"p1", "p2"} {
+		if _, err := gameService.AddPlayer(playerID); err != nil {
+			t.Fatalf("AddPlayer(%s) error = %v", playerID, err)
+		}
+	}
+	res, err := gameService.CreateRoomWithContext(context.Background(), "tictactoe", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	if _, err := gameService.JoinRoom(res.Room.RoomID, "p2", "tictactoe"); err != nil {
+		t.Fatalf("JoinRoom() error = %v", err)
+	}
+	server := newWebSocketTestServer(t, clients, gameService)
+
+	dialer := websocket.Dialer{Subprotocols: []string{SubprotocolDeltaMsgpack}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"?room_id="+res.Room.RoomID+"&player_id=p1", nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != SubprotocolDeltaMsgpack {
+		t.Fatalf("subprotocol = %q, want %q", conn.Subprotocol(), SubprotocolDeltaMsgpack)
+	}
+
+	full := readTestMsgpackEvent(t, conn, EventRoomUpdate)
+	if full.BaseVersion != nil {
+		t.Fatalf("first room_update base_version = %d, want a full snapshot", *full.BaseVersion)
+	}
+
+	move, err := msgpack.Marshal(map[string]interface{}{
+		"type":    actions.TICTACTOE_MOVE,
+		"payload": map[string]interface{}{"row": 0, "col": 0},
+	})
+	if err != nil {
+		t.Fatalf("msgpack.Marshal() error = %v", err)
+	}
+	if err := conn.WriteMessage(websocket.BinaryMessage, move); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+
+	delta := readTestMsgpackEvent(t, conn, EventGameUpdate)
+	if delta.BaseVersion == nil {
+		t.Fatalf("game_update is a full snapshot, want a patch")
+	}
+	if _, ok := delta.Payload.(map[string]interface{})["room_id"]; ok {
+		t.Fatalf("patch %v repeats the unchanged room_id", delta.Payload)
+	}
+}
+
+func encodeTestSnapshot(t *testing.T, encoder *eventEncoder, room *game.Room) encodedEvent {
+	t.Helper()
+
+	message, err := json.Marshal(Event{
+		Type:    EventGameUpdate,
+		Payload: marshalPayload(dto.FromRoomSnapshotForPlayer(room.Snapshot(), "p1")),
+	})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	messageType, data, err := encoder.encode(message)
+	if err != nil {
+		t.Fatalf("encode() error = %v", err)
+	}
+	if messageType != websocket.TextMessage {
+		t.Fatalf("message type = %d, want text", messageType)
+	}
+
+	var wire struct {
+		Payload     json.RawMessage `json:"payload"`
+		BaseVersion *uint64         `json:"base_version"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	return encodedEvent{Payload: decodeTestValue(t, wire.Payload), BaseVersion: wire.BaseVersion}
+}
+
+func decodeTestValue(t *testing.T, data []byte) interface{} {
+	t.Helper()
+
+	value, err := decodeJSONValue(data)
+	if err != nil {
+		t.Fatalf("decodeJSONValue() error = %v", err)
+	}
+	return value
+}
+
+// applyTestPatch applies a patch built by diffObjects the way a client does.
+func applyTestPatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	if appended, ok := patchObject[patchAppendKey].([]interface{}); ok {
+		items, _ := target.([]interface{})
+		return append(append([]interface{}{}, items...), appended...)
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	result := make(map[string]interface{}, len(targetObject))
+	for key, value := range targetObject {
+		result[key] = value
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(result, key)
+			continue
+		}
+		result[key] = applyTestPatch(result[key], value)
+	}
+	return result
+}
+
+func readTestMsgpackEvent(t *testing.T, conn *websocket.Conn, eventType string) encodedEvent {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline() error = %v", err)
+	}
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage() error = %v", err)
+		}
+		if messageType != websocket.BinaryMessage {
+			t.Fatalf("message type = %d, want binary", messageType)
+		}
+
+		var event encodedEvent
+		if err := msgpack.Unmarshal(data, &event); err != nil {
+			t.Fatalf("msgpack.Unmarshal() error = %v", err)
+		}
+		if event.Type == eventType {
+			return event
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/actions"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+	"golang.org/x/time/rate"
+)
+
+// MessageRateLimit is a token bucket: PerSecond tokens refill it, up to
+// Burst.
+type MessageRateLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+var (
+	// DefaultMessageRateLimit applies to every message a player sends.
+	DefaultMessageRateLimit = MessageRateLimit{PerSecond: 10, Burst: 20}
+	// DefaultChatRateLimit applies to chat messages on top of it.
+	DefaultChatRateLimit = MessageRateLimit{PerSecond: 1, Burst: 5}
+	// DefaultConnectionRateLimit applies to each connection, so one tab
+	// cannot spend the whole of its player's bucket.
+	DefaultConnectionRateLimit = MessageRateLimit{PerSecond: 5, Burst: 10}
+)
+
+// AbusePolicy escalates against a connection whose messages keep being
+// rejected, for going over a rate limit or not being understood. Within a
+// Window, the first Warnings rejections are answered, later ones are dropped
+// without a word, and rejection CloseAfter closes the connection with
+// CloseCodeAbuse.
+type AbusePolicy struct {
+	Warnings   int
+	CloseAfter int
+	Window     time.Duration
+}
+
+var DefaultAbusePolicy = AbusePolicy{Warnings: 5, CloseAfter: 30, Window: time.Minute}
+
+const abuseCloseReason = "too many rejected messages"
+
+// messagePenalty is what a rejected message costs its connection.
+type messagePenalty int
+
+const (
+	penaltyWarn messagePenalty = iota
+	penaltyDrop
+	penaltyClose
+)
+
+// connectionAbuse is a connection's own bucket and the rejections counted
+// against it.
+type connectionAbuse struct {
+	mu          sync.Mutex
+	limiter     *rate.Limiter
+	strikes     int
+	firstStrike time.Time
+}
+
+func (a *connectionAbuse) allow(limit MessageRateLimit, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.limiter == nil {
+		a.limiter = rate.NewLimiter(rate.Limit(limit.PerSecond), limit.Burst)
+	}
+	return a.limiter.AllowN(now, 1)
+}
+
+func (a *connectionAbuse) strike(policy AbusePolicy, now time.Time) messagePenalty {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.strikes == 0 || now.Sub(a.firstStrike) > policy.Window {
+		a.strikes = 0
+		a.firstStrike = now
+	}
+	a.strikes++
+	switch {
+	case a.strikes >= policy.CloseAfter:
+		return penaltyClose
+	case a.strikes > policy.Warnings:
+		return penaltyDrop
+	default:
+		return penaltyWarn
+	}
+}
+
+// messageLimiterIdleTTL is how long the bucket of a quiet player is kept.
+const messageLimiterIdleTTL = 3 * time.Minute
+
+type messageBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// messageLimiter keeps a token bucket per room and player, so reconnecting
+// or opening more connections does not refill it.
+type messageLimiter struct {
+	mu        sync.Mutex
+	limit     MessageRateLimit
+	buckets   map[clientKey]*messageBucket
+	lastSweep time.Time
+}
+
+func newMessageLimiter(limit MessageRateLimit) *messageLimiter {
+	return &messageLimiter{limit: limit, buckets: make(map[clientKey]*messageBucket)}
+}
+
+func (l *messageLimiter) allow(key clientKey, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > messageLimiterIdleTTL {
+		for id, bucket := range l.buckets {
+			if now.Sub(bucket.lastSeen) > messageLimiterIdleTTL {
+				delete(l.buckets, id)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	bucket := l.buckets[key]
+	if bucket == nil {
+		bucket = &messageBucket{limiter: rate.NewLimiter(rate.Limit(l.limit.PerSecond), l.limit.Burst)}
+		l.buckets[key] = bucket
+	}
+	bucket.lastSeen = now
+	return bucket.limiter.AllowN(now, 1)
+}
+
+// SetMessageRateLimits replaces the limits on the messages players send and,
+// within them, on their chat messages.
+func (r *ClientRegistry) SetMessageRateLimits(messages MessageRateLimit, chat MessageRateLimit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.messageLimits = newMessageLimiter(messages)
+	r.chatLimits = newMessageLimiter(chat)
+}
+
+// SetConnectionRateLimit replaces the limit each connection has of its own,
+// within its player's. Connections made earlier keep theirs.
+func (r *ClientRegistry) SetConnectionRateLimit(limit MessageRateLimit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.connectionLimit = limit
+}
+
+// SetAbusePolicy replaces how rejected messages are escalated.
+func (r *ClientRegistry) SetAbusePolicy(policy AbusePolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.abusePolicy = policy
+}
+
+// allowMessage takes a token from the connection's and the player's buckets
+// for the message. A rejected message is answered with a rate_limited nack
+// or an error event while the connection is within its warnings; it reports
+// false, and closing is set once the connection has been closed for it.
+func (r *ClientRegistry) allowMessage(ctx context.Context, roomID string, playerID string, client *Client, message WebSocketMessage) (allowed bool, closing bool) {
+	r.mu.RLock()
+	messageLimits, chatLimits, connectionLimit := r.messageLimits, r.chatLimits, r.connectionLimit
+	r.mu.RUnlock()
+
+	key := clientKey{roomID: roomID, playerID: playerID}
+	now := time.Now()
+	limiter := "connection"
+	allowed = client.abuse.allow(connectionLimit, now)
+	if allowed {
+		limiter = "websocket"
+		allowed = messageLimits.allow(key, now)
+	}
+	if allowed && message.Type == actions.CHAT_SEND {
+		limiter = "chat"
+		allowed = chatLimits.allow(key, now)
+	}
+	if allowed {
+		return true, false
+	}
+
+	observability.RateLimitRejections.WithLabelValues(limiter).Inc()
+	observability.Logger().WarnContext(ctx, "websocket message rate limited",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", "websocket_rate_limited",
+		"message_type", message.Type,
+		"limiter", limiter,
+	)
+	penalty := r.penalize(ctx, client, "rate limited")
+	if penalty != penaltyWarn {
+		return false, penalty == penaltyClose
+	}
+	if message.RequestID != "" {
+		sendEvent(ctx, client, EventNack, CommandNackPayload{
+			RequestID: message.RequestID,
+			Code:      "rate_limited",
+			Message:   "too many messages, slow down",
+		})
+		return false, false
+	}
+	sendErrorMessage(ctx, client, "Too many messages, slow down")
+	return false, false
+}
+
+// penalize counts a rejected message against the client's connection and,
+// once the policy says so, closes it with CloseCodeAbuse after the events
+// already queued for it.
+func (r *ClientRegistry) penalize(ctx context.Context, client *Client, cause string) messagePenalty {
+	r.mu.RLock()
+	policy := r.abusePolicy
+	r.mu.RUnlock()
+
+	penalty := client.abuse.strike(policy, time.Now())
+	if penalty == penaltyClose {
+		observability.WebSocketAbuseClosures.Inc()
+		observability.Logger().WarnContext(ctx, "websocket closed for abuse",
+			"room_id", client.RoomID,
+			"player_id", client.PlayerID,
+			"event_type", "websocket_abuse_closed",
+			"close_code", CloseCodeAbuse,
+			"connection_id", client.ConnectionID,
+			"cause", cause,
+		)
+		client.CloseAfterQueued(CloseCodeAbuse, abuseCloseReason)
+	}
+	return penalty
+}
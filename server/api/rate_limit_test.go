@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/tsaqiffatih/mini-game/actions"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+)
+
+func TestHandleWebSocket_ChatOverTheRateLimitIsNacked(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	clients := NewClientRegistry()
+	clients.SetMessageRateLimits(DefaultMessageRateLimit, MessageRateLimit{PerSecond: 0.01, Burst: 1})
+	server := newWebSocketTestServer(t, clients, gameService)
+
+	conn := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	defer conn.Close()
+	readTestWebSocketEvent(t, conn, EventChatHistory)
+
+	payload, _ := json.Marshal(dto.ChatSendPayload{Message: "hello"})
+	writeTestCommand(t, conn, WebSocketMessage{Type: actions.CHAT_SEND, Payload: payload, RequestID: "chat-1"})
+	if ack := readTestAck(t, conn); ack.RequestID != "chat-1" {
+		t.Fatalf("ack = %+v, want chat-1", ack)
+	}
+
+	writeTestCommand(t, conn, WebSocketMessage{Type: actions.CHAT_SEND, Payload: payload, RequestID: "chat-2"})
+	event := readTestWebSocketEvent(t, conn, EventNack)
+	var nack CommandNackPayload
+	if err := json.Unmarshal(event.Payload, &nack); err != nil {
+		t.Fatalf("decode nack: %v", err)
+	}
+	if nack.RequestID != "chat-2" || nack.Code != "rate_limited" {
+		t.Fatalf("nack = %+v, want chat-2 rate_limited", nack)
+	}
+}
+
+func TestHandleWebSocket_ConnectionLimitAppliesWithinPlayerLimit(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	clients := NewClientRegistry()
+	clients.SetConnectionRateLimit(MessageRateLimit{PerSecond: 0.01, Burst: 1})
+	server := newWebSocketTestServer(t, clients, gameService)
+
+	first := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	defer first.Close()
+	readTestWebSocketEvent(t, first, EventChatHistory)
+	second := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	defer second.Close()
+	readTestWebSocketEvent(t, second, EventChatHistory)
+
+	payload, _ := json.Marshal(dto.ChatSendPayload{Message: "hello"})
+	writeTestCommand(t, first, WebSocketMessage{Type: actions.CHAT_SEND, Payload: payload, RequestID: "first-1"})
+	if ack := readTestAck(t, first); ack.RequestID != "first-1" {
+		t.Fatalf("ack = %+v, want first-1", ack)
+	}
+	writeTestCommand(t, first, WebSocketMessage{Type: actions.CHAT_SEND, Payload: payload, RequestID: "first-2"})
+	var nack CommandNackPayload
+	if err := json.Unmarshal(readTestWebSocketEvent(t, first, EventNack).Payload, &nack); err != nil {
+		t.Fatalf("decode nack: %v", err)
+	}
+	if nack.RequestID != "first-2" || nack.Code != "rate_limited" {
+		t.Fatalf("nack = %+v, want first-2 rate_limited", nack)
+	}
+
+	// The other tab has its own bucket.
+	writeTestCommand(t, second, WebSocketMessage{Type: actions.CHAT_SEND, Payload: payload, RequestID: "second-1"})
+	if ack := readTestAck(t, second); ack.RequestID != "second-1" {
+		t.Fatalf("ack = %+v, want second-1", ack)
+	}
+}
+
+func TestHandleWebSocket_RepeatedRejectionsEscalateToClose(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	clients := NewClientRegistry()
+	clients.SetAbusePolicy(AbusePolicy{Warnings: 1, CloseAfter: 3, Window: time.Minute})
+	server := newWebSocketTestServer(t, clients, gameService)
+
+	conn := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	defer conn.Close()
+	readTestWebSocketEvent(t, conn, EventChatHistory)
+
+	for i := 0; i < 3; i++ {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("not json")); err != nil {
+			t.Fatalf("WriteMessage() error = %v", err)
+		}
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline() error = %v", err)
+	}
+	errorsSeen := 0
+	for {
+		var event Event
+		err := conn.ReadJSON(&event)
+		if err == nil {
+			if event.Type == "error" {
+				errorsSeen++
+			}
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != CloseCodeAbuse {
+			t.Fatalf("ReadJSON() error = %v, want close code %d", err, CloseCodeAbuse)
+		}
+		break
+	}
+	if errorsSeen != 1 {
+		t.Fatalf("error events = %d, want 1 warning before the rest were dropped", errorsSeen)
+	}
+}
+
+func TestHandleWebSocket_OversizedMessageClosesConnection(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	clients := NewClientRegistry()
+	server := newWebSocketTestServer(t, clients, gameService)
+
+	conn := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	defer conn.Close()
+	readTestWebSocketEvent(t, conn, EventChatHistory)
+
+	payload, _ := json.Marshal(dto.ChatSendPayload{Message: strings.Repeat("a", int(DefaultMaxMessageSize))})
+	writeTestCommand(t, conn, WebSocketMessage{Type: actions.CHAT_SEND, Payload: payload})
+	assertWebSocketCloseCode(t, conn, websocket.CloseMessageTooBig)
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+func getReactions(w http.ResponseWriter, r *http.Request) {
+	writeSuccessResponse(w, http.StatusOK, dto.FromReactionCatalog())
+}
+
+func processReactionSend(
+	ctx context.Context,
+	player game.PlayerSnapshot,
+	client *Client,
+	clients *ClientRegistry,
+	gameService *service.GameService,
+	roomID string,
+	message WebSocketMessage,
+) error {
+	var payload dto.ReactionSendPayload
+	if err := parsePayload(ctx, client, roomID, message.Type, message.Payload, &payload); err != nil {
+		return err
+	}
+
+	reaction, err := gameService.SendReactionWithContext(ctx, roomID, player.ID, payload.ReactionID)
+	if err != nil {
+		sendErrorMessage(ctx, client, err.Error())
+		return err
+	}
+
+	notifyEphemeralToPlayers(ctx, clients, roomID, reaction.Audience, EventReaction, dto.FromReaction(reaction))
+	return nil
+}
+
+func processTyping(
+	ctx context.Context,
+	player game.PlayerSnapshot,
+	client *Client,
+	clients *ClientRegistry,
+	gameService *service.GameService,
+	roomID string,
+	typing bool,
+) error {
+	state, changed, err := gameService.SetTypingWithContext(ctx, roomID, player.ID, typing)
+	if err != nil {
+		sendErrorMessage(ctx, client, err.Error())
+		return err
+	}
+	if changed {
+		NotifyTypingToClients(ctx, clients, state)
+	}
+	return nil
+}
+
+// NotifyTypingToClients tells the other players in the room a player started
+// or stopped typing.
+func NotifyTypingToClients(ctx context.Context, clients *ClientRegistry, state game.TypingState) {
+	notifyEphemeralToPlayers(ctx, clients, state.RoomID, state.Audience, EventTyping, dto.FromTypingState(state))
+}
+
+// notifyEphemeralToPlayers sends an event that is not worth replaying. It
+// takes no seq and stays out of the room's event log, so a player who
+// resumes never sees it.
+func notifyEphemeralToPlayers(ctx context.Context, clients *ClientRegistry, roomID string, playerIDs []string, eventType string, payload interface{}) {
+	messageBytes, err := json.Marshal(Event{
+		Type:    eventType,
+		Payload: marshalPayload(payload),
+		TraceID: observability.TraceID(ctx),
+	})
+	if err != nil {
+		observability.Logger().Warn("websocket event marshal failed",
+			"room_id", roomID,
+			"player_id", "",
+			"event_type", eventType,
+			"error", err,
+		)
+		return
+	}
+
+	for _, playerID := range playerIDs {
+		deliverToPlayer(ctx, clients, roomID, playerID, eventType, messageBytes)
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/tsaqiffatih/mini-game/actions"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+)
+
+func TestHandleWebSocket_ReactionsAndTypingReachTheRoomWithoutSeq(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	server := newWebSocketTestServer(t, NewClientRegistry(), gameService)
+
+	p1 := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	defer p1.Close()
+	readTestWebSocketEvent(t, p1, EventChatHistory)
+	p2 := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p2")
+	defer p2.Close()
+	readTestWebSocketEvent(t, p2, EventChatHistory)
+
+	writeTestCommand(t, p1, WebSocketMessage{Type: actions.TYPING_START})
+	event := readTestWebSocketEvent(t, p2, EventTyping)
+	var typing dto.TypingDTO
+	if err := json.Unmarshal(event.Payload, &typing); err != nil {
+		t.Fatalf("decode typing: %v", err)
+	}
+	if typing.PlayerID != "p1" || !typing.Typing || typing.ExpiresAt == nil || event.Seq != 0 {
+		t.Fatalf("typing event = %+v seq %d, want p1 typing without seq", typing, event.Seq)
+	}
+
+	payload, _ := json.Marshal(dto.ReactionSendPayload{ReactionID: "good_game"})
+	writeTestCommand(t, p1, WebSocketMessage{Type: actions.REACTION_SEND, Payload: payload})
+	event = readTestWebSocketEvent(t, p2, EventReaction)
+	var reaction dto.ReactionDTO
+	if err := json.Unmarshal(event.Payload, &reaction); err != nil {
+		t.Fatalf("decode reaction: %v", err)
+	}
+	if reaction.PlayerID != "p1" || reaction.ReactionID != "good_game" || event.Seq != 0 {
+		t.Fatalf("reaction event = %+v seq %d, want p1's good_game without seq", reaction, event.Seq)
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+// processRoomInviteCreate answers the host with a single-use invite to share.
+func processRoomInviteCreate(
+	ctx context.Context,
+	player game.PlayerSnapshot,
+	client *Client,
+	gameService *service.GameService,
+	roomID string,
+) error {
+	invite, err := gameService.CreateInviteWithContext(ctx, roomID, player.ID)
+	if err != nil {
+		sendErrorMessage(ctx, client, err.Error())
+		return err
+	}
+
+	sendEvent(ctx, client, EventRoomInvite, dto.RoomInviteDTO{
+		RoomID:    invite.RoomID,
+		Token:     invite.Token,
+		ExpiresAt: invite.ExpiresAt,
+	})
+	return nil
+}
+
+// processRoomKick removes a player at the host's request, tells the room and
+// closes the kicked player's connections.
+func processRoomKick(
+	ctx context.Context,
+	player game.PlayerSnapshot,
+	client *Client,
+	clients *ClientRegistry,
+	gameService *service.GameService,
+	roomID string,
+	message WebSocketMessage,
+) error {
+	var payload dto.RoomKickPayload
+	if err := parsePayload(ctx, client, roomID, message.Type, message.Payload, &payload); err != nil {
+		return err
+	}
+
+	if err := gameService.KickPlayerWithContext(ctx, roomID, player.ID, payload.PlayerID, message.ExpectedStateVersion); err != nil {
+		sendErrorMessage(ctx, client, err.Error())
+		return err
+	}
+
+	NotifyToClientsInRoom(ctx, clients, gameService, roomID, EventPlayerKicked, EventPayload{
+		Message:   fmt.Sprintf("Player %s was removed by the host", payload.PlayerID),
+		Player:    &dto.PlayerDTO{ID: payload.PlayerID, PlayerID: payload.PlayerID},
+		Timestamp: time.Now(),
+	})
+	closePlayerConnections(ctx, clients, roomID, payload.PlayerID, CloseCodePlayerKicked, "removed by host")
+	return nil
+}
+
+// CloseRoomConnections closes the connections of every human player of the
+// room in snapshot, as when the room expires.
+func CloseRoomConnections(ctx context.Context, clients *ClientRegistry, snapshot game.RoomSnapshot, reason string) {
+	for _, player := range snapshot.Players {
+		if !player.IsAI {
+			closePlayerConnections(ctx, clients, snapshot.RoomID, player.ID, CloseCodeRoomExpired, reason)
+		}
+	}
+}
+
+// closePlayerConnections closes every connection of the player to the room,
+// here and, through the cluster, on the nodes holding the others.
+func closePlayerConnections(ctx context.Context, clients *ClientRegistry, roomID string, playerID string, code int, reason string) {
+	for _, client := range clients.RoomClients(roomID, playerID) {
+		client.CloseWithCode(code, reason)
+	}
+	if cluster := clients.Cluster(); cluster != nil && clients.hasRemote(roomID, playerID) {
+		cluster.closeRemote(ctx, roomID, playerID, code, reason)
+	}
+	observability.Logger().InfoContext(ctx, "player connections closed",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", "player_connections_closed",
+		"close_code", code,
+	)
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/tsaqiffatih/mini-game/actions"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+)
+
+func TestHandleWebSocket_HostKickClosesKickedPlayersConnection(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	server := newWebSocketTestServer(t, NewClientRegistry(), gameService)
+
+	host := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p1")
+	defer host.Close()
+	readTestWebSocketEvent(t, host, EventChatHistory)
+	guest := dialTestWebSocket(t, server, "room_id="+roomID+"&player_id=p2")
+	defer guest.Close()
+	readTestWebSocketEvent(t, guest, EventChatHistory)
+
+	payload, err := json.Marshal(dto.RoomKickPayload{PlayerID: "p2"})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	writeTestCommand(t, host, WebSocketMessage{Type: actions.ROOM_KICK, Payload: payload})
+
+	event := readTestWebSocketEvent(t, host, EventPlayerKicked)
+	var kicked RoomEventPayload
+	if err := json.Unmarshal(event.Payload, &kicked); err != nil {
+		t.Fatalf("decode player_kicked: %v", err)
+	}
+	if len(kicked.Room.Players) != 1 || kicked.Room.Settings.HostID != "p1" {
+		t.Fatalf("room after kick = %+v, want only the host p1", kicked.Room)
+	}
+	assertWebSocketCloseCode(t, guest, CloseCodePlayerKicked)
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+// streamKeepAlive is how often an idle event stream gets a comment line, so
+// proxies do not time it out.
+const streamKeepAlive = 15 * time.Second
+
+// EventStreamConnected is the first event of a stream. It carries the
+// connection ID that actions posted for the stream refer to.
+const EventStreamConnected = "stream_connected"
+
+type StreamConnectedPayload struct {
+	ConnectionID string `json:"connection_id"`
+}
+
+// HandleRoomEvents streams a player's events of a room as Server-Sent Events,
+// for networks that block websockets. A stream is a connection like a
+// websocket: it counts for presence, resumes after last_seq or Last-Event-ID,
+// and POST /room/{room_id}/actions takes the place of its incoming messages.
+func HandleRoomEvents(w http.ResponseWriter, r *http.Request, clients *ClientRegistry, gameService *service.GameService) {
+	roomID := mux.Vars(r)["room_id"]
+	playerID, ok := requestPlayerID(w, r, r.URL.Query().Get("player_id"))
+	if !ok || !validateRoomAndPlayerIDs(w, roomID, playerID) {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrorResponse(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	ctx, endSpan := observability.StartSpan(r.Context(), "sse.connect")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+	// The request context ends with the stream, but the player is marked
+	// disconnected after that.
+	detached := context.WithoutCancel(ctx)
+
+	lastSeq, resume := resumeFromStream(r)
+	if cluster := clients.Cluster(); cluster != nil {
+		if owner, remote := cluster.remoteOwner(ctx, roomID); remote {
+			client, code, reason := cluster.connectRemote(ctx, owner, roomID, playerID, lastSeq, resume, func(generation uint64) *Client {
+				return clients.AttachStream(roomID, playerID, generation)
+			})
+			if client == nil {
+				writeErrorResponse(w, streamStatus(code), reason)
+				return
+			}
+			streamEvents(ctx, w, flusher, client)
+			cluster.disconnectRemote(detached, owner, client)
+			return
+		}
+	}
+
+	client, player, eventType, err := connectClient(ctx, clients, gameService, roomID, playerID, lastSeq, resume, func(generation uint64) *Client {
+		return clients.AttachStream(roomID, playerID, generation)
+	})
+	if err != nil {
+		spanErr = err
+		code, reason := websocketCloseForValidationError(err)
+		observability.Logger().WarnContext(ctx, "event stream rejected",
+			"room_id", roomID,
+			"player_id", playerID,
+			"event_type", "sse_session_rejected",
+			"close_code", code,
+			"error", err,
+		)
+		writeErrorResponse(w, streamStatus(code), reason)
+		return
+	}
+
+	observability.Logger().InfoContext(ctx, "event stream connected",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", "sse_connected",
+		"generation", client.Generation,
+		"connection_id", client.ConnectionID,
+	)
+	notifyRoomOnConnection(ctx, clients, gameService, roomID, player, client, eventType, resume)
+
+	streamEvents(ctx, w, flusher, client)
+	observability.Logger().InfoContext(ctx, "event stream disconnected",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", "sse_disconnected",
+		"generation", client.Generation,
+	)
+	handlePlayerDisconnection(detached, clients, gameService, roomID, playerID, player, client)
+}
+
+// streamEvents writes the client's events until the request ends or the
+// client is closed, for example displaced by a newer connection.
+func streamEvents(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, client *Client) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	connected, ok := marshalEvent(Event{
+		Type:    EventStreamConnected,
+		Payload: marshalPayload(StreamConnectedPayload{ConnectionID: client.ConnectionID}),
+		TraceID: observability.TraceID(ctx),
+	}, client)
+	if ok {
+		writeStreamEvent(w, connected)
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(streamKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-client.done:
+			return
+		case message := <-client.Send:
+			if message == nil {
+				// CloseAfterQueued: everything before it has been written.
+				return
+			}
+			if err := writeStreamEvent(w, message); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeStreamEvent writes one event, with its room sequence number as the
+// SSE id so a reconnecting EventSource resumes from it.
+func writeStreamEvent(w http.ResponseWriter, message []byte) error {
+	var event struct {
+		Seq uint64 `json:"seq"`
+	}
+	if err := json.Unmarshal(message, &event); err == nil && event.Seq > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", event.Seq); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "data: %s\n\n", message)
+	return err
+}
+
+// HandleRoomActions runs a message for the event stream named by the
+// connection_id query parameter, as if it had come in on a websocket. The
+// outcome, including any ack or nack, is sent on the stream.
+func HandleRoomActions(w http.ResponseWriter, r *http.Request, clients *ClientRegistry, gameService *service.GameService) {
+	roomID := mux.Vars(r)["room_id"]
+	playerID, ok := requestPlayerID(w, r, r.URL.Query().Get("player_id"))
+	if !ok || !validateRoomAndPlayerIDs(w, roomID, playerID) {
+		return
+	}
+
+	var message WebSocketMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMessageSize)).Decode(&message); err != nil {
+		observability.WebSocketMessagesReceived.WithLabelValues("invalid").Inc()
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid message format")
+		return
+	}
+
+	client := clients.Connection(roomID, playerID, r.URL.Query().Get("connection_id"))
+	if client == nil {
+		writeErrorResponse(w, http.StatusNotFound, "Event stream not found")
+		return
+	}
+
+	ctx := r.Context()
+	if allowed, _ := clients.allowMessage(ctx, roomID, playerID, client, message); !allowed {
+		writeErrorResponse(w, http.StatusTooManyRequests, "Too many messages, slow down")
+		return
+	}
+	if cluster := clients.Cluster(); cluster != nil {
+		if owner, remote := cluster.remoteOwner(ctx, roomID); remote {
+			cluster.forwardMessage(ctx, owner, client, message)
+			writeSuccessResponse(w, http.StatusAccepted, map[string]interface{}{})
+			return
+		}
+	}
+
+	player, err := gameService.GetPlayerInRoomWithContext(ctx, roomID, playerID)
+	if err != nil {
+		writeErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	observability.Logger().InfoContext(ctx, "event stream action received",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", message.Type,
+	)
+	gameService.UpdatePlayerActivityWithContext(ctx, roomID, playerID)
+	handleMessageAction(ctx, clients, gameService, roomID, player, client, message)
+	writeSuccessResponse(w, http.StatusAccepted, map[string]interface{}{})
+}
+
+// resumeFromStream reads where a reconnecting stream left off: the
+// Last-Event-ID an EventSource sends by itself, or last_seq as on /ws.
+func resumeFromStream(r *http.Request) (uint64, bool) {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		if lastSeq, err := strconv.ParseUint(id, 10, 64); err == nil {
+			return lastSeq, true
+		}
+	}
+	return resumeFromQuery(r)
+}
+
+func streamStatus(closeCode int) int {
+	switch closeCode {
+	case CloseCodeRoomExpired, CloseCodePlayerNotFound:
+		return http.StatusNotFound
+	case websocket.CloseTryAgainLater:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/middleware"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+func TestHandleRoomEvents_ActionsArePlayedOverTheStream(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	server := newStreamTestServer(t, NewClientRegistry(), gameService)
+
+	stream := openTestStream(context.Background(), t, server, roomID, "p1")
+	connected := stream.read(t, EventStreamConnected)
+	var payload StreamConnectedPayload
+	if err := json.Unmarshal(connected.Payload, &payload); err != nil {
+		t.Fatalf("decode stream_connected: %v", err)
+	}
+	if payload.ConnectionID == "" {
+		t.Fatalf("stream_connected = %+v, want a connection ID", payload)
+	}
+	if snapshot := stream.read(t, EventRoomUpdate); snapshot.Seq == 0 || stream.lastID != snapshot.Seq {
+		t.Fatalf("room_update seq = %d, id = %d, want the seq as event id", snapshot.Seq, stream.lastID)
+	}
+
+	move := ticTacToeCommand(t, "move-1", 0, 0, nil)
+	status := postTestAction(t, server, roomID, "p1", payload.ConnectionID, move)
+	if status != http.StatusAccepted {
+		t.Fatalf("POST actions status = %d, want %d", status, http.StatusAccepted)
+	}
+	stream.read(t, EventGameUpdate)
+	ack := stream.read(t, EventAck)
+	if !strings.Contains(string(ack.Payload), `"request_id":"move-1"`) {
+		t.Fatalf("ack = %s, want request_id move-1", ack.Payload)
+	}
+
+	if status := postTestAction(t, server, roomID, "p1", "unknown", move); status != http.StatusNotFound {
+		t.Fatalf("POST actions for unknown stream status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestHandleRoomEvents_ClosedStreamMarksPlayerDisconnected(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	clients := NewClientRegistry()
+	server := newStreamTestServer(t, clients, gameService)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := openTestStream(ctx, t, server, roomID, "p1")
+	stream.read(t, EventStreamConnected)
+	if !clients.IsConnected(roomID, "p1") {
+		t.Fatalf("IsConnected(p1) = false, want true while streaming")
+	}
+
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		player, err := gameService.GetPlayerInRoomWithContext(context.Background(), roomID, "p1")
+		if err != nil {
+			t.Fatalf("GetPlayerInRoomWithContext() error = %v", err)
+		}
+		if player.Session == game.PlayerSessionDisconnected && !clients.IsConnected(roomID, "p1") {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("player still connected after the stream closed")
+}
+
+func TestHandleRoomActions_PlayersBehindOneIPDoNotShareALimit(t *testing.T) {
+	gameService, roomID := newResumeTestRoom(t)
+	clients := NewClientRegistry()
+	middleware.SetRateLimit(0.01, 2)
+	t.Cleanup(func() { middleware.SetRateLimit(1, 5) })
+
+	router := mux.NewRouter()
+	router.HandleFunc("/room/{room_id}/events", func(w http.ResponseWriter, r *http.Request) {
+		HandleRoomEvents(w, r, clients, gameService)
+	}).Methods("GET")
+	router.HandleFunc("/room/{room_id}/actions", func(w http.ResponseWriter, r *http.Request) {
+		HandleRoomActions(w, r, clients, gameService)
+	}).Methods("POST")
+	router.Use(middleware.RateLimiterExcept(IsPlayerLimitedRoute))
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	connections := map[string]string{}
+	for _, playerID := range []string{"p1", "p2"} {
+		stream := openTestStream(context.Background(), t, server, roomID, playerID)
+		connected := stream.read(t, EventStreamConnected)
+		var payload StreamConnectedPayload
+		if err := json.Unmarshal(connected.Payload, &payload); err != nil {
+			t.Fatalf("decode stream_connected: %v", err)
+		}
+		connections[playerID] = payload.ConnectionID
+	}
+
+	moves := []struct {
+		playerID string
+		row, col int
+	}{{"p1", 0, 0}, {"p2", 1, 1}, {"p1", 0, 1}, {"p2", 2, 2}}
+	for i, move := range moves {
+		message := ticTacToeCommand(t, "move-"+strconv.Itoa(i), move.row, move.col, nil)
+		if status := postTestAction(t, server, roomID, move.playerID, connections[move.playerID], message); status != http.StatusAccepted {
+			t.Fatalf("POST actions %d by %s status = %d, want %d", i, move.playerID, status, http.StatusAccepted)
+		}
+	}
+
+	response, err := http.Get(server.URL + "/room/" + roomID + "/events?player_id=p1")
+	if err != nil {
+		t.Fatalf("GET events error = %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("GET events past the IP limit status = %d, want %d", response.StatusCode, http.StatusTooManyRequests)
+	}
+}
+
+type testStream struct {
+	events chan testStreamEvent
+	lastID uint64
+}
+
+type testStreamEvent struct {
+	id    uint64
+	event Event
+}
+
+func newStreamTestServer(t *testing.T, clients *ClientRegistry, gameService *service.GameService) *httptest.Server {
+	t.Helper()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/room/{room_id}/events", func(w http.ResponseWriter, r *http.Request) {
+		HandleRoomEvents(w, r, clients, gameService)
+	}).Methods("GET")
+	router.HandleFunc("/room/{room_id}/actions", func(w http.ResponseWriter, r *http.Request) {
+		HandleRoomActions(w, r, clients, gameService)
+	}).Methods("POST")
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func openTestStream(ctx context.Context, t *testing.T, server *httptest.Server, roomID string, playerID string) *testStream {
+	t.Helper()
+
+	url := server.URL + "/room/" + roomID + "/events?player_id=" + playerID
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("GET events error = %v", err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("GET events status = %d, want %d", response.StatusCode, http.StatusOK)
+	}
+
+	stream := &testStream{events: make(chan testStreamEvent, 64)}
+	go func() {
+		defer close(stream.events)
+		scanner := bufio.NewScanner(response.Body)
+		var current testStreamEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				current.id, _ = strconv.ParseUint(strings.TrimPrefix(line, "id: "), 10, 64)
+			case strings.HasPrefix(line, "data: "):
+				_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &current.event)
+			case line == "" && current.event.Type != "":
+				stream.events <- current
+				current = testStreamEvent{}
+			}
+		}
+	}()
+	return stream
+}
+
+func (s *testStream) read(t *testing.T, eventType string) Event {
+	t.Helper()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case received, ok := <-s.events:
+			if !ok {
+				t.Fatalf("stream closed, want %s", eventType)
+			}
+			if received.id > 0 {
+				s.lastID = received.id
+			}
+			if received.event.Type == eventType {
+				return received.event
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", eventType)
+		}
+	}
+}
+
+func postTestAction(t *testing.T, server *httptest.Server, roomID string, playerID string, connectionID string, message WebSocketMessage) int {
+	t.Helper()
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	url := server.URL + "/room/" + roomID + "/actions?player_id=" + playerID + "&connection_id=" + connectionID
+	response, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST actions error = %v", err)
+	}
+	response.Body.Close()
+	return response.StatusCode
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/service"
+)
+
+func RegisterStatsRouter(r *mux.Router, statsService *service.StatsService) {
+	r.HandleFunc("/leaderboard", func(w http.ResponseWriter, r *http.Request) {
+		getLeaderboard(w, r, statsService)
+	}).Methods("GET")
+
+	r.HandleFunc("/players/{player_id}/stats", func(w http.ResponseWriter, r *http.Request) {
+		getPlayerStats(w, r, statsService)
+	}).Methods("GET")
+}
+
+func getLeaderboard(w http.ResponseWriter, r *http.Request, statsService *service.StatsService) {
+	gameType := r.URL.Query().Get("game")
+
+	limit := 0
+	if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil || parsed < 0 {
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := statsService.LeaderboardWithContext(r.Context(), gameType, limit)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeSuccessResponse(w, http.StatusOK, dto.FromLeaderboard(gameType, entries))
+}
+
+func getPlayerStats(w http.ResponseWriter, r *http.Request, statsService *service.StatsService) {
+	playerStats, err := statsService.PlayerStatsWithContext(r.Context(), mux.Vars(r)["player_id"])
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrPlayerNotFound) {
+			status = http.StatusNotFound
+		}
+		writeErrorResponse(w, status, err.Error())
+		return
+	}
+
+	writeSuccessResponse(w, http.StatusOK, dto.FromPlayerStats(playerStats))
+}
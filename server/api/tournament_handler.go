@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/service"
+	"github.com/tsaqiffatih/mini-game/tournament"
+)
+
+const EventTournamentGameStarted = "tournament_game_started"
+
+func RegisterTournamentRouter(r *mux.Router, tournamentService *service.TournamentService) {
+	r.HandleFunc("/tournaments", func(w http.ResponseWriter, r *http.Request) {
+		listTournaments(w, r, tournamentService)
+	}).Methods("GET")
+
+	r.HandleFunc("/tournaments", func(w http.ResponseWriter, r *http.Request) {
+		createTournament(w, r, tournamentService)
+	}).Methods("POST")
+
+	r.HandleFunc("/tournaments/{tournament_id}", func(w http.ResponseWriter, r *http.Request) {
+		getTournament(w, r, tournamentService)
+	}).Methods("GET")
+
+	r.HandleFunc("/tournaments/{tournament_id}/standings", func(w http.ResponseWriter, r *http.Request) {
+		getTournamentStandings(w, r, tournamentService)
+	}).Methods("GET")
+
+	r.HandleFunc("/tournaments/{tournament_id}/players", func(w http.ResponseWriter, r *http.Request) {
+		registerTournamentPlayer(w, r, tournamentService)
+	}).Methods("POST")
+
+	r.HandleFunc("/tournaments/{tournament_id}/start", func(w http.ResponseWriter, r *http.Request) {
+		startTournament(w, r, tournamentService)
+	}).Methods("POST")
+
+	r.HandleFunc("/tournaments/{tournament_id}/results", func(w http.ResponseWriter, r *http.Request) {
+		recordTournamentResult(w, r, tournamentService)
+	}).Methods("POST")
+}
+
+// NotifyTournamentGameStarted tells a connected player where their next
+// tournament game is. Players without an open websocket find the room in the
+// tournament pairings instead.
+func NotifyTournamentGameStarted(ctx context.Context, clients *ClientRegistry, event service.TournamentGameStartedEvent) {
+	sendEventToPlayer(ctx, clients, event.PlayerID, EventTournamentGameStarted, dto.TournamentGameStartedDTO{
+		TournamentID: event.TournamentID,
+		Round:        event.Round,
+		Board:        event.Board,
+		RoomID:       event.RoomID,
+		GameType:     event.GameType,
+		OpponentID:   event.OpponentID,
+		Color:        event.Color,
+	})
+}
+
+func listTournaments(w http.ResponseWriter, r *http.Request, tournamentService *service.TournamentService) {
+	snapshots, err := tournamentService.ListTournamentsWithContext(r.Context())
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	tournaments := make([]dto.TournamentDTO, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		tournaments = append(tournaments, dto.FromTournamentSnapshot(snapshot))
+	}
+	writeSuccessResponse(w, http.StatusOK, tournaments)
+}
+
+func createTournament(w http.ResponseWriter, r *http.Request, tournamentService *service.TournamentService) {
+	var request dto.CreateTournamentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	playerID, ok := requestPlayerID(w, r, request.PlayerID)
+	if !ok {
+		return
+	}
+
+	snapshot, err := tournamentService.CreateTournamentWithContext(
+		r.Context(),
+		request.Name,
+		playerID,
+		tournament.Format(request.Format),
+		request.GameType,
+		request.Rounds,
+	)
+	if err != nil {
+		writeErrorResponse(w, tournamentStatus(err), err.Error())
+		return
+	}
+
+	writeSuccessResponse(w, http.StatusCreated, dto.FromTournamentSnapshot(snapshot))
+}
+
+func getTournament(w http.ResponseWriter, r *http.Request, tournamentService *service.TournamentService) {
+	snapshot, err := tournamentService.TournamentSnapshotWithContext(r.Context(), mux.Vars(r)["tournament_id"])
+	if err != nil {
+		writeErrorResponse(w, tournamentStatus(err), err.Error())
+		return
+	}
+
+	writeSuccessResponse(w, http.StatusOK, dto.FromTournamentSnapshot(snapshot))
+}
+
+func getTournamentStandings(w http.ResponseWriter, r *http.Request, tournamentService *service.TournamentService) {
+	snapshot, err := tournamentService.TournamentSnapshotWithContext(r.Context(), mux.Vars(r)["tournament_id"])
+	if err != nil {
+		writeErrorResponse(w, tournamentStatus(err), err.Error())
+		return
+	}
+
+	writeSuccessResponse(w, http.StatusOK, dto.FromTournamentStandings(snapshot.Standings))
+}
+
+func registerTournamentPlayer(w http.ResponseWriter, r *http.Request, tournamentService *service.TournamentService) {
+	var request dto.RegisterTournamentPlayerRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	playerID, ok := requestPlayerID(w, r, request.PlayerID)
+	if !ok {
+		return
+	}
+
+	snapshot, err := tournamentService.RegisterPlayerWithContext(r.Context(), mux.Vars(r)["tournament_id"], playerID)
+	if err != nil {
+		writeErrorResponse(w, tournamentStatus(err), err.Error())
+		return
+	}
+
+	writeSuccessResponse(w, http.StatusOK, dto.FromTournamentSnapshot(snapshot))
+}
+
+func startTournament(w http.ResponseWriter, r *http.Request, tournamentService *service.TournamentService) {
+	// The body is optional: a signed-in creator starts the event as is.
+	var request dto.StartTournamentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	playerID, ok := requestPlayerID(w, r, request.PlayerID)
+	if !ok {
+		return
+	}
+
+	snapshot, err := tournamentService.StartTournamentWithContext(r.Context(), mux.Vars(r)["tournament_id"], playerID)
+	if err != nil {
+		writeErrorResponse(w, tournamentStatus(err), err.Error())
+		return
+	}
+
+	writeSuccessResponse(w, http.StatusOK, dto.FromTournamentSnapshot(snapshot))
+}
+
+func recordTournamentResult(w http.ResponseWriter, r *http.Request, tournamentService *service.TournamentService) {
+	var request dto.RecordTournamentResultRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	playerID, ok := requestPlayerID(w, r, request.PlayerID)
+	if !ok {
+		return
+	}
+
+	snapshot, err := tournamentService.RecordResultWithContext(
+		r.Context(),
+		mux.Vars(r)["tournament_id"],
+		playerID,
+		request.Round,
+		request.Board,
+		tournament.Result(request.Result),
+	)
+	if err != nil {
+		writeErrorResponse(w, tournamentStatus(err), err.Error())
+		return
+	}
+
+	writeSuccessResponse(w, http.StatusOK, dto.FromTournamentSnapshot(snapshot))
+}
+
+func tournamentStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrTournamentNotFound),
+		errors.Is(err, service.ErrPlayerNotFound),
+		errors.Is(err, tournament.ErrPairingNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, tournament.ErrNotCreator):
+		return http.StatusForbidden
+	case errors.Is(err, tournament.ErrRegistrationClosed),
+		errors.Is(err, tournament.ErrAlreadyRegistered),
+		errors.Is(err, tournament.ErrTournamentNotActive),
+		errors.Is(err, tournament.ErrResultAlreadySet):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
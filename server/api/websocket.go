@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/auth"
 	"github.com/tsaqiffatih/mini-game/game"
 	"github.com/tsaqiffatih/mini-game/internal/observability"
 	"github.com/tsaqiffatih/mini-game/service"
@@ -18,11 +20,23 @@ import (
 type WebSocketMessage struct {
 	Type    string          `json:"type"`
 	Payload json.RawMessage `json:"payload"`
+	// RequestID makes the message a command answered with an ack or nack,
+	// and applied once however often the client retries it.
+	RequestID string `json:"request_id,omitempty"`
+	// ExpectedStateVersion applies a command only if the room is still at
+	// this state version, see stateVersionedMessages.
+	ExpectedStateVersion *uint64 `json:"expected_state_version,omitempty"`
 }
 
 type Event struct {
 	Type    string          `json:"type"`
 	Payload json.RawMessage `json:"payload"`
+	// TraceID identifies the trace that produced the event, so client bug
+	// reports can be matched with server traces.
+	TraceID string `json:"trace_id,omitempty"`
+	// Seq numbers the events of a room, see roomEventLog. State sent on
+	// connect carries the last number it covers.
+	Seq uint64 `json:"seq,omitempty"`
 }
 
 type EventPayload struct {
@@ -41,6 +55,7 @@ type ErrorMessage struct {
 }
 
 var upgrader = websocket.Upgrader{
+	Subprotocols: supportedSubprotocols,
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
@@ -55,18 +70,61 @@ const (
 	EventPlayerLeft         = "player_left"
 	EventChatMessage        = "chat_message"
 	EventChatHistory        = "chat_history"
-
-	writeWait  = 10 * time.Second
-	pingPeriod = 5 * time.Second
-	pongWait   = 10 * time.Second
+	EventResumed            = "resumed"
+	EventAck                = "ack"
+	EventNack               = "nack"
+	EventPlayerKicked       = "player_kicked"
+	EventRoomInvite         = "room_invite"
+	EventReaction           = "reaction"
+	EventTyping             = "typing"
 )
 
+// WebSocketTimeouts are how the server keeps websockets alive: a write
+// gives up after WriteWait, a ping goes out every PingPeriod and a
+// connection with no pong for PongWait is dropped.
+type WebSocketTimeouts struct {
+	WriteWait  time.Duration
+	PingPeriod time.Duration
+	PongWait   time.Duration
+}
+
+// DefaultWebSocketTimeouts are used until SetWebSocketTimeouts is called.
+var DefaultWebSocketTimeouts = WebSocketTimeouts{
+	WriteWait:  10 * time.Second,
+	PingPeriod: 5 * time.Second,
+	PongWait:   10 * time.Second,
+}
+
+var websocketTimeouts = DefaultWebSocketTimeouts
+
+// SetWebSocketTimeouts replaces the timeouts of websockets opened from now
+// on, lobby ones included. Call it before the server starts.
+func SetWebSocketTimeouts(timeouts WebSocketTimeouts) {
+	websocketTimeouts = timeouts
+}
+
+// DefaultMaxMessageSize bounds a message a client sends, in bytes. The
+// largest legitimate one, a chat message, is well under it.
+const DefaultMaxMessageSize int64 = 4096
+
+var maxMessageSize = DefaultMaxMessageSize
+
+// SetMaxMessageSize replaces the size limit of client messages. A websocket
+// sending more is closed with 1009 (message too big). Call it before the
+// server starts.
+func SetMaxMessageSize(size int64) {
+	maxMessageSize = size
+}
+
 const (
 	CloseCodeRoomExpired         = 4001
 	CloseCodeRoomFull            = 4002
 	CloseCodeInvalidRoom         = 4003
 	CloseCodePlayerNotFound      = 4004
 	CloseCodeDuplicateConnection = 4005
+	CloseCodeSessionMismatch     = 4006
+	CloseCodePlayerKicked        = 4007
+	CloseCodeAbuse               = 4008
 )
 
 const closeFrameWait = time.Second
@@ -79,6 +137,10 @@ func HandleWebSocket(
 
 	roomID := r.URL.Query().Get("room_id")
 	playerID := r.URL.Query().Get("player_id")
+	claims, authenticated := auth.ClaimsFromContext(r.Context())
+	if authenticated && playerID == "" {
+		playerID = claims.PlayerID
+	}
 	ctx, endSpan := observability.StartSpan(r.Context(), "websocket.connect")
 	var spanErr error
 	defer func() { endSpan(spanErr) }()
@@ -108,27 +170,29 @@ func HandleWebSocket(
 		closeWebsocketWithCode(conn, code, reason)
 		return
 	}
-
-	player, err := gameService.GetPlayerInRoomWithContext(ctx, roomID, playerID)
-	if err != nil {
-		spanErr = err
-		code, reason := websocketCloseForValidationError(err)
-		observability.Logger().WarnContext(ctx, "websocket session rejected",
+	if authenticated && claims.PlayerID != playerID {
+		observability.Logger().WarnContext(ctx, "websocket session mismatch",
 			"room_id", roomID,
 			"player_id", playerID,
-			"event_type", "websocket_session_rejected",
-			"close_code", code,
-			"close_reason", reason,
-			"error", err,
+			"event_type", "websocket_session_mismatch",
+			"session_player_id", claims.PlayerID,
 		)
-		closeWebsocketWithCode(conn, code, reason)
+		closeWebsocketWithCode(conn, CloseCodeSessionMismatch, "player does not match session")
 		return
 	}
 
-	generation := clients.NextGeneration(playerID)
-	wasConnected := clients.IsConnected(playerID)
-	wasDisconnected := player.Session == game.PlayerSessionDisconnected
-	if err := gameService.MarkPlayerConnectedWithContext(ctx, roomID, playerID); err != nil {
+	lastSeq, resume := resumeFromQuery(r)
+	if cluster := clients.Cluster(); cluster != nil {
+		if owner, remote := cluster.remoteOwner(ctx, roomID); remote {
+			cluster.serveRemoteWebSocket(ctx, conn, owner, roomID, playerID, lastSeq, resume)
+			return
+		}
+	}
+
+	client, player, eventType, err := connectClient(ctx, clients, gameService, roomID, playerID, lastSeq, resume, func(generation uint64) *Client {
+		return clients.AttachWithGeneration(roomID, playerID, generation, conn, websocketTimeouts.PongWait)
+	})
+	if err != nil {
 		spanErr = err
 		code, reason := websocketCloseForValidationError(err)
 		observability.Logger().WarnContext(ctx, "websocket session rejected",
@@ -143,18 +207,17 @@ func HandleWebSocket(
 		return
 	}
 
-	client := clients.AttachWithGeneration(playerID, generation, conn, pongWait)
-
-	go client.WritePump(writeWait, pingPeriod)
+	go client.WritePump(websocketTimeouts.WriteWait, websocketTimeouts.PingPeriod)
 
 	observability.Logger().InfoContext(ctx, "websocket connected",
 		"room_id", roomID,
 		"player_id", playerID,
 		"event_type", "websocket_connected",
 		"generation", client.Generation,
+		"connection_id", client.ConnectionID,
 	)
 
-	notifyRoomOnConnection(ctx, clients, gameService, roomID, player, connectionEventType(wasConnected, wasDisconnected))
+	notifyRoomOnConnection(ctx, clients, gameService, roomID, player, client, eventType, resume)
 
 	done := make(chan websocketReadResult, 1)
 
@@ -175,6 +238,41 @@ func HandleWebSocket(
 	handlePlayerDisconnection(ctx, clients, gameService, roomID, playerID, player, client)
 }
 
+// connectClient marks the player connected to a room held by this node and
+// attaches their connection, replaying what they missed when resume is set.
+// It returns the event announcing the connection to the room.
+func connectClient(
+	ctx context.Context,
+	clients *ClientRegistry,
+	gameService *service.GameService,
+	roomID string,
+	playerID string,
+	lastSeq uint64,
+	resume bool,
+	attach func(generation uint64) *Client,
+) (*Client, game.PlayerSnapshot, string, error) {
+	player, err := gameService.GetPlayerInRoomWithContext(ctx, roomID, playerID)
+	if err != nil {
+		return nil, player, "", err
+	}
+
+	generation := clients.NextGeneration(roomID, playerID)
+	wasConnected := clients.IsConnected(roomID, playerID)
+	wasDisconnected := player.Session == game.PlayerSessionDisconnected
+	if err := gameService.MarkPlayerConnectedWithContext(ctx, roomID, playerID); err != nil {
+		return nil, player, "", err
+	}
+	eventType := connectionEventType(wasConnected, wasDisconnected)
+
+	if !resume {
+		return attach(generation), player, eventType, nil
+	}
+	client := resumeClient(ctx, clients, gameService, roomID, lastSeq, func() *Client {
+		return attach(generation)
+	})
+	return client, player, eventType, nil
+}
+
 func closeWebsocketWithCode(conn *websocket.Conn, code int, reason string) {
 	if conn == nil {
 		return
@@ -211,9 +309,19 @@ func handlePlayerDisconnection(ctx context.Context, clients *ClientRegistry, gam
 	if !clients.RemoveClient(client) {
 		return
 	}
+	// Other tabs or devices keep the player connected to the room.
+	if clients.IsConnected(roomID, playerID) {
+		return
+	}
+	playerDisconnected(ctx, clients, gameService, roomID, playerID, player, clients.Generation(roomID, playerID))
+}
+
+// playerDisconnected marks the player's last connection to the room as gone
+// and schedules their removal unless a newer connection shows up in time.
+func playerDisconnected(ctx context.Context, clients *ClientRegistry, gameService *service.GameService, roomID string, playerID string, player game.PlayerSnapshot, generation uint64) {
 	_ = gameService.MarkPlayerDisconnectedWithContext(ctx, roomID, playerID)
 
-	NotifyToClientsInRoom(clients, gameService, roomID, EventPlayerReconnecting, EventPayload{
+	NotifyToClientsInRoom(ctx, clients, gameService, roomID, EventPlayerReconnecting, EventPayload{
 		Message:   fmt.Sprintf("Player %s disconnected temporarily", playerID),
 		Player:    playerEventDTO(player),
 		Timestamp: time.Now(),
@@ -224,10 +332,15 @@ func handlePlayerDisconnection(ctx context.Context, clients *ClientRegistry, gam
 		"player_id", playerID,
 		"event_type", "player_removal_scheduled",
 		"delay", 30*time.Second,
-		"generation", client.Generation,
+		"generation", generation,
 	)
-	gameService.RemovePlayerAfterDelayForGenerationWithCallback(roomID, playerID, client.Generation, 30*time.Second, clients.IsCurrentDisconnectedGeneration, func() {
-		NotifyToClientsInRoom(clients, gameService, roomID, EventPlayerLeft, EventPayload{
+	isCurrentDisconnectedGeneration := func(playerID string, generation uint64) bool {
+		return clients.IsCurrentDisconnectedGeneration(roomID, playerID, generation)
+	}
+	gameService.RemovePlayerAfterDelayForGenerationWithCallback(roomID, playerID, generation, 30*time.Second, isCurrentDisconnectedGeneration, func() {
+		ctx, endSpan := observability.StartRootSpan(ctx, "room.player_left", slog.String("room_id", roomID), slog.String("player_id", playerID))
+		defer endSpan(nil)
+		NotifyToClientsInRoom(ctx, clients, gameService, roomID, EventPlayerLeft, EventPayload{
 			Message:   fmt.Sprintf("Player %s left the room", playerID),
 			Player:    playerEventDTO(player),
 			Timestamp: time.Now(),
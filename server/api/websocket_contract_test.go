@@ -11,10 +11,13 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/tsaqiffatih/mini-game/api/dto"
 	"github.com/tsaqiffatih/mini-game/game"
 	"github.com/tsaqiffatih/mini-game/infrastructure"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
 	"github.com/tsaqiffatih/mini-game/service"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestNotifyToClientsInRoom_RoomUpdateUsesDirectSnapshotPayload(t *testing.T) {
@@ -30,9 +33,9 @@ func TestNotifyToClientsInRoom_RoomUpdateUsesDirectSnapshotPayload(t *testing.T)
 
 	client := newBufferedTestClient("p1")
 	clients := NewClientRegistry()
-	clients.clients["p1"] = client
+	addTestClient(clients, res.Room.RoomID, client)
 
-	NotifyToClientsInRoom(clients, gameService, res.Room.RoomID, EventRoomUpdate, dto.RoomDTO{
+	NotifyToClientsInRoom(context.Background(), clients, gameService, res.Room.RoomID, EventRoomUpdate, dto.RoomDTO{
 		ID:     res.Room.RoomID,
 		RoomID: res.Room.RoomID,
 	})
@@ -73,6 +76,176 @@ func TestFromRoomSnapshot_ChessCanonicalAndDeprecatedAliasShareState(t *testing.
 	}
 }
 
+func TestNotifyGameUpdateToClients_PremoveOnlyVisibleToOwner(t *testing.T) {
+	room, err := game.NewRoom("chess-room", "chess")
+	if err != nil {
+		t.Fatalf("NewRoom() error = %v", err)
+	}
+	addPlayerToContractRoom(t, room, "p1")
+	addPlayerToContractRoom(t, room, "p2")
+	if _, err := room.QueueChessPremove("p2", "e7", "e5", ""); err != nil {
+		t.Fatalf("QueueChessPremove() error = %v", err)
+	}
+
+	clients := NewClientRegistry()
+	p1Client := newBufferedTestClient("p1")
+	p2Client := newBufferedTestClient("p2")
+	addTestClient(clients, room.RoomID, p1Client)
+	addTestClient(clients, room.RoomID, p2Client)
+
+	NotifyGameUpdateToClients(context.Background(), clients, room.Snapshot())
+
+	var ownerView, opponentView dto.RoomSnapshotDTO
+	if err := json.Unmarshal(readTestEvent(t, p2Client).Payload, &ownerView); err != nil {
+		t.Fatalf("decode owner payload: %v", err)
+	}
+	if err := json.Unmarshal(readTestEvent(t, p1Client).Payload, &opponentView); err != nil {
+		t.Fatalf("decode opponent payload: %v", err)
+	}
+	if ownerView.Chess == nil || ownerView.Chess.Premove == nil || ownerView.Chess.Premove.To != "e5" {
+		t.Fatalf("owner premove = %+v, want e7e5", ownerView.Chess)
+	}
+	if opponentView.Chess == nil || opponentView.Chess.Premove != nil {
+		t.Fatalf("opponent premove = %+v, want hidden", opponentView.Chess)
+	}
+}
+
+func TestFromRoomSnapshotForPlayer_UndoCanRequestIsPerPlayer(t *testing.T) {
+	room, err := game.NewRoom("chess-room", "chess")
+	if err != nil {
+		t.Fatalf("NewRoom() error = %v", err)
+	}
+	addPlayerToContractRoom(t, room, "p1")
+	addPlayerToContractRoom(t, room, "p2")
+	if _, err := room.HandleChessMove("p1", "e2", "e4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+
+	snapshot := room.Snapshot()
+	if view := dto.FromRoomSnapshotForPlayer(snapshot, "p1"); !view.Chess.Undo.CanRequest {
+		t.Fatalf("p1 can_request = false, want true after moving")
+	}
+	if view := dto.FromRoomSnapshotForPlayer(snapshot, "p2"); view.Chess.Undo.CanRequest {
+		t.Fatalf("p2 can_request = true, want false with no move to take back")
+	}
+}
+
+func TestNotifyToClientsInRoom_EmbeddedRoomIsPerPlayer(t *testing.T) {
+	gameService := service.NewGameService(infrastructure.NewMemoryRoomRepository(), game.NewPlayerManager())
+	for _, playerID := range []string{"p1", "p2"} {
+		if _, err := gameService.AddPlayer(playerID); err != nil {
+			t.Fatalf("AddPlayer(%s) error = %v", playerID, err)
+		}
+	}
+	res, err := gameService.CreateRoomWithContext(context.Background(), "chess", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	roomID := res.Room.RoomID
+	if _, err := gameService.JoinRoomWithContext(context.Background(), roomID, "p2", "chess"); err != nil {
+		t.Fatalf("JoinRoomWithContext() error = %v", err)
+	}
+	if _, err := gameService.HandleChessMove(roomID, "p1", "e2", "e4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+
+	clients := NewClientRegistry()
+	p1Client := newBufferedTestClient("p1")
+	p2Client := newBufferedTestClient("p2")
+	addTestClient(clients, roomID, p1Client)
+	addTestClient(clients, roomID, p2Client)
+
+	NotifyToClientsInRoom(context.Background(), clients, gameService, roomID, EventPlayerReconnecting, EventPayload{Message: "p2 is reconnecting"})
+
+	p1Event, p2Event := readTestEvent(t, p1Client), readTestEvent(t, p2Client)
+	if p1Event.Seq != p2Event.Seq {
+		t.Fatalf("seq = %d and %d, want one seq for the event", p1Event.Seq, p2Event.Seq)
+	}
+	var p1Payload, p2Payload RoomEventPayload
+	if err := json.Unmarshal(p1Event.Payload, &p1Payload); err != nil {
+		t.Fatalf("decode p1 payload: %v", err)
+	}
+	if err := json.Unmarshal(p2Event.Payload, &p2Payload); err != nil {
+		t.Fatalf("decode p2 payload: %v", err)
+	}
+	if !p1Payload.Room.Chess.Undo.CanRequest {
+		t.Fatalf("p1 can_request = false, want true after moving")
+	}
+	if p2Payload.Room.Chess.Undo.CanRequest {
+		t.Fatalf("p2 can_request = true, want false with no move to take back")
+	}
+	if !strings.Contains(string(p2Payload.Data), "p2 is reconnecting") {
+		t.Fatalf("p2 data = %s, want the event payload", p2Payload.Data)
+	}
+}
+
+func TestProcessChessPremove_OnlyOwnerHearsOfIt(t *testing.T) {
+	gameService := service.NewGameService(infrastructure.NewMemoryRoomRepository(), game.NewPlayerManager())
+	for _, playerID := range []string{"p1", "p2"} {
+		if _, err := gameService.AddPlayer(playerID); err != nil {
+			t.Fatalf("AddPlayer(%s) error = %v", playerID, err)
+		}
+	}
+	res, err := gameService.CreateRoomWithContext(context.Background(), "chess", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	roomID := res.Room.RoomID
+	if _, err := gameService.JoinRoomWithContext(context.Background(), roomID, "p2", "chess"); err != nil {
+		t.Fatalf("JoinRoomWithContext() error = %v", err)
+	}
+	before, err := gameService.RoomSnapshot(roomID)
+	if err != nil {
+		t.Fatalf("RoomSnapshot() error = %v", err)
+	}
+
+	clients := NewClientRegistry()
+	p1Client := newBufferedTestClient("p1")
+	p2Client := newBufferedTestClient("p2")
+	addTestClient(clients, roomID, p1Client)
+	addTestClient(clients, roomID, p2Client)
+	p2 := game.PlayerSnapshot{ID: "p2", Mark: "black"}
+
+	if err := processChessPremove(context.Background(), p2, p2Client, clients, gameService, roomID, WebSocketMessage{
+		Type:    "CHESS_PREMOVE",
+		Payload: json.RawMessage(`{"from":"e7","to":"e5"}`),
+	}); err != nil {
+		t.Fatalf("processChessPremove() error = %v", err)
+	}
+
+	var ownerView dto.RoomSnapshotDTO
+	if err := json.Unmarshal(readTestEvent(t, p2Client).Payload, &ownerView); err != nil {
+		t.Fatalf("decode owner payload: %v", err)
+	}
+	if ownerView.Chess == nil || ownerView.Chess.Premove == nil || ownerView.Chess.Premove.To != "e5" {
+		t.Fatalf("owner premove = %+v, want e7e5", ownerView.Chess)
+	}
+	if ownerView.StateVersion != before.StateVersion {
+		t.Fatalf("owner state version = %d, want unchanged %d", ownerView.StateVersion, before.StateVersion)
+	}
+	select {
+	case message := <-p1Client.Send:
+		t.Fatalf("opponent received %s for a queued premove", message)
+	default:
+	}
+
+	if err := processChessPremoveCancel(context.Background(), p2, p2Client, clients, gameService, roomID, WebSocketMessage{}); err != nil {
+		t.Fatalf("processChessPremoveCancel() error = %v", err)
+	}
+	var cancelledView dto.RoomSnapshotDTO
+	if err := json.Unmarshal(readTestEvent(t, p2Client).Payload, &cancelledView); err != nil {
+		t.Fatalf("decode owner payload: %v", err)
+	}
+	if cancelledView.Chess == nil || cancelledView.Chess.Premove != nil {
+		t.Fatalf("owner premove after cancel = %+v, want none", cancelledView.Chess)
+	}
+	select {
+	case message := <-p1Client.Send:
+		t.Fatalf("opponent received %s for a cancelled premove", message)
+	default:
+	}
+}
+
 func TestProcessChessMove_InvalidPayloadSendsMoveRejected(t *testing.T) {
 	client := newBufferedTestClient("p1")
 
@@ -128,7 +301,7 @@ func TestHandleWebSocket_PlayerNotFound_UpgradesThenClosesPlayerNotFound(t *test
 	assertWebSocketCloseCode(t, conn, CloseCodePlayerNotFound)
 }
 
-func TestHandleWebSocket_DuplicateConnection_ReplacesExistingConnection(t *testing.T) {
+func TestHandleWebSocket_SecondConnectionKeepsFirstOpen(t *testing.T) {
 	gameService := service.NewGameService(infrastructure.NewMemoryRoomRepository(), game.NewPlayerManager())
 	if _, err := gameService.AddPlayer("p1"); err != nil {
 		t.Fatalf("AddPlayer() error = %v", err)
@@ -137,19 +310,92 @@ func TestHandleWebSocket_DuplicateConnection_ReplacesExistingConnection(t *testi
 	if err != nil {
 		t.Fatalf("CreateRoomWithContext() error = %v", err)
 	}
+	roomID := res.Room.RoomID
 	clients := NewClientRegistry()
 	server := newWebSocketTestServer(t, clients, gameService)
-	query := "room_id=" + res.Room.RoomID + "&player_id=p1"
+	query := "room_id=" + roomID + "&player_id=p1"
 
 	firstConn := dialTestWebSocket(t, server, query)
 	defer firstConn.Close()
-
+	readTestWebSocketEvent(t, firstConn, EventRoomUpdate)
 	secondConn := dialTestWebSocket(t, server, query)
 	defer secondConn.Close()
+	readTestWebSocketEvent(t, secondConn, EventRoomUpdate)
+
+	if got := len(clients.RoomClients(roomID, "p1")); got != 2 {
+		t.Fatalf("connections = %d, want 2", got)
+	}
+	NotifyToClientsInRoom(context.Background(), clients, gameService, roomID, EventRoomUpdate, nil)
+	readTestWebSocketEvent(t, firstConn, EventRoomUpdate)
+	readTestWebSocketEvent(t, secondConn, EventRoomUpdate)
+}
+
+func TestClientRegistry_ConnectionsArePerRoom(t *testing.T) {
+	clients := NewClientRegistry()
+	for generation := uint64(1); generation <= maxConnectionsPerRoom; generation++ {
+		client := newBufferedTestClient("p1")
+		client.Generation = generation
+		addTestClient(clients, "room-a", client)
+	}
+	other := newBufferedTestClient("p1")
+	other.Generation = clients.NextGeneration("room-b", "p1")
+	addTestClient(clients, "room-b", other)
+
+	newest := newBufferedTestClient("p1")
+	newest.Generation = clients.NextGeneration("room-a", "p1")
+	displaced := addTestClient(clients, "room-a", newest)
+	if displaced == nil || displaced.Generation != 1 {
+		t.Fatalf("displaced = %+v, want the generation 1 connection", displaced)
+	}
+	if got := len(clients.RoomClients("room-a", "p1")); got != maxConnectionsPerRoom {
+		t.Fatalf("room-a connections = %d, want %d", got, maxConnectionsPerRoom)
+	}
+	if got := len(clients.PlayerClients("p1")); got != maxConnectionsPerRoom+1 {
+		t.Fatalf("player connections = %d, want %d", got, maxConnectionsPerRoom+1)
+	}
 
-	assertWebSocketCloseCode(t, firstConn, CloseCodeDuplicateConnection)
-	if !clients.IsConnected("p1") {
-		t.Fatalf("new duplicate connection should remain active")
+	clients.RemoveClient(other)
+	if !clients.IsCurrentDisconnectedGeneration("room-b", "p1", other.Generation) {
+		t.Fatalf("room-b generation %d should be the current disconnected one", other.Generation)
+	}
+	if clients.IsCurrentDisconnectedGeneration("room-a", "p1", clients.Generation("room-a", "p1")) {
+		t.Fatalf("room-a is still connected")
+	}
+}
+
+func TestHandlePlayerDisconnection_OtherTabKeepsPlayerConnected(t *testing.T) {
+	gameService := service.NewGameService(infrastructure.NewMemoryRoomRepository(), game.NewPlayerManager())
+	if _, err := gameService.AddPlayer("p1"); err != nil {
+		t.Fatalf("AddPlayer(p1) error = %v", err)
+	}
+	if _, err := gameService.AddPlayer("p2"); err != nil {
+		t.Fatalf("AddPlayer(p2) error = %v", err)
+	}
+	res, err := gameService.CreateRoomWithContext(context.Background(), "tictactoe", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	if _, err := gameService.JoinRoomWithContext(context.Background(), res.Room.RoomID, "p2", "tictactoe"); err != nil {
+		t.Fatalf("JoinRoomWithContext() error = %v", err)
+	}
+
+	clients := NewClientRegistry()
+	p1Client := newBufferedTestClient("p1")
+	firstTab := newBufferedTestClient("p2")
+	secondTab := newBufferedTestClient("p2")
+	addTestClient(clients, res.Room.RoomID, p1Client)
+	addTestClient(clients, res.Room.RoomID, firstTab)
+	addTestClient(clients, res.Room.RoomID, secondTab)
+
+	handlePlayerDisconnection(context.Background(), clients, gameService, res.Room.RoomID, "p2", game.PlayerSnapshot{ID: "p2"}, firstTab)
+
+	select {
+	case message := <-p1Client.Send:
+		t.Fatalf("p1 received %s while p2 still has a tab open", message)
+	default:
+	}
+	if !clients.IsConnected(res.Room.RoomID, "p2") {
+		t.Fatalf("p2 should stay connected through the second tab")
 	}
 }
 
@@ -173,9 +419,8 @@ func TestHandlePlayerDisconnection_BroadcastsReconnectingNotLeft(t *testing.T) {
 	p1Client := newBufferedTestClient("p1")
 	p2Client := newBufferedTestClient("p2")
 	p2Client.Generation = 1
-	clients.clients["p1"] = p1Client
-	clients.clients["p2"] = p2Client
-	clients.generations["p2"] = 1
+	addTestClient(clients, res.Room.RoomID, p1Client)
+	addTestClient(clients, res.Room.RoomID, p2Client)
 
 	handlePlayerDisconnection(
 		context.Background(),
@@ -222,6 +467,14 @@ func newBufferedTestClient(playerID string) *Client {
 	}
 }
 
+func addTestClient(clients *ClientRegistry, roomID string, client *Client) *Client {
+	client.RoomID = roomID
+	if client.ConnectionID == "" {
+		client.ConnectionID = newConnectionID()
+	}
+	return clients.add(client)
+}
+
 func newWebSocketTestServer(t *testing.T, clients *ClientRegistry, gameService *service.GameService) *httptest.Server {
 	t.Helper()
 
@@ -276,6 +529,23 @@ func assertWebSocketCloseCode(t *testing.T, conn *websocket.Conn, expectedCode i
 	}
 }
 
+func readTestWebSocketEvent(t *testing.T, conn *websocket.Conn, eventType string) Event {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline() error = %v", err)
+	}
+	for {
+		var event Event
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("ReadJSON() error = %v, want %s", err, eventType)
+		}
+		if event.Type == eventType {
+			return event
+		}
+	}
+}
+
 func readTestEvent(t *testing.T, client *Client) Event {
 	t.Helper()
 
@@ -307,3 +577,37 @@ func addPlayerToContractRoom(t *testing.T, room *game.Room, playerID string) {
 func roomTestTime() time.Time {
 	return time.Now().UTC()
 }
+
+func TestSendEvent_CountsSentMessagesAndDrops(t *testing.T) {
+	sent := testutil.ToFloat64(observability.WebSocketMessagesSent.WithLabelValues(EventChatMessage))
+	drops := testutil.ToFloat64(observability.WebSocketEnqueueDrops)
+
+	client := newBufferedTestClient("p1")
+	sendEvent(context.Background(), client, EventChatMessage, ErrorMessage{Message: "hello"})
+	sendEvent(context.Background(), client, EventChatMessage, ErrorMessage{Message: "queue is full"})
+
+	if got := testutil.ToFloat64(observability.WebSocketMessagesSent.WithLabelValues(EventChatMessage)) - sent; got != 1 {
+		t.Fatalf("sent messages = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(observability.WebSocketEnqueueDrops) - drops; got != 1 {
+		t.Fatalf("enqueue drops = %v, want 1", got)
+	}
+}
+
+func TestSendEvent_IncludesTraceID(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	client := newBufferedTestClient("p1")
+	sendErrorMessage(ctx, client, "Unsupported message type")
+
+	event := readTestEvent(t, client)
+	if event.TraceID != traceID.String() {
+		t.Fatalf("trace_id = %q, want %q", event.TraceID, traceID.String())
+	}
+}
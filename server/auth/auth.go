@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the shortest password Register accepts.
+const MinPasswordLength = 8
+
+var (
+	ErrInvalidToken    = errors.New("Invalid session token")
+	ErrTokenExpired    = errors.New("Session token expired")
+	ErrInvalidPassword = errors.New("Invalid username or password")
+	ErrPasswordTooWeak = errors.New("Password must be at least 8 characters")
+	ErrSessionEnded    = errors.New("Session has ended, sign in again")
+)
+
+// Account is a registered player. Guests have no account; their identity
+// only lives in the session token.
+type Account struct {
+	ID           string
+	PasswordHash []byte
+	CreatedAt    time.Time
+}
+
+// Claims identify the player behind a session token. Guest tokens carry the
+// nonce of the player record they were issued for.
+type Claims struct {
+	PlayerID  string `json:"sub"`
+	Guest     bool   `json:"guest,omitempty"`
+	Nonce     string `json:"nonce,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func HashPassword(password string) ([]byte, error) {
+	if len(password) < MinPasswordLength {
+		return nil, ErrPasswordTooWeak
+	}
+	return HashSecret(password)
+}
+
+// HashSecret hashes a shared secret, such as a room password, that is not
+// held to the account password rules.
+func HashSecret(secret string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+}
+
+func CheckPassword(hash []byte, password string) error {
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
+type claimsContextKey struct{}
+
+// WithClaims returns a copy of ctx carrying the authenticated claims.
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of an authenticated request.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(Claims)
+	return claims, ok
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// DefaultTokenTTL is how long a session token stays valid.
+const DefaultTokenTTL = 24 * time.Hour
+
+// tokenHeader is the fixed JOSE header of every token; only HS256 is issued
+// or accepted.
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// TokenSigner issues and verifies HS256 JSON Web Tokens.
+type TokenSigner struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewTokenSigner(secret []byte, ttl time.Duration) *TokenSigner {
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	return &TokenSigner{
+		secret: secret,
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+// RandomSecret returns a fresh signing secret. Tokens signed with it do not
+// survive a restart.
+func RandomSecret() ([]byte, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// RandomNonce returns a fresh nonce binding guest tokens to a player record.
+func RandomNonce() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(nonce), nil
+}
+
+func (s *TokenSigner) Issue(playerID string, guest bool, nonce string) (string, Claims, error) {
+	now := s.now()
+	claims := Claims{
+		PlayerID:  playerID,
+		Guest:     guest,
+		Nonce:     nonce,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.ttl).Unix(),
+	}
+
+	token, err := s.encode(tokenHeader, claims)
+	if err != nil {
+		return "", Claims{}, err
+	}
+	return token, claims, nil
+}
+
+func (s *TokenSigner) Verify(token string) (Claims, error) {
+	var claims Claims
+	if !s.decode(tokenHeader, token, &claims) || claims.PlayerID == "" {
+		return Claims{}, ErrInvalidToken
+	}
+	if s.now().Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrTokenExpired
+	}
+	return claims, nil
+}
+
+// encode signs claims under the given header.
+func (s *TokenSigner) encode(header string, claims interface{}) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + s.sign(unsigned), nil
+}
+
+// decode checks that token was signed here under the given header and reads
+// its claims, reporting false for anything else.
+func (s *TokenSigner) decode(header string, token string, claims interface{}) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != header {
+		return false
+	}
+	unsigned := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(s.sign(unsigned))) {
+		return false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(payload, claims) == nil
+}
+
+func (s *TokenSigner) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenSigner_IssueAndVerify(t *testing.T) {
+	signer := NewTokenSigner([]byte("secret"), time.Hour)
+
+	token, issued, err := signer.Issue("p1", true, "n1")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	claims, err := signer.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if claims != issued || claims.PlayerID != "p1" || !claims.Guest {
+		t.Fatalf("claims = %+v, want %+v", claims, issued)
+	}
+}
+
+func TestTokenSigner_RejectsTamperedAndExpiredTokens(t *testing.T) {
+	signer := NewTokenSigner([]byte("secret"), time.Hour)
+	token, _, err := signer.Issue("p1", false, "")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	forged, _, _ := NewTokenSigner([]byte("other"), time.Hour).Issue("p2", false, "")
+	parts := strings.Split(token, ".")
+	forgedParts := strings.Split(forged, ".")
+	for _, candidate := range []string{
+		forged,
+		parts[0] + "." + forgedParts[1] + "." + parts[2],
+		"not-a-token",
+	} {
+		if _, err := signer.Verify(candidate); err != ErrInvalidToken {
+			t.Fatalf("Verify(%q) error = %v, want %v", candidate, err, ErrInvalidToken)
+		}
+	}
+
+	signer.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := signer.Verify(token); err != ErrTokenExpired {
+		t.Fatalf("Verify(expired) error = %v, want %v", err, ErrTokenExpired)
+	}
+}
+
+func TestTokenSigner_InvitesAreNotSessionTokens(t *testing.T) {
+	signer := NewTokenSigner([]byte("secret"), time.Hour)
+
+	invite, issued, err := signer.IssueInvite("room-1", time.Hour)
+	if err != nil {
+		t.Fatalf("IssueInvite() error = %v", err)
+	}
+	claims, err := signer.VerifyInvite(invite)
+	if err != nil {
+		t.Fatalf("VerifyInvite() error = %v", err)
+	}
+	if claims != issued || claims.RoomID != "room-1" || claims.Nonce == "" {
+		t.Fatalf("claims = %+v, want %+v", claims, issued)
+	}
+	if _, err := signer.Verify(invite); err != ErrInvalidToken {
+		t.Fatalf("Verify(invite) error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	session, _, _ := signer.Issue("p1", false, "")
+	if _, err := signer.VerifyInvite(session); err != ErrInvalidInvite {
+		t.Fatalf("VerifyInvite(session token) error = %v, want %v", err, ErrInvalidInvite)
+	}
+
+	signer.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := signer.VerifyInvite(invite); err != ErrInviteExpired {
+		t.Fatalf("VerifyInvite(expired) error = %v, want %v", err, ErrInviteExpired)
+	}
+}
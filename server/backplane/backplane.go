@@ -0,0 +1,206 @@
+// Package backplane connects the replicas of the server. It carries messages
+// between nodes over named channels and keeps the leases that decide which
+// node owns a room.
+package backplane
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrClosed = errors.New("backplane closed")
+
+// Handler receives one published message. Handlers of one subscription run
+// in publish order and must not block for long.
+type Handler func(message []byte)
+
+type Backplane interface {
+	// Publish sends message to every subscriber of channel, on every node.
+	Publish(ctx context.Context, channel string, message []byte) error
+	// Subscribe calls handler for each message published to channel until
+	// the returned function is called.
+	Subscribe(ctx context.Context, channel string, handler Handler) (func(), error)
+	Leases
+	Close() error
+}
+
+// Leases hands out named, expiring ownership records.
+type Leases interface {
+	// AcquireLease takes key for owner, or extends it when owner already
+	// holds it. It reports false when another owner holds a live lease.
+	AcquireLease(ctx context.Context, key string, owner string, ttl time.Duration) (bool, error)
+	// ReleaseLease drops key if owner holds it.
+	ReleaseLease(ctx context.Context, key string, owner string) error
+	// LeaseOwner returns the holder of key, or "" when the lease is free.
+	LeaseOwner(ctx context.Context, key string) (string, error)
+}
+
+// RoomLease is the lease key of the node owning roomID.
+func RoomLease(roomID string) string {
+	return "room/" + roomID
+}
+
+// NodeChannel is the channel a node reads commands addressed to it from.
+func NodeChannel(nodeID string) string {
+	return "node/" + nodeID
+}
+
+type lease struct {
+	owner     string
+	expiresAt time.Time
+}
+
+type subscription struct {
+	messages chan []byte
+	done     chan struct{}
+	close    sync.Once
+}
+
+func (s *subscription) stop() {
+	s.close.Do(func() { close(s.done) })
+}
+
+// Memory is a Backplane for nodes sharing one process. It serves single-node
+// deployments and tests that run several nodes side by side.
+type Memory struct {
+	subscribers map[string]map[*subscription]struct{}
+	leases      map[string]lease
+	closed      bool
+	now         func() time.Time
+	mu          sync.Mutex
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		subscribers: make(map[string]map[*subscription]struct{}),
+		leases:      make(map[string]lease),
+		now:         time.Now,
+	}
+}
+
+func (m *Memory) Publish(ctx context.Context, channel string, message []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return ErrClosed
+	}
+	subs := make([]*subscription, 0, len(m.subscribers[channel]))
+	for sub := range m.subscribers[channel] {
+		subs = append(subs, sub)
+	}
+	m.mu.Unlock()
+
+	for _, sub := range subs {
+		select {
+		case sub.messages <- append([]byte(nil), message...):
+		case <-sub.done:
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(ctx context.Context, channel string, handler Handler) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sub := &subscription{
+		messages: make(chan []byte, 256),
+		done:     make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if m.subscribers[channel] == nil {
+		m.subscribers[channel] = make(map[*subscription]struct{})
+	}
+	m.subscribers[channel][sub] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-sub.done:
+				return
+			case message := <-sub.messages:
+				handler(message)
+			}
+		}
+	}()
+
+	return func() {
+		m.mu.Lock()
+		delete(m.subscribers[channel], sub)
+		m.mu.Unlock()
+		sub.stop()
+	}, nil
+}
+
+func (m *Memory) AcquireLease(ctx context.Context, key string, owner string, ttl time.Duration) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if current, held := m.leases[key]; held && current.owner != owner && now.Before(current.expiresAt) {
+		return false, nil
+	}
+	m.leases[key] = lease{owner: owner, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (m *Memory) ReleaseLease(ctx context.Context, key string, owner string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if current, held := m.leases[key]; held && current.owner == owner {
+		delete(m.leases, key)
+	}
+	return nil
+}
+
+func (m *Memory) LeaseOwner(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, held := m.leases[key]
+	if !held || !m.now().Before(current.expiresAt) {
+		return "", nil
+	}
+	return current.owner, nil
+}
+
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	subscribers := m.subscribers
+	m.subscribers = make(map[string]map[*subscription]struct{})
+	m.closed = true
+	m.mu.Unlock()
+
+	for _, subs := range subscribers {
+		for sub := range subs {
+			sub.stop()
+		}
+	}
+	return nil
+}
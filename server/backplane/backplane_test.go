@@ -0,0 +1,142 @@
+package backplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMemory(t *testing.T) {
+	testBackplane(t, func(t *testing.T) (Backplane, func(time.Duration)) {
+		memory := NewMemory()
+		now := time.Now()
+		memory.now = func() time.Time { return now }
+		return memory, func(d time.Duration) {
+			memory.mu.Lock()
+			now = now.Add(d)
+			memory.mu.Unlock()
+		}
+	})
+}
+
+func TestRedis(t *testing.T) {
+	testBackplane(t, func(t *testing.T) (Backplane, func(time.Duration)) {
+		server := miniredis.RunT(t)
+		return NewRedis(redis.NewClient(&redis.Options{Addr: server.Addr()}), "test:"), server.FastForward
+	})
+}
+
+func testBackplane(t *testing.T, newBackplane func(*testing.T) (Backplane, func(time.Duration))) {
+	ctx := context.Background()
+
+	t.Run("publish reaches every subscriber of the channel", func(t *testing.T) {
+		bp, _ := newBackplane(t)
+		defer bp.Close()
+
+		first := make(chan string, 1)
+		second := make(chan string, 1)
+		other := make(chan string, 1)
+		for channel, received := range map[string]chan string{"events": first, "node/a": other} {
+			received := received
+			if _, err := bp.Subscribe(ctx, channel, func(message []byte) { received <- string(message) }); err != nil {
+				t.Fatalf("Subscribe(%s) error = %v", channel, err)
+			}
+		}
+		if _, err := bp.Subscribe(ctx, "events", func(message []byte) { second <- string(message) }); err != nil {
+			t.Fatalf("Subscribe(events) error = %v", err)
+		}
+
+		if err := bp.Publish(ctx, "events", []byte("hello")); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+
+		for _, received := range []chan string{first, second} {
+			select {
+			case message := <-received:
+				if message != "hello" {
+					t.Fatalf("message = %q, want hello", message)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("subscriber did not receive the message")
+			}
+		}
+		select {
+		case message := <-other:
+			t.Fatalf("other channel received %q", message)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("unsubscribe stops delivery", func(t *testing.T) {
+		bp, _ := newBackplane(t)
+		defer bp.Close()
+
+		received := make(chan string, 1)
+		unsubscribe, err := bp.Subscribe(ctx, "events", func(message []byte) { received <- string(message) })
+		if err != nil {
+			t.Fatalf("Subscribe() error = %v", err)
+		}
+		unsubscribe()
+
+		if err := bp.Publish(ctx, "events", []byte("late")); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+		select {
+		case message := <-received:
+			t.Fatalf("received %q after unsubscribe", message)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("a lease has one owner until it expires", func(t *testing.T) {
+		bp, advance := newBackplane(t)
+		defer bp.Close()
+
+		key := RoomLease("room-1")
+		if acquired, err := bp.AcquireLease(ctx, key, "node-a", time.Second); err != nil || !acquired {
+			t.Fatalf("AcquireLease(node-a) = %v, %v, want true", acquired, err)
+		}
+		if acquired, err := bp.AcquireLease(ctx, key, "node-b", time.Second); err != nil || acquired {
+			t.Fatalf("AcquireLease(node-b) = %v, %v, want false", acquired, err)
+		}
+		if acquired, err := bp.AcquireLease(ctx, key, "node-a", time.Second); err != nil || !acquired {
+			t.Fatalf("renewing AcquireLease(node-a) = %v, %v, want true", acquired, err)
+		}
+		if owner, err := bp.LeaseOwner(ctx, key); err != nil || owner != "node-a" {
+			t.Fatalf("LeaseOwner() = %q, %v, want node-a", owner, err)
+		}
+
+		advance(2 * time.Second)
+		if owner, err := bp.LeaseOwner(ctx, key); err != nil || owner != "" {
+			t.Fatalf("LeaseOwner() after expiry = %q, %v, want none", owner, err)
+		}
+		if acquired, err := bp.AcquireLease(ctx, key, "node-b", time.Second); err != nil || !acquired {
+			t.Fatalf("AcquireLease(node-b) after expiry = %v, %v, want true", acquired, err)
+		}
+	})
+
+	t.Run("only the owner releases a lease", func(t *testing.T) {
+		bp, _ := newBackplane(t)
+		defer bp.Close()
+
+		key := RoomLease("room-1")
+		if _, err := bp.AcquireLease(ctx, key, "node-a", time.Minute); err != nil {
+			t.Fatalf("AcquireLease() error = %v", err)
+		}
+		if err := bp.ReleaseLease(ctx, key, "node-b"); err != nil {
+			t.Fatalf("ReleaseLease(node-b) error = %v", err)
+		}
+		if owner, _ := bp.LeaseOwner(ctx, key); owner != "node-a" {
+			t.Fatalf("LeaseOwner() = %q, want node-a", owner)
+		}
+		if err := bp.ReleaseLease(ctx, key, "node-a"); err != nil {
+			t.Fatalf("ReleaseLease(node-a) error = %v", err)
+		}
+		if owner, _ := bp.LeaseOwner(ctx, key); owner != "" {
+			t.Fatalf("LeaseOwner() after release = %q, want none", owner)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package backplane
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// acquireLeaseScript sets the lease when it is free and extends it when the
+// caller already holds it, in one round trip.
+var acquireLeaseScript = redis.NewScript(`
+local owner = redis.call("GET", KEYS[1])
+if owner == false then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+if owner == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Redis is a Backplane on a Redis server: channels are Redis pub/sub
+// channels and leases are keys with an expiry. Every key and channel is
+// prefixed so several deployments can share one server.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedis(client *redis.Client, prefix string) *Redis {
+	return &Redis{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// DialRedis connects to the server at url, for example
+// redis://localhost:6379/0, and checks that it answers.
+func DialRedis(ctx context.Context, url string, prefix string) (*Redis, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(options)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return NewRedis(client, prefix), nil
+}
+
+func (r *Redis) Publish(ctx context.Context, channel string, message []byte) error {
+	return r.client.Publish(ctx, r.prefix+channel, message).Err()
+}
+
+func (r *Redis) Subscribe(ctx context.Context, channel string, handler Handler) (func(), error) {
+	pubsub := r.client.Subscribe(ctx, r.prefix+channel)
+	// Waiting for the confirmation means no message published after
+	// Subscribe returns can be missed.
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	go func() {
+		for message := range pubsub.Channel() {
+			handler([]byte(message.Payload))
+		}
+	}()
+
+	return func() { _ = pubsub.Close() }, nil
+}
+
+func (r *Redis) AcquireLease(ctx context.Context, key string, owner string, ttl time.Duration) (bool, error) {
+	acquired, err := acquireLeaseScript.Run(ctx, r.client, []string{r.prefix + key}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return acquired == 1, nil
+}
+
+func (r *Redis) ReleaseLease(ctx context.Context, key string, owner string) error {
+	return releaseLeaseScript.Run(ctx, r.client, []string{r.prefix + key}, owner).Err()
+}
+
+func (r *Redis) LeaseOwner(ctx context.Context, key string) (string, error) {
+	owner, err := r.client.Get(ctx, r.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return owner, err
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
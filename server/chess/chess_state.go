@@ -222,6 +222,17 @@ func (cs *ChessGameState) LastUndoablePly() int {
 	return len(cs.history)
 }
 
+// LastPlyBy returns the ply of the latest move made by playerID, or 0 when
+// the player has not moved yet.
+func (cs *ChessGameState) LastPlyBy(playerID string) int {
+	for i := len(cs.history) - 1; i >= 0; i-- {
+		if cs.history[i].Move.Actor.PlayerID == playerID {
+			return cs.history[i].Ply
+		}
+	}
+	return 0
+}
+
 func (cs *ChessGameState) UpdateState(
 	playerID string,
 	playerMark string,
@@ -330,6 +341,32 @@ func (cs *ChessGameState) RollbackToPly(targetPly int) error {
 	return nil
 }
 
+// ValidatePremove performs the checks that can be made before the opponent
+// has moved: both squares must exist and the from-square must currently hold
+// one of the premoving player's pieces. Full legality is only known once the
+// premove is played.
+func (cs *ChessGameState) ValidatePremove(playerMark string, from string, to string, promo string) error {
+	if !cs.isActive {
+		return fmt.Errorf("game not active")
+	}
+	fromSquare, ok := parseSquare(from)
+	if !ok {
+		return fmt.Errorf("invalid premove square")
+	}
+	if _, ok := parseSquare(to); !ok || from == to {
+		return fmt.Errorf("invalid premove square")
+	}
+	if promo != "" && !strings.Contains("qrbn", strings.ToLower(promo)) {
+		return fmt.Errorf("invalid premove promotion")
+	}
+
+	piece := cs.game.Position().Board().Piece(fromSquare)
+	if piece == notnil.NoPiece || colorName(piece.Color()) != playerMark {
+		return fmt.Errorf("invalid premove piece")
+	}
+	return nil
+}
+
 func (cs *ChessGameState) findLegalMove(from, to, promo string) (*notnil.Move, error) {
 	base := from + to
 	needsPromotion := false
@@ -504,6 +541,18 @@ func colorName(color notnil.Color) string {
 	}
 }
 
+func parseSquare(name string) (notnil.Square, bool) {
+	if len(name) != 2 {
+		return notnil.NoSquare, false
+	}
+	file := name[0]
+	rank := name[1]
+	if file < 'a' || file > 'h' || rank < '1' || rank > '8' {
+		return notnil.NoSquare, false
+	}
+	return notnil.NewSquare(notnil.File(file-'a'), notnil.Rank(rank-'1')), true
+}
+
 func kingSquare(pos *notnil.Position, color notnil.Color) string {
 	for square, piece := range pos.Board().SquareMap() {
 		if piece.Type() == notnil.King && piece.Color() == color {
@@ -564,3 +613,43 @@ func moveSound(flags MoveFlags, result *GameResult) string {
 	}
 	return "move"
 }
+
+// RecordedMove is a played move as ReplayChessGame needs it.
+type RecordedMove struct {
+	Actor     MoveActor `json:"actor"`
+	UCI       string    `json:"uci"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RecordedMoves returns the moves played so far, in order.
+func (cs *ChessGameState) RecordedMoves() []RecordedMove {
+	moves := make([]RecordedMove, 0, len(cs.history))
+	for _, entry := range cs.history {
+		moves = append(moves, RecordedMove{
+			Actor:     entry.Move.Actor,
+			UCI:       entry.UCI,
+			CreatedAt: entry.Move.CreatedAt,
+		})
+	}
+	return moves
+}
+
+// ReplayChessGame plays moves from the starting position, rebuilding the
+// history, captures and result they led to.
+func ReplayChessGame(moves []RecordedMove) (*ChessGameState, error) {
+	cs := NewChessGameState()
+	for i, move := range moves {
+		if len(move.UCI) < 4 {
+			return nil, fmt.Errorf("move %d: invalid uci %q", i+1, move.UCI)
+		}
+		_, err := cs.UpdateState(move.Actor.PlayerID, move.Actor.Color, move.Actor.IsAI, move.UCI[0:2], move.UCI[2:4], move.UCI[4:])
+		if err != nil {
+			return nil, fmt.Errorf("move %d %s: %w", i+1, move.UCI, err)
+		}
+		if !move.CreatedAt.IsZero() {
+			cs.history[i].Move.CreatedAt = move.CreatedAt
+			cs.lastMove.CreatedAt = move.CreatedAt
+		}
+	}
+	return cs, nil
+}
@@ -0,0 +1,509 @@
+// Package config holds the settings a server runs with. They start from
+// defaults and are overridden, in order, by a YAML file, environment
+// variables and command line flags, so one build runs in every environment.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/game"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable holding the path of the config
+// file when no -config flag is given.
+const FileEnv = "CONFIG_FILE"
+
+// redacted replaces secrets in Redacted.
+const redacted = "[redacted]"
+
+type Config struct {
+	// File is the config file the settings were read from, if any.
+	File string `yaml:"-"`
+
+	Server    ServerConfig    `yaml:"server"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Room      RoomConfig      `yaml:"room"`
+	Chat      ChatConfig      `yaml:"chat"`
+	Audit     AuditConfig     `yaml:"audit"`
+	WebSocket WebSocketConfig `yaml:"websocket"`
+	HTTP      HTTPConfig      `yaml:"http"`
+	Cleanup   CleanupConfig   `yaml:"cleanup"`
+}
+
+type ServerConfig struct {
+	Port string `yaml:"port"`
+	// NodeID names this node in a cluster; empty means the host name.
+	NodeID string `yaml:"node_id"`
+	// BackplaneURL is the Redis URL rooms are shared through. Empty runs the
+	// server standalone.
+	BackplaneURL     string   `yaml:"backplane_url"`
+	AllowedOrigins   []string `yaml:"allowed_origins"`
+	RoomSnapshotPath string   `yaml:"room_snapshot_path"`
+	// TrustedProxies are the addresses or CIDR ranges of the proxies whose
+	// X-Forwarded-For names the client.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+type AuthConfig struct {
+	// TokenSecret signs session tokens; empty picks a random one at startup.
+	TokenSecret string `yaml:"token_secret"`
+	// AdminToken guards the admin API; empty turns it off.
+	AdminToken string `yaml:"admin_token"`
+}
+
+type RoomConfig struct {
+	FinishedResetDelay time.Duration `yaml:"finished_reset_delay"`
+	ResettingDelay     time.Duration `yaml:"resetting_delay"`
+	AIMoveDelay        time.Duration `yaml:"ai_move_delay"`
+	TakebackTimeout    time.Duration `yaml:"takeback_timeout"`
+	TakebackLimit      int           `yaml:"takeback_limit"`
+	TypingTimeout      time.Duration `yaml:"typing_timeout"`
+	ChatHistory        int           `yaml:"chat_history"`
+}
+
+type ChatConfig struct {
+	BlockedWords []string `yaml:"blocked_words"`
+	// Retention and RetentionMessages bound the chat store; 0 turns a limit
+	// off.
+	Retention         time.Duration `yaml:"retention"`
+	RetentionMessages int           `yaml:"retention_messages"`
+	PruneInterval     time.Duration `yaml:"prune_interval"`
+	// StorePath keeps chat in a file; empty keeps it in memory.
+	StorePath string `yaml:"store_path"`
+}
+
+type AuditConfig struct {
+	// Path appends the audit log to a file; empty keeps the latest
+	// MemoryEvents events in memory.
+	Path         string `yaml:"path"`
+	MemoryEvents int    `yaml:"memory_events"`
+}
+
+type WebSocketConfig struct {
+	WriteWait    time.Duration `yaml:"write_wait"`
+	PingPeriod   time.Duration `yaml:"ping_period"`
+	PongWait     time.Duration `yaml:"pong_wait"`
+	MessageRate  float64       `yaml:"message_rate"`
+	MessageBurst int           `yaml:"message_burst"`
+	ChatRate     float64       `yaml:"chat_rate"`
+	ChatBurst    int           `yaml:"chat_burst"`
+	// ConnectionRate and ConnectionBurst limit each connection within its
+	// player's limit.
+	ConnectionRate  float64 `yaml:"connection_rate"`
+	ConnectionBurst int     `yaml:"connection_burst"`
+	MaxMessageBytes int     `yaml:"max_message_bytes"`
+	// Within AbuseWindow, the first AbuseWarnings rejected messages of a
+	// connection are answered, later ones dropped, and rejection
+	// AbuseCloseAfter closes it.
+	AbuseWarnings   int           `yaml:"abuse_warnings"`
+	AbuseCloseAfter int           `yaml:"abuse_close_after"`
+	AbuseWindow     time.Duration `yaml:"abuse_window"`
+}
+
+type HTTPConfig struct {
+	RateLimit float64 `yaml:"rate_limit"`
+	RateBurst int     `yaml:"rate_burst"`
+}
+
+type CleanupConfig struct {
+	// InactiveAfter is how long players and rooms are kept without activity.
+	InactiveAfter time.Duration `yaml:"inactive_after"`
+	Interval      time.Duration `yaml:"interval"`
+}
+
+// Default returns the settings used where nothing overrides them.
+func Default() Config {
+	room := game.DefaultRoomConfig()
+	return Config{
+		Server: ServerConfig{Port: "8080"},
+		Room: RoomConfig{
+			FinishedResetDelay: room.FinishedResetDelay,
+			ResettingDelay:     room.ResettingDelay,
+			AIMoveDelay:        room.AIMoveDelay,
+			TakebackTimeout:    room.TakebackTimeout,
+			TakebackLimit:      room.TakebackLimit,
+			TypingTimeout:      room.TypingTimeout,
+			ChatHistory:        room.ChatHistory,
+		},
+		Chat: ChatConfig{
+			BlockedWords:      append([]string(nil), game.DefaultChatFilterWords...),
+			Retention:         30 * 24 * time.Hour,
+			RetentionMessages: 1000,
+			PruneInterval:     time.Hour,
+		},
+		Audit: AuditConfig{MemoryEvents: 10000},
+		WebSocket: WebSocketConfig{
+			WriteWait:       10 * time.Second,
+			PingPeriod:      5 * time.Second,
+			PongWait:        10 * time.Second,
+			MessageRate:     10,
+			MessageBurst:    20,
+			ChatRate:        1,
+			ChatBurst:       5,
+			ConnectionRate:  5,
+			ConnectionBurst: 10,
+			MaxMessageBytes: 4096,
+			AbuseWarnings:   5,
+			AbuseCloseAfter: 30,
+			AbuseWindow:     time.Minute,
+		},
+		HTTP: HTTPConfig{RateLimit: 1, RateBurst: 5},
+		Cleanup: CleanupConfig{
+			InactiveAfter: 24 * time.Hour,
+			Interval:      30 * time.Minute,
+		},
+	}
+}
+
+// Load builds the config from the defaults, then the YAML file named by
+// the -config flag or CONFIG_FILE, then environment variables, then the
+// flags in args. An empty environment variable is ignored, except for a
+// list, which it empties. The result is validated.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	flags := flag.NewFlagSet("mini-game", flag.ContinueOnError)
+	file := flags.String("config", "", "path to a YAML config file (env "+FileEnv+")")
+	for _, setting := range settings {
+		flags.String(setting.key, "", fmt.Sprintf("%s (env %s, default %s)", setting.usage, setting.env, setting.format(false)))
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if *file == "" {
+		*file, _ = lookupEnv(FileEnv)
+	}
+	if *file != "" {
+		if err := cfg.readFile(*file); err != nil {
+			return Config{}, err
+		}
+		cfg.File = *file
+	}
+
+	for _, setting := range settings {
+		raw, ok := lookupEnv(setting.env)
+		if !ok || (raw == "" && !setting.isList()) {
+			continue
+		}
+		if err := setting.set(raw); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", setting.env, err)
+		}
+	}
+
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		for _, setting := range settings {
+			if setting.key == f.Name && flagErr == nil {
+				if err := setting.set(f.Value.String()); err != nil {
+					flagErr = fmt.Errorf("-%s: %w", setting.key, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return Config{}, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// readFile overrides the settings found in the YAML file at path. Keys the
+// config does not know are an error, so a typo is not silently ignored.
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every setting out of range.
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(key string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+	positive := func(key string, d time.Duration) {
+		if d <= 0 {
+			invalid(key, "must be positive, got %s", d)
+		}
+	}
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		invalid("server.port", "must be a port number, got %q", c.Server.Port)
+	}
+	if c.Server.BackplaneURL != "" {
+		if _, err := url.Parse(c.Server.BackplaneURL); err != nil {
+			invalid("server.backplane_url", "must be a URL")
+		}
+	}
+
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, err := ParseTrustedProxy(proxy); err != nil {
+			invalid("server.trusted_proxies", "%q is not an address or CIDR range", proxy)
+		}
+	}
+
+	positive("room.finished_reset_delay", c.Room.FinishedResetDelay)
+	positive("room.resetting_delay", c.Room.ResettingDelay)
+	if c.Room.AIMoveDelay < 0 {
+		invalid("room.ai_move_delay", "must not be negative, got %s", c.Room.AIMoveDelay)
+	}
+	positive("room.takeback_timeout", c.Room.TakebackTimeout)
+	if c.Room.TakebackLimit < 0 {
+		invalid("room.takeback_limit", "must not be negative, got %d", c.Room.TakebackLimit)
+	}
+	positive("room.typing_timeout", c.Room.TypingTimeout)
+	if c.Room.ChatHistory < 1 {
+		invalid("room.chat_history", "must be at least 1, got %d", c.Room.ChatHistory)
+	}
+
+	if c.Chat.Retention < 0 {
+		invalid("chat.retention", "must not be negative, got %s", c.Chat.Retention)
+	}
+	if c.Chat.RetentionMessages < 0 {
+		invalid("chat.retention_messages", "must not be negative, got %d", c.Chat.RetentionMessages)
+	}
+	positive("chat.prune_interval", c.Chat.PruneInterval)
+
+	positive("websocket.write_wait", c.WebSocket.WriteWait)
+	positive("websocket.ping_period", c.WebSocket.PingPeriod)
+	if c.WebSocket.PongWait <= c.WebSocket.PingPeriod {
+		invalid("websocket.pong_wait", "must be longer than websocket.ping_period, got %s", c.WebSocket.PongWait)
+	}
+	if c.WebSocket.MessageRate <= 0 || c.WebSocket.MessageBurst < 1 {
+		invalid("websocket.message_rate", "rate must be positive and burst at least 1")
+	}
+	if c.WebSocket.ChatRate <= 0 || c.WebSocket.ChatBurst < 1 {
+		invalid("websocket.chat_rate", "rate must be positive and burst at least 1")
+	}
+	if c.WebSocket.ConnectionRate <= 0 || c.WebSocket.ConnectionBurst < 1 {
+		invalid("websocket.connection_rate", "rate must be positive and burst at least 1")
+	}
+	if c.WebSocket.MaxMessageBytes < 512 {
+		invalid("websocket.max_message_bytes", "must be at least 512, got %d", c.WebSocket.MaxMessageBytes)
+	}
+	if c.WebSocket.AbuseWarnings < 0 {
+		invalid("websocket.abuse_warnings", "must not be negative, got %d", c.WebSocket.AbuseWarnings)
+	}
+	if c.WebSocket.AbuseCloseAfter <= c.WebSocket.AbuseWarnings {
+		invalid("websocket.abuse_close_after", "must be more than websocket.abuse_warnings, got %d", c.WebSocket.AbuseCloseAfter)
+	}
+	positive("websocket.abuse_window", c.WebSocket.AbuseWindow)
+
+	if c.Audit.MemoryEvents < 1 {
+		invalid("audit.memory_events", "must be at least 1, got %d", c.Audit.MemoryEvents)
+	}
+
+	if c.HTTP.RateLimit <= 0 || c.HTTP.RateBurst < 1 {
+		invalid("http.rate_limit", "rate must be positive and burst at least 1")
+	}
+
+	positive("cleanup.inactive_after", c.Cleanup.InactiveAfter)
+	positive("cleanup.interval", c.Cleanup.Interval)
+
+	return errors.Join(errs...)
+}
+
+// Redacted lists every setting by its key, formatted as it would be given,
+// with secrets hidden and the password of the backplane URL masked.
+func (c *Config) Redacted() map[string]string {
+	values := make(map[string]string)
+	for _, setting := range c.settings() {
+		values[setting.key] = setting.format(true)
+	}
+	return values
+}
+
+// Game is the room config the game service gives the rooms it opens.
+func (c RoomConfig) Game() game.RoomConfig {
+	return game.RoomConfig{
+		FinishedResetDelay: c.FinishedResetDelay,
+		ResettingDelay:     c.ResettingDelay,
+		AIMoveDelay:        c.AIMoveDelay,
+		TakebackTimeout:    c.TakebackTimeout,
+		TakebackLimit:      c.TakebackLimit,
+		TypingTimeout:      c.TypingTimeout,
+		ChatHistory:        c.ChatHistory,
+	}
+}
+
+// TrustedProxyPrefixes returns TrustedProxies as the ranges of addresses
+// they cover.
+func (c ServerConfig) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, proxy := range c.TrustedProxies {
+		prefix, err := ParseTrustedProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// ParseTrustedProxy reads a trusted proxy given as an address or a CIDR
+// range.
+func ParseTrustedProxy(proxy string) (netip.Prefix, error) {
+	proxy = strings.TrimSpace(proxy)
+	if strings.Contains(proxy, "/") {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("trusted proxy %q: %w", proxy, err)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(proxy)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("trusted proxy %q: %w", proxy, err)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// setting is one config value: its key in the file and as a flag, its
+// environment variable and where it is stored.
+type setting struct {
+	key    string
+	env    string
+	usage  string
+	secret bool
+	value  any
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "server.port", env: "PORT", usage: "port to listen on", value: &c.Server.Port},
+		{key: "server.node_id", env: "NODE_ID", usage: "name of this node in a cluster", value: &c.Server.NodeID},
+		{key: "server.backplane_url", env: "BACKPLANE_URL", usage: "Redis URL shared by the cluster", value: &c.Server.BackplaneURL},
+		{key: "server.allowed_origins", env: "ALLOWED_ORIGINS", usage: "comma separated CORS origins", value: &c.Server.AllowedOrigins},
+		{key: "server.room_snapshot_path", env: "ROOM_SNAPSHOT_PATH", usage: "file rooms are saved to across restarts", value: &c.Server.RoomSnapshotPath},
+		{key: "server.trusted_proxies", env: "TRUSTED_PROXIES", usage: "comma separated proxies trusted for X-Forwarded-For", value: &c.Server.TrustedProxies},
+		{key: "auth.token_secret", env: "AUTH_TOKEN_SECRET", usage: "secret signing session tokens", secret: true, value: &c.Auth.TokenSecret},
+		{key: "auth.admin_token", env: "ADMIN_TOKEN", usage: "token of the admin API", secret: true, value: &c.Auth.AdminToken},
+		{key: "room.finished_reset_delay", env: "ROOM_FINISHED_RESET_DELAY", usage: "how long a finished game shows before the reset", value: &c.Room.FinishedResetDelay},
+		{key: "room.resetting_delay", env: "ROOM_RESETTING_DELAY", usage: "how long a reset takes", value: &c.Room.ResettingDelay},
+		{key: "room.ai_move_delay", env: "ROOM_AI_MOVE_DELAY", usage: "delay before the AI moves", value: &c.Room.AIMoveDelay},
+		{key: "room.takeback_timeout", env: "ROOM_TAKEBACK_TIMEOUT", usage: "how long a takeback request waits for an answer", value: &c.Room.TakebackTimeout},
+		{key: "room.takeback_limit", env: "ROOM_TAKEBACK_LIMIT", usage: "takebacks each player may request per game", value: &c.Room.TakebackLimit},
+		{key: "room.typing_timeout", env: "ROOM_TYPING_TIMEOUT", usage: "how long a typing indicator lasts", value: &c.Room.TypingTimeout},
+		{key: "room.chat_history", env: "ROOM_CHAT_HISTORY", usage: "chat messages a room keeps for players joining", value: &c.Room.ChatHistory},
+		{key: "chat.blocked_words", env: "CHAT_BLOCKED_WORDS", usage: "comma separated words masked in chat", value: &c.Chat.BlockedWords},
+		{key: "chat.retention", env: "CHAT_RETENTION", usage: "how long chat is kept, 0 for ever", value: &c.Chat.Retention},
+		{key: "chat.retention_messages", env: "CHAT_RETENTION_MESSAGES", usage: "messages kept per room, 0 for all", value: &c.Chat.RetentionMessages},
+		{key: "chat.prune_interval", env: "CHAT_PRUNE_INTERVAL", usage: "how often old chat is dropped", value: &c.Chat.PruneInterval},
+		{key: "chat.store_path", env: "CHAT_STORE_PATH", usage: "file chat is kept in", value: &c.Chat.StorePath},
+		{key: "audit.path", env: "AUDIT_LOG_PATH", usage: "file the audit log is appended to", value: &c.Audit.Path},
+		{key: "audit.memory_events", env: "AUDIT_MEMORY_EVENTS", usage: "audit events kept without a file", value: &c.Audit.MemoryEvents},
+		{key: "websocket.write_wait", env: "WS_WRITE_WAIT", usage: "how long a websocket write may take", value: &c.WebSocket.WriteWait},
+		{key: "websocket.ping_period", env: "WS_PING_PERIOD", usage: "how often websockets are pinged", value: &c.WebSocket.PingPeriod},
+		{key: "websocket.pong_wait", env: "WS_PONG_WAIT", usage: "how long a websocket may go without a pong", value: &c.WebSocket.PongWait},
+		{key: "websocket.message_rate", env: "WS_MESSAGE_RATE", usage: "messages per second a player may send", value: &c.WebSocket.MessageRate},
+		{key: "websocket.message_burst", env: "WS_MESSAGE_BURST", usage: "messages a player may send at once", value: &c.WebSocket.MessageBurst},
+		{key: "websocket.chat_rate", env: "WS_CHAT_RATE", usage: "chat messages per second a player may send", value: &c.WebSocket.ChatRate},
+		{key: "websocket.chat_burst", env: "WS_CHAT_BURST", usage: "chat messages a player may send at once", value: &c.WebSocket.ChatBurst},
+		{key: "websocket.connection_rate", env: "WS_CONNECTION_RATE", usage: "messages per second one connection may send", value: &c.WebSocket.ConnectionRate},
+		{key: "websocket.connection_burst", env: "WS_CONNECTION_BURST", usage: "messages one connection may send at once", value: &c.WebSocket.ConnectionBurst},
+		{key: "websocket.max_message_bytes", env: "WS_MAX_MESSAGE_BYTES", usage: "largest message a client may send", value: &c.WebSocket.MaxMessageBytes},
+		{key: "websocket.abuse_warnings", env: "WS_ABUSE_WARNINGS", usage: "rejected messages answered before they are dropped", value: &c.WebSocket.AbuseWarnings},
+		{key: "websocket.abuse_close_after", env: "WS_ABUSE_CLOSE_AFTER", usage: "rejected messages that close a connection", value: &c.WebSocket.AbuseCloseAfter},
+		{key: "websocket.abuse_window", env: "WS_ABUSE_WINDOW", usage: "window rejected messages are counted in", value: &c.WebSocket.AbuseWindow},
+		{key: "http.rate_limit", env: "HTTP_RATE_LIMIT", usage: "requests per second per client IP", value: &c.HTTP.RateLimit},
+		{key: "http.rate_burst", env: "HTTP_RATE_BURST", usage: "requests a client IP may make at once", value: &c.HTTP.RateBurst},
+		{key: "cleanup.inactive_after", env: "CLEANUP_INACTIVE_AFTER", usage: "how long idle players and rooms are kept", value: &c.Cleanup.InactiveAfter},
+		{key: "cleanup.interval", env: "CLEANUP_INTERVAL", usage: "how often idle players and rooms are removed", value: &c.Cleanup.Interval},
+	}
+}
+
+func (s setting) isList() bool {
+	_, ok := s.value.(*[]string)
+	return ok
+}
+
+func (s setting) set(raw string) error {
+	switch value := s.value.(type) {
+	case *string:
+		*value = raw
+	case *[]string:
+		*value = splitList(raw)
+	case *int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", raw)
+		}
+		*value = n
+	case *float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		*value = f
+	case *time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", raw)
+		}
+		*value = d
+	}
+	return nil
+}
+
+func (s setting) format(redact bool) string {
+	var text string
+	switch value := s.value.(type) {
+	case *string:
+		text = *value
+	case *[]string:
+		text = strings.Join(*value, ",")
+	case *int:
+		text = strconv.Itoa(*value)
+	case *float64:
+		text = strconv.FormatFloat(*value, 'g', -1, 64)
+	case *time.Duration:
+		text = value.String()
+	}
+	if !redact || text == "" {
+		return text
+	}
+	if s.secret {
+		return redacted
+	}
+	if s.key == "server.backplane_url" {
+		if u, err := url.Parse(text); err == nil {
+			return u.Redacted()
+		}
+		return redacted
+	}
+	return text
+}
+
+func splitList(raw string) []string {
+	items := []string{}
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,64 @@
+package game
+
+import "errors"
+
+// ErrRoomNotPlaying is returned when an operator finishes a room that has no
+// game in progress.
+var ErrRoomNotPlaying = errors.New("room has no game in progress")
+
+// ForceFinish ends the game in progress without a result, for operators
+// clearing a stuck room. No outcome is reported, so it counts for nobody; the
+// room resets into a new game as if the game had ended.
+func (r *Room) ForceFinish() (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event := r.beginAuditLocked(AuditForceFinish, "")
+	defer func() { r.recordAuditLocked(event, err) }()
+
+	if r.roomState != RoomStatePlaying {
+		return ErrRoomNotPlaying
+	}
+
+	r.cancelScheduledAIMoveLocked()
+	r.clearChessPremovesLocked()
+	r.clearTakebackLocked()
+	if err := r.transitionLocked(RoomStateFinished); err != nil {
+		return err
+	}
+	r.bumpStateVersionLocked()
+	r.scheduleResetLocked()
+	return nil
+}
+
+// RemovePlayer takes a player out of the room and keeps them from joining
+// again, like KickPlayer without the host checks.
+func (r *Room) RemovePlayer(playerID string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event := r.beginAuditLocked(AuditRemove, "")
+	event.TargetID = playerID
+	defer func() { r.recordAuditLocked(event, err) }()
+
+	player, exists := r.players[playerID]
+	if !exists {
+		return ErrPlayerNotFound
+	}
+	if player.IsAI {
+		return ErrCannotKickPlayer
+	}
+
+	r.banPlayerLocked(playerID)
+	return nil
+}
+
+// StockfishStatus reports the room's engine, or false when it has none.
+func (r *Room) StockfishStatus() (StockfishStatus, bool) {
+	r.mu.RLock()
+	stockfish := r.stockfish
+	r.mu.RUnlock()
+
+	if stockfish == nil {
+		return StockfishStatus{}, false
+	}
+	return stockfish.Status(), true
+}
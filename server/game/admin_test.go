@@ -0,0 +1,38 @@
+package game
+
+import "testing"
+
+func TestRoom_ForceFinish_EndsGameInProgressOnly(t *testing.T) {
+	room := newTicTacToeRoomForTest(t)
+	addPlayerToRoomForTest(t, room, "p1")
+
+	if err := room.ForceFinish(); err != ErrRoomNotPlaying {
+		t.Fatalf("ForceFinish(waiting) error = %v, want %v", err, ErrRoomNotPlaying)
+	}
+	addPlayerToRoomForTest(t, room, "p2")
+	version := room.Snapshot().StateVersion
+
+	if err := room.ForceFinish(); err != nil {
+		t.Fatalf("ForceFinish() error = %v", err)
+	}
+	snapshot := room.Snapshot()
+	if snapshot.RoomState != RoomStateFinished || snapshot.StateVersion <= version {
+		t.Fatalf("room = %s at version %d, want FINISHED after version %d", snapshot.RoomState, snapshot.StateVersion, version)
+	}
+}
+
+func TestRoom_RemovePlayer_KeepsPlayerOut(t *testing.T) {
+	room := newTicTacToeRoomForTest(t)
+	addPlayerToRoomForTest(t, room, "p1")
+	addPlayerToRoomForTest(t, room, "p2")
+
+	if err := room.RemovePlayer("p1"); err != nil {
+		t.Fatalf("RemovePlayer(host) error = %v", err)
+	}
+	if _, err := room.GetPlayer("p1"); err != ErrPlayerNotFound {
+		t.Fatalf("GetPlayer(removed) error = %v, want %v", err, ErrPlayerNotFound)
+	}
+	if _, err := room.AddPlayerWithAccess(PlayerSnapshot{ID: "p1"}, JoinAccess{}); err != ErrPlayerKicked {
+		t.Fatalf("AddPlayerWithAccess(removed) error = %v, want %v", err, ErrPlayerKicked)
+	}
+}
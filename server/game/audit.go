@@ -0,0 +1,155 @@
+package game
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// AuditAction names what an audit event records.
+type AuditAction string
+
+const (
+	// AuditOpen starts the log of a room with how it was set up.
+	AuditOpen            AuditAction = "open"
+	AuditJoin            AuditAction = "join"
+	AuditEnableAI        AuditAction = "enable_ai"
+	AuditLeave           AuditAction = "leave"
+	AuditKick            AuditAction = "kick"
+	AuditRemove          AuditAction = "remove"
+	AuditMove            AuditAction = "move"
+	AuditPremove         AuditAction = "premove"
+	AuditPremoveCancel   AuditAction = "premove_cancel"
+	AuditUndo            AuditAction = "undo"
+	AuditTakebackRequest AuditAction = "takeback_request"
+	AuditTakebackRespond AuditAction = "takeback_respond"
+	AuditTakebackExpire  AuditAction = "takeback_expire"
+	AuditChat            AuditAction = "chat"
+	AuditAIThinking      AuditAction = "ai_thinking"
+	AuditAIMove          AuditAction = "ai_move"
+	AuditForceFinish     AuditAction = "force_finish"
+	AuditResetBegin      AuditAction = "reset_begin"
+	AuditReset           AuditAction = "reset"
+)
+
+// Reasons a player leaves a room, see AuditEvent.Reason.
+const (
+	AuditReasonDisconnected = "disconnected"
+	AuditReasonInactive     = "inactive"
+)
+
+// errAIMoveSuperseded rejects an AI move computed for a position the room has
+// since left, such as after an undo.
+var errAIMoveSuperseded = errors.New("AI move superseded")
+
+// AuditEvent is one entry of a room's audit log: a command the room accepted
+// or rejected, or a change it made on its own such as an AI move or a reset.
+// Events of a room are emitted in the order they took effect, and every
+// change of the state version falls within exactly one event.
+type AuditEvent struct {
+	RoomID        string      `json:"room_id"`
+	Action        AuditAction `json:"action"`
+	PlayerID      string      `json:"player_id,omitempty"`
+	TargetID      string      `json:"target_id,omitempty"`
+	Accepted      bool        `json:"accepted"`
+	Error         string      `json:"error,omitempty"`
+	VersionBefore uint64      `json:"version_before"`
+	VersionAfter  uint64      `json:"version_after"`
+	StateBefore   RoomState   `json:"state_before"`
+	StateAfter    RoomState   `json:"state_after"`
+	At            time.Time   `json:"at"`
+
+	GameType   string          `json:"game_type,omitempty"`
+	AILevel    int             `json:"ai_level,omitempty"`
+	Visibility RoomVisibility  `json:"visibility,omitempty"`
+	Players    []PlayerRecord  `json:"players,omitempty"`
+	Mark       string          `json:"mark,omitempty"`
+	IsAI       bool            `json:"is_ai,omitempty"`
+	Reason     string          `json:"reason,omitempty"`
+	Move       *AuditedMove    `json:"move,omitempty"`
+	Premove    *AuditedPremove `json:"premove,omitempty"`
+	Accept     *bool           `json:"accept,omitempty"`
+	MessageID  string          `json:"message_id,omitempty"`
+	Message    string          `json:"message,omitempty"`
+}
+
+// AuditedMove is a tic-tac-toe cell or a chess move.
+type AuditedMove struct {
+	Row       *int   `json:"row,omitempty"`
+	Col       *int   `json:"col,omitempty"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	Promotion string `json:"promotion,omitempty"`
+}
+
+// AuditedPremove is a queued premove the room played right after the move of
+// the event, or discarded because it was no longer legal.
+type AuditedPremove struct {
+	PlayerID string      `json:"player_id"`
+	Move     AuditedMove `json:"move"`
+	Error    string      `json:"error,omitempty"`
+}
+
+func ticTacToeAuditMove(row int, col int) *AuditedMove {
+	return &AuditedMove{Row: &row, Col: &col}
+}
+
+func chessAuditMove(from string, to string, promotion string) *AuditedMove {
+	return &AuditedMove{From: from, To: to, Promotion: promotion}
+}
+
+// SetAuditor sets where the room's audit events go and emits the AuditOpen
+// event describing the room as it is now. The auditor is called with the
+// room locked, so it must be quick and must not call back into the room.
+func (r *Room) SetAuditor(auditor func(AuditEvent)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.auditor = auditor
+
+	event := r.beginAuditLocked(AuditOpen, "")
+	event.GameType = r.gameType
+	if r.isAIEnabled {
+		event.AILevel = r.aiLevel
+	}
+	event.Visibility = r.visibility
+	for _, player := range r.players {
+		event.Players = append(event.Players, PlayerRecord{
+			ID:         player.ID,
+			Mark:       player.Mark,
+			IsAI:       player.IsAI,
+			LastActive: player.LastActive,
+		})
+	}
+	sort.Slice(event.Players, func(i, j int) bool { return event.Players[i].ID < event.Players[j].ID })
+	r.recordAuditLocked(event, nil)
+}
+
+// beginAuditLocked starts the event of an action, to be finished by
+// recordAuditLocked once the action is decided.
+func (r *Room) beginAuditLocked(action AuditAction, playerID string) AuditEvent {
+	return AuditEvent{
+		RoomID:        r.RoomID,
+		Action:        action,
+		PlayerID:      playerID,
+		VersionBefore: r.stateVersion,
+		StateBefore:   r.roomState,
+	}
+}
+
+// recordAuditLocked completes event with its outcome and hands it to the
+// auditor.
+func (r *Room) recordAuditLocked(event AuditEvent, err error) {
+	if r.auditor == nil {
+		return
+	}
+
+	event.Accepted = err == nil
+	if err != nil {
+		event.Error = err.Error()
+	}
+	event.VersionAfter = r.stateVersion
+	event.StateAfter = r.roomState
+	event.At = time.Now().UTC()
+	r.auditor(event)
+}
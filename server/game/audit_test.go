@@ -0,0 +1,167 @@
+package game
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type auditRecorder struct {
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+func (r *auditRecorder) record(event AuditEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *auditRecorder) snapshot() []AuditEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]AuditEvent(nil), r.events...)
+}
+
+func (r *auditRecorder) waitFor(t *testing.T, action AuditAction, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		for _, event := range r.snapshot() {
+			if event.Action == action {
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("no %s event within %s", action, timeout)
+}
+
+func assertAuditVersionChain(t *testing.T, events []AuditEvent) {
+	t.Helper()
+
+	for i := 1; i < len(events); i++ {
+		if events[i].VersionBefore != events[i-1].VersionAfter {
+			t.Fatalf("event %d (%s) version_before = %d, want %d after %s",
+				i, events[i].Action, events[i].VersionBefore, events[i-1].VersionAfter, events[i-1].Action)
+		}
+		if events[i].StateBefore != events[i-1].StateAfter {
+			t.Fatalf("event %d (%s) state_before = %s, want %s", i, events[i].Action, events[i].StateBefore, events[i-1].StateAfter)
+		}
+	}
+}
+
+func TestRoom_Audit_RecordsAcceptedAndRejectedCommandsInOrder(t *testing.T) {
+	room := newTicTacToeRoomForTest(t)
+	room.SetResetDelays(10*time.Millisecond, 10*time.Millisecond)
+	recorder := &auditRecorder{}
+	room.SetAuditor(recorder.record)
+
+	addPlayerToRoomForTest(t, room, "p1")
+	addPlayerToRoomForTest(t, room, "p2")
+	if _, err := room.HandleTicTacToeMove("p2", 0, 0); err == nil {
+		t.Fatalf("HandleTicTacToeMove(p2 out of turn) error = nil, want error")
+	}
+	moves := []struct {
+		playerID string
+		row, col int
+	}{{"p1", 0, 0}, {"p2", 1, 0}, {"p1", 0, 1}, {"p2", 1, 1}, {"p1", 0, 2}}
+	for _, move := range moves {
+		if _, err := room.HandleTicTacToeMove(move.playerID, move.row, move.col); err != nil {
+			t.Fatalf("HandleTicTacToeMove(%s) error = %v", move.playerID, err)
+		}
+	}
+	if _, err := room.AddChatMessage("p2", "gg"); err != nil {
+		t.Fatalf("AddChatMessage() error = %v", err)
+	}
+	recorder.waitFor(t, AuditReset, 250*time.Millisecond)
+
+	events := recorder.snapshot()
+	want := []AuditAction{
+		AuditOpen, AuditJoin, AuditJoin, AuditMove,
+		AuditMove, AuditMove, AuditMove, AuditMove, AuditMove,
+		AuditChat, AuditResetBegin, AuditReset,
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events = %+v, want actions %v", events, want)
+	}
+	for i, action := range want {
+		if events[i].Action != action || events[i].RoomID != room.RoomID || events[i].At.IsZero() {
+			t.Fatalf("event %d = %+v, want %s of %s", i, events[i], action, room.RoomID)
+		}
+	}
+	assertAuditVersionChain(t, events)
+
+	if events[2].Mark != "O" || events[2].StateAfter != RoomStatePlaying {
+		t.Fatalf("second join = %+v, want mark O and the game started", events[2])
+	}
+	rejected := events[3]
+	if rejected.Accepted || rejected.Error == "" || rejected.VersionAfter != rejected.VersionBefore {
+		t.Fatalf("out of turn move = %+v, want rejected without a version change", rejected)
+	}
+	if rejected.Move == nil || *rejected.Move.Row != 0 || *rejected.Move.Col != 0 {
+		t.Fatalf("out of turn move = %+v, want the cell recorded", rejected.Move)
+	}
+	if winning := events[8]; !winning.Accepted || winning.StateAfter != RoomStateFinished {
+		t.Fatalf("winning move = %+v, want accepted and the room finished", winning)
+	}
+	if chat := events[9]; chat.MessageID == "" || chat.Message != "gg" {
+		t.Fatalf("chat = %+v, want the message recorded", chat)
+	}
+	if reset := events[11]; reset.StateBefore != RoomStateResetting || reset.StateAfter != RoomStatePlaying {
+		t.Fatalf("reset = %+v, want RESETTING to PLAYING", reset)
+	}
+}
+
+func TestRoom_Audit_NotesPremovePlayedInReply(t *testing.T) {
+	room := newChessPremoveTestRoom(t)
+	recorder := &auditRecorder{}
+	room.SetAuditor(recorder.record)
+
+	if _, err := room.QueueChessPremove("p2", "e7", "e5", ""); err != nil {
+		t.Fatalf("QueueChessPremove() error = %v", err)
+	}
+	if _, err := room.HandleChessMove("p1", "e2", "e4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+
+	events := recorder.snapshot()
+	assertAuditVersionChain(t, events)
+	move := events[len(events)-1]
+	if move.Action != AuditMove || move.Premove == nil || move.Premove.PlayerID != "p2" || move.Premove.Move.From != "e7" {
+		t.Fatalf("move = %+v, want p2's premove noted on it", move)
+	}
+	if move.VersionAfter != move.VersionBefore+2 {
+		t.Fatalf("move versions = %d..%d, want the move and the premove", move.VersionBefore, move.VersionAfter)
+	}
+}
+
+func TestRoom_Audit_RecordsLeaveAndKick(t *testing.T) {
+	room := newTicTacToeRoomForTest(t)
+	recorder := &auditRecorder{}
+	addPlayerToRoomForTest(t, room, "p1")
+	addPlayerToRoomForTest(t, room, "p2")
+	room.SetAuditor(recorder.record)
+
+	if err := room.KickPlayer("p2", "p1"); err == nil {
+		t.Fatalf("KickPlayer(not host) error = nil, want error")
+	}
+	room.HandlePlayerDisconnected("p2")
+	room.HandlePlayerDisconnected("p2")
+
+	events := recorder.snapshot()
+	if len(events) != 3 {
+		t.Fatalf("events = %+v, want open, kick and one leave", events)
+	}
+	if open := events[0]; len(open.Players) != 2 || open.GameType != "tictactoe" {
+		t.Fatalf("open = %+v, want the seated players and game type", open)
+	}
+	if kick := events[1]; kick.Action != AuditKick || kick.Accepted || kick.TargetID != "p1" {
+		t.Fatalf("kick = %+v, want a rejected kick of p1", kick)
+	}
+	if leave := events[2]; leave.Action != AuditLeave || leave.Reason != AuditReasonDisconnected || leave.StateAfter != RoomStateWaiting {
+		t.Fatalf("leave = %+v, want p2 leaving the room waiting", leave)
+	}
+	assertAuditVersionChain(t, events)
+}
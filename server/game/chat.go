@@ -9,10 +9,11 @@ import (
 	"unicode/utf8"
 )
 
-const (
-	maxChatMessages     = 50
-	maxChatMessageChars = 300
-)
+const maxChatMessageChars = 300
+
+// DefaultChatHistory is how many chat messages a room keeps for players
+// joining late.
+const DefaultChatHistory = 50
 
 var chatMessageSeq uint64
 
@@ -28,6 +29,10 @@ type ChatMessage struct {
 	PlayerMark string
 	Message    string
 	CreatedAt  time.Time
+	// System marks a message written by the server rather than a player.
+	System bool
+	// Recipient, when set, is the only player the message is shown to.
+	Recipient string
 }
 
 func newChatMessageID(now time.Time) string {
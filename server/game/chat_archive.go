@@ -0,0 +1,83 @@
+package game
+
+import "strings"
+
+// ChatRecord is a chat message as the chat store keeps it, with the players
+// it was shown to. Only they can read it back.
+type ChatRecord struct {
+	Message  ChatMessage
+	Audience []string
+}
+
+func (c ChatRecord) SeenBy(playerID string) bool {
+	for _, id := range c.Audience {
+		if id == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// ChatSearchTerms splits a search query into lowercase words.
+func ChatSearchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !isChatWordRune(r)
+	})
+}
+
+// Matches reports whether every term starts a word of the message.
+func (c ChatRecord) Matches(terms []string) bool {
+	words := ChatSearchTerms(c.Message.Message)
+	for _, term := range terms {
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return len(terms) > 0
+}
+
+// PageChatRecords returns the last limit records of a room's history, oldest
+// first, seen by viewerID and older than the message with ID before when it
+// is set. It reports whether older ones remain.
+func PageChatRecords(records []ChatRecord, viewerID string, before string, limit int) ([]ChatRecord, bool, error) {
+	end := len(records)
+	if before != "" {
+		end = -1
+		for i, record := range records {
+			if record.Message.ID == before {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return nil, false, ErrChatMessageNotFound
+		}
+	}
+
+	page := make([]ChatRecord, 0, limit)
+	i := end - 1
+	for ; i >= 0 && len(page) < limit; i-- {
+		if records[i].SeenBy(viewerID) {
+			page = append(page, records[i])
+		}
+	}
+	more := false
+	for ; i >= 0; i-- {
+		if records[i].SeenBy(viewerID) {
+			more = true
+			break
+		}
+	}
+
+	for left, right := 0, len(page)-1; left < right; left, right = left+1, right-1 {
+		page[left], page[right] = page[right], page[left]
+	}
+	return page, more, nil
+}
@@ -0,0 +1,199 @@
+package game
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultChatFilterWords is the word list used when none is configured.
+var DefaultChatFilterWords = []string{
+	"asshole",
+	"bastard",
+	"bitch",
+	"cunt",
+	"fuck",
+	"motherfucker",
+	"shit",
+}
+
+var (
+	ErrCannotMuteSelf      = errors.New("players cannot mute themselves")
+	ErrChatMessageNotFound = errors.New("chat message not found")
+	ErrCannotReportMessage = errors.New("chat message cannot be reported")
+)
+
+// ChatFilter masks blocked words in chat messages. Words match whole and
+// without regard to case; a nil filter masks nothing.
+type ChatFilter struct {
+	words map[string]struct{}
+}
+
+func NewChatFilter(words []string) *ChatFilter {
+	filter := &ChatFilter{words: make(map[string]struct{}, len(words))}
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" {
+			filter.words[word] = struct{}{}
+		}
+	}
+	return filter
+}
+
+// Mask replaces every rune of a blocked word with '*', so the message keeps
+// its length. It reports whether anything was masked.
+func (f *ChatFilter) Mask(message string) (string, bool) {
+	if f == nil || len(f.words) == 0 {
+		return message, false
+	}
+
+	runes := []rune(message)
+	masked := false
+	for start := 0; start < len(runes); {
+		if !isChatWordRune(runes[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && isChatWordRune(runes[end]) {
+			end++
+		}
+		if _, blocked := f.words[strings.ToLower(string(runes[start:end]))]; blocked {
+			for i := start; i < end; i++ {
+				runes[i] = '*'
+			}
+			masked = true
+		}
+		start = end
+	}
+	if !masked {
+		return message, false
+	}
+	return string(runes), true
+}
+
+func isChatWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// MutePlayer hides the chat messages of targetID from playerID, for as long
+// as the room lasts.
+func (r *Room) MutePlayer(playerID string, targetID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.players[playerID]; !exists {
+		return ErrPlayerNotFound
+	}
+	if playerID == targetID {
+		return ErrCannotMuteSelf
+	}
+	if _, exists := r.players[targetID]; !exists {
+		return ErrPlayerNotFound
+	}
+
+	if r.mutes[playerID] == nil {
+		r.mutes[playerID] = make(map[string]struct{})
+	}
+	r.mutes[playerID][targetID] = struct{}{}
+	return nil
+}
+
+func (r *Room) UnmutePlayer(playerID string, targetID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.players[playerID]; !exists {
+		return ErrPlayerNotFound
+	}
+	delete(r.mutes[playerID], targetID)
+	return nil
+}
+
+// MutedPlayers returns the players playerID muted, sorted.
+func (r *Room) MutedPlayers(playerID string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	muted := make([]string, 0, len(r.mutes[playerID]))
+	for targetID := range r.mutes[playerID] {
+		muted = append(muted, targetID)
+	}
+	sort.Strings(muted)
+	return muted
+}
+
+// AddSystemMessage records a message from the server. With a recipient only
+// that player sees it.
+func (r *Room) AddSystemMessage(recipient string, message string) ChatMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	chatMessage := ChatMessage{
+		ID:        newChatMessageID(now),
+		RoomID:    r.RoomID,
+		Message:   message,
+		CreatedAt: now,
+		System:    true,
+		Recipient: recipient,
+	}
+	r.appendChatMessageLocked(chatMessage)
+	return chatMessage
+}
+
+// ChatMessage looks a message up in the room's history.
+func (r *Room) ChatMessage(messageID string) (ChatMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, message := range r.chatMessages {
+		if message.ID == messageID {
+			return message, nil
+		}
+	}
+	return ChatMessage{}, ErrChatMessageNotFound
+}
+
+// ChatHistoryFor returns the history as viewerID sees it: without the
+// messages of players they muted or system messages meant for someone else.
+func (r *Room) ChatHistoryFor(viewerID string) []ChatMessage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history := make([]ChatMessage, 0, len(r.chatMessages))
+	for _, message := range r.chatMessages {
+		if r.chatVisibleLocked(viewerID, message) {
+			history = append(history, message)
+		}
+	}
+	return history
+}
+
+// ChatAudience returns the players in the room who see message.
+func (r *Room) ChatAudience(message ChatMessage) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	audience := make([]string, 0, len(r.players))
+	for playerID := range r.players {
+		if r.chatVisibleLocked(playerID, message) {
+			audience = append(audience, playerID)
+		}
+	}
+	sort.Strings(audience)
+	return audience
+}
+
+func (r *Room) chatVisibleLocked(viewerID string, message ChatMessage) bool {
+	if message.Recipient != "" {
+		return message.Recipient == viewerID
+	}
+	if message.System {
+		return true
+	}
+	_, muted := r.mutes[viewerID][message.PlayerID]
+	return !muted
+}
@@ -0,0 +1,63 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestChatFilter_MasksBlockedWordsOnly(t *testing.T) {
+	filter := NewChatFilter([]string{"darn", " Heck "})
+
+	masked, ok := filter.Mask("Darn it, what the HECK! darned")
+	if !ok {
+		t.Fatalf("Mask() masked = false, want true")
+	}
+	if masked != "**** it, what the ****! darned" {
+		t.Fatalf("Mask() = %q, want blocked words masked", masked)
+	}
+
+	if clean, ok := filter.Mask("good game"); ok || clean != "good game" {
+		t.Fatalf("Mask(clean) = %q, %v, want it unchanged", clean, ok)
+	}
+
+	var none *ChatFilter
+	if message, ok := none.Mask("darn"); ok || message != "darn" {
+		t.Fatalf("nil Mask() = %q, %v, want it unchanged", message, ok)
+	}
+}
+
+func TestRoom_MutePlayer_HidesTheirMessagesFromTheMuter(t *testing.T) {
+	room := newChatTestRoom(t)
+	if _, err := room.AddPlayer(PlayerSnapshot{ID: "p2"}); err != nil {
+		t.Fatalf("AddPlayer() error = %v", err)
+	}
+
+	if err := room.MutePlayer("p1", "p1"); !errors.Is(err, ErrCannotMuteSelf) {
+		t.Fatalf("MutePlayer(self) error = %v, want %v", err, ErrCannotMuteSelf)
+	}
+	if err := room.MutePlayer("p1", "p2"); err != nil {
+		t.Fatalf("MutePlayer() error = %v", err)
+	}
+	message, err := room.AddChatMessage("p2", "hello")
+	if err != nil {
+		t.Fatalf("AddChatMessage() error = %v", err)
+	}
+	notice := room.AddSystemMessage("p1", "You muted p2.")
+
+	if audience := room.ChatAudience(message); len(audience) != 1 || audience[0] != "p2" {
+		t.Fatalf("ChatAudience() = %v, want only p2", audience)
+	}
+	if history := room.ChatHistoryFor("p1"); len(history) != 1 || history[0].ID != notice.ID {
+		t.Fatalf("ChatHistoryFor(p1) = %+v, want only the notice", history)
+	}
+	if history := room.ChatHistoryFor("p2"); len(history) != 1 || history[0].ID != message.ID {
+		t.Fatalf("ChatHistoryFor(p2) = %+v, want only their message", history)
+	}
+
+	if err := room.UnmutePlayer("p1", "p2"); err != nil {
+		t.Fatalf("UnmutePlayer() error = %v", err)
+	}
+	if history := room.ChatHistoryFor("p1"); len(history) != 2 {
+		t.Fatalf("ChatHistoryFor(p1) after unmute = %+v, want both messages", history)
+	}
+}
@@ -55,15 +55,15 @@ func TestRoom_AddChatMessage_ValidatesMessage(t *testing.T) {
 func TestRoom_ChatHistory_IsBoundedAndCopied(t *testing.T) {
 	room := newChatTestRoom(t)
 
-	for i := 0; i < maxChatMessages+5; i++ {
+	for i := 0; i < DefaultChatHistory+5; i++ {
 		if _, err := room.AddChatMessage("p1", fmt.Sprintf("message-%02d", i)); err != nil {
 			t.Fatalf("AddChatMessage(%d) error = %v", i, err)
 		}
 	}
 
 	history := room.ChatHistory()
-	if len(history) != maxChatMessages {
-		t.Fatalf("history len = %d, want %d", len(history), maxChatMessages)
+	if len(history) != DefaultChatHistory {
+		t.Fatalf("history len = %d, want %d", len(history), DefaultChatHistory)
 	}
 	if history[0].Message != "message-05" {
 		t.Fatalf("oldest retained message = %q, want message-05", history[0].Message)
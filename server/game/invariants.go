@@ -0,0 +1,137 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/tsaqiffatih/mini-game/tictactoe"
+)
+
+// validateRoomSnapshot returns the first rule a room keeps between actions
+// that snapshot breaks, or nil when it holds together. Tests check it under
+// load; ReplayRoom checks it after every event.
+func validateRoomSnapshot(snapshot RoomSnapshot) error {
+	if len(snapshot.Players) > 2 {
+		return fmt.Errorf("players len = %d, want at most 2", len(snapshot.Players))
+	}
+
+	seenPlayers := map[string]bool{}
+	seenMarks := map[string]bool{}
+	for _, player := range snapshot.Players {
+		if player.ID == "" {
+			return fmt.Errorf("player ID is empty")
+		}
+		if seenPlayers[player.ID] {
+			return fmt.Errorf("duplicate player ID %q in snapshot %+v", player.ID, snapshot.Players)
+		}
+		seenPlayers[player.ID] = true
+		if seenMarks[player.Mark] {
+			return fmt.Errorf("duplicate player mark %q in snapshot %+v", player.Mark, snapshot.Players)
+		}
+		seenMarks[player.Mark] = true
+		if player.IsAI && !snapshot.IsAIEnabled {
+			return fmt.Errorf("AI player %q in a room without AI", player.ID)
+		}
+	}
+
+	switch snapshot.RoomState {
+	case RoomStateWaiting:
+		if len(snapshot.Players) == 2 {
+			return fmt.Errorf("room state = %s with 2 players, want PLAYING", snapshot.RoomState)
+		}
+	case RoomStatePlaying, RoomStateFinished, RoomStateResetting:
+		if len(snapshot.Players) != 2 {
+			return fmt.Errorf("room state = %s with %d players, want 2", snapshot.RoomState, len(snapshot.Players))
+		}
+	default:
+		return fmt.Errorf("room state = %q, want a known state", snapshot.RoomState)
+	}
+	if snapshot.IsActive != (snapshot.RoomState == RoomStatePlaying) {
+		return fmt.Errorf("active = %t in room state %s", snapshot.IsActive, snapshot.RoomState)
+	}
+
+	if hostID := snapshot.Settings.HostID; hostID != "" && !seenPlayers[hostID] {
+		return fmt.Errorf("host %q is not in the room", hostID)
+	}
+	if takeback := snapshot.Takeback; takeback.Pending && !seenPlayers[takeback.RequestedBy] {
+		return fmt.Errorf("takeback requested by %q who is not in the room", takeback.RequestedBy)
+	}
+
+	if snapshot.TicTacToe != nil {
+		return validateTicTacToeSnapshot(snapshot)
+	}
+	if snapshot.Chess != nil {
+		return validateChessSnapshot(snapshot)
+	}
+	return nil
+}
+
+func validateTicTacToeSnapshot(snapshot RoomSnapshot) error {
+	state := snapshot.TicTacToe
+	for _, player := range snapshot.Players {
+		if player.Mark != "X" && player.Mark != "O" {
+			return fmt.Errorf("player mark = %q, want X or O", player.Mark)
+		}
+	}
+	if state.Turn != "X" && state.Turn != "O" {
+		return fmt.Errorf("turn = %q, want X or O", state.Turn)
+	}
+	if !isAllowedTicTacToeStatus(state.Status) {
+		return fmt.Errorf("status = %q, want allowed TicTacToe status", state.Status)
+	}
+
+	var xCount, oCount int
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			switch cell := state.Board[row][col]; cell {
+			case "":
+			case "X":
+				xCount++
+			case "O":
+				oCount++
+			default:
+				return fmt.Errorf("board[%d][%d] = %q, want empty, X, or O", row, col, cell)
+			}
+		}
+	}
+	// X always opens, so the players take turns from an X move.
+	if xCount != oCount && xCount != oCount+1 {
+		return fmt.Errorf("board has %d X and %d O, want X to lead by at most one", xCount, oCount)
+	}
+	if state.Status == tictactoe.StatusActive && (state.Turn == "X") != (xCount == oCount) {
+		return fmt.Errorf("turn = %s with %d X and %d O on the board", state.Turn, xCount, oCount)
+	}
+	if snapshot.RoomState == RoomStatePlaying && state.Status != tictactoe.StatusActive {
+		return fmt.Errorf("status = %q while the room is PLAYING", state.Status)
+	}
+	return nil
+}
+
+func validateChessSnapshot(snapshot RoomSnapshot) error {
+	state := snapshot.Chess
+	marks := make(map[string]string, len(snapshot.Players))
+	for _, player := range snapshot.Players {
+		if player.Mark != "white" && player.Mark != "black" {
+			return fmt.Errorf("player mark = %q, want white or black", player.Mark)
+		}
+		marks[player.ID] = player.Mark
+	}
+	if state.AI.Thinking && !snapshot.IsAIEnabled {
+		return fmt.Errorf("AI thinking in a room without AI")
+	}
+	for playerID := range state.Premoves {
+		mark, exists := marks[playerID]
+		if !exists {
+			return fmt.Errorf("premove of %q who is not in the room", playerID)
+		}
+		if mark == state.Turn {
+			return fmt.Errorf("premove of %q left queued on their own turn", playerID)
+		}
+	}
+	return nil
+}
+
+func isAllowedTicTacToeStatus(status tictactoe.GameStatus) bool {
+	return status == tictactoe.StatusWaiting ||
+		status == tictactoe.StatusActive ||
+		status == tictactoe.StatusEnded
+}
@@ -0,0 +1,81 @@
+package game
+
+import (
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/tictactoe"
+)
+
+// GameOutcome describes a finished game. It is reported once per finished
+// game, before the room schedules its automatic reset.
+type GameOutcome struct {
+	RoomID     string
+	GameType   string
+	PlayerIDs  map[string]string // mark -> player ID
+	WinnerID   string
+	Draw       bool
+	AIPlayerID string
+	AILevel    int
+	Moves      int
+	// PGNMoves is the SAN move list of chess games.
+	PGNMoves   []string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+func (r *Room) SetOutcomeNotifier(notifier func(GameOutcome)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.outcomeNotifier = notifier
+}
+
+// reportOutcomeLocked hands the outcome of the game that just finished to the
+// outcome notifier. The notifier runs on its own goroutine because callers
+// hold mu.
+func (r *Room) reportOutcomeLocked() {
+	if r.outcomeNotifier == nil {
+		return
+	}
+
+	outcome := GameOutcome{
+		RoomID:     r.RoomID,
+		GameType:   r.gameType,
+		PlayerIDs:  make(map[string]string, len(r.players)),
+		FinishedAt: time.Now().UTC(),
+	}
+	outcome.StartedAt = r.gameStartedAt
+	for _, player := range r.players {
+		outcome.PlayerIDs[player.Mark] = player.ID
+		if player.IsAI {
+			outcome.AIPlayerID = player.ID
+			outcome.AILevel = r.aiLevel
+		}
+	}
+
+	winnerMark := ""
+	switch r.gameType {
+	case "tictactoe":
+		if r.ticTacToe != nil {
+			outcome.Moves = len(r.ticTacToe.History)
+			winnerMark = r.ticTacToe.Winner
+			if r.ticTacToe.Status == tictactoe.StatusEnded && winnerMark == "Draw" {
+				winnerMark = "draw"
+			}
+		}
+	case "chess":
+		if r.chess != nil {
+			outcome.Moves = r.chess.Ply()
+			outcome.PGNMoves = append([]string(nil), r.chess.PGNMoves()...)
+			winnerMark = r.chess.Winner()
+		}
+	}
+
+	if winnerMark == "draw" || winnerMark == "" {
+		outcome.Draw = true
+	} else {
+		outcome.WinnerID = outcome.PlayerIDs[winnerMark]
+	}
+
+	go r.outcomeNotifier(outcome)
+}
@@ -2,6 +2,7 @@ package game
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"sync"
 	"time"
@@ -23,6 +24,9 @@ type Player struct {
 	IsAI       bool   `json:"is_ai"`
 	LastActive time.Time
 	Session    PlayerSessionStatus
+	// sessionNonce ties guest session tokens to this record, so a token
+	// outlives neither the player nor a later guest under the same name.
+	sessionNonce string
 }
 
 type PlayerManager struct {
@@ -70,6 +74,12 @@ func (pm *PlayerManager) RemoveInactivePlayers(ctx context.Context, duration, ti
 // AddPlayer adds a new player to the manager.
 // Returns an error if the player already exists.
 func (pm *PlayerManager) AddPlayer(playerID string) (PlayerSnapshot, error) {
+	return pm.AddPlayerWithSession(playerID, "")
+}
+
+// AddPlayerWithSession adds a new player whose sessions must carry nonce,
+// see CheckSession. A player added without a nonce has no guest sessions.
+func (pm *PlayerManager) AddPlayerWithSession(playerID string, nonce string) (PlayerSnapshot, error) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
@@ -79,9 +89,10 @@ func (pm *PlayerManager) AddPlayer(playerID string) (PlayerSnapshot, error) {
 	}
 
 	player := &Player{
-		ID:         playerID,
-		LastActive: time.Now(),
-		Session:    PlayerSessionDisconnected,
+		ID:           playerID,
+		LastActive:   time.Now(),
+		Session:      PlayerSessionDisconnected,
+		sessionNonce: nonce,
 	}
 	pm.players[playerID] = player
 	return playerSnapshot(player), nil
@@ -100,6 +111,31 @@ func (pm *PlayerManager) GetPlayer(playerID string) (PlayerSnapshot, error) {
 	return playerSnapshot(player), nil
 }
 
+// CheckSession reports whether a session carrying nonce belongs to the
+// player.
+func (pm *PlayerManager) CheckSession(playerID string, nonce string) bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	player, exists := pm.players[playerID]
+	if !exists || nonce == "" || player.sessionNonce == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(player.sessionNonce), []byte(nonce)) == 1
+}
+
+// SessionNonce returns the nonce the player's sessions carry, empty for a
+// player without guest sessions, so a restored or relayed record keeps it.
+func (pm *PlayerManager) SessionNonce(playerID string) string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	if player, exists := pm.players[playerID]; exists {
+		return player.sessionNonce
+	}
+	return ""
+}
+
 // RemovePlayer removes a player by their ID.
 // It deletes the player from the manager's tracking.
 func (pm *PlayerManager) RemovePlayer(playerID string) {
@@ -0,0 +1,44 @@
+package game
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrPremoveNotSupported = errors.New("premove is only supported for chess")
+	ErrPremoveNotFound     = errors.New("no premove queued")
+)
+
+// chessPremove is a move queued by a player while it is the opponent's turn.
+// It is validated and played in the same critical section as the opponent's
+// move, so it can never race the AI move delay or a second human move.
+type chessPremove struct {
+	From      string
+	To        string
+	Promotion string
+	QueuedAt  time.Time
+}
+
+type ChessPremoveSnapshot struct {
+	From      string
+	To        string
+	Promotion string
+	QueuedAt  time.Time
+}
+
+type ChessPremoveResult struct {
+	// Applied is true when it was already the player's turn and the premove
+	// was played immediately as a regular move.
+	Applied bool
+	Move    *ChessMoveResult
+}
+
+func chessPremoveSnapshot(premove chessPremove) ChessPremoveSnapshot {
+	return ChessPremoveSnapshot{
+		From:      premove.From,
+		To:        premove.To,
+		Promotion: premove.Promotion,
+		QueuedAt:  premove.QueuedAt,
+	}
+}
@@ -0,0 +1,157 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+func newChessPremoveTestRoom(t *testing.T) *Room {
+	t.Helper()
+
+	room, err := NewRoom("chess-premove", "chess")
+	if err != nil {
+		t.Fatalf("NewRoom() error = %v", err)
+	}
+	addPlayerToRoomForTest(t, room, "p1")
+	addPlayerToRoomForTest(t, room, "p2")
+	return room
+}
+
+func TestRoom_QueueChessPremove_PlayedAfterOpponentMove(t *testing.T) {
+	room := newChessPremoveTestRoom(t)
+
+	before := room.Snapshot().StateVersion
+	result, err := room.QueueChessPremove("p2", "e7", "e5", "")
+	if err != nil {
+		t.Fatalf("QueueChessPremove() error = %v", err)
+	}
+	if result.Applied {
+		t.Fatalf("Applied = true, want premove queued for opponent turn")
+	}
+
+	queued := room.Snapshot()
+	if queued.StateVersion != before {
+		t.Fatalf("state version after premove = %d, want %d: the opponent must not see it", queued.StateVersion, before)
+	}
+	if premove, ok := queued.Chess.Premoves["p2"]; !ok || premove.From != "e7" || premove.To != "e5" {
+		t.Fatalf("premoves = %+v, want p2 e7e5", queued.Chess.Premoves)
+	}
+
+	if _, err := room.HandleChessMove("p1", "e2", "e4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+
+	snapshot := room.Snapshot()
+	if moves := len(snapshot.Chess.PGNMoves); moves != 2 {
+		t.Fatalf("PGN moves len = %d, want white move + premove", moves)
+	}
+	if snapshot.Chess.Turn != "white" {
+		t.Fatalf("turn = %q, want white after premove", snapshot.Chess.Turn)
+	}
+	if len(snapshot.Chess.Premoves) != 0 {
+		t.Fatalf("premoves = %+v, want consumed premove", snapshot.Chess.Premoves)
+	}
+	if snapshot.Chess.LastMove == nil || snapshot.Chess.LastMove.Actor.PlayerID != "p2" {
+		t.Fatalf("last move = %+v, want premove by p2", snapshot.Chess.LastMove)
+	}
+}
+
+func TestRoom_QueueChessPremove_IllegalPremoveIsDiscarded(t *testing.T) {
+	room := newChessPremoveTestRoom(t)
+
+	if _, err := room.QueueChessPremove("p2", "e7", "e4", ""); err != nil {
+		t.Fatalf("QueueChessPremove() error = %v", err)
+	}
+	if _, err := room.HandleChessMove("p1", "d2", "d4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+
+	snapshot := room.Snapshot()
+	if moves := len(snapshot.Chess.PGNMoves); moves != 1 {
+		t.Fatalf("PGN moves len = %d, want white move only", moves)
+	}
+	if snapshot.Chess.Turn != "black" {
+		t.Fatalf("turn = %q, want black after discarded premove", snapshot.Chess.Turn)
+	}
+	if len(snapshot.Chess.Premoves) != 0 {
+		t.Fatalf("premoves = %+v, want discarded premove", snapshot.Chess.Premoves)
+	}
+}
+
+func TestRoom_QueueChessPremove_OwnTurnPlaysImmediately(t *testing.T) {
+	room := newChessPremoveTestRoom(t)
+
+	result, err := room.QueueChessPremove("p1", "e2", "e4", "")
+	if err != nil {
+		t.Fatalf("QueueChessPremove() error = %v", err)
+	}
+	if !result.Applied || result.Move == nil {
+		t.Fatalf("result = %+v, want premove applied as a move", result)
+	}
+
+	snapshot := room.Snapshot()
+	if moves := len(snapshot.Chess.PGNMoves); moves != 1 {
+		t.Fatalf("PGN moves len = %d, want 1", moves)
+	}
+}
+
+func TestRoom_QueueChessPremove_RejectsOpponentPiece(t *testing.T) {
+	room := newChessPremoveTestRoom(t)
+
+	before := room.Snapshot().StateVersion
+	if _, err := room.QueueChessPremove("p2", "e2", "e4", ""); err == nil {
+		t.Fatalf("QueueChessPremove() error = nil, want invalid premove piece")
+	}
+	if after := room.Snapshot().StateVersion; after != before {
+		t.Fatalf("state version after rejected premove = %d, want %d", after, before)
+	}
+}
+
+func TestRoom_CancelChessPremove_RemovesQueuedPremove(t *testing.T) {
+	room := newChessPremoveTestRoom(t)
+
+	if err := room.CancelChessPremove("p2"); !errors.Is(err, ErrPremoveNotFound) {
+		t.Fatalf("CancelChessPremove() error = %v, want %v", err, ErrPremoveNotFound)
+	}
+	if _, err := room.QueueChessPremove("p2", "e7", "e5", ""); err != nil {
+		t.Fatalf("QueueChessPremove() error = %v", err)
+	}
+	before := room.Snapshot().StateVersion
+	if err := room.CancelChessPremove("p2"); err != nil {
+		t.Fatalf("CancelChessPremove() error = %v", err)
+	}
+	if after := room.Snapshot().StateVersion; after != before {
+		t.Fatalf("state version after cancel = %d, want %d", after, before)
+	}
+	if _, err := room.HandleChessMove("p1", "e2", "e4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+
+	if moves := len(room.Snapshot().Chess.PGNMoves); moves != 1 {
+		t.Fatalf("PGN moves len = %d, want cancelled premove not played", moves)
+	}
+}
+
+func TestRoom_CancelChessPremove_KeepsPendingTakeback(t *testing.T) {
+	room := newChessPremoveTestRoom(t)
+
+	if _, err := room.HandleChessMove("p1", "e2", "e4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+	if _, err := room.HandleChessMove("p2", "e7", "e5", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+	if _, err := room.QueueChessPremove("p2", "g8", "f6", ""); err != nil {
+		t.Fatalf("QueueChessPremove() error = %v", err)
+	}
+	if _, err := room.RequestTakeback("p1"); err != nil {
+		t.Fatalf("RequestTakeback() error = %v", err)
+	}
+	if err := room.CancelChessPremove("p2"); err != nil {
+		t.Fatalf("CancelChessPremove() error = %v", err)
+	}
+
+	if takeback := room.Snapshot().Takeback; !takeback.Pending || takeback.RequestedBy != "p1" {
+		t.Fatalf("takeback = %+v, want p1's request still pending", takeback)
+	}
+}
@@ -0,0 +1,96 @@
+package game
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ReactionCooldown is how long a player waits between two reactions.
+const ReactionCooldown = 2 * time.Second
+
+// ReactionCatalog lists the reactions players can send. Clients pick the
+// emote to show for each ID.
+var ReactionCatalog = []string{
+	"thumbs_up",
+	"clap",
+	"laugh",
+	"wow",
+	"thinking",
+	"sad",
+	"angry",
+	"good_game",
+}
+
+var (
+	ErrUnknownReaction  = errors.New("unknown reaction")
+	ErrReactionCooldown = errors.New("reactions are cooling down")
+)
+
+// Reaction is a quick emote sent to the room. Reactions are not kept: a
+// player who was not connected never sees them.
+type Reaction struct {
+	RoomID     string
+	PlayerID   string
+	PlayerMark string
+	ReactionID string
+	CreatedAt  time.Time
+	// Audience lists the players shown the reaction: everyone in the room
+	// who has not muted the sender.
+	Audience []string
+}
+
+func IsReaction(reactionID string) bool {
+	for _, id := range ReactionCatalog {
+		if id == reactionID {
+			return true
+		}
+	}
+	return false
+}
+
+// SendReaction checks a reaction from playerID against the catalog and the
+// player's cooldown.
+func (r *Room) SendReaction(playerID string, reactionID string) (Reaction, error) {
+	if !IsReaction(reactionID) {
+		return Reaction{}, ErrUnknownReaction
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	player, exists := r.players[playerID]
+	if !exists {
+		return Reaction{}, ErrPlayerNotFound
+	}
+	now := time.Now().UTC()
+	if last, sent := r.lastReactions[playerID]; sent && now.Sub(last) < ReactionCooldown {
+		return Reaction{}, ErrReactionCooldown
+	}
+	r.lastReactions[playerID] = now
+
+	return Reaction{
+		RoomID:     r.RoomID,
+		PlayerID:   player.ID,
+		PlayerMark: player.Mark,
+		ReactionID: reactionID,
+		CreatedAt:  now,
+		Audience:   r.listenersLocked(playerID, true),
+	}, nil
+}
+
+// listenersLocked returns the players who have not muted senderID, sorted,
+// leaving the sender out unless includeSender.
+func (r *Room) listenersLocked(senderID string, includeSender bool) []string {
+	listeners := make([]string, 0, len(r.players))
+	for playerID := range r.players {
+		if playerID == senderID && !includeSender {
+			continue
+		}
+		if _, muted := r.mutes[playerID][senderID]; !muted {
+			listeners = append(listeners, playerID)
+		}
+	}
+	sort.Strings(listeners)
+	return listeners
+}
@@ -0,0 +1,33 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRoom_SendReaction_ChecksCatalogAndCooldown(t *testing.T) {
+	room := newChatTestRoom(t)
+	if _, err := room.AddPlayer(PlayerSnapshot{ID: "p2"}); err != nil {
+		t.Fatalf("AddPlayer() error = %v", err)
+	}
+
+	if _, err := room.SendReaction("p1", "middle_finger"); !errors.Is(err, ErrUnknownReaction) {
+		t.Fatalf("SendReaction(unknown) error = %v, want %v", err, ErrUnknownReaction)
+	}
+	reaction, err := room.SendReaction("p1", "clap")
+	if err != nil {
+		t.Fatalf("SendReaction() error = %v", err)
+	}
+	if reaction.ReactionID != "clap" || len(reaction.Audience) != 2 {
+		t.Fatalf("reaction = %+v, want clap shown to both players", reaction)
+	}
+	if _, err := room.SendReaction("p1", "wow"); !errors.Is(err, ErrReactionCooldown) {
+		t.Fatalf("SendReaction() within cooldown error = %v, want %v", err, ErrReactionCooldown)
+	}
+	if _, err := room.SendReaction("p2", "wow"); err != nil {
+		t.Fatalf("SendReaction(p2) error = %v, want the cooldown to be per player", err)
+	}
+	if history := room.ChatHistory(); len(history) != 0 {
+		t.Fatalf("chat history = %+v, want reactions kept out of it", history)
+	}
+}
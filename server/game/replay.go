@@ -0,0 +1,301 @@
+package game
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ReplayViolation is a point where a replayed room parted from its audit
+// log or broke a rule it keeps between actions.
+type ReplayViolation struct {
+	// Index is the position of the event in the log, 0 being AuditOpen.
+	Index   int         `json:"index"`
+	Action  AuditAction `json:"action"`
+	Problem string      `json:"problem"`
+}
+
+// ReplayReport is what ReplayRoom rebuilt from a log.
+type ReplayReport struct {
+	RoomID string
+	// Replayed counts the events applied to the room. Skipped counts the
+	// rejected commands that left it unchanged, which are not applied again:
+	// some were turned away on what the log does not hold, such as a room
+	// password or the state version a client expected.
+	Replayed int
+	Skipped  int
+	// RestoredAt is the index of the AuditOpen a restore after a restart
+	// logged for the room, where replay stopped, or 0 when there is none.
+	// What the room was restored from is not in the log.
+	RestoredAt int
+	Snapshot   RoomSnapshot
+	Violations []ReplayViolation
+}
+
+// ReplayRoom rebuilds a room from its audit log by applying every event
+// through the methods that first produced it, and returns the room it ends
+// with. Scheduled work is taken from the log rather than from timers, and AI
+// moves are played as recorded, so no engine runs and the result is the same
+// every time.
+//
+// Each event the rebuilt room records is compared with the logged one, and
+// the room is checked after each, so the report pinpoints where a bug first
+// shows. An error means the log cannot be replayed at all: it must hold a
+// single room from its creation on. A room restored after a restart on the
+// node that logged it is replayed up to the restore.
+func ReplayRoom(events []AuditEvent) (ReplayReport, error) {
+	if len(events) == 0 {
+		return ReplayReport{}, errors.New("audit log has no events")
+	}
+	open := events[0]
+	if open.Action != AuditOpen {
+		return ReplayReport{}, fmt.Errorf("audit log starts with %s, want %s", open.Action, AuditOpen)
+	}
+	if open.VersionBefore != 0 || open.StateBefore != RoomStateWaiting {
+		return ReplayReport{}, fmt.Errorf("room %s opened at version %d in state %s, restored after a restart; only a log from its creation can be replayed",
+			open.RoomID, open.VersionBefore, open.StateBefore)
+	}
+	for _, event := range events[1:] {
+		if event.RoomID != open.RoomID {
+			return ReplayReport{}, fmt.Errorf("audit log mixes rooms %s and %s", open.RoomID, event.RoomID)
+		}
+	}
+
+	room, err := NewRoom(open.RoomID, open.GameType)
+	if err != nil {
+		return ReplayReport{}, err
+	}
+	defer room.Close()
+	room.replay = true
+	if err := room.Configure(RoomSettings{Visibility: open.Visibility}); err != nil {
+		return ReplayReport{}, err
+	}
+	if open.AILevel > 0 {
+		if err := room.EnableAILevel(open.AILevel); err != nil {
+			return ReplayReport{}, err
+		}
+	}
+
+	replay := &roomReplay{room: room, report: ReplayReport{RoomID: open.RoomID}}
+	room.SetAuditor(replay.record)
+	replay.checkOpen(open)
+	for i := 1; i < len(events); i++ {
+		if events[i].Action == AuditOpen {
+			replay.report.RestoredAt = i
+			break
+		}
+		replay.apply(i, events[i-1], events[i])
+	}
+
+	replay.report.Snapshot = room.Snapshot()
+	return replay.report, nil
+}
+
+type roomReplay struct {
+	room     *Room
+	report   ReplayReport
+	recorded []AuditEvent
+}
+
+// record is the auditor of the rebuilt room. ReplayRoom drives the room from
+// a single goroutine, so it needs no lock of its own.
+func (r *roomReplay) record(event AuditEvent) {
+	r.recorded = append(r.recorded, event)
+}
+
+func (r *roomReplay) violation(index int, action AuditAction, format string, args ...any) {
+	r.report.Violations = append(r.report.Violations, ReplayViolation{
+		Index:   index,
+		Action:  action,
+		Problem: fmt.Sprintf(format, args...),
+	})
+}
+
+func (r *roomReplay) checkOpen(open AuditEvent) {
+	got := r.recorded[len(r.recorded)-1]
+	if len(got.Players) != len(open.Players) {
+		r.violation(0, AuditOpen, "room opens with players %+v, log has %+v", got.Players, open.Players)
+		return
+	}
+	for i, player := range got.Players {
+		want := open.Players[i]
+		if player.ID != want.ID || player.Mark != want.Mark || player.IsAI != want.IsAI {
+			r.violation(0, AuditOpen, "room opens with players %+v, log has %+v", got.Players, open.Players)
+			return
+		}
+	}
+}
+
+func (r *roomReplay) apply(index int, previous AuditEvent, event AuditEvent) {
+	if event.VersionBefore != previous.VersionAfter {
+		r.violation(index, event.Action, "log jumps from version %d to %d; events are missing", previous.VersionAfter, event.VersionBefore)
+	}
+	if !event.Accepted && event.VersionAfter == event.VersionBefore {
+		r.report.Skipped++
+		return
+	}
+
+	r.recorded = r.recorded[:0]
+	if err := r.room.replayEvent(event); err != nil {
+		r.violation(index, event.Action, "%v", err)
+		return
+	}
+	r.report.Replayed++
+	if len(r.recorded) != 1 {
+		r.violation(index, event.Action, "room recorded %d events, want 1", len(r.recorded))
+		return
+	}
+
+	got := r.recorded[0]
+	switch {
+	case got.Action != event.Action:
+		r.violation(index, event.Action, "room recorded %s", got.Action)
+	case got.Accepted != event.Accepted:
+		r.violation(index, event.Action, "outcome = %s, log has %s", auditOutcome(got), auditOutcome(event))
+	case got.VersionAfter-got.VersionBefore != event.VersionAfter-event.VersionBefore:
+		r.violation(index, event.Action, "state version moved by %d, log has %d",
+			got.VersionAfter-got.VersionBefore, event.VersionAfter-event.VersionBefore)
+	case got.StateBefore != event.StateBefore || got.StateAfter != event.StateAfter:
+		r.violation(index, event.Action, "room went %s to %s, log has %s to %s",
+			got.StateBefore, got.StateAfter, event.StateBefore, event.StateAfter)
+	case got.Mark != event.Mark:
+		r.violation(index, event.Action, "player mark = %q, log has %q", got.Mark, event.Mark)
+	}
+
+	if err := validateRoomSnapshot(r.room.Snapshot()); err != nil {
+		r.violation(index, event.Action, "%v", err)
+	}
+}
+
+func auditOutcome(event AuditEvent) string {
+	if event.Accepted {
+		return "accepted"
+	}
+	return "rejected: " + event.Error
+}
+
+// replayEvent applies event to the room. What came of it is read from the
+// event the room records, so the results and errors of the commands are
+// dropped here; an error means the event could not be applied at all.
+func (r *Room) replayEvent(event AuditEvent) error {
+	switch event.Action {
+	case AuditJoin:
+		// The access checks were passed with a password or an invite the log
+		// does not hold.
+		_, _ = r.AddPlayer(PlayerSnapshot{ID: event.PlayerID, IsAI: event.IsAI})
+	case AuditEnableAI:
+		_ = r.EnableAILevel(event.AILevel)
+	case AuditLeave:
+		r.mu.Lock()
+		r.leaveLocked(event.PlayerID, event.Reason)
+		r.mu.Unlock()
+	case AuditKick:
+		_ = r.KickPlayer(event.PlayerID, event.TargetID)
+	case AuditRemove:
+		_ = r.RemovePlayer(event.TargetID)
+	case AuditMove:
+		return r.replayMove(event)
+	case AuditPremove:
+		if event.Move == nil {
+			return errors.New("premove has no move")
+		}
+		_, _ = r.QueueChessPremove(event.PlayerID, event.Move.From, event.Move.To, event.Move.Promotion)
+	case AuditPremoveCancel:
+		_ = r.CancelChessPremove(event.PlayerID)
+	case AuditUndo:
+		_ = r.HandleChessUndo(event.PlayerID)
+	case AuditTakebackRequest:
+		_, _ = r.RequestTakeback(event.PlayerID)
+	case AuditTakebackRespond:
+		_, _ = r.RespondTakeback(event.PlayerID, event.Accept != nil && *event.Accept)
+	case AuditChat:
+		_, _ = r.AddChatMessage(event.PlayerID, event.Message)
+	case AuditForceFinish:
+		_ = r.ForceFinish()
+	case AuditTakebackExpire, AuditAIThinking, AuditAIMove, AuditResetBegin, AuditReset:
+		return r.replayScheduled(event)
+	default:
+		return fmt.Errorf("unknown action %q", event.Action)
+	}
+	return nil
+}
+
+func (r *Room) replayMove(event AuditEvent) error {
+	move := event.Move
+	if move == nil {
+		return errors.New("move has no move")
+	}
+
+	switch r.GameType() {
+	case "tictactoe":
+		if move.Row == nil || move.Col == nil {
+			return errors.New("tictactoe move has no cell")
+		}
+		_, _ = r.HandleTicTacToeMove(event.PlayerID, *move.Row, *move.Col)
+	case "chess":
+		_, _ = r.HandleChessMove(event.PlayerID, move.From, move.To, move.Promotion)
+	}
+	return nil
+}
+
+// replayScheduled does the scheduled work of event, provided the room has
+// that work pending as its timer would have found it.
+func (r *Room) replayScheduled(event AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notPending := fmt.Errorf("room has no %s pending", event.Action)
+	switch event.Action {
+	case AuditResetBegin:
+		if r.resetCancel == nil || r.roomState != RoomStateFinished {
+			return notPending
+		}
+		_ = r.beginResetLocked()
+	case AuditReset:
+		if r.resetCancel == nil || r.roomState != RoomStateResetting {
+			return notPending
+		}
+		r.completeResetLocked()
+	case AuditTakebackExpire:
+		if r.takeback == nil {
+			return notPending
+		}
+		r.expireTakebackLocked()
+	case AuditAIThinking:
+		if r.aiMoveCancel != nil || !r.shouldApplyChessAIMoveLocked(event.PlayerID) {
+			return notPending
+		}
+		r.startChessAIMoveLocked(event.PlayerID)
+	case AuditAIMove:
+		return r.replayAIMoveLocked(event, notPending)
+	}
+	return nil
+}
+
+func (r *Room) replayAIMoveLocked(event AuditEvent, notPending error) error {
+	if r.gameType == "tictactoe" {
+		if r.aiMoveCancel == nil || !r.shouldScheduleTicTacToeAIMoveLocked() {
+			return notPending
+		}
+		if event.Move == nil || event.Move.Row == nil || event.Move.Col == nil {
+			return errors.New("tictactoe AI move has no cell")
+		}
+		version := r.aiMoveVersion
+		_ = r.playTicTacToeAIMoveLocked(event.PlayerID, *event.Move.Row, *event.Move.Col)
+		r.clearScheduledAIMoveLocked(version)
+		return nil
+	}
+
+	// A chess AI move is played for the move the room is thinking about, or
+	// for none when it has stopped.
+	version := r.aiMoveVersion
+	if r.aiMoveCancel == nil {
+		version++
+	}
+	if event.Move == nil {
+		r.abandonChessAIMoveLocked(version, event.PlayerID, errors.New(event.Error))
+		return nil
+	}
+	r.playChessAIMoveLocked(context.Background(), version, event.PlayerID, event.Move.From, event.Move.To, event.Move.Promotion)
+	return nil
+}
@@ -0,0 +1,257 @@
+package game
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func assertSamePlayers(t *testing.T, got []PlayerSnapshot, want []PlayerSnapshot) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("players = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Mark != want[i].Mark || got[i].IsAI != want[i].IsAI {
+			t.Fatalf("players = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func firstEmptyCell(t *testing.T, room *Room) (int, int) {
+	t.Helper()
+
+	board := room.Snapshot().TicTacToe.Board
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			if board[row][col] == "" {
+				return row, col
+			}
+		}
+	}
+	t.Fatalf("board %v is full", board)
+	return -1, -1
+}
+
+func TestReplayRoom_RebuildsTicTacToeRoom(t *testing.T) {
+	room := newTicTacToeRoomForTest(t)
+	room.SetResetDelays(10*time.Millisecond, 10*time.Millisecond)
+	recorder := &auditRecorder{}
+	room.SetAuditor(recorder.record)
+
+	addPlayerToRoomForTest(t, room, "p1")
+	addPlayerToRoomForTest(t, room, "p2")
+	if _, err := room.HandleTicTacToeMove("p2", 0, 0); err == nil {
+		t.Fatalf("HandleTicTacToeMove(p2 out of turn) error = nil, want error")
+	}
+	moves := []struct {
+		playerID string
+		row, col int
+	}{{"p1", 0, 0}, {"p2", 1, 0}, {"p1", 0, 1}, {"p2", 1, 1}, {"p1", 0, 2}}
+	for _, move := range moves {
+		if _, err := room.HandleTicTacToeMove(move.playerID, move.row, move.col); err != nil {
+			t.Fatalf("HandleTicTacToeMove(%s) error = %v", move.playerID, err)
+		}
+	}
+	recorder.waitFor(t, AuditReset, 250*time.Millisecond)
+
+	if _, err := room.HandleTicTacToeMove("p1", 1, 1); err != nil {
+		t.Fatalf("HandleTicTacToeMove() error = %v", err)
+	}
+	if _, err := room.RequestTakeback("p1"); err != nil {
+		t.Fatalf("RequestTakeback() error = %v", err)
+	}
+	if _, err := room.RespondTakeback("p2", true); err != nil {
+		t.Fatalf("RespondTakeback() error = %v", err)
+	}
+	if _, err := room.HandleTicTacToeMove("p1", 2, 2); err != nil {
+		t.Fatalf("HandleTicTacToeMove() error = %v", err)
+	}
+	if _, err := room.AddChatMessage("p2", "gg"); err != nil {
+		t.Fatalf("AddChatMessage() error = %v", err)
+	}
+
+	report, err := ReplayRoom(recorder.snapshot())
+	if err != nil {
+		t.Fatalf("ReplayRoom() error = %v", err)
+	}
+	if len(report.Violations) != 0 {
+		t.Fatalf("violations = %+v, want none", report.Violations)
+	}
+	if report.Skipped != 1 {
+		t.Fatalf("skipped = %d, want the rejected move", report.Skipped)
+	}
+
+	want, got := room.Snapshot(), report.Snapshot
+	if got.StateVersion != want.StateVersion || got.RoomState != want.RoomState {
+		t.Fatalf("replayed room = version %d %s, want version %d %s", got.StateVersion, got.RoomState, want.StateVersion, want.RoomState)
+	}
+	if *got.TicTacToe != *want.TicTacToe {
+		t.Fatalf("replayed board = %+v, want %+v", *got.TicTacToe, *want.TicTacToe)
+	}
+	if got.Takeback.Used["p1"] != 1 || got.Settings.HostID != "p1" {
+		t.Fatalf("replayed takeback = %+v host = %q, want p1's takeback used and p1 hosting", got.Takeback, got.Settings.HostID)
+	}
+	assertSamePlayers(t, got.Players, want.Players)
+}
+
+func TestReplayRoom_PlaysRecordedAIMoves(t *testing.T) {
+	room, err := NewRoomWithAILevel("ai-room", "tictactoe", 1)
+	if err != nil {
+		t.Fatalf("NewRoomWithAILevel() error = %v", err)
+	}
+	room.SetAIMoveDelay(time.Millisecond)
+	recorder := &auditRecorder{}
+	room.SetAuditor(recorder.record)
+
+	addPlayerToRoomForTest(t, room, "p1")
+	for filled := 2; filled <= 4; filled += 2 {
+		row, col := firstEmptyCell(t, room)
+		if _, err := room.HandleTicTacToeMove("p1", row, col); err != nil {
+			t.Fatalf("HandleTicTacToeMove() error = %v", err)
+		}
+		waitForFilledCells(t, room, filled, time.Second)
+	}
+
+	// The AI picks its cells at random, so the replay has to take them from
+	// the log to end on the same board.
+	report, err := ReplayRoom(recorder.snapshot())
+	if err != nil {
+		t.Fatalf("ReplayRoom() error = %v", err)
+	}
+	if len(report.Violations) != 0 {
+		t.Fatalf("violations = %+v, want none", report.Violations)
+	}
+	if want := room.Snapshot(); report.Snapshot.TicTacToe.Board != want.TicTacToe.Board || report.Snapshot.StateVersion != want.StateVersion {
+		t.Fatalf("replayed board = %v at version %d, want %v at version %d",
+			report.Snapshot.TicTacToe.Board, report.Snapshot.StateVersion, want.TicTacToe.Board, want.StateVersion)
+	}
+	assertSamePlayers(t, report.Snapshot.Players, room.Snapshot().Players)
+}
+
+// chessAILogForTest is the log of an AI chess room: the AI answers 1. e4 with
+// e5, then its engine fails on its second move.
+func chessAILogForTest() []AuditEvent {
+	event := func(action AuditAction, playerID string, before uint64, after uint64) AuditEvent {
+		return AuditEvent{
+			RoomID:        "chess-ai",
+			Action:        action,
+			PlayerID:      playerID,
+			Accepted:      true,
+			VersionBefore: before,
+			VersionAfter:  after,
+			StateBefore:   RoomStatePlaying,
+			StateAfter:    RoomStatePlaying,
+		}
+	}
+
+	open := event(AuditOpen, "", 0, 0)
+	open.StateBefore, open.StateAfter = RoomStateWaiting, RoomStateWaiting
+	open.GameType = "chess"
+	open.AILevel = 3
+	open.Visibility = RoomVisibilityPublic
+	open.Players = []PlayerRecord{{ID: "AI", Mark: "black", IsAI: true}}
+	join := event(AuditJoin, "p1", 0, 1)
+	join.StateBefore = RoomStateWaiting
+	join.Mark = "white"
+	move := event(AuditMove, "p1", 1, 2)
+	move.Move = chessAuditMove("e2", "e4", "")
+	reply := event(AuditAIMove, "AI", 3, 5)
+	reply.Move = chessAuditMove("e7", "e5", "")
+	secondMove := event(AuditMove, "p1", 5, 6)
+	secondMove.Move = chessAuditMove("g1", "f3", "")
+	failed := event(AuditAIMove, "AI", 7, 8)
+	failed.Accepted = false
+	failed.Error = "stockfish timed out"
+
+	return []AuditEvent{
+		open, join, move, event(AuditAIThinking, "AI", 2, 3), reply,
+		secondMove, event(AuditAIThinking, "AI", 6, 7), failed,
+	}
+}
+
+func TestReplayRoom_ChessAIWithoutEngine(t *testing.T) {
+	t.Setenv("STOCKFISH_PATH", "/nonexistent/stockfish")
+
+	report, err := ReplayRoom(chessAILogForTest())
+	if err != nil {
+		t.Fatalf("ReplayRoom() error = %v", err)
+	}
+	if len(report.Violations) != 0 {
+		t.Fatalf("violations = %+v, want none", report.Violations)
+	}
+	chess := report.Snapshot.Chess
+	if len(chess.PGNMoves) != 3 || chess.Turn != "black" || chess.AI.Thinking {
+		t.Fatalf("replayed chess = %v to move %s thinking %t, want 3 moves with black to move and the AI idle",
+			chess.PGNMoves, chess.Turn, chess.AI.Thinking)
+	}
+	if report.Snapshot.StateVersion != 8 || report.Replayed != 7 {
+		t.Fatalf("replayed %d events to version %d, want 7 events to version 8", report.Replayed, report.Snapshot.StateVersion)
+	}
+}
+
+func TestReplayRoom_StopsWhereTheRoomWasRestored(t *testing.T) {
+	events := chessAILogForTest()
+	restored := events[0]
+	restored.VersionBefore, restored.VersionAfter = 5, 5
+	restored.StateBefore, restored.StateAfter = RoomStatePlaying, RoomStatePlaying
+	restored.Players = []PlayerRecord{{ID: "p1", Mark: "white"}, {ID: "AI", Mark: "black", IsAI: true}}
+	events = append(events[:5:5], append([]AuditEvent{restored}, events[5:]...)...)
+
+	report, err := ReplayRoom(events)
+	if err != nil {
+		t.Fatalf("ReplayRoom() error = %v", err)
+	}
+	if len(report.Violations) != 0 {
+		t.Fatalf("violations = %+v, want none", report.Violations)
+	}
+	if report.RestoredAt != 5 || report.Replayed != 4 || report.Snapshot.StateVersion != 5 {
+		t.Fatalf("replayed %d events to version %d, restored at %d; want 4 events to version 5, restored at 5",
+			report.Replayed, report.Snapshot.StateVersion, report.RestoredAt)
+	}
+}
+
+func TestReplayRoom_ReportsWhereTheRoomDiverges(t *testing.T) {
+	events := chessAILogForTest()
+	events[4].Move = chessAuditMove("e7", "e4", "")
+
+	report, err := ReplayRoom(events)
+	if err != nil {
+		t.Fatalf("ReplayRoom() error = %v", err)
+	}
+	if len(report.Violations) == 0 {
+		t.Fatalf("violations = none, want the illegal AI move reported")
+	}
+	if first := report.Violations[0]; first.Index != 4 || first.Action != AuditAIMove || !strings.Contains(first.Problem, "outcome = rejected") {
+		t.Fatalf("first violation = %+v, want the AI move at 4 rejected", first)
+	}
+
+	events = append(chessAILogForTest()[:3], chessAILogForTest()[4:]...)
+	report, err = ReplayRoom(events)
+	if err != nil {
+		t.Fatalf("ReplayRoom() error = %v", err)
+	}
+	if len(report.Violations) == 0 || !strings.Contains(report.Violations[0].Problem, "jumps from version 2 to 3") {
+		t.Fatalf("violations = %+v, want the missing ai_thinking reported", report.Violations)
+	}
+}
+
+func TestReplayRoom_NeedsLogFromCreation(t *testing.T) {
+	events := chessAILogForTest()
+	if _, err := ReplayRoom(events[1:]); err == nil {
+		t.Fatalf("ReplayRoom(no open) error = nil, want error")
+	}
+
+	restored := events[0]
+	restored.VersionBefore, restored.VersionAfter = 12, 12
+	restored.StateBefore, restored.StateAfter = RoomStatePlaying, RoomStatePlaying
+	if _, err := ReplayRoom([]AuditEvent{restored}); err == nil || !strings.Contains(err.Error(), "restored") {
+		t.Fatalf("ReplayRoom(restored room) error = %v, want restored room rejected", err)
+	}
+
+	events[5].RoomID = "other"
+	if _, err := ReplayRoom(events); err == nil {
+		t.Fatalf("ReplayRoom(two rooms) error = nil, want error")
+	}
+}
@@ -3,11 +3,14 @@ package game
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/tsaqiffatih/mini-game/chess"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
 	"github.com/tsaqiffatih/mini-game/tictactoe"
 )
 
@@ -44,15 +47,39 @@ type Room struct {
 	// snapshots. Increment it only while holding mu and only after mutations
 	// that change the authoritative state a client should reconcile: players,
 	// room lifecycle, game board/chess state, AI thinking, resets, and undo.
-	// Do not increment it for rejected/no-op actions or activity timestamps.
+	// Do not increment it for rejected/no-op actions, activity timestamps, or
+	// queued premoves, which only their owner sees.
 	stateVersion       uint64
 	stockfish          *StockfishEngine
 	finishedResetDelay time.Duration
 	resettingDelay     time.Duration
 	resetCancel        context.CancelFunc
 	resetVersion       uint64
-	stateNotifier      func(RoomSnapshot)
+	stateNotifier      func(context.Context, RoomSnapshot)
+	outcomeNotifier    func(GameOutcome)
+	gameStartedAt      time.Time
 	chatMessages       []ChatMessage
+	chatHistory        int
+	chessPremoves      map[string]chessPremove
+	takeback           *takebackRequest
+	takebackCancel     context.CancelFunc
+	takebackVersion    uint64
+	takebackTimeout    time.Duration
+	takebackLimit      int
+	takebacksUsed      map[string]int
+	visibility         RoomVisibility
+	passwordHash       []byte
+	hostID             string
+	usedInvites        map[string]time.Time
+	kicked             map[string]struct{}
+	mutes              map[string]map[string]struct{}
+	lastReactions      map[string]time.Time
+	typing             map[string]*typingIndicator
+	typingTimeout      time.Duration
+	typingNotifier     func(context.Context, TypingState)
+	auditor            func(AuditEvent)
+	replay             bool // no timers or engine, see ReplayRoom
+	createdAt          time.Time
 	mu                 sync.RWMutex
 }
 
@@ -98,6 +125,9 @@ type ChessStateSnapshot struct {
 	LegalMoves     map[string][]string
 	AI             ChessAISnapshot
 	Undo           ChessUndoSnapshot
+	// Premoves is keyed by player ID. It is private per player and must only
+	// be exposed to the player who queued the premove.
+	Premoves map[string]ChessPremoveSnapshot
 }
 
 type ChessAISnapshot struct {
@@ -109,7 +139,10 @@ type ChessAISnapshot struct {
 }
 
 type ChessUndoSnapshot struct {
+	// CanRequest is true when some player in the room may take back a move
+	// now. RequestableBy says which ones, for snapshots sent to one player.
 	CanRequest      bool
+	RequestableBy   map[string]bool
 	CanUndoNow      bool
 	LastUndoablePly int
 	Pending         string
@@ -126,18 +159,33 @@ type RoomSnapshot struct {
 	Players      []PlayerSnapshot
 	TicTacToe    *TicTacToeStateSnapshot
 	Chess        *ChessStateSnapshot
+	Takeback     TakebackSnapshot
+	Settings     RoomSettingsSnapshot
 }
 
 func NewRoom(roomID string, gameType string) (*Room, error) {
 	room := &Room{
 		RoomID:             roomID,
 		players:            make(map[string]*Player),
+		chessPremoves:      make(map[string]chessPremove),
 		gameType:           gameType,
 		roomState:          RoomStateWaiting,
 		aiLevel:            DefaultAILevel,
 		aiMoveDelay:        DefaultAIMoveDelay,
 		finishedResetDelay: DefaultFinishedResetDelay,
 		resettingDelay:     DefaultResettingDelay,
+		takebackTimeout:    DefaultTakebackTimeout,
+		takebackLimit:      DefaultTakebackLimit,
+		takebacksUsed:      make(map[string]int),
+		chatHistory:        DefaultChatHistory,
+		visibility:         RoomVisibilityPublic,
+		usedInvites:        make(map[string]time.Time),
+		kicked:             make(map[string]struct{}),
+		mutes:              make(map[string]map[string]struct{}),
+		lastReactions:      make(map[string]time.Time),
+		typing:             make(map[string]*typingIndicator),
+		typingTimeout:      DefaultTypingTimeout,
+		createdAt:          time.Now().UTC(),
 	}
 
 	switch gameType {
@@ -164,6 +212,18 @@ func (r *Room) SetResetDelays(finishedDelay time.Duration, resettingDelay time.D
 	}
 }
 
+func (r *Room) SetTakebackPolicy(timeout time.Duration, limit int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if timeout > 0 {
+		r.takebackTimeout = timeout
+	}
+	if limit >= 0 {
+		r.takebackLimit = limit
+	}
+}
+
 func (r *Room) SetAIMoveDelay(delay time.Duration) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -173,7 +233,51 @@ func (r *Room) SetAIMoveDelay(delay time.Duration) {
 	}
 }
 
-func (r *Room) SetStateNotifier(notifier func(RoomSnapshot)) {
+// SetChatHistory sets how many chat messages the room keeps.
+func (r *Room) SetChatHistory(limit int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if limit > 0 {
+		r.chatHistory = limit
+	}
+}
+
+// RoomConfig holds the timings and limits a room runs with.
+type RoomConfig struct {
+	FinishedResetDelay time.Duration
+	ResettingDelay     time.Duration
+	AIMoveDelay        time.Duration
+	TakebackTimeout    time.Duration
+	TakebackLimit      int
+	TypingTimeout      time.Duration
+	ChatHistory        int
+}
+
+// DefaultRoomConfig is what a new room runs with.
+func DefaultRoomConfig() RoomConfig {
+	return RoomConfig{
+		FinishedResetDelay: DefaultFinishedResetDelay,
+		ResettingDelay:     DefaultResettingDelay,
+		AIMoveDelay:        DefaultAIMoveDelay,
+		TakebackTimeout:    DefaultTakebackTimeout,
+		TakebackLimit:      DefaultTakebackLimit,
+		TypingTimeout:      DefaultTypingTimeout,
+		ChatHistory:        DefaultChatHistory,
+	}
+}
+
+// ApplyConfig applies config through the setters, so a value they reject
+// leaves the room's current one.
+func (r *Room) ApplyConfig(config RoomConfig) {
+	r.SetResetDelays(config.FinishedResetDelay, config.ResettingDelay)
+	r.SetAIMoveDelay(config.AIMoveDelay)
+	r.SetTakebackPolicy(config.TakebackTimeout, config.TakebackLimit)
+	r.SetTypingTimeout(config.TypingTimeout)
+	r.SetChatHistory(config.ChatHistory)
+}
+
+func (r *Room) SetStateNotifier(notifier func(context.Context, RoomSnapshot)) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -271,11 +375,17 @@ func (r *Room) Snapshot() RoomSnapshot {
 		IsAIEnabled:  r.isAIEnabled,
 		AILevel:      r.aiLevel,
 		Players:      make([]PlayerSnapshot, 0, len(r.players)),
+		Takeback:     r.takebackSnapshotLocked(),
+		Settings:     r.settingsSnapshotLocked(),
 	}
 
 	for _, player := range r.players {
 		snapshot.Players = append(snapshot.Players, playerSnapshot(player))
 	}
+	// A stable order keeps snapshot deltas from resending the players.
+	sort.Slice(snapshot.Players, func(i, j int) bool {
+		return snapshot.Players[i].ID < snapshot.Players[j].ID
+	})
 
 	if r.ticTacToe != nil {
 		snapshot.TicTacToe = &TicTacToeStateSnapshot{
@@ -305,16 +415,26 @@ func (r *Room) Snapshot() RoomSnapshot {
 			LegalMoves:     r.chess.LegalMoves(),
 			AI:             ai,
 			Undo: ChessUndoSnapshot{
-				CanRequest:      r.isAIEnabled && r.chess.CanUndoAI(),
+				RequestableBy:   r.chessTakebackRequestableByLocked(),
 				CanUndoNow:      r.isAIEnabled && r.chess.CanUndoAI(),
 				LastUndoablePly: r.chess.LastUndoablePly(),
+				Pending:         r.pendingTakebackRequesterLocked(),
 			},
+			Premoves: r.chessPremovesSnapshotLocked(),
 		}
+		snapshot.Chess.Undo.CanRequest = len(snapshot.Chess.Undo.RequestableBy) > 0
 	}
 
 	return snapshot
 }
 
+func (r *Room) CreatedAt() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.createdAt
+}
+
 func (r *Room) GameType() string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -322,10 +442,18 @@ func (r *Room) GameType() string {
 	return r.gameType
 }
 
+func (r *Room) State() RoomState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.roomState
+}
+
 func (r *Room) Close() {
 	r.mu.Lock()
 	r.cancelScheduledResetLocked()
 	r.cancelScheduledAIMoveLocked()
+	r.clearTakebackLocked()
 	stockfish := r.stockfish
 	r.stockfish = nil
 	r.mu.Unlock()
@@ -340,6 +468,7 @@ func (r *Room) transitionLocked(next RoomState) error {
 	case RoomStateWaiting:
 		if next == RoomStatePlaying {
 			r.roomState = next
+			r.gameStartedAt = time.Now().UTC()
 			return nil
 		}
 	case RoomStatePlaying:
@@ -355,6 +484,7 @@ func (r *Room) transitionLocked(next RoomState) error {
 	case RoomStateResetting:
 		if next == RoomStatePlaying {
 			r.roomState = next
+			r.gameStartedAt = time.Now().UTC()
 			return nil
 		}
 	}
@@ -378,6 +508,9 @@ func (r *Room) scheduleResetLocked() {
 	finishedDelay := r.finishedResetDelay
 	resettingDelay := r.resettingDelay
 
+	if r.replay {
+		return
+	}
 	go r.runScheduledReset(ctx, version, finishedDelay, resettingDelay)
 }
 
@@ -386,29 +519,55 @@ func (r *Room) runScheduledReset(ctx context.Context, version uint64, finishedDe
 		return
 	}
 
+	spanCtx, endSpan := r.startSpan(ctx, "room.reset_begin")
 	r.mu.Lock()
 	if ctx.Err() != nil || version != r.resetVersion || r.roomState != RoomStateFinished {
 		r.mu.Unlock()
+		endSpan(nil)
 		return
 	}
-	if err := r.transitionLocked(RoomStateResetting); err != nil {
+	if err := r.beginResetLocked(); err != nil {
 		r.mu.Unlock()
+		endSpan(err)
 		return
 	}
-	r.bumpStateVersionLocked()
 	r.mu.Unlock()
-	r.notifyStateChanged()
+	r.notifyStateChanged(spanCtx)
+	endSpan(nil)
 
 	if !waitForDelay(ctx, resettingDelay) {
 		return
 	}
 
+	spanCtx, endSpan = r.startSpan(ctx, "room.reset_complete")
+	defer endSpan(nil)
 	r.mu.Lock()
 	if ctx.Err() != nil || version != r.resetVersion || r.roomState != RoomStateResetting {
 		r.mu.Unlock()
 		return
 	}
 
+	r.completeResetLocked()
+	r.mu.Unlock()
+	r.notifyStateChanged(spanCtx)
+}
+
+// beginResetLocked moves a finished room into RESETTING, the first step of
+// a scheduled reset.
+func (r *Room) beginResetLocked() error {
+	event := r.beginAuditLocked(AuditResetBegin, "")
+	err := r.transitionLocked(RoomStateResetting)
+	if err == nil {
+		r.bumpStateVersionLocked()
+	}
+	r.recordAuditLocked(event, err)
+	return err
+}
+
+// completeResetLocked starts the next game of a resetting room, or leaves it
+// waiting when a player has gone.
+func (r *Room) completeResetLocked() {
+	event := r.beginAuditLocked(AuditReset, "")
 	switch r.gameType {
 	case "tictactoe":
 		r.resetTicTacToeAfterResettingLocked()
@@ -416,13 +575,12 @@ func (r *Room) runScheduledReset(ctx context.Context, version uint64, finishedDe
 		r.resetChessAfterResettingLocked()
 	}
 	r.bumpStateVersionLocked()
+	r.recordAuditLocked(event, nil)
 
 	if r.resetCancel != nil {
 		r.resetCancel()
 		r.resetCancel = nil
 	}
-	r.mu.Unlock()
-	r.notifyStateChanged()
 }
 
 func waitForDelay(ctx context.Context, delay time.Duration) bool {
@@ -477,7 +635,67 @@ func (r *Room) chessAISnapshotLocked() ChessAISnapshot {
 	return ai
 }
 
-func (r *Room) notifyStateChanged() {
+func (r *Room) takebackSnapshotLocked() TakebackSnapshot {
+	snapshot := TakebackSnapshot{
+		Limit: r.takebackLimit,
+		Used:  make(map[string]int, len(r.takebacksUsed)),
+	}
+	for playerID, used := range r.takebacksUsed {
+		snapshot.Used[playerID] = used
+	}
+	if r.takeback != nil {
+		snapshot.Pending = true
+		snapshot.RequestedBy = r.takeback.RequesterID
+		snapshot.ExpiresAt = r.takeback.ExpiresAt
+	}
+	return snapshot
+}
+
+// chessTakebackRequestableByLocked returns the chess players whose
+// RequestTakeback would be accepted now. Against the AI that undoes the last
+// turn at once; between humans it asks the opponent, within each player's
+// per-game limit.
+func (r *Room) chessTakebackRequestableByLocked() map[string]bool {
+	requestable := make(map[string]bool)
+	for _, player := range r.players {
+		if player.IsAI {
+			continue
+		}
+		if r.isAIEnabled {
+			if r.chess.CanUndoAI() && (r.roomState == RoomStatePlaying || r.roomState == RoomStateFinished) {
+				requestable[player.ID] = true
+			}
+			continue
+		}
+		if r.roomState == RoomStatePlaying &&
+			r.takeback == nil &&
+			r.takebacksUsed[player.ID] < r.takebackLimit &&
+			r.hasMoveToTakeBackLocked(player) {
+			requestable[player.ID] = true
+		}
+	}
+	return requestable
+}
+
+func (r *Room) pendingTakebackRequesterLocked() string {
+	if r.takeback == nil {
+		return ""
+	}
+	return r.takeback.RequesterID
+}
+
+func (r *Room) chessPremovesSnapshotLocked() map[string]ChessPremoveSnapshot {
+	premoves := make(map[string]ChessPremoveSnapshot, len(r.chessPremoves))
+	for playerID, premove := range r.chessPremoves {
+		premoves[playerID] = chessPremoveSnapshot(premove)
+	}
+	return premoves
+}
+
+// notifyStateChanged reports a change made outside any client request. ctx
+// carries the span of the scheduled work; it is detached from cancellation
+// because the work's own context is often cancelled right after the change.
+func (r *Room) notifyStateChanged(ctx context.Context) {
 	r.mu.RLock()
 	notifier := r.stateNotifier
 	r.mu.RUnlock()
@@ -486,7 +704,11 @@ func (r *Room) notifyStateChanged() {
 		return
 	}
 
-	notifier(r.Snapshot())
+	notifier(context.WithoutCancel(ctx), r.Snapshot())
+}
+
+func (r *Room) startSpan(ctx context.Context, name string) (context.Context, func(error)) {
+	return observability.StartSpan(ctx, name, slog.String("room_id", r.RoomID))
 }
 
 func normalizeAILevel(level int) int {
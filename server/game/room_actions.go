@@ -13,8 +13,21 @@ import (
 var (
 	ErrPlayerNotFound   = errors.New("player not found")
 	ErrInvalidGameState = errors.New("invalid game state")
+	// ErrStateVersionMismatch rejects a command made against a state
+	// version the room has already left.
+	ErrStateVersionMismatch = errors.New("room state has changed")
 )
 
+// checkStateVersionLocked rejects a command whose player acted on a state
+// version the room has since left. The commands taking an expectedVersion
+// apply whatever the version when it is nil.
+func (r *Room) checkStateVersionLocked(expectedVersion *uint64) error {
+	if expectedVersion != nil && *expectedVersion != r.stateVersion {
+		return ErrStateVersionMismatch
+	}
+	return nil
+}
+
 type TicTacToeMoveResult struct {
 	State     TicTacToeStateSnapshot
 	GameEnded bool
@@ -30,11 +43,28 @@ func (r *Room) HandleTicTacToeMove(
 	playerID string,
 	row int,
 	col int,
+) (*TicTacToeMoveResult, error) {
+	return r.HandleTicTacToeMoveWithContext(context.Background(), playerID, row, col, nil)
+}
+
+func (r *Room) HandleTicTacToeMoveWithContext(
+	ctx context.Context,
+	playerID string,
+	row int,
+	col int,
+	expectedVersion *uint64,
 ) (*TicTacToeMoveResult, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	result, err := r.handleTicTacToeMoveLocked(playerID, row, col)
+	event := r.beginAuditLocked(AuditMove, playerID)
+	event.Move = ticTacToeAuditMove(row, col)
+	var result *TicTacToeMoveResult
+	err := r.checkStateVersionLocked(expectedVersion)
+	if err == nil {
+		result, err = r.handleTicTacToeMoveLocked(playerID, row, col)
+	}
+	r.recordAuditLocked(event, err)
 	if err != nil {
 		return nil, err
 	}
@@ -62,6 +92,9 @@ func (r *Room) handleTicTacToeMoveLocked(
 	if err := r.ticTacToe.ApplyMove(player.Mark, row, col); err != nil {
 		return nil, err
 	}
+	if r.takeback != nil {
+		r.clearTakebackLocked()
+	}
 
 	result := &TicTacToeMoveResult{
 		State: TicTacToeStateSnapshot{
@@ -78,6 +111,7 @@ func (r *Room) handleTicTacToeMoveLocked(
 			return nil, err
 		}
 		r.bumpStateVersionLocked()
+		r.reportOutcomeLocked()
 		r.scheduleResetLocked()
 		return result, nil
 	}
@@ -104,7 +138,14 @@ func (r *Room) handleTicTacToeAIMoveLocked() error {
 		return nil
 	}
 
-	_, err := r.handleTicTacToeMoveLocked(aiPlayer.ID, move.Row, move.Col)
+	return r.playTicTacToeAIMoveLocked(aiPlayer.ID, move.Row, move.Col)
+}
+
+func (r *Room) playTicTacToeAIMoveLocked(aiPlayerID string, row int, col int) error {
+	event := r.beginAuditLocked(AuditAIMove, aiPlayerID)
+	event.Move = ticTacToeAuditMove(row, col)
+	_, err := r.handleTicTacToeMoveLocked(aiPlayerID, row, col)
+	r.recordAuditLocked(event, err)
 	return err
 }
 
@@ -129,19 +170,32 @@ func (r *Room) HandleChessMove(
 	to string,
 	promotion string,
 ) (*ChessMoveResult, error) {
-	return r.HandleChessMoveWithContext(context.Background(), playerID, from, to, promotion)
+	return r.HandleChessMoveWithContext(context.Background(), playerID, from, to, promotion, nil)
 }
 
 func (r *Room) HandleChessMoveWithContext(
-	_ context.Context,
+	ctx context.Context,
 	playerID string,
 	from string,
 	to string,
 	promotion string,
+	expectedVersion *uint64,
 ) (*ChessMoveResult, error) {
+	_, endSpan := r.startSpan(ctx, "room.chess_move")
 	r.mu.Lock()
-	result, aiMove, err := r.handleChessMoveLocked(playerID, from, to, promotion)
+	var (
+		result *ChessMoveResult
+		aiMove chessAIMoveRequest
+	)
+	event := r.beginAuditLocked(AuditMove, playerID)
+	event.Move = chessAuditMove(from, to, promotion)
+	err := r.checkStateVersionLocked(expectedVersion)
+	if err == nil {
+		result, aiMove, err = r.handleChessMoveLocked(&event, playerID, from, to, promotion)
+	}
+	r.recordAuditLocked(event, err)
 	r.mu.Unlock()
+	endSpan(err)
 
 	if err != nil {
 		return nil, err
@@ -152,9 +206,18 @@ func (r *Room) HandleChessMoveWithContext(
 }
 
 func (r *Room) HandleChessUndo(playerID string) error {
+	return r.HandleChessUndoAtVersion(playerID, nil)
+}
+
+func (r *Room) HandleChessUndoAtVersion(playerID string, expectedVersion *uint64) (err error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	event := r.beginAuditLocked(AuditUndo, playerID)
+	defer func() { r.recordAuditLocked(event, err) }()
 
+	if err := r.checkStateVersionLocked(expectedVersion); err != nil {
+		return err
+	}
 	if r.chess == nil || r.gameType != "chess" {
 		return ErrInvalidGameState
 	}
@@ -169,15 +232,221 @@ func (r *Room) HandleChessUndo(playerID string) error {
 		return errors.New("undo is only supported for AI rooms")
 	}
 
+	return r.undoAITurnLocked(player)
+}
+
+// RequestTakeback asks to roll the game back to before playerID's latest
+// move. AI rooms undo immediately; human rooms create a pending request that
+// the opponent must answer before the takeback timeout expires.
+func (r *Room) RequestTakeback(playerID string) (*TakebackResult, error) {
+	return r.RequestTakebackAtVersion(playerID, nil)
+}
+
+func (r *Room) RequestTakebackAtVersion(playerID string, expectedVersion *uint64) (_ *TakebackResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event := r.beginAuditLocked(AuditTakebackRequest, playerID)
+	defer func() { r.recordAuditLocked(event, err) }()
+
+	if err := r.checkStateVersionLocked(expectedVersion); err != nil {
+		return nil, err
+	}
+	player, exists := r.players[playerID]
+	if !exists {
+		return nil, ErrPlayerNotFound
+	}
+	if player.IsAI {
+		return nil, errors.New("AI cannot request takeback")
+	}
+
+	if r.isAIEnabled {
+		if r.roomState != RoomStatePlaying && r.roomState != RoomStateFinished {
+			return nil, errors.New("game is not active")
+		}
+		if err := r.undoAITurnLocked(player); err != nil {
+			return nil, err
+		}
+		return &TakebackResult{Applied: true}, nil
+	}
+
+	if r.roomState != RoomStatePlaying {
+		return nil, errors.New("game is not active")
+	}
+	if r.takeback != nil {
+		return nil, ErrTakebackPending
+	}
+	if r.takebacksUsed[playerID] >= r.takebackLimit {
+		return nil, ErrTakebackLimitReached
+	}
+	if !r.hasMoveToTakeBackLocked(player) {
+		return nil, ErrTakebackNothingToUndo
+	}
+
+	now := time.Now().UTC()
+	r.takeback = &takebackRequest{
+		RequesterID: playerID,
+		RequestedAt: now,
+		ExpiresAt:   now.Add(r.takebackTimeout),
+	}
+	r.scheduleTakebackExpiryLocked()
+	r.bumpStateVersionLocked()
+	return &TakebackResult{}, nil
+}
+
+// RespondTakeback answers the opponent's pending takeback request. Accepting
+// rolls the game back to before the requester's latest move and counts
+// against the requester's per-game limit.
+func (r *Room) RespondTakeback(playerID string, accept bool) (*TakebackResult, error) {
+	return r.RespondTakebackAtVersion(playerID, accept, nil)
+}
+
+func (r *Room) RespondTakebackAtVersion(playerID string, accept bool, expectedVersion *uint64) (_ *TakebackResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event := r.beginAuditLocked(AuditTakebackRespond, playerID)
+	event.Accept = &accept
+	defer func() { r.recordAuditLocked(event, err) }()
+
+	if err := r.checkStateVersionLocked(expectedVersion); err != nil {
+		return nil, err
+	}
+	if _, exists := r.players[playerID]; !exists {
+		return nil, ErrPlayerNotFound
+	}
+	if r.takeback == nil {
+		return nil, ErrTakebackNotPending
+	}
+	if r.takeback.RequesterID == playerID {
+		return nil, ErrTakebackOwnRequest
+	}
+
+	requester, exists := r.players[r.takeback.RequesterID]
+	r.clearTakebackLocked()
+	if !accept || !exists {
+		r.bumpStateVersionLocked()
+		return &TakebackResult{}, nil
+	}
+
+	if err := r.rollbackMoveByLocked(requester); err != nil {
+		r.bumpStateVersionLocked()
+		return nil, err
+	}
+	r.takebacksUsed[requester.ID]++
+	r.bumpStateVersionLocked()
+	return &TakebackResult{Applied: true}, nil
+}
+
+func (r *Room) undoAITurnLocked(player *Player) error {
 	r.cancelScheduledAIMoveLocked()
-	if err := r.chess.RollbackLastAITurn(); err != nil {
-		return err
+
+	switch r.gameType {
+	case "chess":
+		if r.chess == nil {
+			return ErrInvalidGameState
+		}
+		if err := r.chess.RollbackLastAITurn(); err != nil {
+			return err
+		}
+		r.clearChessPremovesLocked()
+	case "tictactoe":
+		if err := r.rollbackMoveByLocked(player); err != nil {
+			return err
+		}
+	default:
+		return ErrInvalidGameState
 	}
+
+	r.cancelScheduledResetLocked()
 	r.roomState = RoomStatePlaying
 	r.bumpStateVersionLocked()
 	return nil
 }
 
+func (r *Room) hasMoveToTakeBackLocked(player *Player) bool {
+	switch r.gameType {
+	case "chess":
+		return r.chess != nil && r.chess.LastPlyBy(player.ID) > 0
+	case "tictactoe":
+		return r.ticTacToe != nil && r.ticTacToe.LastMoveIndexBy(player.Mark) >= 0
+	}
+	return false
+}
+
+// rollbackMoveByLocked restores the position before player's latest move,
+// dropping any replies made after it.
+func (r *Room) rollbackMoveByLocked(player *Player) error {
+	if !r.hasMoveToTakeBackLocked(player) {
+		return ErrTakebackNothingToUndo
+	}
+
+	switch r.gameType {
+	case "chess":
+		if err := r.chess.RollbackToPly(r.chess.LastPlyBy(player.ID) - 1); err != nil {
+			return err
+		}
+		r.clearChessPremovesLocked()
+	case "tictactoe":
+		if err := r.ticTacToe.RollbackTo(r.ticTacToe.LastMoveIndexBy(player.Mark)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Room) scheduleTakebackExpiryLocked() {
+	if r.takebackCancel != nil {
+		r.takebackCancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.takebackCancel = cancel
+	r.takebackVersion++
+	if r.replay {
+		return
+	}
+	go r.runTakebackExpiry(ctx, r.takebackVersion, r.takebackTimeout)
+}
+
+func (r *Room) runTakebackExpiry(ctx context.Context, version uint64, timeout time.Duration) {
+	if !waitForDelay(ctx, timeout) {
+		return
+	}
+
+	spanCtx, endSpan := r.startSpan(ctx, "room.takeback_expiry")
+	defer endSpan(nil)
+	r.mu.Lock()
+	if ctx.Err() != nil || version != r.takebackVersion || r.takeback == nil {
+		r.mu.Unlock()
+		return
+	}
+	r.expireTakebackLocked()
+	r.mu.Unlock()
+
+	r.notifyStateChanged(spanCtx)
+}
+
+// expireTakebackLocked drops the pending takeback request unanswered.
+func (r *Room) expireTakebackLocked() {
+	event := r.beginAuditLocked(AuditTakebackExpire, r.takeback.RequesterID)
+	r.clearTakebackLocked()
+	r.bumpStateVersionLocked()
+	r.recordAuditLocked(event, nil)
+}
+
+func (r *Room) clearTakebackLocked() {
+	if r.takebackCancel != nil {
+		r.takebackCancel()
+		r.takebackCancel = nil
+	}
+	r.takebackVersion++
+	r.takeback = nil
+}
+
+func (r *Room) resetTakebacksLocked() {
+	r.clearTakebackLocked()
+	r.takebacksUsed = make(map[string]int)
+}
+
 type chessAIMoveRequest struct {
 	shouldMove bool
 	playerID   string
@@ -185,7 +454,27 @@ type chessAIMoveRequest struct {
 	engine     *StockfishEngine
 }
 
+// handleChessMoveLocked plays a move and then any premove queued in reply,
+// which it notes on event.
 func (r *Room) handleChessMoveLocked(
+	event *AuditEvent,
+	playerID string,
+	from string,
+	to string,
+	promotion string,
+) (*ChessMoveResult, chessAIMoveRequest, error) {
+	result, aiMove, err := r.applyChessMoveLocked(playerID, from, to, promotion)
+	if err != nil {
+		return nil, chessAIMoveRequest{}, err
+	}
+
+	if premoveAIMove, played := r.playPendingPremoveLocked(event, playerID); played {
+		aiMove = premoveAIMove
+	}
+	return result, aiMove, nil
+}
+
+func (r *Room) applyChessMoveLocked(
 	playerID string,
 	from string,
 	to string,
@@ -213,6 +502,9 @@ func (r *Room) handleChessMoveLocked(
 	if err != nil {
 		return nil, chessAIMoveRequest{}, err
 	}
+	if r.takeback != nil {
+		r.clearTakebackLocked()
+	}
 
 	pgn := append([]string(nil), r.chess.PGNMoves()...)
 	moveResult := &ChessMoveResult{
@@ -226,7 +518,9 @@ func (r *Room) handleChessMoveLocked(
 			return nil, chessAIMoveRequest{}, err
 		}
 		r.aiThinking = false
+		r.clearChessPremovesLocked()
 		r.bumpStateVersionLocked()
+		r.reportOutcomeLocked()
 		r.scheduleResetLocked()
 		return moveResult, chessAIMoveRequest{}, nil
 	}
@@ -235,23 +529,198 @@ func (r *Room) handleChessMoveLocked(
 	return moveResult, r.chessAIMoveRequestLocked(player), nil
 }
 
+// playPendingPremoveLocked plays the premove queued by the opponent of
+// lastPlayerID immediately after that player's move, before anyone else can
+// observe the position. A premove that is no longer legal is discarded.
+func (r *Room) playPendingPremoveLocked(event *AuditEvent, lastPlayerID string) (chessAIMoveRequest, bool) {
+	delete(r.chessPremoves, lastPlayerID)
+	if r.chess == nil || r.roomState != RoomStatePlaying || !r.chess.IsActive() {
+		r.clearChessPremovesLocked()
+		return chessAIMoveRequest{}, false
+	}
+
+	for playerID, premove := range r.chessPremoves {
+		player, exists := r.players[playerID]
+		if !exists || r.chess.CurrentTurn() != player.Mark {
+			continue
+		}
+
+		delete(r.chessPremoves, playerID)
+		_, aiMove, err := r.applyChessMoveLocked(playerID, premove.From, premove.To, premove.Promotion)
+		event.Premove = &AuditedPremove{
+			PlayerID: playerID,
+			Move:     *chessAuditMove(premove.From, premove.To, premove.Promotion),
+		}
+		if err != nil {
+			event.Premove.Error = err.Error()
+			observability.Logger().Info("chess premove discarded",
+				"room_id", r.RoomID,
+				"player_id", playerID,
+				"event_type", "chess_premove_discarded",
+				"from", premove.From,
+				"to", premove.To,
+				"error", err,
+			)
+			return chessAIMoveRequest{}, false
+		}
+		return aiMove, true
+	}
+	return chessAIMoveRequest{}, false
+}
+
+func (r *Room) QueueChessPremove(
+	playerID string,
+	from string,
+	to string,
+	promotion string,
+) (*ChessPremoveResult, error) {
+	return r.QueueChessPremoveAtVersion(playerID, from, to, promotion, nil)
+}
+
+func (r *Room) QueueChessPremoveAtVersion(
+	playerID string,
+	from string,
+	to string,
+	promotion string,
+	expectedVersion *uint64,
+) (*ChessPremoveResult, error) {
+	r.mu.Lock()
+	event := r.beginAuditLocked(AuditPremove, playerID)
+	event.Move = chessAuditMove(from, to, promotion)
+	var (
+		result *ChessPremoveResult
+		aiMove chessAIMoveRequest
+	)
+	err := r.checkStateVersionLocked(expectedVersion)
+	if err == nil {
+		result, aiMove, err = r.queueChessPremoveLocked(&event, playerID, from, to, promotion)
+	}
+	r.recordAuditLocked(event, err)
+	r.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	r.scheduleChessAIMove(aiMove)
+	return result, nil
+}
+
+func (r *Room) queueChessPremoveLocked(
+	event *AuditEvent,
+	playerID string,
+	from string,
+	to string,
+	promotion string,
+) (*ChessPremoveResult, chessAIMoveRequest, error) {
+	if r.chess == nil || r.gameType != "chess" {
+		return nil, chessAIMoveRequest{}, ErrPremoveNotSupported
+	}
+	player, exists := r.players[playerID]
+	if !exists {
+		return nil, chessAIMoveRequest{}, ErrPlayerNotFound
+	}
+	if player.IsAI {
+		return nil, chessAIMoveRequest{}, errors.New("AI cannot premove")
+	}
+	if r.roomState != RoomStatePlaying {
+		return nil, chessAIMoveRequest{}, errors.New("game is not active")
+	}
+
+	// The opponent may already have moved by the time the premove arrives.
+	// In that case the premove is simply the player's next move.
+	if r.chess.CurrentTurn() == player.Mark {
+		result, aiMove, err := r.handleChessMoveLocked(event, playerID, from, to, promotion)
+		if err != nil {
+			return nil, chessAIMoveRequest{}, err
+		}
+		return &ChessPremoveResult{Applied: true, Move: result}, aiMove, nil
+	}
+
+	if err := r.chess.ValidatePremove(player.Mark, from, to, promotion); err != nil {
+		return nil, chessAIMoveRequest{}, err
+	}
+	r.chessPremoves[playerID] = chessPremove{
+		From:      from,
+		To:        to,
+		Promotion: promotion,
+		QueuedAt:  time.Now().UTC(),
+	}
+	// A queued premove is private to its owner, so it does not move the state
+	// version every client reconciles against.
+	return &ChessPremoveResult{}, chessAIMoveRequest{}, nil
+}
+
+func (r *Room) CancelChessPremove(playerID string) error {
+	return r.CancelChessPremoveAtVersion(playerID, nil)
+}
+
+func (r *Room) CancelChessPremoveAtVersion(playerID string, expectedVersion *uint64) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event := r.beginAuditLocked(AuditPremoveCancel, playerID)
+	defer func() { r.recordAuditLocked(event, err) }()
+
+	if err := r.checkStateVersionLocked(expectedVersion); err != nil {
+		return err
+	}
+	if _, exists := r.players[playerID]; !exists {
+		return ErrPlayerNotFound
+	}
+	if _, exists := r.chessPremoves[playerID]; !exists {
+		return ErrPremoveNotFound
+	}
+
+	delete(r.chessPremoves, playerID)
+	return nil
+}
+
+func (r *Room) clearChessPremovesLocked() {
+	for playerID := range r.chessPremoves {
+		delete(r.chessPremoves, playerID)
+	}
+}
+
 func (r *Room) HandlePlayerDisconnected(playerID string) bool {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	return r.removePlayerLocked(playerID)
+	return r.leaveLocked(playerID, AuditReasonDisconnected)
+}
+
+// leaveLocked removes a player who left on their own and reports whether the
+// room is now empty.
+func (r *Room) leaveLocked(playerID string, reason string) bool {
+	if _, exists := r.players[playerID]; !exists {
+		return false
+	}
+
+	event := r.beginAuditLocked(AuditLeave, playerID)
+	event.Reason = reason
+	empty := r.removePlayerLocked(playerID)
+	r.recordAuditLocked(event, nil)
+	return empty
 }
 
 func (r *Room) AddChatMessage(playerID string, message string) (ChatMessage, error) {
+	return r.AddChatMessageAtVersion(playerID, message, nil)
+}
+
+func (r *Room) AddChatMessageAtVersion(playerID string, message string, expectedVersion *uint64) (_ ChatMessage, err error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	event := r.beginAuditLocked(AuditChat, playerID)
+	event.Message = message
+	defer func() { r.recordAuditLocked(event, err) }()
 
+	if err := r.checkStateVersionLocked(expectedVersion); err != nil {
+		return ChatMessage{}, err
+	}
 	player, exists := r.players[playerID]
 	if !exists {
 		return ChatMessage{}, ErrPlayerNotFound
 	}
 
-	message, err := normalizeChatMessage(message)
+	message, err = normalizeChatMessage(message)
 	if err != nil {
 		return ChatMessage{}, err
 	}
@@ -266,11 +735,13 @@ func (r *Room) AddChatMessage(playerID string, message string) (ChatMessage, err
 		CreatedAt:  now,
 	}
 
-	r.chatMessages = append(r.chatMessages, chatMessage)
-	if len(r.chatMessages) > maxChatMessages {
-		r.chatMessages = append([]ChatMessage(nil), r.chatMessages[len(r.chatMessages)-maxChatMessages:]...)
-	}
+	event.MessageID = chatMessage.ID
+	event.Message = chatMessage.Message
 
+	// The message ends the sender's typing; clients clear the indicator
+	// when it arrives.
+	r.clearTypingLocked(playerID)
+	r.appendChatMessageLocked(chatMessage)
 	return chatMessage, nil
 }
 
@@ -315,7 +786,7 @@ func (r *Room) RemoveInactivePlayers(now time.Time, duration time.Duration) {
 
 	for playerID, player := range r.players {
 		if now.Sub(player.LastActive) > duration {
-			r.removePlayerLocked(playerID)
+			r.leaveLocked(playerID, AuditReasonInactive)
 			observability.Logger().Info("inactive player removed from room",
 				"room_id", r.RoomID,
 				"player_id", playerID,
@@ -347,6 +818,14 @@ func (r *Room) removePlayerLocked(playerID string) bool {
 
 	player.Session = PlayerSessionRemoved
 	delete(r.players, playerID)
+	delete(r.chessPremoves, playerID)
+	r.clearTypingLocked(playerID)
+	if r.takeback != nil {
+		r.clearTakebackLocked()
+	}
+	if r.hostID == playerID {
+		r.electHostLocked()
+	}
 	r.bumpStateVersionLocked()
 
 	if len(r.players) == 0 {
@@ -387,11 +866,13 @@ func (r *Room) resetTicTacToeWaitingLocked() {
 
 	r.ticTacToe.Reset("X")
 	r.ticTacToe.Status = tictactoe.StatusWaiting
+	r.resetTakebacksLocked()
 }
 
 func (r *Room) resetTicTacToeAfterResettingLocked() {
 	r.cancelScheduledAIMoveLocked()
 	r.ticTacToe.Reset("X")
+	r.resetTakebacksLocked()
 	if len(r.players) == 2 {
 		r.ticTacToe.Status = tictactoe.StatusActive
 		_ = r.transitionLocked(RoomStatePlaying)
@@ -405,6 +886,8 @@ func (r *Room) resetTicTacToeAfterResettingLocked() {
 func (r *Room) resetChessLocked() {
 	r.chess = chess.NewChessGameState()
 	r.aiThinking = false
+	r.clearChessPremovesLocked()
+	r.resetTakebacksLocked()
 }
 
 func (r *Room) resetChessAfterResettingLocked() {
@@ -422,6 +905,21 @@ func (r *Room) AddPlayer(playerSnapshot PlayerSnapshot) (*JoinRoomResponse, erro
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	event := r.beginAuditLocked(AuditJoin, playerSnapshot.ID)
+	event.IsAI = playerSnapshot.IsAI
+	res, err := r.addPlayerLocked(playerSnapshot)
+	r.recordJoinLocked(event, res, err)
+	return res, err
+}
+
+func (r *Room) recordJoinLocked(event AuditEvent, res *JoinRoomResponse, err error) {
+	if res != nil {
+		event.Mark = res.PlayerMark
+	}
+	r.recordAuditLocked(event, err)
+}
+
+func (r *Room) addPlayerLocked(playerSnapshot PlayerSnapshot) (*JoinRoomResponse, error) {
 	if len(r.players) >= 2 {
 		return nil, errors.New("room is full")
 	}
@@ -443,6 +941,9 @@ func (r *Room) AddPlayer(playerSnapshot PlayerSnapshot) (*JoinRoomResponse, erro
 
 	player.LastActive = time.Now()
 	r.players[player.ID] = player
+	if r.hostID == "" && !player.IsAI {
+		r.hostID = player.ID
+	}
 
 	switch r.gameType {
 	case "tictactoe":
@@ -490,9 +991,12 @@ func (r *Room) EnableAI() error {
 	return r.EnableAILevel(DefaultAILevel)
 }
 
-func (r *Room) EnableAILevel(aiLevel int) error {
+func (r *Room) EnableAILevel(aiLevel int) (err error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	event := r.beginAuditLocked(AuditEnableAI, "")
+	event.AILevel = normalizeAILevel(aiLevel)
+	defer func() { r.recordAuditLocked(event, err) }()
 
 	if r.gameType != "tictactoe" && r.gameType != "chess" {
 		return errors.New("AI only supported for tictactoe and chess")
@@ -519,6 +1023,9 @@ func (r *Room) EnableAILevel(aiLevel int) error {
 		aiPlayer.Mark = "O"
 	case "chess":
 		aiPlayer.Mark = "black"
+		if r.replay {
+			break
+		}
 		engine, err := NewStockfishEngine(stockfishPathFromEnv(), r.aiLevel)
 		if err != nil {
 			return err
@@ -584,6 +1091,13 @@ func (r *Room) chatHistoryLocked() []ChatMessage {
 	return append([]ChatMessage(nil), r.chatMessages...)
 }
 
+func (r *Room) appendChatMessageLocked(chatMessage ChatMessage) {
+	r.chatMessages = append(r.chatMessages, chatMessage)
+	if len(r.chatMessages) > r.chatHistory {
+		r.chatMessages = append([]ChatMessage(nil), r.chatMessages[len(r.chatMessages)-r.chatHistory:]...)
+	}
+}
+
 func (r *Room) currentAIPlayerLocked() *Player {
 	for _, player := range r.players {
 		if player.IsAI {
@@ -659,6 +1173,9 @@ func (r *Room) scheduleAIMoveLocked() {
 	version := r.aiMoveVersion
 	delay := r.aiMoveDelay
 
+	if r.replay {
+		return
+	}
 	go r.runScheduledAIMove(ctx, version, delay)
 }
 
@@ -668,6 +1185,8 @@ func (r *Room) runScheduledAIMove(ctx context.Context, version uint64, delay tim
 		return
 	}
 
+	spanCtx, endSpan := r.startSpan(ctx, "room.tictactoe_ai_move")
+	defer endSpan(nil)
 	var changed bool
 	r.mu.Lock()
 	if ctx.Err() == nil && version == r.aiMoveVersion && r.shouldScheduleTicTacToeAIMoveLocked() {
@@ -679,7 +1198,7 @@ func (r *Room) runScheduledAIMove(ctx context.Context, version uint64, delay tim
 	r.mu.Unlock()
 
 	if changed {
-		r.notifyStateChanged()
+		r.notifyStateChanged(spanCtx)
 	}
 }
 
@@ -693,41 +1212,82 @@ func (r *Room) scheduleChessAIMove(request chessAIMoveRequest) {
 		r.mu.Unlock()
 		return
 	}
+	aiCtx, version := r.startChessAIMoveLocked(request.playerID)
+	delay := r.aiMoveDelay
+	r.mu.Unlock()
+
+	go r.runScheduledChessAIMove(aiCtx, version, delay, request)
+}
+
+// startChessAIMoveLocked shows the AI thinking and returns the context and
+// version of its move.
+func (r *Room) startChessAIMoveLocked(aiPlayerID string) (context.Context, uint64) {
 	aiCtx, cancel := context.WithCancel(context.Background())
 	r.aiMoveCancel = cancel
 	r.aiMoveVersion++
+	event := r.beginAuditLocked(AuditAIThinking, aiPlayerID)
 	r.aiThinking = true
 	r.bumpStateVersionLocked()
-	version := r.aiMoveVersion
-	delay := r.aiMoveDelay
-	r.mu.Unlock()
-
-	go r.runScheduledChessAIMove(aiCtx, version, delay, request)
+	r.recordAuditLocked(event, nil)
+	return aiCtx, r.aiMoveVersion
 }
 
 func (r *Room) runScheduledChessAIMove(aiCtx context.Context, version uint64, delay time.Duration, request chessAIMoveRequest) {
 	if !waitForDelay(aiCtx, delay) {
-		r.finishScheduledAIMove(version, false)
+		r.finishScheduledAIMove(aiCtx, version, request.playerID, aiCtx.Err(), false)
 		return
 	}
 
+	aiCtx, endSpan := r.startSpan(aiCtx, "room.chess_ai_move")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
 	fen, engine, ok := r.currentChessAIRequest(version, request.playerID)
 	if !ok {
-		r.finishScheduledAIMove(version, false)
+		r.finishScheduledAIMove(aiCtx, version, request.playerID, errAIMoveSuperseded, false)
 		return
 	}
 
 	aiFrom, aiTo, aiPromotion, err := engine.BestMove(aiCtx, fen)
 	if err != nil {
-		r.finishScheduledAIMove(version, true)
+		spanErr = err
+		r.finishScheduledAIMove(aiCtx, version, request.playerID, err, true)
 		return
 	}
 
-	var changed bool
 	r.mu.Lock()
-	if aiCtx.Err() == nil && version == r.aiMoveVersion && r.shouldApplyChessAIMoveLocked(request.playerID) {
-		if _, _, err := r.handleChessMoveLocked(request.playerID, aiFrom, aiTo, aiPromotion); err == nil {
+	changed, next := r.playChessAIMoveLocked(aiCtx, version, request.playerID, aiFrom, aiTo, aiPromotion)
+	r.mu.Unlock()
+
+	// A human premove played right after the AI move hands the turn straight
+	// back to the AI.
+	r.scheduleChessAIMove(next)
+	if changed {
+		r.notifyStateChanged(aiCtx)
+	}
+}
+
+// playChessAIMoveLocked plays the engine's move for the AI move at version
+// unless the game has moved on, and returns whether it was played and the
+// next AI move to schedule.
+func (r *Room) playChessAIMoveLocked(
+	aiCtx context.Context,
+	version uint64,
+	aiPlayerID string,
+	from string,
+	to string,
+	promotion string,
+) (bool, chessAIMoveRequest) {
+	var changed bool
+	var next chessAIMoveRequest
+	event := r.beginAuditLocked(AuditAIMove, aiPlayerID)
+	event.Move = chessAuditMove(from, to, promotion)
+	moveErr := errAIMoveSuperseded
+	if aiCtx.Err() == nil && version == r.aiMoveVersion && r.shouldApplyChessAIMoveLocked(aiPlayerID) {
+		var aiMove chessAIMoveRequest
+		if _, aiMove, moveErr = r.handleChessMoveLocked(&event, aiPlayerID, from, to, promotion); moveErr == nil {
 			changed = true
+			next = aiMove
 		}
 	}
 	if version == r.aiMoveVersion {
@@ -735,11 +1295,8 @@ func (r *Room) runScheduledChessAIMove(aiCtx context.Context, version uint64, de
 		r.clearScheduledAIMoveLocked(version)
 		r.bumpStateVersionLocked()
 	}
-	r.mu.Unlock()
-
-	if changed {
-		r.notifyStateChanged()
-	}
+	r.recordAuditLocked(event, moveErr)
+	return changed, next
 }
 
 func (r *Room) currentChessAIRequest(version uint64, aiPlayerID string) (string, *StockfishEngine, bool) {
@@ -778,20 +1335,32 @@ func (r *Room) clearScheduledAIMoveLocked(version uint64) {
 	r.aiMoveCancel = nil
 }
 
-func (r *Room) finishScheduledAIMove(version uint64, notify bool) {
-	var changed bool
+// finishScheduledAIMove ends an AI move that was not played because of
+// cause.
+func (r *Room) finishScheduledAIMove(ctx context.Context, version uint64, aiPlayerID string, cause error, notify bool) {
 	r.mu.Lock()
-	if version == r.aiMoveVersion {
-		changed = r.aiThinking
-		r.aiThinking = false
-		r.clearScheduledAIMoveLocked(version)
-		if changed {
-			r.bumpStateVersionLocked()
-		}
-	}
+	changed := r.abandonChessAIMoveLocked(version, aiPlayerID, cause)
 	r.mu.Unlock()
 
 	if notify && changed {
-		r.notifyStateChanged()
+		r.notifyStateChanged(ctx)
+	}
+}
+
+// abandonChessAIMoveLocked stops the AI thinking about the move at version
+// and reports whether it still was.
+func (r *Room) abandonChessAIMoveLocked(version uint64, aiPlayerID string, cause error) bool {
+	if version != r.aiMoveVersion {
+		return false
+	}
+
+	event := r.beginAuditLocked(AuditAIMove, aiPlayerID)
+	changed := r.aiThinking
+	r.aiThinking = false
+	r.clearScheduledAIMoveLocked(version)
+	if changed {
+		r.bumpStateVersionLocked()
+		r.recordAuditLocked(event, cause)
 	}
+	return changed
 }
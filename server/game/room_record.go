@@ -0,0 +1,278 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/chess"
+	"github.com/tsaqiffatih/mini-game/tictactoe"
+)
+
+// RoomRecord is what a room keeps across a server restart: the game so far,
+// its players, chat and settings. Scheduled work, pending takebacks,
+// premoves, typing and reaction cooldowns are not kept.
+type RoomRecord struct {
+	RoomID        string               `json:"room_id"`
+	GameType      string               `json:"game_type"`
+	RoomState     RoomState            `json:"room_state"`
+	StateVersion  uint64               `json:"state_version"`
+	IsAIEnabled   bool                 `json:"is_ai_enabled"`
+	AILevel       int                  `json:"ai_level"`
+	Players       []PlayerRecord       `json:"players"`
+	TicTacToe     *TicTacToeRecord     `json:"tictactoe,omitempty"`
+	Chess         *ChessRecord         `json:"chess,omitempty"`
+	Chat          []ChatMessage        `json:"chat,omitempty"`
+	Mutes         map[string][]string  `json:"mutes,omitempty"`
+	Visibility    RoomVisibility       `json:"visibility"`
+	PasswordHash  []byte               `json:"password_hash,omitempty"`
+	HostID        string               `json:"host_id,omitempty"`
+	UsedInvites   map[string]time.Time `json:"used_invites,omitempty"`
+	Kicked        []string             `json:"kicked,omitempty"`
+	TakebacksUsed map[string]int       `json:"takebacks_used,omitempty"`
+	GameStartedAt time.Time            `json:"game_started_at"`
+	CreatedAt     time.Time            `json:"created_at"`
+}
+
+type PlayerRecord struct {
+	ID         string    `json:"player_id"`
+	Mark       string    `json:"player_mark"`
+	IsAI       bool      `json:"is_ai"`
+	LastActive time.Time `json:"last_active"`
+	// SessionNonce binds a guest's tokens to the player, see
+	// PlayerManager.CheckSession. Room.Record leaves it to the caller, which
+	// knows the players.
+	SessionNonce string `json:"session_nonce,omitempty"`
+}
+
+type TicTacToeRecord struct {
+	Board   [3][3]string           `json:"board"`
+	Turn    string                 `json:"turn"`
+	Winner  string                 `json:"winner,omitempty"`
+	Status  tictactoe.GameStatus   `json:"status"`
+	History []tictactoe.MoveRecord `json:"history,omitempty"`
+}
+
+// ChessRecord keeps the moves of a chess game; the position is rebuilt by
+// playing them again and checked against FEN.
+type ChessRecord struct {
+	FEN   string               `json:"fen"`
+	Moves []chess.RecordedMove `json:"moves,omitempty"`
+}
+
+// Record returns what the room needs to be restored after a restart.
+func (r *Room) Record() RoomRecord {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record := RoomRecord{
+		RoomID:        r.RoomID,
+		GameType:      r.gameType,
+		RoomState:     r.roomState,
+		StateVersion:  r.stateVersion,
+		IsAIEnabled:   r.isAIEnabled,
+		AILevel:       r.aiLevel,
+		Players:       make([]PlayerRecord, 0, len(r.players)),
+		Chat:          append([]ChatMessage(nil), r.chatMessages...),
+		Visibility:    r.visibility,
+		PasswordHash:  append([]byte(nil), r.passwordHash...),
+		HostID:        r.hostID,
+		UsedInvites:   make(map[string]time.Time, len(r.usedInvites)),
+		TakebacksUsed: make(map[string]int, len(r.takebacksUsed)),
+		GameStartedAt: r.gameStartedAt,
+		CreatedAt:     r.createdAt,
+	}
+	for _, player := range r.players {
+		record.Players = append(record.Players, PlayerRecord{
+			ID:         player.ID,
+			Mark:       player.Mark,
+			IsAI:       player.IsAI,
+			LastActive: player.LastActive,
+		})
+	}
+	sort.Slice(record.Players, func(i, j int) bool {
+		return record.Players[i].ID < record.Players[j].ID
+	})
+	if r.ticTacToe != nil {
+		record.TicTacToe = &TicTacToeRecord{
+			Board:   r.ticTacToe.Board,
+			Turn:    r.ticTacToe.Turn,
+			Winner:  r.ticTacToe.Winner,
+			Status:  r.ticTacToe.Status,
+			History: append([]tictactoe.MoveRecord(nil), r.ticTacToe.History...),
+		}
+	}
+	if r.chess != nil {
+		record.Chess = &ChessRecord{FEN: r.chess.FEN(), Moves: r.chess.RecordedMoves()}
+	}
+	for playerID, muted := range r.mutes {
+		if len(muted) == 0 {
+			continue
+		}
+		if record.Mutes == nil {
+			record.Mutes = make(map[string][]string)
+		}
+		for targetID := range muted {
+			record.Mutes[playerID] = append(record.Mutes[playerID], targetID)
+		}
+		sort.Strings(record.Mutes[playerID])
+	}
+	for nonce, expiresAt := range r.usedInvites {
+		record.UsedInvites[nonce] = expiresAt
+	}
+	for playerID := range r.kicked {
+		record.Kicked = append(record.Kicked, playerID)
+	}
+	sort.Strings(record.Kicked)
+	for playerID, used := range r.takebacksUsed {
+		record.TakebacksUsed[playerID] = used
+	}
+	return record
+}
+
+// RestoreRoom rebuilds a room from its record. Its players come back
+// disconnected, to be marked connected as they reconnect, and an AI chess
+// room gets a new engine. Call Resume once the room is reachable to restart
+// what it had scheduled.
+func RestoreRoom(record RoomRecord) (*Room, error) {
+	room, err := NewRoom(record.RoomID, record.GameType)
+	if err != nil {
+		return nil, err
+	}
+
+	switch record.RoomState {
+	case RoomStateWaiting, RoomStatePlaying, RoomStateFinished:
+		room.roomState = record.RoomState
+	case RoomStateResetting:
+		// The reset starts over from the finished game.
+		room.roomState = RoomStateFinished
+	default:
+		return nil, fmt.Errorf("unknown room state %q", record.RoomState)
+	}
+
+	switch record.GameType {
+	case "tictactoe":
+		if record.TicTacToe == nil {
+			return nil, errors.New("tictactoe room has no board")
+		}
+		state, err := restoreTicTacToe(*record.TicTacToe)
+		if err != nil {
+			return nil, err
+		}
+		room.ticTacToe = state
+	case "chess":
+		if record.Chess == nil {
+			return nil, errors.New("chess room has no game")
+		}
+		state, err := chess.ReplayChessGame(record.Chess.Moves)
+		if err != nil {
+			return nil, err
+		}
+		if state.FEN() != record.Chess.FEN {
+			return nil, fmt.Errorf("chess moves lead to %q, want %q", state.FEN(), record.Chess.FEN)
+		}
+		room.chess = state
+	}
+
+	for _, player := range record.Players {
+		session := PlayerSessionDisconnected
+		if player.IsAI {
+			session = PlayerSessionConnected
+		}
+		room.players[player.ID] = &Player{
+			ID:         player.ID,
+			Mark:       player.Mark,
+			IsAI:       player.IsAI,
+			LastActive: player.LastActive,
+			Session:    session,
+		}
+	}
+	if record.IsAIEnabled {
+		room.isAIEnabled = true
+		room.aiLevel = normalizeAILevel(record.AILevel)
+		if record.GameType == "chess" {
+			engine, err := NewStockfishEngine(stockfishPathFromEnv(), room.aiLevel)
+			if err != nil {
+				return nil, err
+			}
+			room.stockfish = engine
+		}
+	}
+
+	room.chatMessages = append([]ChatMessage(nil), record.Chat...)
+	for playerID, muted := range record.Mutes {
+		room.mutes[playerID] = make(map[string]struct{}, len(muted))
+		for _, targetID := range muted {
+			room.mutes[playerID][targetID] = struct{}{}
+		}
+	}
+	if record.Visibility != "" {
+		room.visibility = record.Visibility
+	}
+	room.passwordHash = append([]byte(nil), record.PasswordHash...)
+	room.hostID = record.HostID
+	for nonce, expiresAt := range record.UsedInvites {
+		room.usedInvites[nonce] = expiresAt
+	}
+	for _, playerID := range record.Kicked {
+		room.kicked[playerID] = struct{}{}
+	}
+	for playerID, used := range record.TakebacksUsed {
+		room.takebacksUsed[playerID] = used
+	}
+	room.gameStartedAt = record.GameStartedAt
+	room.createdAt = record.CreatedAt
+	// Clients that saw the room before the restart take the restored room as
+	// newer than anything they hold.
+	room.stateVersion = record.StateVersion
+	room.bumpStateVersionLocked()
+	return room, nil
+}
+
+// restoreTicTacToe plays the recorded moves again, so the board and result
+// follow the rules rather than the file.
+func restoreTicTacToe(record TicTacToeRecord) (*tictactoe.TictactoeGameState, error) {
+	firstTurn := record.Turn
+	if len(record.History) > 0 {
+		firstTurn = record.History[0].Mark
+	}
+	state := tictactoe.NewGameState(firstTurn)
+	if record.Status == tictactoe.StatusWaiting {
+		return state, nil
+	}
+
+	state.Reset(firstTurn)
+	for i, move := range record.History {
+		if err := state.ApplyMove(move.Mark, move.Row, move.Col); err != nil {
+			return nil, fmt.Errorf("move %d: %w", i+1, err)
+		}
+	}
+	if state.Board != record.Board || state.Status != record.Status {
+		return nil, errors.New("tictactoe moves do not match the board")
+	}
+	return state, nil
+}
+
+// Resume restarts what a restored room had scheduled: the reset after a
+// finished game, or the AI's move when it is the AI's turn.
+func (r *Room) Resume() {
+	r.mu.Lock()
+	var request chessAIMoveRequest
+	switch {
+	case r.roomState == RoomStateFinished:
+		r.scheduleResetLocked()
+	case r.gameType == "tictactoe" && r.shouldScheduleTicTacToeAIMoveLocked():
+		r.scheduleAIMoveLocked()
+	case r.gameType == "chess":
+		for _, player := range r.players {
+			if !player.IsAI {
+				request = r.chessAIMoveRequestLocked(player)
+				break
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	r.scheduleChessAIMove(request)
+}
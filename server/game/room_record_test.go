@@ -0,0 +1,105 @@
+package game
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func restoreRecordForTest(t *testing.T, room *Room) *Room {
+	t.Helper()
+
+	data, err := json.Marshal(room.Record())
+	if err != nil {
+		t.Fatalf("Marshal(record) error = %v", err)
+	}
+	var record RoomRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("Unmarshal(record) error = %v", err)
+	}
+	restored, err := RestoreRoom(record)
+	if err != nil {
+		t.Fatalf("RestoreRoom() error = %v", err)
+	}
+	return restored
+}
+
+func TestRestoreRoom_TicTacToeKeepsBoardPlayersAndChat(t *testing.T) {
+	room := newTicTacToeRoomForTest(t)
+	addPlayerToRoomForTest(t, room, "p1")
+	addPlayerToRoomForTest(t, room, "p2")
+	if _, err := room.HandleTicTacToeMove("p1", 0, 0); err != nil {
+		t.Fatalf("HandleTicTacToeMove(p1) error = %v", err)
+	}
+	if _, err := room.HandleTicTacToeMove("p2", 1, 1); err != nil {
+		t.Fatalf("HandleTicTacToeMove(p2) error = %v", err)
+	}
+	if _, err := room.AddChatMessage("p1", "good luck"); err != nil {
+		t.Fatalf("AddChatMessage() error = %v", err)
+	}
+	if err := room.MutePlayer("p2", "p1"); err != nil {
+		t.Fatalf("MutePlayer() error = %v", err)
+	}
+	before := room.Snapshot()
+
+	restored := restoreRecordForTest(t, room)
+	after := restored.Snapshot()
+	if after.RoomID != before.RoomID || after.RoomState != RoomStatePlaying || after.TicTacToe.Board != before.TicTacToe.Board || after.TicTacToe.Turn != "X" {
+		t.Fatalf("restored room = %+v, want board %v with X to move", after, before.TicTacToe.Board)
+	}
+	if after.StateVersion <= before.StateVersion {
+		t.Fatalf("restored state_version = %d, want above %d", after.StateVersion, before.StateVersion)
+	}
+	for _, player := range after.Players {
+		if player.Session != PlayerSessionDisconnected {
+			t.Fatalf("restored player %s session = %s, want %s", player.ID, player.Session, PlayerSessionDisconnected)
+		}
+	}
+	if history := restored.ChatHistoryFor("p1"); len(history) != 1 || history[0].Message != "good luck" {
+		t.Fatalf("restored chat = %+v, want the message from p1", history)
+	}
+	if muted := restored.MutedPlayers("p2"); len(muted) != 1 || muted[0] != "p1" {
+		t.Fatalf("restored mutes of p2 = %v, want [p1]", muted)
+	}
+	if _, err := restored.HandleTicTacToeMove("p1", 0, 1); err != nil {
+		t.Fatalf("HandleTicTacToeMove() after restore error = %v", err)
+	}
+}
+
+func TestRestoreRoom_ChessReplaysMoves(t *testing.T) {
+	room, err := NewRoom("room-1", "chess")
+	if err != nil {
+		t.Fatalf("NewRoom() error = %v", err)
+	}
+	addPlayerToRoomForTest(t, room, "p1")
+	addPlayerToRoomForTest(t, room, "p2")
+	for _, move := range []struct{ playerID, from, to string }{
+		{"p1", "e2", "e4"},
+		{"p2", "e7", "e5"},
+		{"p1", "g1", "f3"},
+	} {
+		if _, err := room.HandleChessMove(move.playerID, move.from, move.to, ""); err != nil {
+			t.Fatalf("HandleChessMove(%s %s%s) error = %v", move.playerID, move.from, move.to, err)
+		}
+	}
+	before := room.Snapshot().Chess
+
+	after := restoreRecordForTest(t, room).Snapshot().Chess
+	if after.FEN != before.FEN || after.Ply != 3 || len(after.PGNMoves) != 3 || after.PGNMoves[2] != before.PGNMoves[2] {
+		t.Fatalf("restored chess = %s after %v, want %s after %v", after.FEN, after.PGNMoves, before.FEN, before.PGNMoves)
+	}
+}
+
+func TestRestoreRoom_RejectsMovesNotMatchingBoard(t *testing.T) {
+	room := newTicTacToeRoomForTest(t)
+	addPlayerToRoomForTest(t, room, "p1")
+	addPlayerToRoomForTest(t, room, "p2")
+	if _, err := room.HandleTicTacToeMove("p1", 0, 0); err != nil {
+		t.Fatalf("HandleTicTacToeMove() error = %v", err)
+	}
+
+	record := room.Record()
+	record.TicTacToe.Board[2][2] = "O"
+	if _, err := RestoreRoom(record); err == nil {
+		t.Fatalf("RestoreRoom(tampered board) error = nil, want an error")
+	}
+}
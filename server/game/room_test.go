@@ -172,7 +172,7 @@ func TestRoom_HandleChessMove_AIEnabled_AppliesStockfishMove(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if _, err := room.HandleChessMoveWithContext(ctx, "p1", "e2", "e4", ""); err != nil {
+	if _, err := room.HandleChessMoveWithContext(ctx, "p1", "e2", "e4", "", nil); err != nil {
 		t.Fatalf("HandleChessMoveWithContext() error = %v", err)
 	}
 
@@ -206,7 +206,7 @@ func TestRoom_HandleChessUndo_AIEnabled_RollsBackHumanAndAIMove(t *testing.T) {
 	addPlayerToRoomForTest(t, room, "p1")
 
 	before := room.Snapshot()
-	if _, err := room.HandleChessMoveWithContext(context.Background(), "p1", "e2", "e4", ""); err != nil {
+	if _, err := room.HandleChessMoveWithContext(context.Background(), "p1", "e2", "e4", "", nil); err != nil {
 		t.Fatalf("HandleChessMoveWithContext() error = %v", err)
 	}
 	waitForChessMoves(t, room, 2, time.Second)
@@ -269,6 +269,82 @@ func TestRoom_HandleChessMove_RejectedMoveDoesNotIncrementStateVersion(t *testin
 	}
 }
 
+func TestRoom_HandleChessMove_ExpectedStateVersionMismatch_ReturnsError(t *testing.T) {
+	room, err := NewRoom("chess-precondition", "chess")
+	if err != nil {
+		t.Fatalf("NewRoom() error = %v", err)
+	}
+	addPlayerToRoomForTest(t, room, "p1")
+	addPlayerToRoomForTest(t, room, "p2")
+
+	version := room.Snapshot().StateVersion
+	stale := version - 1
+	if _, err := room.HandleChessMoveWithContext(context.Background(), "p1", "e2", "e4", "", &stale); err != ErrStateVersionMismatch {
+		t.Fatalf("HandleChessMoveWithContext(stale) error = %v, want %v", err, ErrStateVersionMismatch)
+	}
+	if after := room.Snapshot().StateVersion; after != version {
+		t.Fatalf("state version after stale move = %d, want %d", after, version)
+	}
+
+	if _, err := room.HandleChessMoveWithContext(context.Background(), "p1", "e2", "e4", "", &version); err != nil {
+		t.Fatalf("HandleChessMoveWithContext(current) error = %v", err)
+	}
+}
+
+func TestRoom_StaleStateVersion_RejectsEveryCommand(t *testing.T) {
+	room, err := NewRoom("chess-stale", "chess")
+	if err != nil {
+		t.Fatalf("NewRoom() error = %v", err)
+	}
+	defer room.Close()
+	addPlayerToRoomForTest(t, room, "p1")
+	addPlayerToRoomForTest(t, room, "p2")
+	if _, err := room.HandleChessMove("p1", "e2", "e4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+	if _, err := room.QueueChessPremove("p1", "d2", "d4", ""); err != nil {
+		t.Fatalf("QueueChessPremove() error = %v", err)
+	}
+
+	before := room.Snapshot()
+	stale := before.StateVersion - 1
+	commands := map[string]func() error{
+		"RequestTakeback": func() error {
+			_, err := room.RequestTakebackAtVersion("p1", &stale)
+			return err
+		},
+		"RespondTakeback": func() error {
+			_, err := room.RespondTakebackAtVersion("p2", true, &stale)
+			return err
+		},
+		"QueueChessPremove": func() error {
+			_, err := room.QueueChessPremoveAtVersion("p2", "e7", "e5", "", &stale)
+			return err
+		},
+		"CancelChessPremove": func() error { return room.CancelChessPremoveAtVersion("p1", &stale) },
+		"HandleChessUndo":    func() error { return room.HandleChessUndoAtVersion("p1", &stale) },
+		"AddChatMessage": func() error {
+			_, err := room.AddChatMessageAtVersion("p1", "hi", &stale)
+			return err
+		},
+		"KickPlayer": func() error { return room.KickPlayerAtVersion("p1", "p2", &stale) },
+	}
+	for name, command := range commands {
+		if err := command(); err != ErrStateVersionMismatch {
+			t.Fatalf("%s(stale) error = %v, want %v", name, err, ErrStateVersionMismatch)
+		}
+	}
+
+	after := room.Snapshot()
+	if after.StateVersion != before.StateVersion || len(after.Players) != 2 || len(room.ChatHistory()) != 0 {
+		t.Fatalf("room after stale commands = version %d, %d players, %d chat messages, want it unchanged",
+			after.StateVersion, len(after.Players), len(room.ChatHistory()))
+	}
+	if _, queued := after.Chess.Premoves["p1"]; !queued {
+		t.Fatalf("premoves after stale cancel = %+v, want p1's kept", after.Chess.Premoves)
+	}
+}
+
 func TestRoom_HandleTicTacToeMove_OutOfBounds_ReturnsError(t *testing.T) {
 	room := newTicTacToeRoomForTest(t)
 	addPlayerToRoomForTest(t, room, "p1")
@@ -893,12 +969,6 @@ func assertValidTicTacToeBoard(t *testing.T, board [3][3]string) {
 	}
 }
 
-func isAllowedTicTacToeStatus(status tictactoe.GameStatus) bool {
-	return status == tictactoe.StatusWaiting ||
-		status == tictactoe.StatusActive ||
-		status == tictactoe.StatusEnded
-}
-
 func countFilledCells(board [3][3]string) int {
 	count := 0
 	for row := 0; row < 3; row++ {
@@ -970,35 +1040,5 @@ func validateRoomSnapshotInvariant(snapshot RoomSnapshot, expectedPlayers int) e
 	if len(snapshot.Players) != expectedPlayers {
 		return fmt.Errorf("players len = %d, want %d", len(snapshot.Players), expectedPlayers)
 	}
-	if snapshot.TicTacToe.Turn != "X" && snapshot.TicTacToe.Turn != "O" {
-		return fmt.Errorf("turn = %q, want X or O", snapshot.TicTacToe.Turn)
-	}
-	if !isAllowedTicTacToeStatus(snapshot.TicTacToe.Status) {
-		return fmt.Errorf("status = %q, want allowed TicTacToe status", snapshot.TicTacToe.Status)
-	}
-
-	seenPlayers := map[string]bool{}
-	for _, player := range snapshot.Players {
-		if player.ID == "" {
-			return fmt.Errorf("player ID is empty")
-		}
-		if seenPlayers[player.ID] {
-			return fmt.Errorf("duplicate player ID %q in snapshot %+v", player.ID, snapshot.Players)
-		}
-		seenPlayers[player.ID] = true
-		if player.Mark != "X" && player.Mark != "O" {
-			return fmt.Errorf("player mark = %q, want X or O", player.Mark)
-		}
-	}
-
-	for row := 0; row < 3; row++ {
-		for col := 0; col < 3; col++ {
-			cell := snapshot.TicTacToe.Board[row][col]
-			if cell != "" && cell != "X" && cell != "O" {
-				return fmt.Errorf("board[%d][%d] = %q, want empty, X, or O", row, col, cell)
-			}
-		}
-	}
-
-	return nil
+	return validateRoomSnapshot(snapshot)
 }
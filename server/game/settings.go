@@ -0,0 +1,232 @@
+package game
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+type RoomVisibility string
+
+const (
+	RoomVisibilityPublic  RoomVisibility = "public"
+	RoomVisibilityPrivate RoomVisibility = "private"
+)
+
+var (
+	ErrInvalidVisibility   = errors.New("unknown room visibility")
+	ErrRoomPrivate         = errors.New("room is private")
+	ErrRoomPasswordInvalid = errors.New("wrong room password")
+	ErrInviteUsed          = errors.New("invite has already been used")
+	ErrNotRoomHost         = errors.New("only the host can do that")
+	ErrPlayerKicked        = errors.New("player was kicked from this room")
+	ErrCannotKickPlayer    = errors.New("player cannot be kicked")
+)
+
+// RoomSettings are chosen when a room is created. PasswordHash is a bcrypt
+// hash and never leaves the room.
+type RoomSettings struct {
+	Visibility   RoomVisibility
+	PasswordHash []byte
+}
+
+// RoomSettingsSnapshot is the public view of the settings, without the
+// password.
+type RoomSettingsSnapshot struct {
+	Visibility  RoomVisibility
+	HasPassword bool
+	HostID      string
+}
+
+// JoinAccess is what a joining player holds. The password is checked by the
+// caller, since bcrypt is too slow to run under the room lock; an invite is
+// checked against the invites already spent here.
+type JoinAccess struct {
+	PasswordVerified bool
+	InviteNonce      string
+	InviteExpiresAt  time.Time
+}
+
+// RoomListing is how an open room shows in the lobby.
+type RoomListing struct {
+	RoomID      string
+	GameType    string
+	HostID      string
+	HasPassword bool
+	CreatedAt   time.Time
+}
+
+// Configure applies the settings of a new room, before anyone joins.
+func (r *Room) Configure(settings RoomSettings) error {
+	switch settings.Visibility {
+	case "":
+		settings.Visibility = RoomVisibilityPublic
+	case RoomVisibilityPublic, RoomVisibilityPrivate:
+	default:
+		return ErrInvalidVisibility
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.visibility = settings.Visibility
+	r.passwordHash = append([]byte(nil), settings.PasswordHash...)
+	return nil
+}
+
+func (r *Room) Visibility() RoomVisibility {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.visibility
+}
+
+// PasswordHash returns the bcrypt hash of the room password, or nil when the
+// room has none.
+func (r *Room) PasswordHash() []byte {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.passwordHash
+}
+
+func (r *Room) HostID() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.hostID
+}
+
+// AddPlayerWithAccess adds a player who is not the creator of the room. An
+// invite lets them in whatever the settings; otherwise a room with a password
+// needs it verified and a private room without one turns them away. Kicked
+// players cannot come back.
+func (r *Room) AddPlayerWithAccess(playerSnapshot PlayerSnapshot, access JoinAccess) (res *JoinRoomResponse, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event := r.beginAuditLocked(AuditJoin, playerSnapshot.ID)
+	event.IsAI = playerSnapshot.IsAI
+	defer func() { r.recordJoinLocked(event, res, err) }()
+
+	if err := r.checkAccessLocked(playerSnapshot.ID, access, time.Now()); err != nil {
+		return nil, err
+	}
+	res, err = r.addPlayerLocked(playerSnapshot)
+	if err != nil {
+		return nil, err
+	}
+	if access.InviteNonce != "" {
+		r.usedInvites[access.InviteNonce] = access.InviteExpiresAt
+	}
+	return res, nil
+}
+
+func (r *Room) checkAccessLocked(playerID string, access JoinAccess, now time.Time) error {
+	if _, kicked := r.kicked[playerID]; kicked {
+		return ErrPlayerKicked
+	}
+
+	// A spent invite only needs remembering until it would have expired.
+	for nonce, expiresAt := range r.usedInvites {
+		if !now.Before(expiresAt) {
+			delete(r.usedInvites, nonce)
+		}
+	}
+	if access.InviteNonce != "" {
+		if _, used := r.usedInvites[access.InviteNonce]; used {
+			return ErrInviteUsed
+		}
+		return nil
+	}
+
+	if len(r.passwordHash) > 0 {
+		if !access.PasswordVerified {
+			return ErrRoomPasswordInvalid
+		}
+		return nil
+	}
+	if r.visibility == RoomVisibilityPrivate {
+		return ErrRoomPrivate
+	}
+	return nil
+}
+
+// KickPlayer removes a player at the host's request and keeps them from
+// joining again.
+func (r *Room) KickPlayer(hostID string, playerID string) error {
+	return r.KickPlayerAtVersion(hostID, playerID, nil)
+}
+
+func (r *Room) KickPlayerAtVersion(hostID string, playerID string, expectedVersion *uint64) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event := r.beginAuditLocked(AuditKick, hostID)
+	event.TargetID = playerID
+	defer func() { r.recordAuditLocked(event, err) }()
+
+	if err := r.checkStateVersionLocked(expectedVersion); err != nil {
+		return err
+	}
+	if hostID == "" || hostID != r.hostID {
+		return ErrNotRoomHost
+	}
+	player, exists := r.players[playerID]
+	if !exists {
+		return ErrPlayerNotFound
+	}
+	if playerID == hostID || player.IsAI {
+		return ErrCannotKickPlayer
+	}
+
+	r.banPlayerLocked(playerID)
+	return nil
+}
+
+func (r *Room) banPlayerLocked(playerID string) {
+	r.removePlayerLocked(playerID)
+	r.kicked[playerID] = struct{}{}
+}
+
+// Listing returns the lobby entry of the room. It reports false unless the
+// room is public, has no AI and its host is waiting for an opponent.
+func (r *Room) Listing() (RoomListing, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.visibility != RoomVisibilityPublic || r.isAIEnabled || r.roomState != RoomStateWaiting ||
+		len(r.players) != 1 || r.hostID == "" {
+		return RoomListing{}, false
+	}
+	return RoomListing{
+		RoomID:      r.RoomID,
+		GameType:    r.gameType,
+		HostID:      r.hostID,
+		HasPassword: len(r.passwordHash) > 0,
+		CreatedAt:   r.createdAt,
+	}, true
+}
+
+func (r *Room) settingsSnapshotLocked() RoomSettingsSnapshot {
+	return RoomSettingsSnapshot{
+		Visibility:  r.visibility,
+		HasPassword: len(r.passwordHash) > 0,
+		HostID:      r.hostID,
+	}
+}
+
+// electHostLocked hands the room to the remaining human player with the
+// lowest ID when the host leaves.
+func (r *Room) electHostLocked() {
+	candidates := make([]string, 0, len(r.players))
+	for id, player := range r.players {
+		if !player.IsAI {
+			candidates = append(candidates, id)
+		}
+	}
+	sort.Strings(candidates)
+
+	r.hostID = ""
+	if len(candidates) > 0 {
+		r.hostID = candidates[0]
+	}
+}
@@ -0,0 +1,85 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoom_AddPlayerWithAccess_PrivateRoomNeedsAnUnusedInvite(t *testing.T) {
+	room := newTicTacToeRoomForTest(t)
+	if err := room.Configure(RoomSettings{Visibility: RoomVisibilityPrivate}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	addPlayerToRoomForTest(t, room, "p1")
+
+	if _, err := room.AddPlayerWithAccess(PlayerSnapshot{ID: "p2"}, JoinAccess{}); err != ErrRoomPrivate {
+		t.Fatalf("AddPlayerWithAccess(no invite) error = %v, want %v", err, ErrRoomPrivate)
+	}
+
+	invite := JoinAccess{InviteNonce: "n1", InviteExpiresAt: time.Now().Add(time.Hour)}
+	if _, err := room.AddPlayerWithAccess(PlayerSnapshot{ID: "p2"}, invite); err != nil {
+		t.Fatalf("AddPlayerWithAccess(invite) error = %v", err)
+	}
+	if err := room.KickPlayer("p1", "p2"); err != nil {
+		t.Fatalf("KickPlayer() error = %v", err)
+	}
+	if _, err := room.AddPlayerWithAccess(PlayerSnapshot{ID: "p3"}, invite); err != ErrInviteUsed {
+		t.Fatalf("AddPlayerWithAccess(used invite) error = %v, want %v", err, ErrInviteUsed)
+	}
+}
+
+func TestRoom_AddPlayerWithAccess_PasswordMustBeVerified(t *testing.T) {
+	room := newTicTacToeRoomForTest(t)
+	if err := room.Configure(RoomSettings{PasswordHash: []byte("hash")}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	addPlayerToRoomForTest(t, room, "p1")
+
+	if _, err := room.AddPlayerWithAccess(PlayerSnapshot{ID: "p2"}, JoinAccess{}); err != ErrRoomPasswordInvalid {
+		t.Fatalf("AddPlayerWithAccess(no password) error = %v, want %v", err, ErrRoomPasswordInvalid)
+	}
+	if _, err := room.AddPlayerWithAccess(PlayerSnapshot{ID: "p2"}, JoinAccess{PasswordVerified: true}); err != nil {
+		t.Fatalf("AddPlayerWithAccess(password) error = %v", err)
+	}
+
+	settings := room.Snapshot().Settings
+	if !settings.HasPassword || settings.Visibility != RoomVisibilityPublic || settings.HostID != "p1" {
+		t.Fatalf("settings = %+v, want a public room with a password hosted by p1", settings)
+	}
+}
+
+func TestRoom_KickPlayer_OnlyHostAndKickedPlayerCannotRejoin(t *testing.T) {
+	room := newTicTacToeRoomForTest(t)
+	addPlayerToRoomForTest(t, room, "p1")
+	addPlayerToRoomForTest(t, room, "p2")
+
+	if err := room.KickPlayer("p2", "p1"); err != ErrNotRoomHost {
+		t.Fatalf("KickPlayer(by guest) error = %v, want %v", err, ErrNotRoomHost)
+	}
+	if err := room.KickPlayer("p1", "p1"); err != ErrCannotKickPlayer {
+		t.Fatalf("KickPlayer(self) error = %v, want %v", err, ErrCannotKickPlayer)
+	}
+	if err := room.KickPlayer("p1", "p2"); err != nil {
+		t.Fatalf("KickPlayer() error = %v", err)
+	}
+	if _, err := room.GetPlayer("p2"); err != ErrPlayerNotFound {
+		t.Fatalf("GetPlayer(kicked) error = %v, want %v", err, ErrPlayerNotFound)
+	}
+
+	invite := JoinAccess{InviteNonce: "n1", InviteExpiresAt: time.Now().Add(time.Hour)}
+	if _, err := room.AddPlayerWithAccess(PlayerSnapshot{ID: "p2"}, invite); err != ErrPlayerKicked {
+		t.Fatalf("AddPlayerWithAccess(kicked) error = %v, want %v", err, ErrPlayerKicked)
+	}
+}
+
+func TestRoom_HostLeaving_HandsRoomToRemainingPlayer(t *testing.T) {
+	room := newTicTacToeRoomForTest(t)
+	addPlayerToRoomForTest(t, room, "p1")
+	addPlayerToRoomForTest(t, room, "p2")
+
+	room.HandlePlayerDisconnected("p1")
+
+	if host := room.HostID(); host != "p2" {
+		t.Fatalf("HostID() = %q, want %q", host, "p2")
+	}
+}
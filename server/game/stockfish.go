@@ -7,11 +7,14 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"os/exec"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/tsaqiffatih/mini-game/internal/observability"
 )
 
 const defaultStockfishPath = "stockfish/stockfish"
@@ -24,6 +27,24 @@ type StockfishEngine struct {
 	stdin     io.WriteCloser
 	stdout    *bufio.Reader
 	mu        sync.Mutex
+
+	// status is kept apart from mu, which a search holds for seconds, so
+	// operators can read it while the engine thinks.
+	status   StockfishStatus
+	statusMu sync.Mutex
+}
+
+// StockfishStatus describes an engine process for operators.
+type StockfishStatus struct {
+	Path         string
+	Level        int
+	PID          int
+	Running      bool
+	Searching    bool
+	StartedAt    time.Time
+	Searches     int
+	LastSearchAt time.Time
+	LastError    string
 }
 
 func NewStockfishEngine(path string, level int) (*StockfishEngine, error) {
@@ -63,6 +84,11 @@ func (e *StockfishEngine) start() error {
 
 	e.stdin = stdin
 	e.stdout = bufio.NewReader(stdout)
+	e.updateStatus(func(status *StockfishStatus) {
+		status.PID = e.cmd.Process.Pid
+		status.Running = true
+		status.StartedAt = time.Now().UTC()
+	})
 
 	if err := e.writeLine("uci"); err != nil {
 		e.Close()
@@ -106,7 +132,13 @@ func (e *StockfishEngine) ready() error {
 	return e.readUntil("readyok", 2*time.Second)
 }
 
-func (e *StockfishEngine) BestMove(ctx context.Context, fen string) (string, string, string, error) {
+func (e *StockfishEngine) BestMove(ctx context.Context, fen string) (from string, to string, promotion string, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, endSpan := observability.StartSpan(ctx, "stockfish.bestmove", slog.Int("level", e.level))
+	defer func() { endSpan(err) }()
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
@@ -114,11 +146,22 @@ func (e *StockfishEngine) BestMove(ctx context.Context, fen string) (string, str
 		return "", "", "", errors.New("stockfish is not running")
 	}
 
-	if ctx == nil {
-		ctx = context.Background()
-	}
 	moveCtx, cancel := context.WithTimeout(ctx, e.thinkTime+2*time.Second)
 	defer cancel()
+	startedAt := time.Now()
+	e.updateStatus(func(status *StockfishStatus) {
+		status.Searching = true
+		status.Searches++
+		status.LastSearchAt = startedAt.UTC()
+	})
+	defer func() {
+		e.updateStatus(func(status *StockfishStatus) {
+			status.Searching = false
+			if err != nil {
+				status.LastError = err.Error()
+			}
+		})
+	}()
 
 	if err := e.writeLine("position fen " + fen); err != nil {
 		return "", "", "", err
@@ -153,11 +196,15 @@ func (e *StockfishEngine) BestMove(ctx context.Context, fen string) (string, str
 	select {
 	case <-moveCtx.Done():
 		e.stopLocked()
+		if errors.Is(moveCtx.Err(), context.DeadlineExceeded) {
+			observability.StockfishTimeouts.Inc()
+		}
 		return "", "", "", moveCtx.Err()
 	case err := <-errCh:
 		e.stopLocked()
 		return "", "", "", err
 	case line := <-lineCh:
+		observability.StockfishBestMoveDuration.Observe(time.Since(startedAt).Seconds())
 		return parseBestMove(line)
 	}
 }
@@ -189,6 +236,36 @@ func (e *StockfishEngine) stopLocked() {
 	}
 	e.cmd = nil
 	e.stdout = nil
+	e.updateStatus(func(status *StockfishStatus) {
+		status.PID = 0
+		status.Running = false
+	})
+}
+
+// Status reports the engine process without waiting for a search to end.
+func (e *StockfishEngine) Status() StockfishStatus {
+	e.statusMu.Lock()
+	defer e.statusMu.Unlock()
+
+	status := e.status
+	status.Path = e.path
+	status.Level = e.level
+	return status
+}
+
+func (e *StockfishEngine) updateStatus(update func(*StockfishStatus)) {
+	e.statusMu.Lock()
+	defer e.statusMu.Unlock()
+
+	update(&e.status)
+}
+
+// StockfishBinary returns the engine binary rooms start and whether it can
+// be run, so a missing binary shows before a player picks the AI.
+func StockfishBinary() (string, error) {
+	path := stockfishPathFromEnv()
+	_, err := exec.LookPath(path)
+	return path, err
 }
 
 func (e *StockfishEngine) writeLine(command string) error {
@@ -0,0 +1,42 @@
+package game
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	DefaultTakebackTimeout = 15 * time.Second
+	DefaultTakebackLimit   = 3
+)
+
+var (
+	ErrTakebackPending       = errors.New("takeback already pending")
+	ErrTakebackNotPending    = errors.New("no takeback pending")
+	ErrTakebackLimitReached  = errors.New("takeback limit reached")
+	ErrTakebackNothingToUndo = errors.New("no moves to take back")
+	ErrTakebackOwnRequest    = errors.New("cannot answer your own takeback request")
+)
+
+// takebackRequest is a pending human-vs-human request to roll the game back
+// to before the requester's latest move. It stays pending until the opponent
+// answers, either player moves, or the timeout expires.
+type takebackRequest struct {
+	RequesterID string
+	RequestedAt time.Time
+	ExpiresAt   time.Time
+}
+
+type TakebackSnapshot struct {
+	Pending     bool
+	RequestedBy string
+	ExpiresAt   time.Time
+	Limit       int
+	Used        map[string]int
+}
+
+type TakebackResult struct {
+	// Applied is true when the takeback was rolled back immediately, which
+	// happens in AI rooms and when the opponent accepts.
+	Applied bool
+}
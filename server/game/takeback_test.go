@@ -0,0 +1,263 @@
+package game
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newChessTakebackTestRoom(t *testing.T) *Room {
+	t.Helper()
+
+	room, err := NewRoom("chess-takeback", "chess")
+	if err != nil {
+		t.Fatalf("NewRoom() error = %v", err)
+	}
+	addPlayerToRoomForTest(t, room, "p1")
+	addPlayerToRoomForTest(t, room, "p2")
+	return room
+}
+
+func TestRoom_RespondTakeback_AcceptRollsBackRequesterMove(t *testing.T) {
+	room := newChessTakebackTestRoom(t)
+
+	if _, err := room.HandleChessMove("p1", "e2", "e4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+	if _, err := room.HandleChessMove("p2", "e7", "e5", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+
+	result, err := room.RequestTakeback("p1")
+	if err != nil {
+		t.Fatalf("RequestTakeback() error = %v", err)
+	}
+	if result.Applied {
+		t.Fatalf("Applied = true, want pending request in human room")
+	}
+
+	pending := room.Snapshot()
+	if !pending.Takeback.Pending || pending.Takeback.RequestedBy != "p1" {
+		t.Fatalf("takeback = %+v, want pending request by p1", pending.Takeback)
+	}
+	if pending.Chess.Undo.Pending != "p1" {
+		t.Fatalf("undo pending = %q, want p1", pending.Chess.Undo.Pending)
+	}
+
+	if _, err := room.RespondTakeback("p1", true); !errors.Is(err, ErrTakebackOwnRequest) {
+		t.Fatalf("RespondTakeback() by requester error = %v, want %v", err, ErrTakebackOwnRequest)
+	}
+
+	result, err = room.RespondTakeback("p2", true)
+	if err != nil {
+		t.Fatalf("RespondTakeback() error = %v", err)
+	}
+	if !result.Applied {
+		t.Fatalf("Applied = false, want accepted takeback applied")
+	}
+
+	snapshot := room.Snapshot()
+	if moves := len(snapshot.Chess.PGNMoves); moves != 0 {
+		t.Fatalf("PGN moves len = %d, want both plies rolled back", moves)
+	}
+	if snapshot.Chess.Turn != "white" {
+		t.Fatalf("turn = %q, want white after takeback", snapshot.Chess.Turn)
+	}
+	if snapshot.Takeback.Pending {
+		t.Fatalf("takeback pending = true, want cleared")
+	}
+	if used := snapshot.Takeback.Used["p1"]; used != 1 {
+		t.Fatalf("takebacks used by p1 = %d, want 1", used)
+	}
+}
+
+func TestRoom_RespondTakeback_DeclineKeepsPosition(t *testing.T) {
+	room := newChessTakebackTestRoom(t)
+
+	if _, err := room.HandleChessMove("p1", "e2", "e4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+	if _, err := room.RequestTakeback("p1"); err != nil {
+		t.Fatalf("RequestTakeback() error = %v", err)
+	}
+	if _, err := room.RequestTakeback("p1"); !errors.Is(err, ErrTakebackPending) {
+		t.Fatalf("second RequestTakeback() error = %v, want %v", err, ErrTakebackPending)
+	}
+
+	result, err := room.RespondTakeback("p2", false)
+	if err != nil {
+		t.Fatalf("RespondTakeback() error = %v", err)
+	}
+	if result.Applied {
+		t.Fatalf("Applied = true, want declined takeback")
+	}
+
+	snapshot := room.Snapshot()
+	if moves := len(snapshot.Chess.PGNMoves); moves != 1 {
+		t.Fatalf("PGN moves len = %d, want position kept", moves)
+	}
+	if snapshot.Takeback.Pending || snapshot.Takeback.Used["p1"] != 0 {
+		t.Fatalf("takeback = %+v, want cleared without using the limit", snapshot.Takeback)
+	}
+}
+
+func TestRoom_RequestTakeback_ExpiresAfterTimeout(t *testing.T) {
+	room := newChessTakebackTestRoom(t)
+	room.SetTakebackPolicy(10*time.Millisecond, DefaultTakebackLimit)
+
+	if _, err := room.HandleChessMove("p1", "e2", "e4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+	if _, err := room.RequestTakeback("p1"); err != nil {
+		t.Fatalf("RequestTakeback() error = %v", err)
+	}
+
+	deadline := time.Now().Add(250 * time.Millisecond)
+	for time.Now().Before(deadline) && room.Snapshot().Takeback.Pending {
+		time.Sleep(time.Millisecond)
+	}
+
+	if room.Snapshot().Takeback.Pending {
+		t.Fatalf("takeback pending = true, want expired request")
+	}
+	if _, err := room.RespondTakeback("p2", true); !errors.Is(err, ErrTakebackNotPending) {
+		t.Fatalf("RespondTakeback() error = %v, want %v", err, ErrTakebackNotPending)
+	}
+}
+
+func TestRoom_RequestTakeback_MoveCancelsPendingRequest(t *testing.T) {
+	room := newChessTakebackTestRoom(t)
+
+	if _, err := room.HandleChessMove("p1", "e2", "e4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+	if _, err := room.RequestTakeback("p1"); err != nil {
+		t.Fatalf("RequestTakeback() error = %v", err)
+	}
+	if _, err := room.HandleChessMove("p2", "e7", "e5", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+
+	if room.Snapshot().Takeback.Pending {
+		t.Fatalf("takeback pending = true, want request cancelled by move")
+	}
+}
+
+func TestRoom_RequestTakeback_EnforcesLimit(t *testing.T) {
+	room := newChessTakebackTestRoom(t)
+	room.SetTakebackPolicy(0, 1)
+
+	if _, err := room.RequestTakeback("p1"); !errors.Is(err, ErrTakebackNothingToUndo) {
+		t.Fatalf("RequestTakeback() before moving error = %v, want %v", err, ErrTakebackNothingToUndo)
+	}
+
+	if _, err := room.HandleChessMove("p1", "e2", "e4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+	if _, err := room.RequestTakeback("p1"); err != nil {
+		t.Fatalf("RequestTakeback() error = %v", err)
+	}
+	if _, err := room.RespondTakeback("p2", true); err != nil {
+		t.Fatalf("RespondTakeback() error = %v", err)
+	}
+	if _, err := room.HandleChessMove("p1", "d2", "d4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+
+	if _, err := room.RequestTakeback("p1"); !errors.Is(err, ErrTakebackLimitReached) {
+		t.Fatalf("RequestTakeback() error = %v, want %v", err, ErrTakebackLimitReached)
+	}
+}
+
+func TestRoom_Snapshot_UndoCanRequestFollowsTakebackLimit(t *testing.T) {
+	room := newChessTakebackTestRoom(t)
+	room.SetTakebackPolicy(0, 1)
+
+	if undo := room.Snapshot().Chess.Undo; undo.CanRequest {
+		t.Fatalf("undo = %+v, want no takeback before any move", undo)
+	}
+	if _, err := room.HandleChessMove("p1", "e2", "e4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+	if undo := room.Snapshot().Chess.Undo; !undo.RequestableBy["p1"] || undo.RequestableBy["p2"] {
+		t.Fatalf("requestable by = %v, want p1 only", undo.RequestableBy)
+	}
+
+	if _, err := room.RequestTakeback("p1"); err != nil {
+		t.Fatalf("RequestTakeback() error = %v", err)
+	}
+	if undo := room.Snapshot().Chess.Undo; undo.CanRequest {
+		t.Fatalf("undo = %+v, want no request while one is pending", undo)
+	}
+	if _, err := room.RespondTakeback("p2", true); err != nil {
+		t.Fatalf("RespondTakeback() error = %v", err)
+	}
+	if _, err := room.HandleChessMove("p1", "d2", "d4", ""); err != nil {
+		t.Fatalf("HandleChessMove() error = %v", err)
+	}
+
+	undo := room.Snapshot().Chess.Undo
+	if undo.CanRequest || undo.RequestableBy["p1"] {
+		t.Fatalf("undo = %+v, want p1's takeback limit used up", undo)
+	}
+}
+
+func TestRoom_RequestTakeback_TicTacToeHumanRoom(t *testing.T) {
+	room, err := NewRoom("ttt-takeback", "tictactoe")
+	if err != nil {
+		t.Fatalf("NewRoom() error = %v", err)
+	}
+	addPlayerToRoomForTest(t, room, "p1")
+	addPlayerToRoomForTest(t, room, "p2")
+
+	if _, err := room.HandleTicTacToeMove("p1", 0, 0); err != nil {
+		t.Fatalf("HandleTicTacToeMove() error = %v", err)
+	}
+	if _, err := room.HandleTicTacToeMove("p2", 1, 1); err != nil {
+		t.Fatalf("HandleTicTacToeMove() error = %v", err)
+	}
+	if _, err := room.RequestTakeback("p2"); err != nil {
+		t.Fatalf("RequestTakeback() error = %v", err)
+	}
+	if _, err := room.RespondTakeback("p1", true); err != nil {
+		t.Fatalf("RespondTakeback() error = %v", err)
+	}
+
+	snapshot := room.Snapshot()
+	if filled := countFilledCells(snapshot.TicTacToe.Board); filled != 1 {
+		t.Fatalf("filled cells = %d, want p2 move rolled back", filled)
+	}
+	if snapshot.TicTacToe.Turn != "O" {
+		t.Fatalf("turn = %q, want O after takeback", snapshot.TicTacToe.Turn)
+	}
+}
+
+func TestRoom_RequestTakeback_TicTacToeAIRoomUndoesImmediately(t *testing.T) {
+	room, err := NewRoomWithAILevel("ttt-ai-takeback", "tictactoe", 10)
+	if err != nil {
+		t.Fatalf("NewRoomWithAILevel() error = %v", err)
+	}
+	room.SetAIMoveDelay(10 * time.Millisecond)
+	addPlayerToRoomForTest(t, room, "p1")
+
+	if _, err := room.HandleTicTacToeMove("p1", 0, 0); err != nil {
+		t.Fatalf("HandleTicTacToeMove() error = %v", err)
+	}
+	waitForFilledCells(t, room, 2, 250*time.Millisecond)
+
+	result, err := room.RequestTakeback("p1")
+	if err != nil {
+		t.Fatalf("RequestTakeback() error = %v", err)
+	}
+	if !result.Applied {
+		t.Fatalf("Applied = false, want immediate undo in AI room")
+	}
+
+	snapshot := room.Snapshot()
+	if filled := countFilledCells(snapshot.TicTacToe.Board); filled != 0 {
+		t.Fatalf("filled cells = %d, want human move and AI reply undone", filled)
+	}
+	if snapshot.TicTacToe.Turn != "X" {
+		t.Fatalf("turn = %q, want X after undo", snapshot.TicTacToe.Turn)
+	}
+}
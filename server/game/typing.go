@@ -0,0 +1,114 @@
+package game
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultTypingTimeout is how long a typing indicator lasts unless the
+// player reports typing again or stops.
+const DefaultTypingTimeout = 5 * time.Second
+
+// TypingState tells the room a player started or stopped typing. Like
+// reactions it is not kept.
+type TypingState struct {
+	RoomID    string
+	PlayerID  string
+	Typing    bool
+	ExpiresAt time.Time
+	// Audience lists the players shown the change: everyone else in the
+	// room who has not muted the typist.
+	Audience []string
+}
+
+// typingIndicator is a player's typing state until expiresAt. A refresh
+// replaces it, so a timer finding another indicator in its place is stale.
+type typingIndicator struct {
+	expiresAt time.Time
+	timer     *time.Timer
+}
+
+func (r *Room) SetTypingTimeout(timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if timeout > 0 {
+		r.typingTimeout = timeout
+	}
+}
+
+// SetTypingNotifier registers the callback told when a typing indicator
+// expires on its own.
+func (r *Room) SetTypingNotifier(notifier func(context.Context, TypingState)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.typingNotifier = notifier
+}
+
+// SetTyping starts, refreshes or stops the typing indicator of playerID. It
+// reports whether the indicator was switched on or off, which is when the
+// other players need telling; a refresh only pushes the expiry back.
+func (r *Room) SetTyping(playerID string, typing bool) (TypingState, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.players[playerID]; !exists {
+		return TypingState{}, false, ErrPlayerNotFound
+	}
+	if !typing {
+		return r.stopTypingLocked(playerID)
+	}
+
+	_, wasTyping := r.typing[playerID]
+	r.clearTypingLocked(playerID)
+	indicator := &typingIndicator{expiresAt: time.Now().UTC().Add(r.typingTimeout)}
+	indicator.timer = time.AfterFunc(r.typingTimeout, func() {
+		r.expireTyping(playerID, indicator)
+	})
+	r.typing[playerID] = indicator
+
+	return TypingState{
+		RoomID:    r.RoomID,
+		PlayerID:  playerID,
+		Typing:    true,
+		ExpiresAt: indicator.expiresAt,
+		Audience:  r.listenersLocked(playerID, false),
+	}, !wasTyping, nil
+}
+
+func (r *Room) stopTypingLocked(playerID string) (TypingState, bool, error) {
+	state := TypingState{
+		RoomID:   r.RoomID,
+		PlayerID: playerID,
+		Audience: r.listenersLocked(playerID, false),
+	}
+	return state, r.clearTypingLocked(playerID), nil
+}
+
+func (r *Room) expireTyping(playerID string, indicator *typingIndicator) {
+	r.mu.Lock()
+	if r.typing[playerID] != indicator {
+		r.mu.Unlock()
+		return
+	}
+	state, _, _ := r.stopTypingLocked(playerID)
+	notifier := r.typingNotifier
+	r.mu.Unlock()
+
+	if notifier != nil {
+		notifier(context.Background(), state)
+	}
+}
+
+// clearTypingLocked drops the player's indicator without telling anyone,
+// reporting whether there was one.
+func (r *Room) clearTypingLocked(playerID string) bool {
+	indicator, typing := r.typing[playerID]
+	if !typing {
+		return false
+	}
+	indicator.timer.Stop()
+	delete(r.typing, playerID)
+	return true
+}
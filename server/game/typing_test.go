@@ -0,0 +1,42 @@
+package game
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRoom_SetTyping_ExpiresAndTellsTheOtherPlayers(t *testing.T) {
+	room := newChatTestRoom(t)
+	if _, err := room.AddPlayer(PlayerSnapshot{ID: "p2"}); err != nil {
+		t.Fatalf("AddPlayer() error = %v", err)
+	}
+	room.SetTypingTimeout(20 * time.Millisecond)
+	expired := make(chan TypingState, 1)
+	room.SetTypingNotifier(func(_ context.Context, state TypingState) {
+		expired <- state
+	})
+
+	state, changed, err := room.SetTyping("p1", true)
+	if err != nil {
+		t.Fatalf("SetTyping() error = %v", err)
+	}
+	if !changed || !state.Typing || len(state.Audience) != 1 || state.Audience[0] != "p2" {
+		t.Fatalf("SetTyping() = %+v, %v, want p1 typing shown to p2", state, changed)
+	}
+	if _, changed, _ := room.SetTyping("p1", true); changed {
+		t.Fatalf("SetTyping() refresh changed = true, want false")
+	}
+
+	select {
+	case state := <-expired:
+		if state.PlayerID != "p1" || state.Typing {
+			t.Fatalf("expired state = %+v, want p1 stopped typing", state)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("typing indicator did not expire")
+	}
+	if _, changed, _ := room.SetTyping("p1", false); changed {
+		t.Fatalf("SetTyping(false) after expiry changed = true, want false")
+	}
+}
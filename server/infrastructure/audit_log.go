@@ -0,0 +1,149 @@
+package infrastructure
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/tsaqiffatih/mini-game/game"
+)
+
+// DefaultAuditMemoryEvents is how many events a MemoryAuditLog keeps.
+const DefaultAuditMemoryEvents = 10000
+
+// MemoryAuditLog keeps the latest audit events of all rooms; the oldest make
+// way once it holds limit events.
+type MemoryAuditLog struct {
+	limit  int
+	events []game.AuditEvent
+	mu     sync.RWMutex
+}
+
+func NewMemoryAuditLog(limit int) *MemoryAuditLog {
+	if limit <= 0 {
+		limit = DefaultAuditMemoryEvents
+	}
+	return &MemoryAuditLog{limit: limit}
+}
+
+func (l *MemoryAuditLog) Append(ctx context.Context, event game.AuditEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events = append(l.events, event)
+	if len(l.events) > l.limit {
+		l.events = append([]game.AuditEvent(nil), l.events[len(l.events)-l.limit:]...)
+	}
+	return nil
+}
+
+func (l *MemoryAuditLog) RoomEvents(ctx context.Context, roomID string) ([]game.AuditEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	events := make([]game.AuditEvent, 0)
+	for _, event := range l.events {
+		if event.RoomID == roomID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// FileAuditLog appends every audit event to a JSON lines file and never
+// rewrites it. Reading a room's events scans the whole file.
+type FileAuditLog struct {
+	path string
+	file *os.File
+	mu   sync.Mutex
+}
+
+func OpenFileAuditLog(path string) (*FileAuditLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditLog{path: path, file: file}, nil
+}
+
+func (l *FileAuditLog) Append(ctx context.Context, event game.AuditEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+func (l *FileAuditLog) RoomEvents(ctx context.Context, roomID string) ([]game.AuditEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ReadAuditLogFile(l.path, roomID)
+}
+
+func (l *FileAuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
+
+// ReadAuditLogFile returns the events of a room from an audit log file,
+// oldest first. An empty roomID returns the events of every room.
+func ReadAuditLogFile(path string, roomID string) ([]game.AuditEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	events, err := ReadAuditEvents(file, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("audit log %s: %w", path, err)
+	}
+	return events, nil
+}
+
+// ReadAuditEvents reads JSON lines audit events, keeping those of roomID, or
+// all of them when roomID is empty.
+func ReadAuditEvents(reader io.Reader, roomID string) ([]game.AuditEvent, error) {
+	events := make([]game.AuditEvent, 0)
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event game.AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if roomID == "" || event.RoomID == roomID {
+			events = append(events, event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
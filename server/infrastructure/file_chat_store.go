@@ -0,0 +1,179 @@
+package infrastructure
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/game"
+)
+
+// chatFileRecord is one line of a chat store file.
+type chatFileRecord struct {
+	ID         string    `json:"id"`
+	RoomID     string    `json:"room_id"`
+	PlayerID   string    `json:"player_id,omitempty"`
+	PlayerMark string    `json:"player_mark,omitempty"`
+	Message    string    `json:"message"`
+	CreatedAt  time.Time `json:"created_at"`
+	System     bool      `json:"system,omitempty"`
+	Recipient  string    `json:"recipient,omitempty"`
+	Audience   []string  `json:"audience"`
+}
+
+// FileChatStore is a MemoryChatStore that survives restarts: every message
+// is appended to a JSON lines file, which is read back on open and rewritten
+// without the dropped messages when pruned.
+type FileChatStore struct {
+	*MemoryChatStore
+	path string
+	file *os.File
+}
+
+func OpenFileChatStore(path string, retention ChatRetention) (*FileChatStore, error) {
+	store := &FileChatStore{MemoryChatStore: NewMemoryChatStore(retention), path: path}
+
+	existing, err := os.Open(path)
+	switch {
+	case err == nil:
+		defer existing.Close()
+		scanner := bufio.NewScanner(existing)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			var record chatFileRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				return nil, fmt.Errorf("chat store %s line %d: %w", path, line, err)
+			}
+			store.appendLocked(record.chatRecord())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	// Rewriting drops what the retention no longer allows.
+	store.pruneLocked(time.Now())
+	if err := store.rewriteLocked(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *FileChatStore) Append(ctx context.Context, record game.ChatRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	line, err := json.Marshal(newChatFileRecord(record))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.appendLocked(record)
+	return nil
+}
+
+// Prune drops the messages past the retention, from memory and the file.
+func (s *FileChatStore) Prune(ctx context.Context, now time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pruned := s.pruneLocked(now)
+	// The per room cap drops messages from memory as they come; the rewrite
+	// drops them from the file too.
+	return pruned, s.rewriteLocked()
+}
+
+func (s *FileChatStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+// rewriteLocked replaces the file with the records kept in memory and
+// reopens it for appending.
+func (s *FileChatStore) rewriteLocked() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, record := range s.recordsLocked() {
+		if err := encoder.Encode(newChatFileRecord(record)); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.file = file
+	return nil
+}
+
+func newChatFileRecord(record game.ChatRecord) chatFileRecord {
+	message := record.Message
+	return chatFileRecord{
+		ID:         message.ID,
+		RoomID:     message.RoomID,
+		PlayerID:   message.PlayerID,
+		PlayerMark: message.PlayerMark,
+		Message:    message.Message,
+		CreatedAt:  message.CreatedAt,
+		System:     message.System,
+		Recipient:  message.Recipient,
+		Audience:   record.Audience,
+	}
+}
+
+func (r chatFileRecord) chatRecord() game.ChatRecord {
+	return game.ChatRecord{
+		Message: game.ChatMessage{
+			ID:         r.ID,
+			RoomID:     r.RoomID,
+			PlayerID:   r.PlayerID,
+			PlayerMark: r.PlayerMark,
+			Message:    r.Message,
+			CreatedAt:  r.CreatedAt,
+			System:     r.System,
+			Recipient:  r.Recipient,
+		},
+		Audience: r.Audience,
+	}
+}
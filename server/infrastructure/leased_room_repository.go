@@ -0,0 +1,211 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/backplane"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+)
+
+// DefaultRoomLeaseTTL is how long a room stays owned by a node that stopped
+// renewing its leases.
+const DefaultRoomLeaseTTL = 30 * time.Second
+
+type roomStore interface {
+	GetByID(ctx context.Context, roomID string) (*game.Room, error)
+	Save(ctx context.Context, room *game.Room) error
+	Delete(ctx context.Context, roomID string) error
+	List(ctx context.Context) ([]*game.Room, error)
+}
+
+// LeasedRoomRepository keeps rooms in a local store and holds a backplane
+// lease for each of them, so exactly one node owns a room ID across the
+// cluster. Leases must be renewed with KeepLeases; a node that stops renewing
+// loses its rooms to whichever node creates them next, and a room whose lease
+// is lost is evicted so two nodes never serve it at once.
+type LeasedRoomRepository struct {
+	rooms  roomStore
+	leases backplane.Leases
+	nodeID string
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	renewedAt map[string]time.Time
+	leaseLost func(ctx context.Context, snapshot game.RoomSnapshot)
+}
+
+func NewLeasedRoomRepository(rooms roomStore, leases backplane.Leases, nodeID string, ttl time.Duration) *LeasedRoomRepository {
+	return &LeasedRoomRepository{
+		rooms:     rooms,
+		leases:    leases,
+		nodeID:    nodeID,
+		ttl:       ttl,
+		renewedAt: make(map[string]time.Time),
+	}
+}
+
+// SetLeaseLostNotifier is called with the last state of each room evicted
+// because its lease went to another node or could not be renewed before it
+// ran out, so the caller can disconnect the room's players.
+func (r *LeasedRoomRepository) SetLeaseLostNotifier(notifier func(ctx context.Context, snapshot game.RoomSnapshot)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.leaseLost = notifier
+}
+
+func (r *LeasedRoomRepository) GetByID(ctx context.Context, roomID string) (*game.Room, error) {
+	return r.rooms.GetByID(ctx, roomID)
+}
+
+func (r *LeasedRoomRepository) Save(ctx context.Context, room *game.Room) error {
+	acquired, err := r.leases.AcquireLease(ctx, backplane.RoomLease(room.RoomID), r.nodeID, r.ttl)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return errors.New("room already exists")
+	}
+
+	if err := r.rooms.Save(ctx, room); err != nil {
+		_ = r.leases.ReleaseLease(ctx, backplane.RoomLease(room.RoomID), r.nodeID)
+		return err
+	}
+	r.markRenewed(room.RoomID, time.Now())
+	return nil
+}
+
+func (r *LeasedRoomRepository) Delete(ctx context.Context, roomID string) error {
+	if err := r.rooms.Delete(ctx, roomID); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	delete(r.renewedAt, roomID)
+	r.mu.Unlock()
+	return r.leases.ReleaseLease(ctx, backplane.RoomLease(roomID), r.nodeID)
+}
+
+func (r *LeasedRoomRepository) List(ctx context.Context) ([]*game.Room, error) {
+	return r.rooms.List(ctx)
+}
+
+// Ping reports whether the backplane holding the leases answers.
+func (r *LeasedRoomRepository) Ping(ctx context.Context) error {
+	_, err := r.leases.LeaseOwner(ctx, backplane.RoomLease(""))
+	return err
+}
+
+// KeepLeases renews the lease of every local room each interval until ctx is
+// done. interval should be well below the lease TTL.
+func (r *LeasedRoomRepository) KeepLeases(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.renewLeases(ctx)
+		}
+	}
+}
+
+// ReleaseLeases gives up the lease of every local room, for a node that is
+// shutting down. The rooms it saved can then be restored by whichever node
+// starts next instead of waiting out the lease TTL.
+func (r *LeasedRoomRepository) ReleaseLeases(ctx context.Context) error {
+	rooms, err := r.rooms.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, room := range rooms {
+		if err := r.leases.ReleaseLease(ctx, backplane.RoomLease(room.RoomID), r.nodeID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *LeasedRoomRepository) renewLeases(ctx context.Context) {
+	rooms, err := r.rooms.List(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, room := range rooms {
+		now := time.Now()
+		acquired, err := r.leases.AcquireLease(ctx, backplane.RoomLease(room.RoomID), r.nodeID, r.ttl)
+		if err != nil {
+			observability.Logger().WarnContext(ctx, "room lease renewal failed",
+				"room_id", room.RoomID,
+				"player_id", "",
+				"event_type", "room_lease_error",
+				"error", err,
+			)
+			// Past its TTL the lease may already be someone else's.
+			if now.Sub(r.lastRenewed(room.RoomID, now)) >= r.ttl {
+				r.evict(ctx, room)
+			}
+			continue
+		}
+		if !acquired {
+			observability.Logger().WarnContext(ctx, "room lease lost to another node",
+				"room_id", room.RoomID,
+				"player_id", "",
+				"event_type", "room_lease_lost",
+			)
+			r.evict(ctx, room)
+			continue
+		}
+		r.markRenewed(room.RoomID, now)
+	}
+}
+
+// evict drops a room this node no longer owns without touching its lease,
+// which is not this node's to release.
+func (r *LeasedRoomRepository) evict(ctx context.Context, room *game.Room) {
+	snapshot := room.Snapshot()
+	room.Close()
+	if err := r.rooms.Delete(ctx, room.RoomID); err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	delete(r.renewedAt, room.RoomID)
+	notify := r.leaseLost
+	r.mu.Unlock()
+
+	observability.Logger().WarnContext(ctx, "room evicted after losing its lease",
+		"room_id", room.RoomID,
+		"player_id", "",
+		"event_type", "room_lease_evicted",
+	)
+	if notify != nil {
+		notify(ctx, snapshot)
+	}
+}
+
+func (r *LeasedRoomRepository) markRenewed(roomID string, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.renewedAt[roomID] = at
+}
+
+// lastRenewed returns when the lease of roomID was last taken or extended.
+// A room saved straight into the local store counts as renewed now.
+func (r *LeasedRoomRepository) lastRenewed(roomID string, now time.Time) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	at, ok := r.renewedAt[roomID]
+	if !ok {
+		r.renewedAt[roomID] = now
+		return now
+	}
+	return at
+}
@@ -0,0 +1,52 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/tsaqiffatih/mini-game/auth"
+)
+
+type MemoryAccountRepository struct {
+	accounts map[string]*auth.Account
+	mu       sync.RWMutex
+}
+
+func NewMemoryAccountRepository() *MemoryAccountRepository {
+	return &MemoryAccountRepository{
+		accounts: make(map[string]*auth.Account),
+	}
+}
+
+func (r *MemoryAccountRepository) GetByID(ctx context.Context, accountID string) (*auth.Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	account, exists := r.accounts[accountID]
+	if exists {
+		return account, nil
+	}
+
+	return nil, errors.New("account not found")
+}
+
+func (r *MemoryAccountRepository) Save(ctx context.Context, account *auth.Account) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.accounts[account.ID]; exists {
+		return errors.New("account already exists")
+	}
+
+	r.accounts[account.ID] = account
+	return nil
+}
@@ -0,0 +1,153 @@
+package infrastructure
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/game"
+)
+
+const (
+	DefaultChatMaxAge     = 30 * 24 * time.Hour
+	DefaultChatMaxPerRoom = 1000
+)
+
+// ChatRetention bounds what a chat store keeps. A zero field keeps
+// everything along that dimension.
+type ChatRetention struct {
+	// MaxAge is how long a message is kept; Prune drops older ones.
+	MaxAge time.Duration
+	// MaxPerRoom is how many messages a room keeps; the oldest make way.
+	MaxPerRoom int
+}
+
+// DefaultChatRetention keeps 30 days and 1000 messages per room.
+var DefaultChatRetention = ChatRetention{MaxAge: DefaultChatMaxAge, MaxPerRoom: DefaultChatMaxPerRoom}
+
+// MemoryChatStore keeps chat by room, in the order it was sent.
+type MemoryChatStore struct {
+	retention ChatRetention
+	rooms     map[string][]game.ChatRecord
+	mu        sync.RWMutex
+}
+
+func NewMemoryChatStore(retention ChatRetention) *MemoryChatStore {
+	return &MemoryChatStore{
+		retention: retention,
+		rooms:     make(map[string][]game.ChatRecord),
+	}
+}
+
+func (s *MemoryChatStore) Append(ctx context.Context, record game.ChatRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.appendLocked(record)
+	return nil
+}
+
+func (s *MemoryChatStore) appendLocked(record game.ChatRecord) {
+	roomID := record.Message.RoomID
+	records := append(s.rooms[roomID], record)
+	if max := s.retention.MaxPerRoom; max > 0 && len(records) > max {
+		records = append([]game.ChatRecord(nil), records[len(records)-max:]...)
+	}
+	s.rooms[roomID] = records
+}
+
+func (s *MemoryChatStore) History(ctx context.Context, roomID string, viewerID string, before string, limit int) ([]game.ChatRecord, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return game.PageChatRecords(s.rooms[roomID], viewerID, before, limit)
+}
+
+// Search returns up to limit messages playerID saw that match every term,
+// the newest first.
+func (s *MemoryChatStore) Search(ctx context.Context, playerID string, terms []string, limit int) ([]game.ChatRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := make([]game.ChatRecord, 0)
+	for _, records := range s.rooms {
+		for _, record := range records {
+			if record.SeenBy(playerID) && record.Matches(terms) {
+				matches = append(matches, record)
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Message.CreatedAt.After(matches[j].Message.CreatedAt)
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// Prune drops the messages older than the retention's MaxAge and reports
+// how many went.
+func (s *MemoryChatStore) Prune(ctx context.Context, now time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pruneLocked(now), nil
+}
+
+func (s *MemoryChatStore) pruneLocked(now time.Time) int {
+	if s.retention.MaxAge <= 0 {
+		return 0
+	}
+
+	cutoff := now.Add(-s.retention.MaxAge)
+	pruned := 0
+	for roomID, records := range s.rooms {
+		keep := sort.Search(len(records), func(i int) bool {
+			return records[i].Message.CreatedAt.After(cutoff)
+		})
+		if keep == 0 {
+			continue
+		}
+		pruned += keep
+		if keep == len(records) {
+			delete(s.rooms, roomID)
+			continue
+		}
+		s.rooms[roomID] = append([]game.ChatRecord(nil), records[keep:]...)
+	}
+	return pruned
+}
+
+// recordsLocked returns every record kept, room by room, for writing them
+// out.
+func (s *MemoryChatStore) recordsLocked() []game.ChatRecord {
+	roomIDs := make([]string, 0, len(s.rooms))
+	for roomID := range s.rooms {
+		roomIDs = append(roomIDs, roomID)
+	}
+	sort.Strings(roomIDs)
+
+	var records []game.ChatRecord
+	for _, roomID := range roomIDs {
+		records = append(records, s.rooms[roomID]...)
+	}
+	return records
+}
@@ -0,0 +1,72 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/tsaqiffatih/mini-game/tournament"
+)
+
+type MemoryTournamentRepository struct {
+	tournaments map[string]*tournament.Tournament
+	mu          sync.RWMutex
+}
+
+func NewMemoryTournamentRepository() *MemoryTournamentRepository {
+	return &MemoryTournamentRepository{
+		tournaments: make(map[string]*tournament.Tournament),
+	}
+}
+
+func (r *MemoryTournamentRepository) GetByID(ctx context.Context, tournamentID string) (*tournament.Tournament, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, exists := r.tournaments[tournamentID]
+	if exists {
+		return t, nil
+	}
+
+	return nil, errors.New("tournament not found")
+}
+
+func (r *MemoryTournamentRepository) Save(ctx context.Context, t *tournament.Tournament) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tournaments[t.ID]; exists {
+		return errors.New("tournament already exists")
+	}
+
+	r.tournaments[t.ID] = t
+	return nil
+}
+
+// List returns tournaments ordered by creation time, oldest first.
+func (r *MemoryTournamentRepository) List(ctx context.Context) ([]*tournament.Tournament, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tournaments := make([]*tournament.Tournament, 0, len(r.tournaments))
+	for _, t := range r.tournaments {
+		tournaments = append(tournaments, t)
+	}
+	sort.Slice(tournaments, func(i, j int) bool {
+		return tournaments[i].CreatedAt.Before(tournaments[j].CreatedAt)
+	})
+	return tournaments, nil
+}
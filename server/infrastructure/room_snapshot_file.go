@@ -0,0 +1,71 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/game"
+)
+
+// roomSnapshotVersion is bumped when the file layout changes in a way older
+// servers cannot read.
+const roomSnapshotVersion = 1
+
+// roomSnapshotFile is the file rooms are saved to on shutdown.
+type roomSnapshotFile struct {
+	Version int               `json:"version"`
+	SavedAt time.Time         `json:"saved_at"`
+	Rooms   []game.RoomRecord `json:"rooms"`
+}
+
+// SaveRoomRecords writes the rooms to path, replacing what was there only
+// once the whole file is written.
+func SaveRoomRecords(path string, records []game.RoomRecord) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(tmp)
+	if err := encoder.Encode(roomSnapshotFile{
+		Version: roomSnapshotVersion,
+		SavedAt: time.Now().UTC(),
+		Rooms:   records,
+	}); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// LoadRoomRecords reads the rooms saved at path. A missing file holds no
+// rooms.
+func LoadRoomRecords(path string) ([]game.RoomRecord, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file roomSnapshotFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("room snapshot %s: %w", path, err)
+	}
+	if file.Version != roomSnapshotVersion {
+		return nil, fmt.Errorf("room snapshot %s: unsupported version %d", path, file.Version)
+	}
+	return file.Rooms, nil
+}
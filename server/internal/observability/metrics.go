@@ -0,0 +1,141 @@
+package observability
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "mini_game"
+
+var (
+	WebSocketClients = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "websocket_clients",
+		Help:      "Websocket clients currently attached to the client registry.",
+	})
+	WebSocketMessagesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "websocket_messages_received_total",
+		Help:      "Inbound websocket messages by action type.",
+	}, []string{"type"})
+	WebSocketMessagesSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "websocket_messages_sent_total",
+		Help:      "Outbound websocket events queued for a client or relayed to another node, by event type.",
+	}, []string{"type"})
+	WebSocketEnqueueDrops = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "websocket_enqueue_drops_total",
+		Help:      "Outbound messages dropped because a client's send queue was full. The client is closed.",
+	})
+	MoveDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "move_duration_seconds",
+		Help:      "Time to apply an accepted player move, by game type.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5},
+	}, []string{"game_type"})
+	StockfishBestMoveDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "stockfish_bestmove_duration_seconds",
+		Help:      "Time Stockfish took to answer a bestmove search.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2, 3, 5, 10},
+	})
+	StockfishTimeouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "stockfish_timeouts_total",
+		Help:      "Stockfish bestmove searches that hit their deadline.",
+	})
+	RateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by a rate limiter, by limiter.",
+	}, []string{"limiter"})
+	WebSocketAbuseClosures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "websocket_abuse_closures_total",
+		Help:      "Connections closed for sending too many rejected messages.",
+	})
+	RoomCleanupRemovals = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "room_cleanup_removals_total",
+		Help:      "Rooms removed by the inactive room cleanup.",
+	})
+)
+
+var roomsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(metricsNamespace, "", "rooms"),
+	"Live rooms by game type and room state.",
+	[]string{"game_type", "state"},
+	nil,
+)
+
+// RoomCount is the number of live rooms of one game type in one state.
+type RoomCount struct {
+	GameType string
+	State    string
+	Rooms    int
+}
+
+// roomCollector reads room counts at scrape time, so the gauge can never
+// drift from the room repository.
+type roomCollector struct {
+	counter func() []RoomCount
+	mu      sync.RWMutex
+}
+
+func (c *roomCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- roomsDesc
+}
+
+func (c *roomCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	counter := c.counter
+	c.mu.RUnlock()
+	if counter == nil {
+		return
+	}
+
+	for _, count := range counter() {
+		ch <- prometheus.MustNewConstMetric(roomsDesc, prometheus.GaugeValue, float64(count.Rooms), count.GameType, count.State)
+	}
+}
+
+var (
+	rooms           = &roomCollector{}
+	metricsRegistry = prometheus.NewRegistry()
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		rooms,
+		WebSocketClients,
+		WebSocketMessagesReceived,
+		WebSocketMessagesSent,
+		WebSocketEnqueueDrops,
+		MoveDuration,
+		StockfishBestMoveDuration,
+		StockfishTimeouts,
+		RateLimitRejections,
+		WebSocketAbuseClosures,
+		RoomCleanupRemovals,
+	)
+}
+
+// SetRoomCounter sets the function the rooms gauge reads on every scrape.
+func SetRoomCounter(counter func() []RoomCount) {
+	rooms.mu.Lock()
+	defer rooms.mu.Unlock()
+
+	rooms.counter = counter
+}
+
+// MetricsHandler serves every metric in the Prometheus text format.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
@@ -8,22 +8,23 @@ import (
 	"net/http"
 	"os"
 	"time"
-)
-
-type contextKey string
 
-const (
-	traceIDKey contextKey = "trace_id"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+var logger = slog.New(newTraceHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 	Level: slog.LevelInfo,
-}))
+})))
 
 func Init(serviceName string) {
-	logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	logger = slog.New(newTraceHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
-	}))
+	})))
 	slog.SetDefault(logger.With("service", serviceName))
 }
 
@@ -31,42 +32,50 @@ func Logger() *slog.Logger {
 	return slog.Default()
 }
 
-func WithTraceID(ctx context.Context, traceID string) context.Context {
-	if traceID == "" {
-		return ctx
+// TraceID returns the W3C trace ID of the span in ctx, or "" when ctx is not
+// part of a sampled trace.
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
 	}
-	return context.WithValue(ctx, traceIDKey, traceID)
+	return spanContext.TraceID().String()
 }
 
-func TraceID(ctx context.Context) string {
-	traceID, _ := ctx.Value(traceIDKey).(string)
-	return traceID
+// StartSpan starts a child span of the span in ctx. The returned function
+// ends it, recording err when it is not nil.
+func StartSpan(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, func(error)) {
+	return startSpan(ctx, name, attrs)
 }
 
-func StartSpan(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, func(error)) {
-	startedAt := time.Now()
-	fields := []slog.Attr{
-		slog.String("event_type", "trace_span_start"),
-		slog.String("span", name),
-	}
-	fields = append(fields, traceAttrs(ctx)...)
-	fields = append(fields, attrs...)
-	Logger().LogAttrs(ctx, slog.LevelDebug, "trace span started", fields...)
+// StartRootSpan starts a span in a new trace that links back to the span in
+// ctx. Use it for units of work inside a long-lived span, such as one message
+// of a websocket connection, so each unit gets its own trace.
+func StartRootSpan(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, func(error)) {
+	return startSpan(ctx, name, attrs,
+		trace.WithNewRoot(),
+		trace.WithLinks(trace.LinkFromContext(ctx)),
+	)
+}
+
+func startSpan(ctx context.Context, name string, attrs []slog.Attr, options ...trace.SpanStartOption) (context.Context, func(error)) {
+	options = append(options, trace.WithAttributes(spanAttributes(attrs)...))
+	ctx, span := tracer().Start(ctx, name, options...)
 
 	return ctx, func(err error) {
-		fields := []slog.Attr{
-			slog.String("event_type", "trace_span_end"),
-			slog.String("span", name),
-			slog.Duration("duration", time.Since(startedAt)),
-		}
-		fields = append(fields, traceAttrs(ctx)...)
-		fields = append(fields, attrs...)
 		if err != nil {
-			fields = append(fields, slog.String("error", err.Error()))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+
+			fields := []slog.Attr{
+				slog.String("event_type", "trace_span_error"),
+				slog.String("span", name),
+				slog.String("error", err.Error()),
+			}
+			fields = append(fields, attrs...)
 			Logger().LogAttrs(ctx, slog.LevelWarn, "trace span ended with error", fields...)
-			return
 		}
-		Logger().LogAttrs(ctx, slog.LevelDebug, "trace span ended", fields...)
+		span.End()
 	}
 }
 
@@ -78,14 +87,35 @@ func RoomAttrs(roomID, playerID, eventType string) []slog.Attr {
 	}
 }
 
+// RequestMiddleware continues the trace of an incoming W3C traceparent
+// header, or starts a new one, in a server span around the request. The
+// trace ID is echoed in the X-Trace-ID response header.
 func RequestMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		startedAt := time.Now()
-		traceID := r.Header.Get("traceparent")
-		if traceID == "" {
-			traceID = r.Header.Get("X-Request-ID")
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer().Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+		if requestID := r.Header.Get("X-Request-ID"); requestID != "" {
+			span.SetAttributes(attribute.String("request_id", requestID))
+		}
+		if traceID := TraceID(ctx); traceID != "" {
+			w.Header().Set("X-Trace-ID", traceID)
 		}
-		ctx := WithTraceID(r.Context(), traceID)
 
 		recorder := &responseRecorder{
 			ResponseWriter: w,
@@ -94,27 +124,22 @@ func RequestMiddleware(next http.Handler) http.Handler {
 
 		next.ServeHTTP(recorder, r.WithContext(ctx))
 
-		attrs := []slog.Attr{
+		span.SetAttributes(attribute.Int("http.response.status_code", recorder.statusCode))
+		if recorder.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.statusCode))
+		}
+
+		Logger().LogAttrs(ctx, slog.LevelInfo, "request completed",
 			slog.String("event_type", "http_request"),
 			slog.String("method", r.Method),
 			slog.String("path", r.URL.Path),
 			slog.Int("status", recorder.statusCode),
 			slog.Duration("duration", time.Since(startedAt)),
 			slog.String("remote_addr", r.RemoteAddr),
-		}
-		attrs = append(attrs, traceAttrs(ctx)...)
-		Logger().LogAttrs(ctx, slog.LevelInfo, "request completed", attrs...)
+		)
 	})
 }
 
-func traceAttrs(ctx context.Context) []slog.Attr {
-	traceID := TraceID(ctx)
-	if traceID == "" {
-		return nil
-	}
-	return []slog.Attr{slog.String("trace_id", traceID)}
-}
-
 type responseRecorder struct {
 	http.ResponseWriter
 	statusCode int
@@ -0,0 +1,140 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/tsaqiffatih/mini-game"
+
+// Trace exporters accepted by InitTracing. The names follow the
+// OTEL_TRACES_EXPORTER environment variable.
+const (
+	TracesExporterNone    = "none"
+	TracesExporterConsole = "console"
+	TracesExporterOTLP    = "otlp"
+)
+
+// The propagator is installed even without InitTracing so incoming trace
+// context is always honoured.
+func init() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// InitTracing installs the global tracer provider. exporter selects where
+// finished spans go:
+//   - "otlp" sends them over OTLP/HTTP to the collector configured by the
+//     standard OTEL_EXPORTER_OTLP_* environment variables;
+//   - "console" writes them to stdout;
+//   - "none" or "" records no spans, but trace IDs are still generated and
+//     propagated so logs and websocket events can be correlated.
+//
+// The returned function flushes pending spans and must be called on shutdown.
+func InitTracing(ctx context.Context, serviceName string, exporter string) (func(context.Context) error, error) {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	}
+
+	switch exporter {
+	case "", TracesExporterNone:
+	case TracesExporterConsole:
+		spanExporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithBatcher(spanExporter))
+	case TracesExporterOTLP:
+		spanExporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithBatcher(spanExporter))
+	default:
+		return nil, fmt.Errorf("unsupported traces exporter %q", exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// InjectTrace returns the trace context of ctx as W3C headers, for messages
+// that leave the process without an HTTP request.
+func InjectTrace(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// ExtractTrace continues a trace context written by InjectTrace.
+func ExtractTrace(ctx context.Context, headers map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+}
+
+func spanAttributes(attrs []slog.Attr) []attribute.KeyValue {
+	values := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		value := attr.Value.Resolve()
+		switch value.Kind() {
+		case slog.KindString:
+			values = append(values, attribute.String(attr.Key, value.String()))
+		case slog.KindInt64:
+			values = append(values, attribute.Int64(attr.Key, value.Int64()))
+		case slog.KindBool:
+			values = append(values, attribute.Bool(attr.Key, value.Bool()))
+		case slog.KindFloat64:
+			values = append(values, attribute.Float64(attr.Key, value.Float64()))
+		default:
+			values = append(values, attribute.String(attr.Key, value.String()))
+		}
+	}
+	return values
+}
+
+// traceHandler adds the trace and span IDs of the record's context to every
+// log line, so logs written with the *Context methods join their trace.
+type traceHandler struct {
+	slog.Handler
+}
+
+func newTraceHandler(handler slog.Handler) slog.Handler {
+	return traceHandler{Handler: handler}
+}
+
+func (h traceHandler) Handle(ctx context.Context, record slog.Record) error {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if spanContext.HasTraceID() {
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceHandler) WithGroup(name string) slog.Handler {
+	return traceHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -3,9 +3,14 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -13,11 +18,16 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"github.com/tsaqiffatih/mini-game/api"
+	"github.com/tsaqiffatih/mini-game/api/dto"
+	"github.com/tsaqiffatih/mini-game/auth"
+	"github.com/tsaqiffatih/mini-game/backplane"
+	"github.com/tsaqiffatih/mini-game/config"
 	"github.com/tsaqiffatih/mini-game/game"
 	"github.com/tsaqiffatih/mini-game/infrastructure"
 	"github.com/tsaqiffatih/mini-game/internal/observability"
 	"github.com/tsaqiffatih/mini-game/middleware"
 	"github.com/tsaqiffatih/mini-game/service"
+	"github.com/tsaqiffatih/mini-game/stats"
 )
 
 func main() {
@@ -32,25 +42,244 @@ func main() {
 		}
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Besides serving, the binary runs operator commands, taking the same
+	// flags as the server:
+	//   mini-game healthcheck       probes /readyz of the running server; the
+	//                               image has no shell or curl to do it.
+	//   mini-game audit ROOM_ID     prints the room's events from the audit
+	//                               log file as JSON lines.
+	//   mini-game replay ROOM_ID [FILE]
+	//                               rebuilds the room from its events in FILE,
+	//                               "-" for stdin, or the audit log file, and
+	//                               prints it with any violations found.
+	args := os.Args[1:]
+	var command, auditRoomID, replayFile string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	switch command {
+	case "", "healthcheck":
+	case "audit":
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			fmt.Fprintln(os.Stderr, "usage: mini-game audit ROOM_ID [flags]")
+			os.Exit(2)
+		}
+		auditRoomID, args = args[0], args[1:]
+	case "replay":
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			fmt.Fprintln(os.Stderr, "usage: mini-game replay ROOM_ID [FILE] [flags]")
+			os.Exit(2)
+		}
+		auditRoomID, args = args[0], args[1:]
+		if len(args) > 0 && (args[0] == "-" || !strings.HasPrefix(args[0], "-")) {
+			replayFile, args = args[0], args[1:]
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", command)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(args, os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		logger.Error("invalid configuration", "event_type", "startup", "error", err)
+		os.Exit(1)
+	}
+	switch command {
+	case "healthcheck":
+		if err := checkReady(cfg.Server.Port); err != nil {
+			logger.Error("healthcheck failed", "event_type", "healthcheck", "error", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case "audit":
+		if err := printAudit(os.Stdout, cfg.Audit.Path, auditRoomID); err != nil {
+			fmt.Fprintf(os.Stderr, "audit: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case "replay":
+		violations, err := printReplay(os.Stdout, os.Stdin, replayFile, cfg.Audit.Path, auditRoomID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+			os.Exit(1)
+		}
+		if violations > 0 {
+			fmt.Fprintf(os.Stderr, "replay: %d violations\n", violations)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if cfg.File != "" {
+		logger.Info("configuration loaded", "event_type", "startup", "path", cfg.File)
+	}
+
+	shutdownTracing, err := observability.InitTracing(context.Background(), "mini-game", os.Getenv("OTEL_TRACES_EXPORTER"))
+	if err != nil {
+		logger.Error("failed to initialize tracing", "event_type", "startup", "error", err)
+		os.Exit(1)
 	}
 
 	playerManager := game.NewPlayerManager()
-	roomRepository := infrastructure.NewMemoryRoomRepository()
+	var roomRepository service.RoomRepository = infrastructure.NewMemoryRoomRepository()
+
+	// BACKPLANE_URL turns this process into one node of a cluster sharing
+	// rooms through Redis. Without it the server runs standalone.
+	var roomBackplane backplane.Backplane
+	var leasedRooms *infrastructure.LeasedRoomRepository
+	nodeID := cfg.Server.NodeID
+	if nodeID == "" {
+		nodeID, _ = os.Hostname()
+	}
+	if cfg.Server.BackplaneURL != "" {
+		roomBackplane, err = backplane.DialRedis(context.Background(), cfg.Server.BackplaneURL, "mini-game:")
+		if err != nil {
+			logger.Error("failed to connect to backplane", "event_type", "startup", "error", err)
+			os.Exit(1)
+		}
+		leasedRooms = infrastructure.NewLeasedRoomRepository(roomRepository, roomBackplane, nodeID, infrastructure.DefaultRoomLeaseTTL)
+		roomRepository = leasedRooms
+	}
+
 	gameService := service.NewGameService(roomRepository, playerManager)
+	gameService.SetRoomConfig(cfg.Room.Game())
 	clients := api.NewClientRegistry()
-	gameService.SetRoomNotifier(func(snapshot game.RoomSnapshot) {
-		api.NotifyGameUpdateToClients(clients, snapshot)
+	clients.SetMessageRateLimits(
+		api.MessageRateLimit{PerSecond: cfg.WebSocket.MessageRate, Burst: cfg.WebSocket.MessageBurst},
+		api.MessageRateLimit{PerSecond: cfg.WebSocket.ChatRate, Burst: cfg.WebSocket.ChatBurst},
+	)
+	api.SetWebSocketTimeouts(api.WebSocketTimeouts{
+		WriteWait:  cfg.WebSocket.WriteWait,
+		PingPeriod: cfg.WebSocket.PingPeriod,
+		PongWait:   cfg.WebSocket.PongWait,
+	})
+	clients.SetConnectionRateLimit(api.MessageRateLimit{PerSecond: cfg.WebSocket.ConnectionRate, Burst: cfg.WebSocket.ConnectionBurst})
+	clients.SetAbusePolicy(api.AbusePolicy{
+		Warnings:   cfg.WebSocket.AbuseWarnings,
+		CloseAfter: cfg.WebSocket.AbuseCloseAfter,
+		Window:     cfg.WebSocket.AbuseWindow,
+	})
+	api.SetMaxMessageSize(int64(cfg.WebSocket.MaxMessageBytes))
+	var cluster *api.Cluster
+	if roomBackplane != nil {
+		cluster = api.NewCluster(nodeID, roomBackplane, clients, gameService)
+	}
+	observability.SetRoomCounter(gameService.RoomCounts)
+	gameService.SetRoomNotifier(func(ctx context.Context, snapshot game.RoomSnapshot) {
+		api.NotifyGameUpdateToClients(ctx, clients, snapshot)
+	})
+	gameService.SetTypingNotifier(func(ctx context.Context, state game.TypingState) {
+		api.NotifyTypingToClients(ctx, clients, state)
+	})
+	tournamentService := service.NewTournamentService(infrastructure.NewMemoryTournamentRepository(), gameService)
+	tournamentService.SetGameStartedNotifier(func(ctx context.Context, event service.TournamentGameStartedEvent) {
+		api.NotifyTournamentGameStarted(ctx, clients, event)
+	})
+	tokenSecret := []byte(cfg.Auth.TokenSecret)
+	if len(tokenSecret) == 0 {
+		secret, err := auth.RandomSecret()
+		if err != nil {
+			logger.Error("failed to generate token secret", "event_type", "startup", "error", err)
+			os.Exit(1)
+		}
+		tokenSecret = secret
+		logger.Warn("AUTH_TOKEN_SECRET not set, sessions will not survive a restart", "event_type", "startup")
+	}
+	tokenSigner := auth.NewTokenSigner(tokenSecret, auth.DefaultTokenTTL)
+	authService := service.NewAuthService(
+		infrastructure.NewMemoryAccountRepository(),
+		tokenSigner,
+		gameService,
+	)
+	gameService.SetInviteSigner(tokenSigner)
+	gameService.SetChatFilter(game.NewChatFilter(cfg.Chat.BlockedWords))
+	chatRetention := infrastructure.ChatRetention{MaxAge: cfg.Chat.Retention, MaxPerRoom: cfg.Chat.RetentionMessages}
+	var chatStore service.ChatStore = infrastructure.NewMemoryChatStore(chatRetention)
+	if cfg.Chat.StorePath != "" {
+		fileStore, err := infrastructure.OpenFileChatStore(cfg.Chat.StorePath, chatRetention)
+		if err != nil {
+			logger.Error("failed to open chat store", "event_type", "startup", "path", cfg.Chat.StorePath, "error", err)
+			os.Exit(1)
+		}
+		defer fileStore.Close()
+		chatStore = fileStore
+	}
+	gameService.SetChatStore(chatStore)
+	var auditLog service.AuditLog = infrastructure.NewMemoryAuditLog(cfg.Audit.MemoryEvents)
+	if cfg.Audit.Path != "" {
+		fileLog, err := infrastructure.OpenFileAuditLog(cfg.Audit.Path)
+		if err != nil {
+			logger.Error("failed to open audit log", "event_type", "startup", "path", cfg.Audit.Path, "error", err)
+			os.Exit(1)
+		}
+		defer fileLog.Close()
+		auditLog = fileLog
+	}
+	gameService.SetAuditLog(auditLog)
+	statsStore := stats.NewStore()
+	statsService := service.NewStatsService(statsStore, gameService)
+	lobbyService := service.NewLobbyService(gameService, statsStore)
+	gameService.SetRoomChangeNotifier(lobbyService.HandleRoomChange)
+	if cluster != nil {
+		cluster.SetLobby(lobbyService)
+	}
+	if leasedRooms != nil {
+		leasedRooms.SetLeaseLostNotifier(func(ctx context.Context, snapshot game.RoomSnapshot) {
+			api.CloseRoomConnections(ctx, clients, snapshot, "room moved to another node")
+			lobbyService.HandleRoomChange(ctx, snapshot.RoomID)
+		})
+	}
+	gameService.SetGameOutcomeNotifier(func(outcome game.GameOutcome) {
+		statsService.RecordGameOutcome(outcome)
+		tournamentService.HandleGameOutcome(outcome)
 	})
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 	gameService.SetContext(ctx)
 
+	middleware.SetRateLimit(cfg.HTTP.RateLimit, cfg.HTTP.RateBurst)
+	trustedProxies, err := cfg.Server.TrustedProxyPrefixes()
+	if err != nil {
+		logger.Error("invalid trusted proxies", "event_type", "startup", "error", err)
+		os.Exit(1)
+	}
+	middleware.SetTrustedProxies(trustedProxies)
 	middleware.StartRateLimiterCleanup(ctx)
 
+	if cluster != nil {
+		if err := cluster.Start(ctx); err != nil {
+			logger.Error("failed to join cluster", "event_type", "startup", "error", err)
+			os.Exit(1)
+		}
+		go leasedRooms.KeepLeases(ctx, infrastructure.DefaultRoomLeaseTTL/3)
+		logger.Info("cluster node started", "event_type", "startup", "node_id", nodeID)
+	}
+
+	roomSnapshotPath := cfg.Server.RoomSnapshotPath
+	if roomSnapshotPath != "" {
+		records, err := infrastructure.LoadRoomRecords(roomSnapshotPath)
+		if err != nil {
+			logger.Error("failed to read room snapshot", "event_type", "startup", "path", roomSnapshotPath, "error", err)
+			os.Exit(1)
+		}
+		if len(records) > 0 {
+			restored, err := gameService.RestoreRoomsWithContext(ctx, records)
+			if err != nil {
+				logger.Error("failed to restore rooms", "event_type", "startup", "error", err)
+				os.Exit(1)
+			}
+			logger.Info("rooms restored", "event_type", "startup", "rooms", restored, "saved", len(records))
+			// The snapshot is used once; a crash before the next shutdown must
+			// not bring these games back as they were.
+			if err := os.Remove(roomSnapshotPath); err != nil {
+				logger.Warn("failed to remove room snapshot", "event_type", "startup", "path", roomSnapshotPath, "error", err)
+			}
+		}
+	}
+
 	r := mux.NewRouter()
 
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -63,35 +292,48 @@ func main() {
 		json.NewEncoder(w).Encode(response)
 	})
 
+	r.Handle("/metrics", observability.MetricsHandler()).Methods("GET")
+
+	api.RegisterHealthRouter(r, gameService)
 	api.RegisterRouter(
 		r,
 		clients,
 		gameService,
+		authService,
 	)
+	api.RegisterAuthRouter(r, authService)
+	api.RegisterTournamentRouter(r, tournamentService)
+	api.RegisterStatsRouter(r, statsService)
+	api.RegisterLobbyRouter(r, lobbyService)
+	if cfg.Auth.AdminToken != "" {
+		api.RegisterAdminRouter(r, clients, gameService, cfg)
+		api.RegisterDebugRouter(r, clients, gameService, cfg.Auth.AdminToken, nodeID)
+	} else {
+		logger.Warn("ADMIN_TOKEN is not set, admin API and debug status disabled", "event_type", "startup")
+	}
 
 	corsHandler := handlers.CORS(
 		middleware.CORSAllowedHeaders(),
 		middleware.CORSAllowedMethods(),
-		middleware.CORSAllowedOrigins(),
+		middleware.CORSAllowedOrigins(cfg.Server.AllowedOrigins),
 	)
 
-	tickerInterval := 30 * time.Minute
-	duration := 24 * time.Hour
-
-	go playerManager.RemoveInactivePlayers(ctx, duration, tickerInterval)
-	go gameService.StartRoomCleanup(ctx, duration, tickerInterval)
+	go playerManager.RemoveInactivePlayers(ctx, cfg.Cleanup.InactiveAfter, cfg.Cleanup.Interval)
+	go gameService.StartRoomCleanup(ctx, cfg.Cleanup.InactiveAfter, cfg.Cleanup.Interval)
+	go gameService.StartChatPruning(ctx, cfg.Chat.PruneInterval)
 
 	r.Use(observability.RequestMiddleware)
-	r.Use(middleware.RateLimiter)
+	r.Use(middleware.RateLimiterExcept(api.IsPlayerLimitedRoute))
+	r.Use(middleware.Authenticate(authService, api.IsPublicRoute))
 
 	server := &http.Server{
-		Addr:              ":" + port,
+		Addr:              ":" + cfg.Server.Port,
 		Handler:           corsHandler(r),
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
 	go func() {
-		logger.Info("server starting", "event_type", "startup", "port", port)
+		logger.Info("server starting", "event_type", "startup", "port", cfg.Server.Port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Error("server failed", "event_type", "startup", "error", err)
 			stop()
@@ -101,14 +343,109 @@ func main() {
 	<-ctx.Done()
 	logger.Info("shutdown signal received", "event_type", "shutdown")
 
-	clients.CloseAll()
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	gameService.Drain()
+	api.DrainClients(shutdownCtx, clients, 5*time.Second, time.Second)
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		logger.Error("server shutdown failed", "event_type", "shutdown", "error", err)
 	}
+	if roomSnapshotPath != "" {
+		records, err := gameService.RoomRecordsWithContext(shutdownCtx)
+		if err == nil {
+			err = infrastructure.SaveRoomRecords(roomSnapshotPath, records)
+		}
+		if err != nil {
+			logger.Error("failed to save rooms", "event_type", "shutdown", "path", roomSnapshotPath, "error", err)
+		} else {
+			logger.Info("rooms saved", "event_type", "shutdown", "path", roomSnapshotPath, "rooms", len(records))
+		}
+	}
+	if leasedRooms != nil {
+		if err := leasedRooms.ReleaseLeases(shutdownCtx); err != nil {
+			logger.Warn("failed to release room leases", "event_type", "shutdown", "error", err)
+		}
+	}
 	if err := gameService.CleanupRooms(shutdownCtx, 0); err != nil {
 		logger.Warn("room cleanup failed during shutdown", "event_type", "shutdown", "error", err)
 	}
+	if cluster != nil {
+		cluster.Stop()
+		_ = roomBackplane.Close()
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Warn("tracing shutdown failed", "event_type", "shutdown", "error", err)
+	}
 	logger.Info("shutdown complete", "event_type", "shutdown")
 }
+
+// checkReady asks the server listening on port whether it is ready.
+func checkReady(port string) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	response, err := client.Get("http://127.0.0.1:" + port + "/readyz")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("readyz answered %s", response.Status)
+	}
+	return nil
+}
+
+// printAudit writes the events of a room in the audit log file at path to w,
+// one JSON object per line.
+func printAudit(w io.Writer, path string, roomID string) error {
+	if path == "" {
+		return errors.New("audit.path is not set; without a file the log is served by GET /admin/rooms/{room_id}/audit")
+	}
+	events, err := infrastructure.ReadAuditLogFile(path, roomID)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printReplay rebuilds a room from its events in file, stdin when file is
+// "-" and the audit log at auditPath when it is empty, and writes the result
+// to w. It returns how many violations the replay found.
+func printReplay(w io.Writer, stdin io.Reader, file string, auditPath string, roomID string) (int, error) {
+	var events []game.AuditEvent
+	var err error
+	switch file {
+	case "-":
+		events, err = infrastructure.ReadAuditEvents(stdin, roomID)
+	case "":
+		if auditPath == "" {
+			return 0, errors.New("name the events file, or set audit.path to replay from the audit log")
+		}
+		events, err = infrastructure.ReadAuditLogFile(auditPath, roomID)
+	default:
+		events, err = infrastructure.ReadAuditLogFile(file, roomID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, fmt.Errorf("no events of room %s", roomID)
+	}
+
+	report, err := game.ReplayRoom(events)
+	if err != nil {
+		return 0, err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(dto.FromReplayReport(report)); err != nil {
+		return 0, err
+	}
+	return len(report.Violations), nil
+}
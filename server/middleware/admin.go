@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+)
+
+// AdminTokenHeader carries the operator token on admin requests. It is kept
+// apart from the Authorization header, which holds player sessions.
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminAuth is a middleware that lets through only requests carrying the
+// operator token.
+func AdminAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented := r.Header.Get(AdminTokenHeader)
+			if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				observability.Logger().WarnContext(r.Context(), "admin authentication failed",
+					"room_id", "",
+					"player_id", "",
+					"event_type", "admin_authentication_failed",
+					"path", r.URL.Path,
+				)
+				writeUnauthorized(w, "Admin token required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
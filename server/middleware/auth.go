@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/tsaqiffatih/mini-game/auth"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+)
+
+type Authenticator interface {
+	AuthenticateWithContext(ctx context.Context, token string) (auth.Claims, error)
+}
+
+// Authenticate is a middleware that resolves the session token of a request
+// into auth claims on the request context. Requests without a valid token are
+// rejected unless public reports the route as open to anonymous callers.
+//
+// The token is read from the Authorization bearer header, or from the
+// access_token query parameter for clients that cannot set headers such as
+// browser websockets.
+func Authenticate(authenticator Authenticator, public func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := requestToken(r)
+			if token == "" {
+				if public(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				writeUnauthorized(w, "Authentication required")
+				return
+			}
+
+			claims, err := authenticator.AuthenticateWithContext(r.Context(), token)
+			if err != nil {
+				observability.Logger().WarnContext(r.Context(), "authentication failed",
+					"room_id", "",
+					"player_id", "",
+					"event_type", "authentication_failed",
+					"path", r.URL.Path,
+					"error", err,
+				)
+				writeUnauthorized(w, err.Error())
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+func requestToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return r.URL.Query().Get("access_token")
+}
+
+func writeUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	w.WriteHeader(http.StatusUnauthorized)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"data":    map[string]interface{}{},
+		"error":   message,
+	})
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// trustedProxies are the peers whose X-Forwarded-For is believed.
+var trustedProxies []netip.Prefix
+
+// SetTrustedProxies sets the proxies, such as Caddy in front of the server,
+// whose X-Forwarded-For header names the client. Call it before the server
+// starts.
+func SetTrustedProxies(proxies []netip.Prefix) {
+	trustedProxies = append([]netip.Prefix(nil), proxies...)
+}
+
+// ClientIP returns the address of the client behind r. A request from a
+// trusted proxy is attributed to the nearest address in X-Forwarded-For that
+// is not itself a trusted proxy; anything further left could be made up by
+// the client.
+func ClientIP(r *http.Request) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	addr, err := netip.ParseAddr(peer)
+	if err != nil {
+		return peer
+	}
+	// An IPv4 peer on a dual-stack listener shares a bucket with the same
+	// peer seen over IPv4.
+	client := addr.Unmap().String()
+	if !isTrustedProxy(addr) {
+		return client
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// A malformed hop was not written by a trusted proxy.
+			break
+		}
+		client = hop.Unmap().String()
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+	return client
+}
+
+func isTrustedProxy(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	SetTrustedProxies([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+	t.Cleanup(func() { SetTrustedProxies(nil) })
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{name: "untrusted peer without header", remoteAddr: "203.0.113.5:4000", want: "203.0.113.5"},
+		{name: "untrusted peer with forged header", remoteAddr: "203.0.113.5:4000", forwardedFor: []string{"198.51.100.7"}, want: "203.0.113.5"},
+		{name: "trusted proxy without header", remoteAddr: "10.0.0.2:4000", want: "10.0.0.2"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.2:4000", forwardedFor: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "spoofed leftmost hops", remoteAddr: "10.0.0.2:4000", forwardedFor: []string{"1.1.1.1, 8.8.8.8, 198.51.100.7"}, want: "198.51.100.7"},
+		{name: "chain of trusted proxies", remoteAddr: "10.0.0.2:4000", forwardedFor: []string{"1.1.1.1, 198.51.100.7, 10.0.0.3"}, want: "198.51.100.7"},
+		{name: "hops across header lines", remoteAddr: "10.0.0.2:4000", forwardedFor: []string{"1.1.1.1", "198.51.100.7, 10.0.0.3"}, want: "198.51.100.7"},
+		{name: "malformed nearest hop", remoteAddr: "10.0.0.2:4000", forwardedFor: []string{"198.51.100.7, not-an-ip"}, want: "10.0.0.2"},
+		{name: "malformed hop left of the client", remoteAddr: "10.0.0.2:4000", forwardedFor: []string{"not-an-ip, 198.51.100.7"}, want: "198.51.100.7"},
+		{name: "all-trusted chain", remoteAddr: "10.0.0.2:4000", forwardedFor: []string{"10.0.0.4, 10.0.0.3"}, want: "10.0.0.4"},
+		{name: "IPv4-mapped trusted peer", remoteAddr: "[::ffff:10.0.0.2]:4000", forwardedFor: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "IPv4-mapped untrusted peer", remoteAddr: "[::ffff:203.0.113.5]:4000", forwardedFor: []string{"198.51.100.7"}, want: "203.0.113.5"},
+		{name: "IPv4-mapped hop", remoteAddr: "10.0.0.2:4000", forwardedFor: []string{"::ffff:198.51.100.7"}, want: "198.51.100.7"},
+		{name: "IPv6 client", remoteAddr: "10.0.0.2:4000", forwardedFor: []string{"2001:db8::1"}, want: "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := ClientIP(r); got != tt.want {
+				t.Fatalf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
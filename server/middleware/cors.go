@@ -1,27 +1,25 @@
 package middleware
 
 import (
-	"os"
 	"strings"
 
 	"github.com/gorilla/handlers"
 	"github.com/tsaqiffatih/mini-game/internal/observability"
 )
 
-// CORSAllowedOrigins sets the allowed origins for CORS requests.
-// It reads the allowed origins from environment variables.
-func CORSAllowedOrigins() handlers.CORSOption {
-	allowedOrigins := os.Getenv("ALLOWED_ORIGINS")
-	origins := strings.Split(allowedOrigins, ",")
+// CORSAllowedOrigins sets the allowed origins for CORS requests. With none,
+// no cross-origin request is allowed; "*" allows every origin.
+func CORSAllowedOrigins(origins []string) handlers.CORSOption {
 	observability.Logger().Info("cors configured",
 		"room_id", "",
 		"player_id", "",
 		"event_type", "cors_configured",
-		"allowed_origins", allowedOrigins,
+		"allowed_origins", strings.Join(origins, ","),
 	)
-	// if allowedOrigins == "" {
-	// 	allowedOrigins = "http://localhost:5173"
-	// }
+	if len(origins) == 0 {
+		// The handler would take an empty list as allowing everything.
+		return handlers.AllowedOriginValidator(func(string) bool { return false })
+	}
 	return handlers.AllowedOrigins(origins)
 }
 
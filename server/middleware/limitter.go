@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/tsaqiffatih/mini-game/internal/observability"
 	"golang.org/x/time/rate"
 )
 
@@ -17,8 +18,21 @@ type Client struct {
 var (
 	mu      sync.Mutex
 	clients = make(map[string]*Client)
+	// perSecond and burst size the token bucket each client IP gets.
+	perSecond rate.Limit = 1
+	burst                = 5
 )
 
+// SetRateLimit sizes the buckets of clients seen from now on:
+// requestsPerSecond refill a bucket holding up to requestBurst requests.
+func SetRateLimit(requestsPerSecond float64, requestBurst int) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	perSecond = rate.Limit(requestsPerSecond)
+	burst = requestBurst
+}
+
 // getClient retrieves or creates a rate limiter for a client based on their IP.
 // It ensures rate-limiting for incoming requests.
 func getClient(ip string) *rate.Limiter {
@@ -27,7 +41,7 @@ func getClient(ip string) *rate.Limiter {
 
 	client, exists := clients[ip]
 	if !exists {
-		limiter := rate.NewLimiter(1, 5)
+		limiter := rate.NewLimiter(perSecond, burst)
 		clients[ip] = &Client{limiter, time.Now()}
 		return limiter
 	}
@@ -76,12 +90,30 @@ func StartRateLimiterCleanup(ctx context.Context) {
 // RateLimiter is a middleware that limits the rate of incoming requests.
 // It uses the rate limiter to reject excessive requests.
 func RateLimiter(next http.Handler) http.Handler {
+	return RateLimiterExcept(nil)(next)
+}
 
+// RateLimiterExcept is RateLimiter for every request exempt does not
+// report. Exempt routes are limited by their handlers instead, per player
+// rather than per IP, so players behind one NAT do not share a bucket.
+func RateLimiterExcept(exempt func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return rateLimit(next, exempt)
+	}
+}
+
+func rateLimit(next http.Handler, exempt func(*http.Request) bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := r.RemoteAddr
+		if exempt != nil && exempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := ClientIP(r)
 		limiter := getClient(ip)
 
 		if !limiter.Allow() {
+			observability.RateLimitRejections.WithLabelValues("http").Inc()
 			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
 			return
 		}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+)
+
+var ErrAuditLogDisabled = errors.New("Audit log is not enabled")
+
+// AuditLog keeps the audit events of every room in the order they were
+// recorded. Appending must be quick: rooms append while locked.
+type AuditLog interface {
+	Append(ctx context.Context, event game.AuditEvent) error
+	// RoomEvents returns the events of a room, oldest first.
+	RoomEvents(ctx context.Context, roomID string) ([]game.AuditEvent, error)
+}
+
+// SetAuditLog sets where rooms record what was done in them. It applies to
+// rooms created after the call.
+func (s *GameService) SetAuditLog(log AuditLog) {
+	s.auditLog = log
+}
+
+// RoomAuditWithContext returns the audit events of a room, including one
+// that is gone.
+func (s *GameService) RoomAuditWithContext(ctx context.Context, roomID string) ([]game.AuditEvent, error) {
+	if s.auditLog == nil {
+		return nil, ErrAuditLogDisabled
+	}
+	return s.auditLog.RoomEvents(ctx, roomID)
+}
+
+// recordAudit keeps an event in the audit log. Rooms carry on without it, so
+// a failure is only logged. The log outlives the service's context: the
+// events of a shutdown, as rooms drain and are cleaned up, must not be lost.
+func (s *GameService) recordAudit(event game.AuditEvent) {
+	ctx := context.WithoutCancel(s.context())
+	if err := s.auditLog.Append(ctx, event); err != nil {
+		observability.Logger().WarnContext(ctx, "audit event not stored",
+			"room_id", event.RoomID,
+			"player_id", event.PlayerID,
+			"event_type", "audit_log_error",
+			"action", event.Action,
+			"error", err,
+		)
+	}
+}
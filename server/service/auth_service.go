@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/auth"
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+)
+
+// guestIDAttempts bounds how many generated guest IDs are tried before
+// giving up on a crowded player namespace.
+const guestIDAttempts = 5
+
+var (
+	ErrUsernameRequired = errors.New("Username is required")
+	ErrUsernameTaken    = errors.New("Username already taken")
+)
+
+type AccountRepository interface {
+	GetByID(ctx context.Context, accountID string) (*auth.Account, error)
+	Save(ctx context.Context, account *auth.Account) error
+}
+
+// Session is a signed-in player together with the token that proves it.
+type Session struct {
+	Token  string
+	Claims auth.Claims
+	Player game.PlayerSnapshot
+}
+
+// AuthService owns player identity: registered accounts sign in with a
+// password, guests sign in with just a name. Both receive a signed session
+// token whose subject is their player ID.
+type AuthService struct {
+	accounts AccountRepository
+	tokens   *auth.TokenSigner
+	games    *GameService
+}
+
+func NewAuthService(accounts AccountRepository, tokens *auth.TokenSigner, games *GameService) *AuthService {
+	return &AuthService{
+		accounts: accounts,
+		tokens:   tokens,
+		games:    games,
+	}
+}
+
+func (s *AuthService) RegisterWithContext(ctx context.Context, username string, password string) (Session, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "auth.register")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	username = strings.TrimSpace(username)
+	if username == "" {
+		spanErr = ErrUsernameRequired
+		return Session{}, ErrUsernameRequired
+	}
+	if _, err := s.accounts.GetByID(ctx, username); err == nil {
+		spanErr = ErrUsernameTaken
+		return Session{}, ErrUsernameTaken
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		spanErr = err
+		return Session{}, err
+	}
+
+	// Claiming the player ID first keeps a guest from holding the same name
+	// as the new account.
+	player, err := s.games.playerManager.AddPlayer(username)
+	if err != nil {
+		spanErr = ErrUsernameTaken
+		return Session{}, ErrUsernameTaken
+	}
+	account := &auth.Account{
+		ID:           username,
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.accounts.Save(ctx, account); err != nil {
+		s.games.playerManager.RemovePlayer(username)
+		spanErr = ErrUsernameTaken
+		return Session{}, ErrUsernameTaken
+	}
+
+	session, err := s.issueSession(player, false, "")
+	if err != nil {
+		spanErr = err
+		return Session{}, err
+	}
+
+	observability.Logger().InfoContext(ctx, "account registered",
+		"room_id", "",
+		"player_id", username,
+		"event_type", "account_registered",
+	)
+	return session, nil
+}
+
+func (s *AuthService) LoginWithContext(ctx context.Context, username string, password string) (Session, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "auth.login")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	account, err := s.accounts.GetByID(ctx, strings.TrimSpace(username))
+	if err != nil {
+		spanErr = auth.ErrInvalidPassword
+		return Session{}, auth.ErrInvalidPassword
+	}
+	if err := auth.CheckPassword(account.PasswordHash, password); err != nil {
+		spanErr = err
+		return Session{}, err
+	}
+
+	session, err := s.issueSession(s.ensurePlayer(account.ID), false, "")
+	if err != nil {
+		spanErr = err
+		return Session{}, err
+	}
+
+	observability.Logger().InfoContext(ctx, "player logged in",
+		"room_id", "",
+		"player_id", account.ID,
+		"event_type", "player_logged_in",
+	)
+	return session, nil
+}
+
+// GuestWithContext signs in an anonymous player under playerID, or under a
+// generated ID when playerID is empty. Names of registered accounts are not
+// available to guests. The session lasts as long as the guest's player: once
+// it is removed, the token is refused even if the name is taken again.
+func (s *AuthService) GuestWithContext(ctx context.Context, playerID string) (Session, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "auth.guest")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	nonce, err := auth.RandomNonce()
+	if err != nil {
+		spanErr = err
+		return Session{}, err
+	}
+	player, err := s.addGuestPlayer(ctx, strings.TrimSpace(playerID), nonce)
+	if err != nil {
+		spanErr = err
+		return Session{}, err
+	}
+
+	session, err := s.issueSession(player, true, nonce)
+	if err != nil {
+		s.games.playerManager.RemovePlayer(player.ID)
+		spanErr = err
+		return Session{}, err
+	}
+
+	observability.Logger().InfoContext(ctx, "guest signed in",
+		"room_id", "",
+		"player_id", player.ID,
+		"event_type", "guest_signed_in",
+	)
+	return session, nil
+}
+
+// AuthenticateWithContext verifies a session token. Account players unknown
+// to this node, because they were dropped for inactivity, are restored so
+// their token keeps working. A guest token only holds while the player it
+// was issued for is still here, and never for the name of an account.
+func (s *AuthService) AuthenticateWithContext(ctx context.Context, token string) (auth.Claims, error) {
+	if err := ctx.Err(); err != nil {
+		return auth.Claims{}, err
+	}
+
+	claims, err := s.tokens.Verify(token)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+	_, accountErr := s.accounts.GetByID(ctx, claims.PlayerID)
+	if claims.Guest {
+		if accountErr == nil {
+			return auth.Claims{}, auth.ErrInvalidToken
+		}
+		if !s.games.playerManager.CheckSession(claims.PlayerID, claims.Nonce) {
+			return auth.Claims{}, auth.ErrSessionEnded
+		}
+		return claims, nil
+	}
+	if accountErr != nil {
+		return auth.Claims{}, auth.ErrInvalidToken
+	}
+	s.ensurePlayer(claims.PlayerID)
+	return claims, nil
+}
+
+func (s *AuthService) addGuestPlayer(ctx context.Context, playerID string, nonce string) (game.PlayerSnapshot, error) {
+	if playerID != "" {
+		if _, err := s.accounts.GetByID(ctx, playerID); err == nil {
+			return game.PlayerSnapshot{}, ErrUsernameTaken
+		}
+		return s.games.playerManager.AddPlayerWithSession(playerID, nonce)
+	}
+
+	var err error
+	for i := 0; i < guestIDAttempts; i++ {
+		var player game.PlayerSnapshot
+		player, err = s.games.playerManager.AddPlayerWithSession("guest-"+strings.ToLower(generateRandomRoomCode()), nonce)
+		if err == nil {
+			return player, nil
+		}
+	}
+	return game.PlayerSnapshot{}, err
+}
+
+func (s *AuthService) ensurePlayer(playerID string) game.PlayerSnapshot {
+	if player, err := s.games.playerManager.GetPlayer(playerID); err == nil {
+		return player
+	}
+	if player, err := s.games.playerManager.AddPlayer(playerID); err == nil {
+		return player
+	}
+	player, _ := s.games.playerManager.GetPlayer(playerID)
+	return player
+}
+
+func (s *AuthService) issueSession(player game.PlayerSnapshot, guest bool, nonce string) (Session, error) {
+	token, claims, err := s.tokens.Issue(player.ID, guest, nonce)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{
+		Token:  token,
+		Claims: claims,
+		Player: player,
+	}, nil
+}
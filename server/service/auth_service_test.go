@@ -0,0 +1,184 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/tsaqiffatih/mini-game/auth"
+	"github.com/tsaqiffatih/mini-game/infrastructure"
+)
+
+func newAuthServiceForTest() (*AuthService, *GameService) {
+	gameService, _ := newIntegrationGameService()
+	authService := NewAuthService(
+		infrastructure.NewMemoryAccountRepository(),
+		auth.NewTokenSigner([]byte("test-secret"), 0),
+		gameService,
+	)
+	return authService, gameService
+}
+
+func TestAuthService_RegisterAndLogin(t *testing.T) {
+	authService, gameService := newAuthServiceForTest()
+	ctx := gameService.context()
+
+	if _, err := authService.RegisterWithContext(ctx, "alice", "short"); err != auth.ErrPasswordTooWeak {
+		t.Fatalf("RegisterWithContext(short password) error = %v, want %v", err, auth.ErrPasswordTooWeak)
+	}
+	registered, err := authService.RegisterWithContext(ctx, "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("RegisterWithContext() error = %v", err)
+	}
+	if registered.Player.ID != "alice" || registered.Claims.Guest {
+		t.Fatalf("session = %+v, want registered alice", registered)
+	}
+	if _, err := authService.RegisterWithContext(ctx, "alice", "another password"); err != ErrUsernameTaken {
+		t.Fatalf("RegisterWithContext(duplicate) error = %v, want %v", err, ErrUsernameTaken)
+	}
+	if _, err := authService.LoginWithContext(ctx, "alice", "wrong password"); err != auth.ErrInvalidPassword {
+		t.Fatalf("LoginWithContext(wrong password) error = %v, want %v", err, auth.ErrInvalidPassword)
+	}
+
+	// Inactive players are dropped from the player manager; a valid session
+	// brings the account's player back.
+	gameService.playerManager.RemovePlayer("alice")
+	claims, err := authService.AuthenticateWithContext(ctx, registered.Token)
+	if err != nil {
+		t.Fatalf("AuthenticateWithContext() error = %v", err)
+	}
+	if claims.PlayerID != "alice" {
+		t.Fatalf("claims player = %q, want alice", claims.PlayerID)
+	}
+	if _, err := gameService.playerManager.GetPlayer("alice"); err != nil {
+		t.Fatalf("GetPlayer(alice) error = %v, want player restored", err)
+	}
+
+	loggedIn, err := authService.LoginWithContext(ctx, "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("LoginWithContext() error = %v", err)
+	}
+	if loggedIn.Claims.PlayerID != "alice" || loggedIn.Token == "" {
+		t.Fatalf("login session = %+v, want a token for alice", loggedIn)
+	}
+}
+
+func TestAuthService_Guest(t *testing.T) {
+	authService, gameService := newAuthServiceForTest()
+	ctx := gameService.context()
+
+	if _, err := authService.RegisterWithContext(ctx, "alice", "correct horse"); err != nil {
+		t.Fatalf("RegisterWithContext() error = %v", err)
+	}
+	gameService.playerManager.RemovePlayer("alice")
+	if _, err := authService.GuestWithContext(ctx, "alice"); err != ErrUsernameTaken {
+		t.Fatalf("GuestWithContext(account name) error = %v, want %v", err, ErrUsernameTaken)
+	}
+
+	named, err := authService.GuestWithContext(ctx, "bob")
+	if err != nil {
+		t.Fatalf("GuestWithContext(bob) error = %v", err)
+	}
+	if named.Player.ID != "bob" || !named.Claims.Guest {
+		t.Fatalf("guest session = %+v, want guest bob", named)
+	}
+	if _, err := authService.GuestWithContext(ctx, "bob"); err == nil {
+		t.Fatalf("GuestWithContext(bob) twice error = nil, want name in use")
+	}
+
+	generated, err := authService.GuestWithContext(ctx, "")
+	if err != nil {
+		t.Fatalf("GuestWithContext(\"\") error = %v", err)
+	}
+	if generated.Player.ID == "" || generated.Player.ID == "bob" {
+		t.Fatalf("generated guest ID = %q, want a fresh ID", generated.Player.ID)
+	}
+}
+
+func TestAuthService_GuestTokenEndsWithItsPlayer(t *testing.T) {
+	authService, gameService := newAuthServiceForTest()
+	ctx := gameService.context()
+
+	first, err := authService.GuestWithContext(ctx, "bob")
+	if err != nil {
+		t.Fatalf("GuestWithContext(bob) error = %v", err)
+	}
+	if _, err := authService.AuthenticateWithContext(ctx, first.Token); err != nil {
+		t.Fatalf("AuthenticateWithContext() error = %v", err)
+	}
+
+	// Removed for inactivity: the token must not bring bob back.
+	gameService.playerManager.RemovePlayer("bob")
+	if _, err := authService.AuthenticateWithContext(ctx, first.Token); err != auth.ErrSessionEnded {
+		t.Fatalf("AuthenticateWithContext(removed guest) error = %v, want %v", err, auth.ErrSessionEnded)
+	}
+	if _, err := gameService.playerManager.GetPlayer("bob"); err == nil {
+		t.Fatalf("GetPlayer(bob) error = nil, want the removed guest left out")
+	}
+
+	// Nor may it act for a new guest under the same name.
+	second, err := authService.GuestWithContext(ctx, "bob")
+	if err != nil {
+		t.Fatalf("GuestWithContext(bob again) error = %v", err)
+	}
+	if _, err := authService.AuthenticateWithContext(ctx, first.Token); err != auth.ErrSessionEnded {
+		t.Fatalf("AuthenticateWithContext(old token) error = %v, want %v", err, auth.ErrSessionEnded)
+	}
+	if _, err := authService.AuthenticateWithContext(ctx, second.Token); err != nil {
+		t.Fatalf("AuthenticateWithContext(new token) error = %v", err)
+	}
+
+	// Nor for an account registered under the name later.
+	gameService.playerManager.RemovePlayer("bob")
+	if _, err := authService.RegisterWithContext(ctx, "bob", "correct horse"); err != nil {
+		t.Fatalf("RegisterWithContext(bob) error = %v", err)
+	}
+	if _, err := authService.AuthenticateWithContext(ctx, second.Token); err != auth.ErrInvalidToken {
+		t.Fatalf("AuthenticateWithContext(guest token for account) error = %v, want %v", err, auth.ErrInvalidToken)
+	}
+}
+
+func TestAuthService_RestoredGuestKeepsOnlyItsOwnTokens(t *testing.T) {
+	authService, gameService := newAuthServiceForTest()
+	ctx := gameService.context()
+
+	session, err := authService.GuestWithContext(ctx, "bob")
+	if err != nil {
+		t.Fatalf("GuestWithContext(bob) error = %v", err)
+	}
+	if _, err := gameService.CreateRoomWithContext(ctx, "tictactoe", "bob"); err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	records, err := gameService.RoomRecordsWithContext(ctx)
+	if err != nil {
+		t.Fatalf("RoomRecordsWithContext() error = %v", err)
+	}
+
+	// After a restart bob comes back with the room. A guest token signed
+	// in for the name elsewhere, or for a player added without a session,
+	// must not take the record over.
+	restartedAuth, restartedGames := newAuthServiceForTest()
+	if restored, err := restartedGames.RestoreRoomsWithContext(ctx, records); err != nil || restored != 1 {
+		t.Fatalf("RestoreRoomsWithContext() = %d, %v, want 1 room", restored, err)
+	}
+	if _, err := restartedGames.AddPlayer("carol"); err != nil {
+		t.Fatalf("AddPlayer(carol) error = %v", err)
+	}
+	otherAuth, _ := newAuthServiceForTest()
+	otherBob, err := otherAuth.GuestWithContext(ctx, "bob")
+	if err != nil {
+		t.Fatalf("GuestWithContext(other bob) error = %v", err)
+	}
+	carol, err := otherAuth.GuestWithContext(ctx, "carol")
+	if err != nil {
+		t.Fatalf("GuestWithContext(carol) error = %v", err)
+	}
+
+	if _, err := restartedAuth.AuthenticateWithContext(ctx, otherBob.Token); err != auth.ErrSessionEnded {
+		t.Fatalf("AuthenticateWithContext(other bob) error = %v, want %v", err, auth.ErrSessionEnded)
+	}
+	if _, err := restartedAuth.AuthenticateWithContext(ctx, carol.Token); err != auth.ErrSessionEnded {
+		t.Fatalf("AuthenticateWithContext(carol without a session) error = %v, want %v", err, auth.ErrSessionEnded)
+	}
+	if _, err := restartedAuth.AuthenticateWithContext(ctx, session.Token); err != nil {
+		t.Fatalf("AuthenticateWithContext(restored bob) error = %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxChatReports bounds the reports kept in memory; the oldest go first.
+const maxChatReports = 1000
+
+// ChatReport is a chat message a player flagged for review. The message is
+// copied, since the room may trim it from its history or go away.
+type ChatReport struct {
+	ID         string
+	RoomID     string
+	MessageID  string
+	ReporterID string
+	ReportedID string
+	Message    string
+	Reason     string
+	CreatedAt  time.Time
+}
+
+// ChatReportStore keeps the chat reports waiting for review.
+type ChatReportStore struct {
+	mu      sync.Mutex
+	reports []ChatReport
+	nextID  uint64
+}
+
+func NewChatReportStore() *ChatReportStore {
+	return &ChatReportStore{}
+}
+
+// Add stores report under a new ID and returns it.
+func (s *ChatReportStore) Add(report ChatReport) ChatReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	report.ID = fmt.Sprintf("report_%d", s.nextID)
+	s.reports = append(s.reports, report)
+	if over := len(s.reports) - maxChatReports; over > 0 {
+		s.reports = append([]ChatReport(nil), s.reports[over:]...)
+	}
+	return report
+}
+
+// List returns the stored reports, the newest first.
+func (s *ChatReportStore) List() []ChatReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reports := make([]ChatReport, 0, len(s.reports))
+	for i := len(s.reports) - 1; i >= 0; i-- {
+		reports = append(reports, s.reports[i])
+	}
+	return reports
+}
@@ -3,21 +3,41 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/rand"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
+	"github.com/tsaqiffatih/mini-game/auth"
 	"github.com/tsaqiffatih/mini-game/game"
 	"github.com/tsaqiffatih/mini-game/internal/observability"
 )
 
 var (
-	ErrGameTypeRequired = errors.New("RoomID and GameType are required")
-	ErrPlayerNotFound   = errors.New("Player not found")
-	ErrRoomNotFound     = errors.New("Room not found")
-	ErrGameTypeMismatch = errors.New("Game type not match")
+	ErrGameTypeRequired    = errors.New("RoomID and GameType are required")
+	ErrPlayerNotFound      = errors.New("Player not found")
+	ErrRoomNotFound        = errors.New("Room not found")
+	ErrGameTypeMismatch    = errors.New("Game type not match")
+	ErrInviteInvalid       = errors.New("Invite is invalid or expired")
+	ErrInvitesDisabled     = errors.New("Invites are not enabled")
+	ErrReportReasonTooLong = errors.New("Report reason is too long")
+	ErrChatStoreDisabled   = errors.New("Chat history is not enabled")
+	ErrChatQueryEmpty      = errors.New("Search query is empty")
+	ErrServerDraining      = errors.New("Server is restarting, no new rooms can be created")
 )
 
+const (
+	DefaultChatPageLimit = 50
+	MaxChatPageLimit     = 200
+)
+
+// maxReportReasonChars bounds the reason a player gives for a report.
+const maxReportReasonChars = 200
+
 var (
 	roomCodeRandMu sync.Mutex
 	roomCodeRand   = rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -30,11 +50,51 @@ type RoomRepository interface {
 	List(ctx context.Context) ([]*game.Room, error)
 }
 
+// ChatStore keeps chat beyond the last messages a room holds, across resets
+// and after the room is gone.
+type ChatStore interface {
+	Append(ctx context.Context, record game.ChatRecord) error
+	// History returns up to limit messages of the room viewerID saw, oldest
+	// first, older than the message with ID before when set, and whether
+	// older ones remain.
+	History(ctx context.Context, roomID string, viewerID string, before string, limit int) ([]game.ChatRecord, bool, error)
+	// Search returns up to limit messages playerID saw matching every term,
+	// newest first.
+	Search(ctx context.Context, playerID string, terms []string, limit int) ([]game.ChatRecord, error)
+	// Prune drops what the store's retention no longer allows.
+	Prune(ctx context.Context, now time.Time) (int, error)
+}
+
 type GameService struct {
-	rooms         RoomRepository
-	playerManager *game.PlayerManager
-	ctx           context.Context
-	roomNotifier  func(game.RoomSnapshot)
+	rooms           RoomRepository
+	playerManager   *game.PlayerManager
+	ctx             context.Context
+	roomNotifier    func(context.Context, game.RoomSnapshot)
+	outcomeNotifier func(game.GameOutcome)
+	invites         *auth.TokenSigner
+	roomChanged     func(context.Context, string)
+	typingNotifier  func(context.Context, game.TypingState)
+	chatFilter      *game.ChatFilter
+	chatStore       ChatStore
+	auditLog        AuditLog
+	reports         *ChatReportStore
+	draining        atomic.Bool
+	roomConfig      game.RoomConfig
+}
+
+// ChatPage is a page of a room's chat history. Older remain when More, from
+// before the first message of the page.
+type ChatPage struct {
+	Messages []game.ChatMessage
+	More     bool
+}
+
+// ChatPost is a chat message with the players who see it. Notice is the
+// system message telling the sender the filter masked part of it.
+type ChatPost struct {
+	Message  game.ChatMessage
+	Audience []string
+	Notice   *game.ChatMessage
 }
 
 func NewGameService(
@@ -45,6 +105,8 @@ func NewGameService(
 		rooms:         rooms,
 		playerManager: playerManager,
 		ctx:           context.Background(),
+		roomConfig:    game.DefaultRoomConfig(),
+		reports:       NewChatReportStore(),
 	}
 }
 
@@ -55,10 +117,58 @@ func (s *GameService) SetContext(ctx context.Context) {
 	s.ctx = ctx
 }
 
-func (s *GameService) SetRoomNotifier(notifier func(game.RoomSnapshot)) {
+// SetChatFilter sets the filter masking blocked words in chat. Without one
+// messages are kept as sent.
+func (s *GameService) SetChatFilter(filter *game.ChatFilter) {
+	s.chatFilter = filter
+}
+
+// SetChatStore sets where chat is kept for history and search. Without one
+// only the last messages of live rooms are kept.
+func (s *GameService) SetChatStore(store ChatStore) {
+	s.chatStore = store
+}
+
+// ChatReports returns the store of the reports players made.
+func (s *GameService) ChatReports() *ChatReportStore {
+	return s.reports
+}
+
+func (s *GameService) SetRoomNotifier(notifier func(context.Context, game.RoomSnapshot)) {
 	s.roomNotifier = notifier
 }
 
+// SetGameOutcomeNotifier registers a callback for every finished game in
+// rooms created after the call.
+func (s *GameService) SetGameOutcomeNotifier(notifier func(game.GameOutcome)) {
+	s.outcomeNotifier = notifier
+}
+
+// SetTypingNotifier registers a callback for typing indicators that expired
+// without the player stopping them, for rooms created after the call.
+func (s *GameService) SetTypingNotifier(notifier func(context.Context, game.TypingState)) {
+	s.typingNotifier = notifier
+}
+
+// SetRoomChangeNotifier registers a callback for every change of who is in
+// a room or of its state, including its removal, for rooms created after the
+// call. It gets the room ID; the room may no longer exist.
+func (s *GameService) SetRoomChangeNotifier(notifier func(context.Context, string)) {
+	s.roomChanged = notifier
+}
+
+// SetInviteSigner sets the signer of room invites. Without one, no invites
+// can be created.
+func (s *GameService) SetInviteSigner(signer *auth.TokenSigner) {
+	s.invites = signer
+}
+
+// SetRoomConfig sets the timings and limits rooms are created or restored
+// with. Rooms already open keep theirs.
+func (s *GameService) SetRoomConfig(config game.RoomConfig) {
+	s.roomConfig = config
+}
+
 func (s *GameService) context() context.Context {
 	if s.ctx == nil {
 		return context.Background()
@@ -66,11 +176,35 @@ func (s *GameService) context() context.Context {
 	return s.ctx
 }
 
-func (s *GameService) attachRoomNotifier(room *game.Room) {
+// prepareRoom gives a room the service's config and notifiers before it is
+// saved.
+func (s *GameService) prepareRoom(room *game.Room) {
 	if room == nil {
 		return
 	}
-	room.SetStateNotifier(s.roomNotifier)
+	room.ApplyConfig(s.roomConfig)
+	notifier, changed := s.roomNotifier, s.roomChanged
+	if changed == nil {
+		room.SetStateNotifier(notifier)
+	} else {
+		room.SetStateNotifier(func(ctx context.Context, snapshot game.RoomSnapshot) {
+			if notifier != nil {
+				notifier(ctx, snapshot)
+			}
+			changed(ctx, snapshot.RoomID)
+		})
+	}
+	room.SetOutcomeNotifier(s.outcomeNotifier)
+	room.SetTypingNotifier(s.typingNotifier)
+	if s.auditLog != nil {
+		room.SetAuditor(s.recordAudit)
+	}
+}
+
+func (s *GameService) notifyRoomChanged(ctx context.Context, roomID string) {
+	if s.roomChanged != nil {
+		s.roomChanged(ctx, roomID)
+	}
 }
 
 type RoomCreatedEvent struct {
@@ -78,19 +212,53 @@ type RoomCreatedEvent struct {
 	Payload game.RoomDTO
 }
 
+// RoomOptions are the settings the creator picks for a room.
+type RoomOptions struct {
+	Visibility game.RoomVisibility
+	Password   string
+}
+
+// RoomAccess is what a player presents to join a private room or one with a
+// password.
+type RoomAccess struct {
+	Password string
+	Invite   string
+}
+
+type RoomInvite struct {
+	RoomID    string
+	Token     string
+	ExpiresAt time.Time
+}
+
 func (s *GameService) AddPlayer(playerID string) (game.PlayerSnapshot, error) {
 	return s.playerManager.AddPlayer(playerID)
 }
 
+// AddPlayerWithSession adds a player whose guest tokens carry nonce, such as
+// a guest signed in on another node.
+func (s *GameService) AddPlayerWithSession(playerID string, nonce string) (game.PlayerSnapshot, error) {
+	return s.playerManager.AddPlayerWithSession(playerID, nonce)
+}
+
 func (s *GameService) CreateRoom(gameType string, playerID string) (*game.JoinRoomResponse, error) {
 	return s.CreateRoomWithContext(s.context(), gameType, playerID)
 }
 
 func (s *GameService) CreateRoomWithContext(ctx context.Context, gameType string, playerID string) (*game.JoinRoomResponse, error) {
+	return s.CreateRoomWithOptionsWithContext(ctx, gameType, playerID, RoomOptions{})
+}
+
+func (s *GameService) CreateRoomWithOptionsWithContext(ctx context.Context, gameType string, playerID string, options RoomOptions) (*game.JoinRoomResponse, error) {
 	ctx, endSpan := observability.StartSpan(ctx, "game.create_room")
 	var spanErr error
 	defer func() { endSpan(spanErr) }()
 
+	if s.draining.Load() {
+		spanErr = ErrServerDraining
+		return nil, ErrServerDraining
+	}
+
 	if gameType == "" {
 		spanErr = ErrGameTypeRequired
 		return nil, ErrGameTypeRequired
@@ -102,6 +270,15 @@ func (s *GameService) CreateRoomWithContext(ctx context.Context, gameType string
 		return nil, ErrPlayerNotFound
 	}
 
+	settings := game.RoomSettings{Visibility: options.Visibility}
+	if options.Password != "" {
+		settings.PasswordHash, err = auth.HashSecret(options.Password)
+		if err != nil {
+			spanErr = err
+			return nil, err
+		}
+	}
+
 	roomID := generateRandomRoomCode()
 
 	room, err := game.NewRoom(roomID, gameType)
@@ -109,7 +286,11 @@ func (s *GameService) CreateRoomWithContext(ctx context.Context, gameType string
 		spanErr = err
 		return nil, err
 	}
-	s.attachRoomNotifier(room)
+	if err := room.Configure(settings); err != nil {
+		spanErr = err
+		return nil, err
+	}
+	s.prepareRoom(room)
 
 	if err := s.rooms.Save(ctx, room); err != nil {
 		spanErr = err
@@ -126,7 +307,9 @@ func (s *GameService) CreateRoomWithContext(ctx context.Context, gameType string
 		"player_id", playerID,
 		"event_type", "room_created",
 		"game_type", gameType,
+		"visibility", room.Visibility(),
 	)
+	s.notifyRoomChanged(ctx, roomID)
 	return res, nil
 }
 
@@ -143,6 +326,11 @@ func (s *GameService) CreateRoomWithAILevelWithContext(ctx context.Context, game
 	var spanErr error
 	defer func() { endSpan(spanErr) }()
 
+	if s.draining.Load() {
+		spanErr = ErrServerDraining
+		return nil, ErrServerDraining
+	}
+
 	if gameType == "" {
 		spanErr = ErrGameTypeRequired
 		return nil, ErrGameTypeRequired
@@ -161,7 +349,7 @@ func (s *GameService) CreateRoomWithAILevelWithContext(ctx context.Context, game
 		spanErr = err
 		return nil, err
 	}
-	s.attachRoomNotifier(room)
+	s.prepareRoom(room)
 
 	if err := s.rooms.Save(ctx, room); err != nil {
 		room.Close()
@@ -186,11 +374,75 @@ func (s *GameService) CreateRoomWithAILevelWithContext(ctx context.Context, game
 	return res, nil
 }
 
+// CreateMatchRoomWithContext creates a room with all players already seated,
+// in order, so the first player gets X or white. It is used for scheduled
+// games such as tournament pairings.
+func (s *GameService) CreateMatchRoomWithContext(ctx context.Context, gameType string, playerIDs ...string) (game.RoomSnapshot, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "game.create_match_room")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	if s.draining.Load() {
+		spanErr = ErrServerDraining
+		return game.RoomSnapshot{}, ErrServerDraining
+	}
+
+	if gameType == "" {
+		spanErr = ErrGameTypeRequired
+		return game.RoomSnapshot{}, ErrGameTypeRequired
+	}
+
+	players := make([]game.PlayerSnapshot, 0, len(playerIDs))
+	for _, playerID := range playerIDs {
+		player, err := s.playerManager.GetPlayer(playerID)
+		if err != nil {
+			spanErr = ErrPlayerNotFound
+			return game.RoomSnapshot{}, ErrPlayerNotFound
+		}
+		players = append(players, player)
+	}
+
+	roomID := generateRandomRoomCode()
+	room, err := game.NewRoom(roomID, gameType)
+	if err != nil {
+		spanErr = err
+		return game.RoomSnapshot{}, err
+	}
+	s.prepareRoom(room)
+
+	if err := s.rooms.Save(ctx, room); err != nil {
+		room.Close()
+		spanErr = err
+		return game.RoomSnapshot{}, err
+	}
+
+	for _, player := range players {
+		if _, err := room.AddPlayer(player); err != nil {
+			_ = s.rooms.Delete(ctx, roomID)
+			spanErr = err
+			return game.RoomSnapshot{}, err
+		}
+	}
+
+	observability.Logger().InfoContext(ctx, "match room created",
+		"room_id", roomID,
+		"player_id", "",
+		"event_type", "room_created",
+		"game_type", gameType,
+		"players", playerIDs,
+	)
+	return room.Snapshot(), nil
+}
+
 func (s *GameService) JoinRoom(roomID string, playerID string, gameType string) (*game.JoinRoomResponse, error) {
 	return s.JoinRoomWithContext(s.context(), roomID, playerID, gameType)
 }
 
 func (s *GameService) JoinRoomWithContext(ctx context.Context, roomID string, playerID string, gameType string) (*game.JoinRoomResponse, error) {
+	return s.JoinRoomWithAccessWithContext(ctx, roomID, playerID, gameType, RoomAccess{})
+}
+
+func (s *GameService) JoinRoomWithAccessWithContext(ctx context.Context, roomID string, playerID string, gameType string, access RoomAccess) (*game.JoinRoomResponse, error) {
 	ctx, endSpan := observability.StartSpan(ctx, "game.join_room")
 	var spanErr error
 	defer func() { endSpan(spanErr) }()
@@ -212,7 +464,13 @@ func (s *GameService) JoinRoomWithContext(ctx context.Context, roomID string, pl
 		return nil, ErrGameTypeMismatch
 	}
 
-	res, err := room.AddPlayer(player)
+	grant, err := s.joinAccess(room, access)
+	if err != nil {
+		spanErr = err
+		return nil, err
+	}
+
+	res, err := room.AddPlayerWithAccess(player, grant)
 	if err != nil {
 		spanErr = err
 		return nil, err
@@ -222,10 +480,103 @@ func (s *GameService) JoinRoomWithContext(ctx context.Context, roomID string, pl
 		"player_id", playerID,
 		"event_type", "player_joined",
 		"game_type", gameType,
+		"invited", grant.InviteNonce != "",
 	)
+	s.notifyRoomChanged(ctx, roomID)
 	return res, nil
 }
 
+// joinAccess checks what the player presented against the room: the invite
+// signature and room, or the password.
+func (s *GameService) joinAccess(room *game.Room, access RoomAccess) (game.JoinAccess, error) {
+	if access.Invite != "" {
+		if s.invites == nil {
+			return game.JoinAccess{}, ErrInviteInvalid
+		}
+		claims, err := s.invites.VerifyInvite(access.Invite)
+		if err != nil || claims.RoomID != room.RoomID {
+			return game.JoinAccess{}, ErrInviteInvalid
+		}
+		return game.JoinAccess{
+			InviteNonce:     claims.Nonce,
+			InviteExpiresAt: time.Unix(claims.ExpiresAt, 0),
+		}, nil
+	}
+
+	hash := room.PasswordHash()
+	if len(hash) == 0 || access.Password == "" {
+		return game.JoinAccess{}, nil
+	}
+	if err := auth.CheckPassword(hash, access.Password); err != nil {
+		return game.JoinAccess{}, game.ErrRoomPasswordInvalid
+	}
+	return game.JoinAccess{PasswordVerified: true}, nil
+}
+
+// CreateInviteWithContext signs a single-use invite to the room. Only the
+// host may invite.
+func (s *GameService) CreateInviteWithContext(ctx context.Context, roomID string, playerID string) (RoomInvite, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "game.create_invite")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	if s.invites == nil {
+		spanErr = ErrInvitesDisabled
+		return RoomInvite{}, ErrInvitesDisabled
+	}
+	room, err := s.rooms.GetByID(ctx, roomID)
+	if err != nil {
+		spanErr = ErrRoomNotFound
+		return RoomInvite{}, ErrRoomNotFound
+	}
+	if room.HostID() != playerID {
+		spanErr = game.ErrNotRoomHost
+		return RoomInvite{}, game.ErrNotRoomHost
+	}
+
+	token, claims, err := s.invites.IssueInvite(roomID, auth.DefaultInviteTTL)
+	if err != nil {
+		spanErr = err
+		return RoomInvite{}, err
+	}
+	observability.Logger().InfoContext(ctx, "room invite created",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", "room_invite_created",
+	)
+	return RoomInvite{
+		RoomID:    roomID,
+		Token:     token,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0).UTC(),
+	}, nil
+}
+
+// KickPlayerWithContext removes a player from the room at the host's
+// request. The player cannot join the room again.
+func (s *GameService) KickPlayerWithContext(ctx context.Context, roomID string, hostID string, playerID string, expectedVersion *uint64) error {
+	ctx, endSpan := observability.StartSpan(ctx, "game.kick_player")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	room, err := s.rooms.GetByID(ctx, roomID)
+	if err != nil {
+		spanErr = ErrRoomNotFound
+		return ErrRoomNotFound
+	}
+	if err := room.KickPlayerAtVersion(hostID, playerID, expectedVersion); err != nil {
+		spanErr = err
+		return err
+	}
+	observability.Logger().InfoContext(ctx, "player kicked from room",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", "player_kicked",
+		"host_id", hostID,
+	)
+	s.notifyRoomChanged(ctx, roomID)
+	return nil
+}
+
 func (s *GameService) GetPlayerInRoom(roomID string, playerID string) (game.PlayerSnapshot, error) {
 	return s.GetPlayerInRoomWithContext(s.context(), roomID, playerID)
 }
@@ -266,11 +617,14 @@ func (s *GameService) CreateRoomWithAIByIDWithContext(ctx context.Context, roomI
 }
 
 func (s *GameService) CreateRoomWithAIByIDLevelWithContext(ctx context.Context, roomID string, gameType string, aiLevel int) (*RoomCreatedEvent, error) {
+	if s.draining.Load() {
+		return nil, ErrServerDraining
+	}
 	room, err := game.NewRoomWithAILevel(roomID, gameType, aiLevel)
 	if err != nil {
 		return nil, err
 	}
-	s.attachRoomNotifier(room)
+	s.prepareRoom(room)
 
 	if err := s.rooms.Save(ctx, room); err != nil {
 		room.Close()
@@ -305,7 +659,7 @@ func (s *GameService) RoomPlayersSnapshot(roomID string) ([]game.PlayerSnapshot,
 	return room.Snapshot().Players, nil
 }
 
-func (s *GameService) HandleChatMessageWithContext(ctx context.Context, roomID string, playerID string, message string) (game.ChatMessage, error) {
+func (s *GameService) HandleChatMessageWithContext(ctx context.Context, roomID string, playerID string, message string, expectedVersion *uint64) (ChatPost, error) {
 	ctx, endSpan := observability.StartSpan(ctx, "game.chat_message")
 	var spanErr error
 	defer func() { endSpan(spanErr) }()
@@ -313,13 +667,21 @@ func (s *GameService) HandleChatMessageWithContext(ctx context.Context, roomID s
 	room, err := s.rooms.GetByID(ctx, roomID)
 	if err != nil {
 		spanErr = err
-		return game.ChatMessage{}, err
+		return ChatPost{}, err
 	}
 
-	chatMessage, err := room.AddChatMessage(playerID, message)
+	message, masked := s.chatFilter.Mask(message)
+	chatMessage, err := room.AddChatMessageAtVersion(playerID, message, expectedVersion)
 	if err != nil {
 		spanErr = err
-		return game.ChatMessage{}, err
+		return ChatPost{}, err
+	}
+
+	post := ChatPost{Message: chatMessage, Audience: room.ChatAudience(chatMessage)}
+	s.archiveChat(ctx, chatMessage, post.Audience)
+	if masked {
+		notice := s.addSystemMessage(ctx, room, playerID, "Part of your message was hidden by the chat filter.")
+		post.Notice = &notice
 	}
 
 	observability.Logger().InfoContext(ctx, "chat message handled",
@@ -327,17 +689,239 @@ func (s *GameService) HandleChatMessageWithContext(ctx context.Context, roomID s
 		"player_id", playerID,
 		"event_type", "chat_message",
 		"message_id", chatMessage.ID,
+		"masked", masked,
 	)
-	return chatMessage, nil
+	return post, nil
+}
+
+// ChatHistoryWithContext returns the chat of the room as playerID sees it.
+func (s *GameService) ChatHistoryWithContext(ctx context.Context, roomID string, playerID string) ([]game.ChatMessage, error) {
+	room, err := s.rooms.GetByID(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	return room.ChatHistoryFor(playerID), nil
 }
 
-func (s *GameService) ChatHistoryWithContext(ctx context.Context, roomID string) ([]game.ChatMessage, error) {
+// MutePlayerWithContext hides targetID's chat from playerID, or shows it
+// again when mute is false. It returns the system message confirming it to
+// playerID.
+func (s *GameService) MutePlayerWithContext(ctx context.Context, roomID string, playerID string, targetID string, mute bool) (game.ChatMessage, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "game.mute_player")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	room, err := s.rooms.GetByID(ctx, roomID)
+	if err != nil {
+		spanErr = ErrRoomNotFound
+		return game.ChatMessage{}, ErrRoomNotFound
+	}
+
+	eventType, notice := "player_muted", fmt.Sprintf("You muted %s. Their messages are hidden from you.", targetID)
+	if mute {
+		err = room.MutePlayer(playerID, targetID)
+	} else {
+		err = room.UnmutePlayer(playerID, targetID)
+		eventType, notice = "player_unmuted", fmt.Sprintf("You unmuted %s.", targetID)
+	}
+	if err != nil {
+		spanErr = err
+		return game.ChatMessage{}, err
+	}
+
+	observability.Logger().InfoContext(ctx, "chat mute changed",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", eventType,
+		"target_id", targetID,
+	)
+	return s.addSystemMessage(ctx, room, playerID, notice), nil
+}
+
+// ReportChatMessageWithContext stores a report of another player's message
+// for review and returns it with the system message acknowledging it.
+func (s *GameService) ReportChatMessageWithContext(ctx context.Context, roomID string, reporterID string, messageID string, reason string) (ChatReport, game.ChatMessage, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "game.chat_report")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > maxReportReasonChars {
+		spanErr = ErrReportReasonTooLong
+		return ChatReport{}, game.ChatMessage{}, ErrReportReasonTooLong
+	}
 	room, err := s.rooms.GetByID(ctx, roomID)
 	if err != nil {
+		spanErr = ErrRoomNotFound
+		return ChatReport{}, game.ChatMessage{}, ErrRoomNotFound
+	}
+	if _, err := room.GetPlayer(reporterID); err != nil {
+		spanErr = ErrPlayerNotFound
+		return ChatReport{}, game.ChatMessage{}, ErrPlayerNotFound
+	}
+	reported, err := room.ChatMessage(messageID)
+	if err != nil {
+		spanErr = err
+		return ChatReport{}, game.ChatMessage{}, err
+	}
+	if reported.System || reported.PlayerID == reporterID {
+		spanErr = game.ErrCannotReportMessage
+		return ChatReport{}, game.ChatMessage{}, game.ErrCannotReportMessage
+	}
+
+	report := s.reports.Add(ChatReport{
+		RoomID:     roomID,
+		MessageID:  messageID,
+		ReporterID: reporterID,
+		ReportedID: reported.PlayerID,
+		Message:    reported.Message,
+		Reason:     reason,
+		CreatedAt:  time.Now().UTC(),
+	})
+	observability.Logger().InfoContext(ctx, "chat message reported",
+		"room_id", roomID,
+		"player_id", reporterID,
+		"event_type", "chat_report",
+		"report_id", report.ID,
+		"message_id", messageID,
+		"reported_id", reported.PlayerID,
+	)
+	notice := s.addSystemMessage(ctx, room, reporterID, "Thanks, your report was sent for review.")
+	return report, notice, nil
+}
+
+// ChatPageWithContext pages back through the chat of a room as playerID saw
+// it. It reads the chat store, so the room may be gone.
+func (s *GameService) ChatPageWithContext(ctx context.Context, roomID string, playerID string, before string, limit int) (ChatPage, error) {
+	if s.chatStore == nil {
+		return ChatPage{}, ErrChatStoreDisabled
+	}
+	records, more, err := s.chatStore.History(ctx, roomID, playerID, before, chatLimit(limit))
+	if err != nil {
+		return ChatPage{}, err
+	}
+
+	page := ChatPage{Messages: make([]game.ChatMessage, 0, len(records)), More: more}
+	for _, record := range records {
+		page.Messages = append(page.Messages, record.Message)
+	}
+	return page, nil
+}
+
+// SearchChatWithContext finds the messages playerID saw, in any room, with
+// every word of query, the newest first.
+func (s *GameService) SearchChatWithContext(ctx context.Context, playerID string, query string, limit int) ([]game.ChatMessage, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "game.chat_search")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	if s.chatStore == nil {
+		spanErr = ErrChatStoreDisabled
+		return nil, ErrChatStoreDisabled
+	}
+	terms := game.ChatSearchTerms(query)
+	if len(terms) == 0 {
+		spanErr = ErrChatQueryEmpty
+		return nil, ErrChatQueryEmpty
+	}
+	records, err := s.chatStore.Search(ctx, playerID, terms, chatLimit(limit))
+	if err != nil {
+		spanErr = err
 		return nil, err
 	}
 
-	return room.ChatHistory(), nil
+	messages := make([]game.ChatMessage, 0, len(records))
+	for _, record := range records {
+		messages = append(messages, record.Message)
+	}
+	return messages, nil
+}
+
+// StartChatPruning applies the chat store's retention every interval.
+func (s *GameService) StartChatPruning(ctx context.Context, interval time.Duration) {
+	if s.chatStore == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			pruned, err := s.chatStore.Prune(ctx, now)
+			if err != nil {
+				observability.Logger().WarnContext(ctx, "chat pruning failed",
+					"room_id", "",
+					"player_id", "",
+					"event_type", "chat_prune_error",
+					"error", err,
+				)
+				continue
+			}
+			if pruned > 0 {
+				observability.Logger().InfoContext(ctx, "chat pruned",
+					"room_id", "",
+					"player_id", "",
+					"event_type", "chat_pruned",
+					"messages", pruned,
+				)
+			}
+		}
+	}
+}
+
+func (s *GameService) addSystemMessage(ctx context.Context, room *game.Room, recipient string, message string) game.ChatMessage {
+	notice := room.AddSystemMessage(recipient, message)
+	s.archiveChat(ctx, notice, []string{recipient})
+	return notice
+}
+
+// archiveChat keeps a message in the chat store. Chat carries on without it,
+// so a failure is only logged.
+func (s *GameService) archiveChat(ctx context.Context, message game.ChatMessage, audience []string) {
+	if s.chatStore == nil {
+		return
+	}
+	if err := s.chatStore.Append(ctx, game.ChatRecord{Message: message, Audience: audience}); err != nil {
+		observability.Logger().WarnContext(ctx, "chat message not stored",
+			"room_id", message.RoomID,
+			"player_id", message.PlayerID,
+			"event_type", "chat_store_error",
+			"message_id", message.ID,
+			"error", err,
+		)
+	}
+}
+
+func chatLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultChatPageLimit
+	}
+	if limit > MaxChatPageLimit {
+		return MaxChatPageLimit
+	}
+	return limit
+}
+
+func (s *GameService) SendReactionWithContext(ctx context.Context, roomID string, playerID string, reactionID string) (game.Reaction, error) {
+	room, err := s.rooms.GetByID(ctx, roomID)
+	if err != nil {
+		return game.Reaction{}, ErrRoomNotFound
+	}
+	return room.SendReaction(playerID, reactionID)
+}
+
+// SetTypingWithContext switches the typing indicator of playerID. It reports
+// whether the other players need telling.
+func (s *GameService) SetTypingWithContext(ctx context.Context, roomID string, playerID string, typing bool) (game.TypingState, bool, error) {
+	room, err := s.rooms.GetByID(ctx, roomID)
+	if err != nil {
+		return game.TypingState{}, false, ErrRoomNotFound
+	}
+	return room.SetTyping(playerID, typing)
 }
 
 func (s *GameService) RemovePlayerAfterDelay(roomID string, playerID string, delay time.Duration, isConnected func(string) bool) {
@@ -414,12 +998,14 @@ func (s *GameService) removePlayerAfterDelayWithContext(ctx context.Context, roo
 				"generation", generation,
 			)
 			_ = s.rooms.Delete(ctx, roomID)
+			s.notifyRoomChanged(ctx, roomID)
 			return
 		}
 
 		if onRemoved != nil {
 			onRemoved()
 		}
+		s.notifyRoomChanged(ctx, roomID)
 
 		observability.Logger().InfoContext(ctx, "player removed after disconnect grace period",
 			"room_id", roomID,
@@ -477,6 +1063,7 @@ func (s *GameService) CleanupRooms(ctx context.Context, inactiveFor time.Duratio
 		room.RemoveInactivePlayers(now, inactiveFor)
 		if room.IsEmpty() || roomInactive(room, now, inactiveFor) {
 			room.Close()
+			observability.RoomCleanupRemovals.Inc()
 			observability.Logger().InfoContext(ctx, "room cleanup removed room",
 				"room_id", room.RoomID,
 				"player_id", "",
@@ -485,12 +1072,68 @@ func (s *GameService) CleanupRooms(ctx context.Context, inactiveFor time.Duratio
 			if err := s.rooms.Delete(ctx, room.RoomID); err != nil {
 				return err
 			}
+			s.notifyRoomChanged(ctx, room.RoomID)
+			continue
 		}
+		// Inactive players may have left, opening the room to others.
+		s.notifyRoomChanged(ctx, room.RoomID)
 	}
 
 	return nil
 }
 
+// PlayerRoomsWithContext returns a snapshot of every room the player is in,
+// ordered by room ID, so one player can follow several games at once.
+func (s *GameService) PlayerRoomsWithContext(ctx context.Context, playerID string) ([]game.RoomSnapshot, error) {
+	rooms, err := s.rooms.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]game.RoomSnapshot, 0)
+	for _, room := range rooms {
+		snapshot := room.Snapshot()
+		for _, player := range snapshot.Players {
+			if player.ID == playerID {
+				snapshots = append(snapshots, snapshot)
+				break
+			}
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].RoomID < snapshots[j].RoomID
+	})
+	return snapshots, nil
+}
+
+// RoomCounts counts live rooms by game type and room state for the rooms
+// gauge.
+func (s *GameService) RoomCounts() []observability.RoomCount {
+	rooms, err := s.rooms.List(s.context())
+	if err != nil {
+		return nil
+	}
+
+	type roomKey struct {
+		gameType string
+		state    game.RoomState
+	}
+	counts := make(map[roomKey]int)
+	for _, room := range rooms {
+		counts[roomKey{gameType: room.GameType(), state: room.State()}]++
+	}
+
+	result := make([]observability.RoomCount, 0, len(counts))
+	for key, rooms := range counts {
+		result = append(result, observability.RoomCount{
+			GameType: key.gameType,
+			State:    string(key.state),
+			Rooms:    rooms,
+		})
+	}
+	return result
+}
+
 func (s *GameService) StartRoomCleanup(ctx context.Context, inactiveFor time.Duration, tickerInterval time.Duration) {
 	ticker := time.NewTicker(tickerInterval)
 	defer ticker.Stop()
@@ -510,6 +1153,255 @@ func roomInactive(room *game.Room, now time.Time, inactiveFor time.Duration) boo
 	return !lastActive.IsZero() && now.Sub(lastActive) > inactiveFor
 }
 
+//
+// ===============================
+// ADMIN
+// ===============================
+//
+
+// RoomStatus is what operators see of a live room.
+type RoomStatus struct {
+	Snapshot   game.RoomSnapshot
+	CreatedAt  time.Time
+	LastActive time.Time
+}
+
+// RoomStockfish is the engine of one AI chess room.
+type RoomStockfish struct {
+	RoomID string
+	Status game.StockfishStatus
+}
+
+// RoomStatusesWithContext returns every room this node holds, ordered by room
+// ID.
+func (s *GameService) RoomStatusesWithContext(ctx context.Context) ([]RoomStatus, error) {
+	rooms, err := s.rooms.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]RoomStatus, 0, len(rooms))
+	for _, room := range rooms {
+		statuses = append(statuses, RoomStatus{
+			Snapshot:   room.Snapshot(),
+			CreatedAt:  room.CreatedAt(),
+			LastActive: room.LastActive(),
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Snapshot.RoomID < statuses[j].Snapshot.RoomID
+	})
+	return statuses, nil
+}
+
+// ForceFinishRoomWithContext ends the room's game without a result.
+func (s *GameService) ForceFinishRoomWithContext(ctx context.Context, roomID string) (game.RoomSnapshot, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "game.force_finish")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	room, err := s.rooms.GetByID(ctx, roomID)
+	if err != nil {
+		spanErr = ErrRoomNotFound
+		return game.RoomSnapshot{}, ErrRoomNotFound
+	}
+	if err := room.ForceFinish(); err != nil {
+		spanErr = err
+		return game.RoomSnapshot{}, err
+	}
+	observability.Logger().WarnContext(ctx, "room finished by operator",
+		"room_id", roomID,
+		"player_id", "",
+		"event_type", "room_force_finished",
+	)
+	s.notifyRoomChanged(ctx, roomID)
+	return room.Snapshot(), nil
+}
+
+// DeleteRoomWithContext closes and drops the room at once, whoever is in it.
+// It returns the room as it was so the caller can disconnect its players.
+func (s *GameService) DeleteRoomWithContext(ctx context.Context, roomID string) (game.RoomSnapshot, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "game.delete_room")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	room, err := s.rooms.GetByID(ctx, roomID)
+	if err != nil {
+		spanErr = ErrRoomNotFound
+		return game.RoomSnapshot{}, ErrRoomNotFound
+	}
+	snapshot := room.Snapshot()
+	room.Close()
+	if err := s.rooms.Delete(ctx, roomID); err != nil {
+		spanErr = err
+		return game.RoomSnapshot{}, err
+	}
+	observability.Logger().WarnContext(ctx, "room deleted by operator",
+		"room_id", roomID,
+		"player_id", "",
+		"event_type", "room_deleted",
+	)
+	s.notifyRoomChanged(ctx, roomID)
+	return snapshot, nil
+}
+
+// RemovePlayerWithContext kicks a player out of a room on an operator's
+// say, host or not.
+func (s *GameService) RemovePlayerWithContext(ctx context.Context, roomID string, playerID string) error {
+	ctx, endSpan := observability.StartSpan(ctx, "game.remove_player")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	room, err := s.rooms.GetByID(ctx, roomID)
+	if err != nil {
+		spanErr = ErrRoomNotFound
+		return ErrRoomNotFound
+	}
+	if err := room.RemovePlayer(playerID); err != nil {
+		spanErr = err
+		return err
+	}
+	observability.Logger().WarnContext(ctx, "player removed by operator",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", "player_kicked",
+	)
+	s.notifyRoomChanged(ctx, roomID)
+	return nil
+}
+
+// StockfishStatusesWithContext returns the engine of every room that has
+// one, ordered by room ID.
+func (s *GameService) StockfishStatusesWithContext(ctx context.Context) ([]RoomStockfish, error) {
+	rooms, err := s.rooms.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	engines := make([]RoomStockfish, 0)
+	for _, room := range rooms {
+		if status, ok := room.StockfishStatus(); ok {
+			engines = append(engines, RoomStockfish{RoomID: room.RoomID, Status: status})
+		}
+	}
+	sort.Slice(engines, func(i, j int) bool {
+		return engines[i].RoomID < engines[j].RoomID
+	})
+	return engines, nil
+}
+
+//
+// ===============================
+// DRAIN AND RESTORE
+// ===============================
+//
+
+// Drain stops the service creating rooms ahead of a shutdown. Games in
+// progress go on.
+func (s *GameService) Drain() {
+	s.draining.Store(true)
+}
+
+func (s *GameService) Draining() bool {
+	return s.draining.Load()
+}
+
+// CheckRoomsWithContext reports whether the room repository answers. A
+// repository backed by a remote store is asked through its Ping method.
+func (s *GameService) CheckRoomsWithContext(ctx context.Context) error {
+	if pinger, ok := s.rooms.(interface{ Ping(context.Context) error }); ok {
+		return pinger.Ping(ctx)
+	}
+	_, err := s.rooms.List(ctx)
+	return err
+}
+
+// RoomRecordsWithContext returns the record of every room this node holds,
+// ordered by room ID, for RestoreRoomsWithContext after a restart.
+func (s *GameService) RoomRecordsWithContext(ctx context.Context) ([]game.RoomRecord, error) {
+	rooms, err := s.rooms.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]game.RoomRecord, 0, len(rooms))
+	for _, room := range rooms {
+		record := room.Record()
+		for i := range record.Players {
+			record.Players[i].SessionNonce = s.playerManager.SessionNonce(record.Players[i].ID)
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].RoomID < records[j].RoomID
+	})
+	return records, nil
+}
+
+// RestoreRoomsWithContext brings back the rooms recorded before a restart
+// under their old codes, so players reconnect into the same games. A room
+// that cannot be rebuilt, or whose code is taken, is skipped with a warning.
+// It reports how many rooms came back.
+func (s *GameService) RestoreRoomsWithContext(ctx context.Context, records []game.RoomRecord) (int, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "game.restore_rooms")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	restored := 0
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			spanErr = err
+			return restored, err
+		}
+		if _, err := s.rooms.GetByID(ctx, record.RoomID); err == nil {
+			observability.Logger().WarnContext(ctx, "room not restored, code taken",
+				"room_id", record.RoomID,
+				"player_id", "",
+				"event_type", "room_restore_failed",
+			)
+			continue
+		}
+
+		room, err := game.RestoreRoom(record)
+		if err != nil {
+			observability.Logger().WarnContext(ctx, "room not restored",
+				"room_id", record.RoomID,
+				"player_id", "",
+				"event_type", "room_restore_failed",
+				"error", err,
+			)
+			continue
+		}
+		s.prepareRoom(room)
+		if err := s.rooms.Save(ctx, room); err != nil {
+			room.Close()
+			observability.Logger().WarnContext(ctx, "room not restored",
+				"room_id", record.RoomID,
+				"player_id", "",
+				"event_type", "room_restore_failed",
+				"error", err,
+			)
+			continue
+		}
+		for _, player := range record.Players {
+			if !player.IsAI {
+				// Already known players keep their entry.
+				_, _ = s.playerManager.AddPlayerWithSession(player.ID, player.SessionNonce)
+			}
+		}
+		room.Resume()
+		restored++
+		observability.Logger().InfoContext(ctx, "room restored",
+			"room_id", record.RoomID,
+			"player_id", "",
+			"event_type", "room_restored",
+			"room_state", string(record.RoomState),
+		)
+		s.notifyRoomChanged(ctx, record.RoomID)
+	}
+	return restored, nil
+}
+
 //
 // ===============================
 // TIC TAC TOE
@@ -522,7 +1414,7 @@ func (s *GameService) HandleTicTacToeMove(
 	row int,
 	col int,
 ) (*game.TicTacToeMoveResult, error) {
-	return s.HandleTicTacToeMoveWithContext(s.context(), roomID, playerID, row, col)
+	return s.HandleTicTacToeMoveWithContext(s.context(), roomID, playerID, row, col, nil)
 }
 
 func (s *GameService) HandleTicTacToeMoveWithContext(
@@ -531,10 +1423,12 @@ func (s *GameService) HandleTicTacToeMoveWithContext(
 	playerID string,
 	row int,
 	col int,
+	expectedVersion *uint64,
 ) (*game.TicTacToeMoveResult, error) {
 	ctx, endSpan := observability.StartSpan(ctx, "game.tictactoe_move")
 	var spanErr error
 	defer func() { endSpan(spanErr) }()
+	startedAt := time.Now()
 
 	room, err := s.rooms.GetByID(ctx, roomID)
 	if err != nil {
@@ -542,16 +1436,19 @@ func (s *GameService) HandleTicTacToeMoveWithContext(
 		return nil, err
 	}
 
-	result, err := room.HandleTicTacToeMove(
+	result, err := room.HandleTicTacToeMoveWithContext(
+		ctx,
 		playerID,
 		row,
 		col,
+		expectedVersion,
 	)
 	if err != nil {
 		spanErr = err
 		return nil, err
 	}
 
+	observability.MoveDuration.WithLabelValues("tictactoe").Observe(time.Since(startedAt).Seconds())
 	observability.Logger().InfoContext(ctx, "tictactoe move handled",
 		"room_id", roomID,
 		"player_id", playerID,
@@ -576,7 +1473,7 @@ func (s *GameService) HandleChessMove(
 	to string,
 	promotion string,
 ) (*game.ChessMoveResult, error) {
-	return s.HandleChessMoveWithContext(s.context(), roomID, playerID, from, to, promotion)
+	return s.HandleChessMoveWithContext(s.context(), roomID, playerID, from, to, promotion, nil)
 }
 
 func (s *GameService) HandleChessMoveWithContext(
@@ -586,10 +1483,12 @@ func (s *GameService) HandleChessMoveWithContext(
 	from string,
 	to string,
 	promotion string,
+	expectedVersion *uint64,
 ) (*game.ChessMoveResult, error) {
 	ctx, endSpan := observability.StartSpan(ctx, "game.chess_move")
 	var spanErr error
 	defer func() { endSpan(spanErr) }()
+	startedAt := time.Now()
 
 	room, err := s.rooms.GetByID(ctx, roomID)
 	if err != nil {
@@ -603,11 +1502,13 @@ func (s *GameService) HandleChessMoveWithContext(
 		from,
 		to,
 		promotion,
+		expectedVersion,
 	)
 	if err != nil {
 		spanErr = err
 		return nil, err
 	}
+	observability.MoveDuration.WithLabelValues("chess").Observe(time.Since(startedAt).Seconds())
 	observability.Logger().InfoContext(ctx, "chess move handled",
 		"room_id", roomID,
 		"player_id", playerID,
@@ -618,7 +1519,7 @@ func (s *GameService) HandleChessMoveWithContext(
 	return result, nil
 }
 
-func (s *GameService) HandleChessUndoWithContext(ctx context.Context, roomID string, playerID string) error {
+func (s *GameService) HandleChessUndoWithContext(ctx context.Context, roomID string, playerID string, expectedVersion *uint64) error {
 	ctx, endSpan := observability.StartSpan(ctx, "game.chess_undo")
 	var spanErr error
 	defer func() { endSpan(spanErr) }()
@@ -629,7 +1530,7 @@ func (s *GameService) HandleChessUndoWithContext(ctx context.Context, roomID str
 		return err
 	}
 
-	if err := room.HandleChessUndo(playerID); err != nil {
+	if err := room.HandleChessUndoAtVersion(playerID, expectedVersion); err != nil {
 		spanErr = err
 		return err
 	}
@@ -642,6 +1543,113 @@ func (s *GameService) HandleChessUndoWithContext(ctx context.Context, roomID str
 	return nil
 }
 
+func (s *GameService) HandleChessPremoveWithContext(
+	ctx context.Context,
+	roomID string,
+	playerID string,
+	from string,
+	to string,
+	promotion string,
+	expectedVersion *uint64,
+) (*game.ChessPremoveResult, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "game.chess_premove")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	room, err := s.rooms.GetByID(ctx, roomID)
+	if err != nil {
+		spanErr = err
+		return nil, err
+	}
+
+	result, err := room.QueueChessPremoveAtVersion(playerID, from, to, promotion, expectedVersion)
+	if err != nil {
+		spanErr = err
+		return nil, err
+	}
+
+	observability.Logger().InfoContext(ctx, "chess premove handled",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", "chess_premove",
+		"from", from,
+		"to", to,
+		"applied", result.Applied,
+	)
+	return result, nil
+}
+
+func (s *GameService) CancelChessPremoveWithContext(ctx context.Context, roomID string, playerID string, expectedVersion *uint64) error {
+	room, err := s.rooms.GetByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	if err := room.CancelChessPremoveAtVersion(playerID, expectedVersion); err != nil {
+		return err
+	}
+
+	observability.Logger().InfoContext(ctx, "chess premove cancelled",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", "chess_premove_cancelled",
+	)
+	return nil
+}
+
+func (s *GameService) RequestTakebackWithContext(ctx context.Context, roomID string, playerID string, expectedVersion *uint64) (*game.TakebackResult, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "game.takeback_request")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	room, err := s.rooms.GetByID(ctx, roomID)
+	if err != nil {
+		spanErr = err
+		return nil, err
+	}
+
+	result, err := room.RequestTakebackAtVersion(playerID, expectedVersion)
+	if err != nil {
+		spanErr = err
+		return nil, err
+	}
+
+	observability.Logger().InfoContext(ctx, "takeback requested",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", "takeback_request",
+		"applied", result.Applied,
+	)
+	return result, nil
+}
+
+func (s *GameService) RespondTakebackWithContext(ctx context.Context, roomID string, playerID string, accept bool, expectedVersion *uint64) (*game.TakebackResult, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "game.takeback_respond")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	room, err := s.rooms.GetByID(ctx, roomID)
+	if err != nil {
+		spanErr = err
+		return nil, err
+	}
+
+	result, err := room.RespondTakebackAtVersion(playerID, accept, expectedVersion)
+	if err != nil {
+		spanErr = err
+		return nil, err
+	}
+
+	observability.Logger().InfoContext(ctx, "takeback answered",
+		"room_id", roomID,
+		"player_id", playerID,
+		"event_type", "takeback_respond",
+		"accepted", accept,
+		"applied", result.Applied,
+	)
+	return result, nil
+}
+
 func generateRandomRoomCode() string {
 	const possibleCharacters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	gameCode := make([]byte, 7)
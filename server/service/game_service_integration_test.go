@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/tsaqiffatih/mini-game/game"
 	"github.com/tsaqiffatih/mini-game/infrastructure"
@@ -187,3 +190,241 @@ func TestGameServiceIntegration_AIFlow(t *testing.T) {
 		t.Fatalf("human player count = %d, want 1 in snapshot %+v", humanPlayers, snapshot.Players)
 	}
 }
+
+func TestRoomCounts_GroupsRoomsByGameTypeAndState(t *testing.T) {
+	service, _ := newIntegrationGameService()
+	for _, playerID := range []string{"p1", "p2", "p3"} {
+		addIntegrationPlayer(t, service, playerID)
+	}
+
+	roomID := createIntegrationTicTacToeRoom(t, service, "p1")
+	if _, err := service.JoinRoom(roomID, "p2", "tictactoe"); err != nil {
+		t.Fatalf("JoinRoom() error = %v", err)
+	}
+	createIntegrationTicTacToeRoom(t, service, "p3")
+
+	counts := make(map[string]int)
+	for _, count := range service.RoomCounts() {
+		counts[count.GameType+"/"+count.State] = count.Rooms
+	}
+	if counts["tictactoe/PLAYING"] != 1 || counts["tictactoe/WAITING"] != 1 || len(counts) != 2 {
+		t.Fatalf("room counts = %v, want one playing and one waiting tictactoe room", counts)
+	}
+}
+
+func TestGameServiceIntegration_ChatOutlivesTheRoomInTheFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat.jsonl")
+	store, err := infrastructure.OpenFileChatStore(path, infrastructure.ChatRetention{MaxPerRoom: 3})
+	if err != nil {
+		t.Fatalf("OpenFileChatStore() error = %v", err)
+	}
+	service, _ := newIntegrationGameService()
+	service.SetChatStore(store)
+	addIntegrationPlayer(t, service, "p1")
+	addIntegrationPlayer(t, service, "p2")
+	ctx := context.Background()
+	res, err := service.CreateRoomWithContext(ctx, "tictactoe", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	roomID := res.Room.RoomID
+	if _, err := service.JoinRoomWithContext(ctx, roomID, "p2", "tictactoe"); err != nil {
+		t.Fatalf("JoinRoomWithContext() error = %v", err)
+	}
+
+	for _, message := range []string{"first", "meet at the lake", "bring snacks"} {
+		if _, err := service.HandleChatMessageWithContext(ctx, roomID, "p1", message, nil); err != nil {
+			t.Fatalf("HandleChatMessageWithContext(%q) error = %v", message, err)
+		}
+	}
+	if _, err := service.MutePlayerWithContext(ctx, roomID, "p2", "p1", true); err != nil {
+		t.Fatalf("MutePlayerWithContext() error = %v", err)
+	}
+	if err := service.CleanupRooms(ctx, 0); err != nil {
+		t.Fatalf("CleanupRooms() error = %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	reopened, err := infrastructure.OpenFileChatStore(path, infrastructure.ChatRetention{MaxPerRoom: 3})
+	if err != nil {
+		t.Fatalf("OpenFileChatStore(reopen) error = %v", err)
+	}
+	defer reopened.Close()
+	service.SetChatStore(reopened)
+
+	// The cap of 3 dropped "first"; p2's mute notice only shows to p2.
+	page, err := service.ChatPageWithContext(ctx, roomID, "p1", "", 1)
+	if err != nil {
+		t.Fatalf("ChatPageWithContext() error = %v", err)
+	}
+	if len(page.Messages) != 1 || page.Messages[0].Message != "bring snacks" || !page.More {
+		t.Fatalf("p1 last page = %+v, want \"bring snacks\" with more before it", page)
+	}
+	page, err = service.ChatPageWithContext(ctx, roomID, "p1", page.Messages[0].ID, 10)
+	if err != nil {
+		t.Fatalf("ChatPageWithContext(before) error = %v", err)
+	}
+	if len(page.Messages) != 1 || page.Messages[0].Message != "meet at the lake" || page.More {
+		t.Fatalf("p1 earlier page = %+v, want only \"meet at the lake\"", page)
+	}
+
+	found, err := service.SearchChatWithContext(ctx, "p2", "LAKE", 0)
+	if err != nil {
+		t.Fatalf("SearchChatWithContext() error = %v", err)
+	}
+	if len(found) != 1 || found[0].RoomID != roomID {
+		t.Fatalf("search = %+v, want the lake message of room %s", found, roomID)
+	}
+	if found, _ := service.SearchChatWithContext(ctx, "p3", "lake", 0); len(found) != 0 {
+		t.Fatalf("search by an outsider = %+v, want nothing", found)
+	}
+
+	if pruned, err := reopened.Prune(ctx, time.Now()); err != nil || pruned != 0 {
+		t.Fatalf("Prune() = %d, %v, want nothing pruned without a max age", pruned, err)
+	}
+}
+
+func TestGameServiceIntegration_DrainedRoomsComeBackAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	before, _ := newIntegrationGameService()
+	addIntegrationPlayer(t, before, "p1")
+	addIntegrationPlayer(t, before, "p2")
+	res, err := before.CreateRoomWithContext(ctx, "tictactoe", "p1")
+	if err != nil {
+		t.Fatalf("CreateRoomWithContext() error = %v", err)
+	}
+	roomID := res.Room.RoomID
+	if _, err := before.JoinRoomWithContext(ctx, roomID, "p2", "tictactoe"); err != nil {
+		t.Fatalf("JoinRoomWithContext() error = %v", err)
+	}
+	if _, err := before.HandleTicTacToeMoveWithContext(ctx, roomID, "p1", 2, 2, nil); err != nil {
+		t.Fatalf("HandleTicTacToeMoveWithContext() error = %v", err)
+	}
+
+	before.Drain()
+	if _, err := before.CreateRoomWithContext(ctx, "tictactoe", "p2"); err != ErrServerDraining {
+		t.Fatalf("CreateRoomWithContext() while draining error = %v, want %v", err, ErrServerDraining)
+	}
+	records, err := before.RoomRecordsWithContext(ctx)
+	if err != nil {
+		t.Fatalf("RoomRecordsWithContext() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "rooms.json")
+	if err := infrastructure.SaveRoomRecords(path, records); err != nil {
+		t.Fatalf("SaveRoomRecords() error = %v", err)
+	}
+
+	after, _ := newIntegrationGameService()
+	loaded, err := infrastructure.LoadRoomRecords(path)
+	if err != nil {
+		t.Fatalf("LoadRoomRecords() error = %v", err)
+	}
+	restored, err := after.RestoreRoomsWithContext(ctx, loaded)
+	if err != nil || restored != 1 {
+		t.Fatalf("RestoreRoomsWithContext() = %d, %v, want 1 room", restored, err)
+	}
+	if err := after.MarkPlayerConnectedWithContext(ctx, roomID, "p2"); err != nil {
+		t.Fatalf("MarkPlayerConnectedWithContext() error = %v", err)
+	}
+	if _, err := after.HandleTicTacToeMoveWithContext(ctx, roomID, "p2", 0, 0, nil); err != nil {
+		t.Fatalf("HandleTicTacToeMoveWithContext() after restore error = %v", err)
+	}
+	snapshot, err := after.RoomSnapshotWithContext(ctx, roomID)
+	if err != nil {
+		t.Fatalf("RoomSnapshotWithContext() error = %v", err)
+	}
+	if snapshot.TicTacToe.Board[2][2] != "X" || snapshot.TicTacToe.Board[0][0] != "O" {
+		t.Fatalf("board after restore = %v, want X at 2,2 and O at 0,0", snapshot.TicTacToe.Board)
+	}
+}
+
+func TestGameService_AuditLog_KeepsRoomCommandsInFile(t *testing.T) {
+	service, _ := newIntegrationGameService()
+	ctx := context.Background()
+	if _, err := service.RoomAuditWithContext(ctx, "ROOM"); err != ErrAuditLogDisabled {
+		t.Fatalf("RoomAuditWithContext() without a log error = %v, want %v", err, ErrAuditLogDisabled)
+	}
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := infrastructure.OpenFileAuditLog(path)
+	if err != nil {
+		t.Fatalf("OpenFileAuditLog() error = %v", err)
+	}
+	defer auditLog.Close()
+	service.SetAuditLog(auditLog)
+
+	addIntegrationPlayer(t, service, "p1")
+	addIntegrationPlayer(t, service, "p2")
+	roomID := createIntegrationTicTacToeRoom(t, service, "p1")
+	otherRoomID := createIntegrationTicTacToeRoom(t, service, "p2")
+	if _, err := service.JoinRoom(roomID, "p2", "tictactoe"); err != nil {
+		t.Fatalf("JoinRoom() error = %v", err)
+	}
+	if _, err := service.HandleTicTacToeMoveWithContext(ctx, roomID, "p1", 4, 4, nil); err == nil {
+		t.Fatalf("HandleTicTacToeMoveWithContext(out of bounds) error = nil, want error")
+	}
+	if _, err := service.HandleTicTacToeMoveWithContext(ctx, roomID, "p1", 1, 1, nil); err != nil {
+		t.Fatalf("HandleTicTacToeMoveWithContext() error = %v", err)
+	}
+
+	events, err := service.RoomAuditWithContext(ctx, roomID)
+	if err != nil {
+		t.Fatalf("RoomAuditWithContext() error = %v", err)
+	}
+	actions := make([]game.AuditAction, 0, len(events))
+	for _, event := range events {
+		if event.RoomID != roomID {
+			t.Fatalf("event of room %s in the log of %s", event.RoomID, roomID)
+		}
+		actions = append(actions, event.Action)
+	}
+	want := []game.AuditAction{game.AuditOpen, game.AuditJoin, game.AuditJoin, game.AuditMove, game.AuditMove}
+	if fmt.Sprint(actions) != fmt.Sprint(want) {
+		t.Fatalf("actions = %v, want %v", actions, want)
+	}
+	if events[3].Accepted || !events[4].Accepted || events[4].VersionAfter <= events[4].VersionBefore {
+		t.Fatalf("moves = %+v, %+v, want the first rejected and the second applied", events[3], events[4])
+	}
+
+	other, err := infrastructure.ReadAuditLogFile(path, otherRoomID)
+	if err != nil {
+		t.Fatalf("ReadAuditLogFile() error = %v", err)
+	}
+	if len(other) != 2 || other[1].PlayerID != "p2" {
+		t.Fatalf("other room events = %+v, want its open and p2 joining", other)
+	}
+}
+
+func TestGameService_AuditLog_KeepsEventsAfterShutdownBegins(t *testing.T) {
+	service, repo := newIntegrationGameService()
+	ctx, cancel := context.WithCancel(context.Background())
+	service.SetContext(ctx)
+	service.SetAuditLog(infrastructure.NewMemoryAuditLog(100))
+
+	addIntegrationPlayer(t, service, "p1")
+	addIntegrationPlayer(t, service, "p2")
+	roomID := createIntegrationTicTacToeRoom(t, service, "p1")
+	if _, err := service.JoinRoom(roomID, "p2", "tictactoe"); err != nil {
+		t.Fatalf("JoinRoom() error = %v", err)
+	}
+	room, err := repo.GetByID(context.Background(), roomID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+
+	// A game still being played while the server drains its clients.
+	cancel()
+	if _, err := room.HandleTicTacToeMove("p1", 1, 1); err != nil {
+		t.Fatalf("HandleTicTacToeMove() error = %v", err)
+	}
+
+	events, err := service.RoomAuditWithContext(context.Background(), roomID)
+	if err != nil {
+		t.Fatalf("RoomAuditWithContext() error = %v", err)
+	}
+	if len(events) != 4 || events[3].Action != game.AuditMove {
+		t.Fatalf("events = %+v, want the move made after shutdown began", events)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/tsaqiffatih/mini-game/auth"
 	"github.com/tsaqiffatih/mini-game/game"
 )
 
@@ -218,6 +219,62 @@ func TestGameService_JoinRoom_RoomNotFound_ReturnsError(t *testing.T) {
 	}
 }
 
+func TestGameService_JoinRoomWithAccess_ChecksRoomPassword(t *testing.T) {
+	service, _ := newGameServiceForTest()
+	addServicePlayerForTest(t, service, "p1")
+	addServicePlayerForTest(t, service, "p2")
+	res, err := service.CreateRoomWithOptionsWithContext(context.Background(), "tictactoe", "p1", RoomOptions{Password: "letmein"})
+	if err != nil {
+		t.Fatalf("CreateRoomWithOptionsWithContext() error = %v", err)
+	}
+	roomID := res.Room.RoomID
+
+	_, err = service.JoinRoomWithAccessWithContext(context.Background(), roomID, "p2", "tictactoe", RoomAccess{Password: "wrong"})
+	if err != game.ErrRoomPasswordInvalid {
+		t.Fatalf("JoinRoomWithAccessWithContext(wrong password) error = %v, want %v", err, game.ErrRoomPasswordInvalid)
+	}
+	if _, err := service.JoinRoomWithAccessWithContext(context.Background(), roomID, "p2", "tictactoe", RoomAccess{Password: "letmein"}); err != nil {
+		t.Fatalf("JoinRoomWithAccessWithContext(password) error = %v", err)
+	}
+}
+
+func TestGameService_CreateInvite_AdmitsOnePlayerToPrivateRoom(t *testing.T) {
+	service, _ := newGameServiceForTest()
+	service.SetInviteSigner(auth.NewTokenSigner([]byte("secret"), time.Hour))
+	for _, id := range []string{"p1", "p2", "p3"} {
+		addServicePlayerForTest(t, service, id)
+	}
+	res, err := service.CreateRoomWithOptionsWithContext(context.Background(), "tictactoe", "p1", RoomOptions{Visibility: game.RoomVisibilityPrivate})
+	if err != nil {
+		t.Fatalf("CreateRoomWithOptionsWithContext() error = %v", err)
+	}
+	roomID := res.Room.RoomID
+
+	if _, err := service.CreateInviteWithContext(context.Background(), roomID, "p2"); err != game.ErrNotRoomHost {
+		t.Fatalf("CreateInviteWithContext(not host) error = %v, want %v", err, game.ErrNotRoomHost)
+	}
+	invite, err := service.CreateInviteWithContext(context.Background(), roomID, "p1")
+	if err != nil {
+		t.Fatalf("CreateInviteWithContext() error = %v", err)
+	}
+
+	if _, err := service.JoinRoom(roomID, "p2", "tictactoe"); err != game.ErrRoomPrivate {
+		t.Fatalf("JoinRoom(private) error = %v, want %v", err, game.ErrRoomPrivate)
+	}
+	if _, err := service.JoinRoomWithAccessWithContext(context.Background(), roomID, "p2", "tictactoe", RoomAccess{Invite: "forged"}); err != ErrInviteInvalid {
+		t.Fatalf("JoinRoomWithAccessWithContext(forged invite) error = %v, want %v", err, ErrInviteInvalid)
+	}
+	if _, err := service.JoinRoomWithAccessWithContext(context.Background(), roomID, "p2", "tictactoe", RoomAccess{Invite: invite.Token}); err != nil {
+		t.Fatalf("JoinRoomWithAccessWithContext(invite) error = %v", err)
+	}
+	if err := service.KickPlayerWithContext(context.Background(), roomID, "p1", "p2", nil); err != nil {
+		t.Fatalf("KickPlayerWithContext() error = %v", err)
+	}
+	if _, err := service.JoinRoomWithAccessWithContext(context.Background(), roomID, "p3", "tictactoe", RoomAccess{Invite: invite.Token}); err != game.ErrInviteUsed {
+		t.Fatalf("JoinRoomWithAccessWithContext(reused invite) error = %v, want %v", err, game.ErrInviteUsed)
+	}
+}
+
 func TestGameService_GetPlayerInRoom_Existing_ReturnsSnapshot(t *testing.T) {
 	service, _ := newGameServiceForTest()
 	addServicePlayerForTest(t, service, "p1")
@@ -248,6 +305,95 @@ func TestGameService_GetPlayerInRoom_PlayerNotInRoom_ReturnsError(t *testing.T)
 	}
 }
 
+func TestGameService_PlayerRooms_ListsEveryRoomOfThePlayer(t *testing.T) {
+	service, _ := newGameServiceForTest()
+	addServicePlayerForTest(t, service, "p1")
+	addServicePlayerForTest(t, service, "p2")
+	first := createTicTacToeRoomForServiceTest(t, service, "p1")
+	second := createTicTacToeRoomForServiceTest(t, service, "p1")
+	createTicTacToeRoomForServiceTest(t, service, "p2")
+
+	rooms, err := service.PlayerRoomsWithContext(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("PlayerRoomsWithContext() error = %v", err)
+	}
+	if len(rooms) != 2 {
+		t.Fatalf("rooms len = %d, want 2", len(rooms))
+	}
+	want := []string{first, second}
+	if want[0] > want[1] {
+		want[0], want[1] = want[1], want[0]
+	}
+	for i, room := range rooms {
+		if room.RoomID != want[i] {
+			t.Fatalf("rooms[%d] = %q, want %q", i, room.RoomID, want[i])
+		}
+	}
+}
+
+func TestGameService_HandleChatMessage_MasksAndReportsMessages(t *testing.T) {
+	service, _ := newGameServiceForTest()
+	service.SetChatFilter(game.NewChatFilter([]string{"darn"}))
+	addServicePlayerForTest(t, service, "p1")
+	addServicePlayerForTest(t, service, "p2")
+	roomID := createTicTacToeRoomForServiceTest(t, service, "p1")
+	if _, err := service.JoinRoom(roomID, "p2", "tictactoe"); err != nil {
+		t.Fatalf("JoinRoom() error = %v", err)
+	}
+	ctx := context.Background()
+
+	post, err := service.HandleChatMessageWithContext(ctx, roomID, "p2", "darn you", nil)
+	if err != nil {
+		t.Fatalf("HandleChatMessageWithContext() error = %v", err)
+	}
+	if post.Message.Message != "**** you" || len(post.Audience) != 2 {
+		t.Fatalf("post = %+v, want a masked message seen by both players", post)
+	}
+	if post.Notice == nil || !post.Notice.System || post.Notice.Recipient != "p2" {
+		t.Fatalf("notice = %+v, want a system message to p2", post.Notice)
+	}
+
+	if _, _, err := service.ReportChatMessageWithContext(ctx, roomID, "p2", post.Message.ID, ""); !errors.Is(err, game.ErrCannotReportMessage) {
+		t.Fatalf("ReportChatMessageWithContext(own message) error = %v, want %v", err, game.ErrCannotReportMessage)
+	}
+	report, notice, err := service.ReportChatMessageWithContext(ctx, roomID, "p1", post.Message.ID, " rude ")
+	if err != nil {
+		t.Fatalf("ReportChatMessageWithContext() error = %v", err)
+	}
+	if report.ReportedID != "p2" || report.Reason != "rude" || report.Message != "**** you" {
+		t.Fatalf("report = %+v, want p2's message reported as rude", report)
+	}
+	if notice.Recipient != "p1" {
+		t.Fatalf("notice recipient = %q, want p1", notice.Recipient)
+	}
+	if reports := service.ChatReports().List(); len(reports) != 1 || reports[0].ID != report.ID {
+		t.Fatalf("stored reports = %+v, want the report", reports)
+	}
+}
+
+func TestGameService_SetRoomConfig_AppliesToNewRooms(t *testing.T) {
+	service, _ := newGameServiceForTest()
+	config := game.DefaultRoomConfig()
+	config.ChatHistory = 2
+	service.SetRoomConfig(config)
+	addServicePlayerForTest(t, service, "p1")
+	roomID := createTicTacToeRoomForServiceTest(t, service, "p1")
+	ctx := context.Background()
+
+	for _, message := range []string{"one", "two", "three"} {
+		if _, err := service.HandleChatMessageWithContext(ctx, roomID, "p1", message, nil); err != nil {
+			t.Fatalf("HandleChatMessageWithContext(%q) error = %v", message, err)
+		}
+	}
+	history, err := service.ChatHistoryWithContext(ctx, roomID, "p1")
+	if err != nil {
+		t.Fatalf("ChatHistoryWithContext() error = %v", err)
+	}
+	if len(history) != 2 || history[0].Message != "two" {
+		t.Fatalf("history = %+v, want the last two messages", history)
+	}
+}
+
 func TestGameService_HandleTicTacToeMove_Valid_DelegatesToRoom(t *testing.T) {
 	service, _ := newGameServiceForTest()
 	addServicePlayerForTest(t, service, "p1")
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/stats"
+)
+
+const (
+	DefaultLobbyLimit = 50
+	MaxLobbyLimit     = 200
+)
+
+// LobbyRoom is a public room whose host is waiting for an opponent. There is
+// no rating system, so the host's record in the game type stands in for one.
+type LobbyRoom struct {
+	RoomID      string
+	GameType    string
+	HostID      string
+	HostRecord  stats.Record
+	HasPassword bool
+	CreatedAt   time.Time
+}
+
+// LobbyFilter narrows the lobby. The zero value matches every room.
+type LobbyFilter struct {
+	GameType string
+	// HasPassword, when set, keeps only the rooms with a password or only
+	// those without.
+	HasPassword *bool
+	// MinHostGames keeps the rooms whose host played at least that many
+	// games of the room's type.
+	MinHostGames int
+}
+
+func (f LobbyFilter) Matches(room LobbyRoom) bool {
+	if f.GameType != "" && room.GameType != f.GameType {
+		return false
+	}
+	if f.HasPassword != nil && room.HasPassword != *f.HasPassword {
+		return false
+	}
+	return room.HostRecord.Games >= f.MinHostGames
+}
+
+// LobbyUpdate reports a room entering, changing in or leaving the lobby.
+// Room is nil once it left.
+type LobbyUpdate struct {
+	RoomID string
+	Room   *LobbyRoom
+}
+
+// remoteLobbyRoom is a room listed by another node, kept until that node
+// removes it or stops announcing it.
+type remoteLobbyRoom struct {
+	room   LobbyRoom
+	nodeID string
+	seenAt time.Time
+}
+
+// LobbyService lists the open rooms across the cluster and tells subscribers
+// as rooms open up, fill or go away. It learns of changes to this node's
+// rooms through the game service's room change notifier, and of other
+// nodes' rooms through HandleRemoteUpdate.
+type LobbyService struct {
+	games        *GameService
+	stats        *stats.Store
+	mu           sync.Mutex
+	listed       map[string]LobbyRoom
+	remote       map[string]remoteLobbyRoom
+	listeners    map[int]func(LobbyUpdate)
+	nextListener int
+	localChange  func(ctx context.Context, update LobbyUpdate)
+}
+
+func NewLobbyService(games *GameService, store *stats.Store) *LobbyService {
+	return &LobbyService{
+		games:     games,
+		stats:     store,
+		listed:    make(map[string]LobbyRoom),
+		remote:    make(map[string]remoteLobbyRoom),
+		listeners: make(map[int]func(LobbyUpdate)),
+	}
+}
+
+// ListWithContext returns up to limit open rooms matching filter, the
+// longest waiting first.
+func (s *LobbyService) ListWithContext(ctx context.Context, filter LobbyFilter, limit int) ([]LobbyRoom, error) {
+	if filter.GameType != "" && filter.GameType != "chess" && filter.GameType != "tictactoe" {
+		return nil, ErrUnsupportedGameType
+	}
+	if limit <= 0 {
+		limit = DefaultLobbyLimit
+	}
+	if limit > MaxLobbyLimit {
+		limit = MaxLobbyLimit
+	}
+
+	local, err := s.LocalRoomsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	open := make([]LobbyRoom, 0)
+	here := make(map[string]bool, len(local))
+	for _, entry := range local {
+		here[entry.RoomID] = true
+		if filter.Matches(entry) {
+			open = append(open, entry)
+		}
+	}
+	s.mu.Lock()
+	for roomID, remote := range s.remote {
+		if !here[roomID] && filter.Matches(remote.room) {
+			open = append(open, remote.room)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(open, func(i, j int) bool {
+		if !open[i].CreatedAt.Equal(open[j].CreatedAt) {
+			return open[i].CreatedAt.Before(open[j].CreatedAt)
+		}
+		return open[i].RoomID < open[j].RoomID
+	})
+	if len(open) > limit {
+		open = open[:limit]
+	}
+	return open, nil
+}
+
+// LocalRoomsWithContext returns the open rooms of this node, for announcing
+// them to the other nodes.
+func (s *LobbyService) LocalRoomsWithContext(ctx context.Context) ([]LobbyRoom, error) {
+	rooms, err := s.games.rooms.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	open := make([]LobbyRoom, 0)
+	for _, room := range rooms {
+		if listing, ok := room.Listing(); ok {
+			open = append(open, s.lobbyRoom(listing))
+		}
+	}
+	return open, nil
+}
+
+// SetLocalChangeNotifier is called with every update to a room of this
+// node, so the cluster can announce it to the other nodes.
+func (s *LobbyService) SetLocalChangeNotifier(notifier func(ctx context.Context, update LobbyUpdate)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.localChange = notifier
+}
+
+// HandleRoomChange looks the room up again and tells the subscribers when
+// its lobby entry changed.
+func (s *LobbyService) HandleRoomChange(ctx context.Context, roomID string) {
+	var entry *LobbyRoom
+	if room, err := s.games.rooms.GetByID(ctx, roomID); err == nil {
+		if listing, ok := room.Listing(); ok {
+			listed := s.lobbyRoom(listing)
+			entry = &listed
+		}
+	}
+
+	s.mu.Lock()
+	previous, wasListed := s.listed[roomID]
+	if entry == nil {
+		if !wasListed {
+			s.mu.Unlock()
+			return
+		}
+		delete(s.listed, roomID)
+	} else {
+		if wasListed && previous == *entry {
+			s.mu.Unlock()
+			return
+		}
+		s.listed[roomID] = *entry
+	}
+	update := LobbyUpdate{RoomID: roomID, Room: entry}
+	s.notify(update)
+	notifier := s.localChange
+	s.mu.Unlock()
+
+	if notifier != nil {
+		notifier(ctx, update)
+	}
+}
+
+// HandleRemoteUpdate records a lobby update nodeID announced for one of its
+// rooms and tells the subscribers when the entry changed. A removal only
+// drops the entry nodeID listed, so the late removal from a room's old owner
+// does not hide it once another node took it over.
+func (s *LobbyService) HandleRemoteUpdate(nodeID string, update LobbyUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, wasListed := s.remote[update.RoomID]
+	if update.Room == nil {
+		if !wasListed || previous.nodeID != nodeID {
+			return
+		}
+		delete(s.remote, update.RoomID)
+	} else {
+		s.remote[update.RoomID] = remoteLobbyRoom{room: *update.Room, nodeID: nodeID, seenAt: time.Now()}
+		if wasListed && previous.room == *update.Room {
+			return
+		}
+	}
+	s.notify(update)
+}
+
+// ExpireRemote drops the rooms of other nodes last announced before cutoff,
+// such as those of a node that stopped without removing them.
+func (s *LobbyService) ExpireRemote(cutoff time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for roomID, remote := range s.remote {
+		if remote.seenAt.Before(cutoff) {
+			delete(s.remote, roomID)
+			s.notify(LobbyUpdate{RoomID: roomID})
+		}
+	}
+}
+
+// notify runs the listeners under s.mu so every subscriber sees a room's
+// updates in order; they must not block.
+func (s *LobbyService) notify(update LobbyUpdate) {
+	for _, listener := range s.listeners {
+		listener(update)
+	}
+}
+
+// Subscribe calls listener with every lobby update until the returned
+// function is called. The listener must not block.
+func (s *LobbyService) Subscribe(listener func(LobbyUpdate)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextListener
+	s.nextListener++
+	s.listeners[id] = listener
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.listeners, id)
+	}
+}
+
+func (s *LobbyService) lobbyRoom(listing game.RoomListing) LobbyRoom {
+	return LobbyRoom{
+		RoomID:      listing.RoomID,
+		GameType:    listing.GameType,
+		HostID:      listing.HostID,
+		HostRecord:  s.stats.Record(listing.HostID, listing.GameType),
+		HasPassword: listing.HasPassword,
+		CreatedAt:   listing.CreatedAt,
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/stats"
+)
+
+func newLobbyServiceForTest(t *testing.T, playerIDs ...string) (*LobbyService, *GameService) {
+	t.Helper()
+
+	gameService, _ := newIntegrationGameService()
+	for _, playerID := range playerIDs {
+		addIntegrationPlayer(t, gameService, playerID)
+	}
+
+	lobbyService := NewLobbyService(gameService, stats.NewStore())
+	gameService.SetRoomChangeNotifier(lobbyService.HandleRoomChange)
+	return lobbyService, gameService
+}
+
+func TestLobbyService_ListsOnlyPublicRoomsWaitingForAnOpponent(t *testing.T) {
+	lobbyService, gameService := newLobbyServiceForTest(t, "p1", "p2", "p3", "p4")
+	ctx := gameService.context()
+
+	open, err := gameService.CreateRoomWithOptionsWithContext(ctx, "chess", "p1", RoomOptions{})
+	if err != nil {
+		t.Fatalf("CreateRoomWithOptionsWithContext() error = %v", err)
+	}
+	if _, err := gameService.CreateRoomWithOptionsWithContext(ctx, "chess", "p2", RoomOptions{Visibility: game.RoomVisibilityPrivate}); err != nil {
+		t.Fatalf("CreateRoomWithOptionsWithContext(private) error = %v", err)
+	}
+	full, err := gameService.CreateRoomWithOptionsWithContext(ctx, "chess", "p3", RoomOptions{})
+	if err != nil {
+		t.Fatalf("CreateRoomWithOptionsWithContext() error = %v", err)
+	}
+	if _, err := gameService.JoinRoomWithContext(ctx, full.Room.RoomID, "p4", "chess"); err != nil {
+		t.Fatalf("JoinRoomWithContext() error = %v", err)
+	}
+
+	rooms, err := lobbyService.ListWithContext(ctx, LobbyFilter{GameType: "chess"}, 0)
+	if err != nil {
+		t.Fatalf("ListWithContext() error = %v", err)
+	}
+	if len(rooms) != 1 || rooms[0].RoomID != open.Room.RoomID || rooms[0].HostID != "p1" {
+		t.Fatalf("lobby = %+v, want only room %s hosted by p1", rooms, open.Room.RoomID)
+	}
+
+	rooms, err = lobbyService.ListWithContext(ctx, LobbyFilter{GameType: "tictactoe"}, 0)
+	if err != nil {
+		t.Fatalf("ListWithContext(tictactoe) error = %v", err)
+	}
+	if len(rooms) != 0 {
+		t.Fatalf("tictactoe lobby = %+v, want empty", rooms)
+	}
+}
+
+func TestLobbyService_TellsSubscribersWhenRoomsOpenAndFill(t *testing.T) {
+	lobbyService, gameService := newLobbyServiceForTest(t, "p1", "p2")
+	ctx := gameService.context()
+
+	var updates []LobbyUpdate
+	unsubscribe := lobbyService.Subscribe(func(update LobbyUpdate) {
+		updates = append(updates, update)
+	})
+	defer unsubscribe()
+
+	res, err := gameService.CreateRoomWithOptionsWithContext(ctx, "tictactoe", "p1", RoomOptions{})
+	if err != nil {
+		t.Fatalf("CreateRoomWithOptionsWithContext() error = %v", err)
+	}
+	if _, err := gameService.JoinRoomWithContext(ctx, res.Room.RoomID, "p2", "tictactoe"); err != nil {
+		t.Fatalf("JoinRoomWithContext() error = %v", err)
+	}
+
+	if len(updates) != 2 {
+		t.Fatalf("updates = %+v, want the room opening and filling", updates)
+	}
+	if updates[0].Room == nil || updates[0].Room.RoomID != res.Room.RoomID {
+		t.Fatalf("first update = %+v, want room %s listed", updates[0], res.Room.RoomID)
+	}
+	if updates[1].RoomID != res.Room.RoomID || updates[1].Room != nil {
+		t.Fatalf("second update = %+v, want room %s removed", updates[1], res.Room.RoomID)
+	}
+}
+
+func TestLobbyService_ListsRoomsAnnouncedByOtherNodes(t *testing.T) {
+	lobbyService, gameService := newLobbyServiceForTest(t, "p1")
+	ctx := gameService.context()
+
+	local, err := gameService.CreateRoomWithOptionsWithContext(ctx, "chess", "p1", RoomOptions{})
+	if err != nil {
+		t.Fatalf("CreateRoomWithOptionsWithContext() error = %v", err)
+	}
+	var updates []LobbyUpdate
+	unsubscribe := lobbyService.Subscribe(func(update LobbyUpdate) {
+		updates = append(updates, update)
+	})
+	defer unsubscribe()
+
+	remote := LobbyRoom{RoomID: "REMOTE1", GameType: "chess", HostID: "p9", CreatedAt: time.Now()}
+	lobbyService.HandleRemoteUpdate("node-b", LobbyUpdate{RoomID: remote.RoomID, Room: &remote})
+	rooms, err := lobbyService.ListWithContext(ctx, LobbyFilter{}, 0)
+	if err != nil {
+		t.Fatalf("ListWithContext() error = %v", err)
+	}
+	if len(rooms) != 2 || rooms[0].RoomID != local.Room.RoomID || rooms[1].RoomID != remote.RoomID {
+		t.Fatalf("lobby = %+v, want room %s and the remote room", rooms, local.Room.RoomID)
+	}
+	if len(updates) != 1 || updates[0].Room == nil || updates[0].RoomID != remote.RoomID {
+		t.Fatalf("updates = %+v, want the remote room listed", updates)
+	}
+
+	// Another node cannot unlist a room it did not list.
+	lobbyService.HandleRemoteUpdate("node-c", LobbyUpdate{RoomID: remote.RoomID})
+	if rooms, _ := lobbyService.ListWithContext(ctx, LobbyFilter{}, 0); len(rooms) != 2 {
+		t.Fatalf("lobby after a removal from node-c = %+v, want the remote room kept", rooms)
+	}
+
+	lobbyService.ExpireRemote(time.Now().Add(time.Second))
+	if rooms, _ := lobbyService.ListWithContext(ctx, LobbyFilter{}, 0); len(rooms) != 1 || rooms[0].RoomID != local.Room.RoomID {
+		t.Fatalf("lobby after expiry = %+v, want only room %s", rooms, local.Room.RoomID)
+	}
+	if len(updates) != 2 || updates[1].RoomID != remote.RoomID || updates[1].Room != nil {
+		t.Fatalf("updates = %+v, want the remote room removed", updates)
+	}
+}
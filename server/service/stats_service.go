@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+	"github.com/tsaqiffatih/mini-game/stats"
+)
+
+const (
+	DefaultLeaderboardLimit = 20
+	MaxLeaderboardLimit     = 100
+)
+
+// StatsService records finished games for every human player and serves
+// leaderboards and per-player statistics.
+type StatsService struct {
+	store *stats.Store
+	games *GameService
+}
+
+func NewStatsService(store *stats.Store, games *GameService) *StatsService {
+	return &StatsService{
+		store: store,
+		games: games,
+	}
+}
+
+// RecordGameOutcome stores one record per human player of a finished game.
+func (s *StatsService) RecordGameOutcome(outcome game.GameOutcome) {
+	duration := outcome.FinishedAt.Sub(outcome.StartedAt)
+	if outcome.StartedAt.IsZero() || duration < 0 {
+		duration = 0
+	}
+
+	opening := ""
+	if outcome.GameType == "chess" {
+		opening = stats.OpeningName(outcome.PGNMoves)
+	}
+
+	for _, playerID := range outcome.PlayerIDs {
+		if playerID == outcome.AIPlayerID {
+			continue
+		}
+
+		record := stats.GameRecord{
+			RoomID:     outcome.RoomID,
+			GameType:   outcome.GameType,
+			PlayerID:   playerID,
+			Result:     stats.ResultDraw,
+			Moves:      outcome.Moves,
+			Duration:   duration,
+			Opening:    opening,
+			FinishedAt: outcome.FinishedAt,
+		}
+		for _, otherID := range outcome.PlayerIDs {
+			if otherID != playerID {
+				record.OpponentID = otherID
+			}
+		}
+		if record.OpponentID != "" && record.OpponentID == outcome.AIPlayerID {
+			record.OpponentIsAI = true
+			record.AILevel = outcome.AILevel
+		}
+		switch {
+		case outcome.Draw:
+		case outcome.WinnerID == playerID:
+			record.Result = stats.ResultWin
+		default:
+			record.Result = stats.ResultLoss
+		}
+
+		s.store.Add(record)
+		observability.Logger().InfoContext(s.games.context(), "game result recorded",
+			"room_id", outcome.RoomID,
+			"player_id", playerID,
+			"event_type", "game_result_recorded",
+			"game_type", outcome.GameType,
+			"result", string(record.Result),
+		)
+	}
+}
+
+// LeaderboardWithContext ranks players of gameType. An empty gameType ranks
+// all games together.
+func (s *StatsService) LeaderboardWithContext(ctx context.Context, gameType string, limit int) ([]stats.LeaderboardEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if gameType != "" && gameType != "chess" && gameType != "tictactoe" {
+		return nil, ErrUnsupportedGameType
+	}
+
+	if limit <= 0 {
+		limit = DefaultLeaderboardLimit
+	}
+	if limit > MaxLeaderboardLimit {
+		limit = MaxLeaderboardLimit
+	}
+	return s.store.Leaderboard(gameType, limit), nil
+}
+
+// PlayerStatsWithContext returns the statistics of a known player. Players
+// that were removed for inactivity keep their statistics.
+func (s *StatsService) PlayerStatsWithContext(ctx context.Context, playerID string) (stats.PlayerStats, error) {
+	if err := ctx.Err(); err != nil {
+		return stats.PlayerStats{}, err
+	}
+	if _, err := s.games.playerManager.GetPlayer(playerID); err != nil && !s.store.HasPlayer(playerID) {
+		return stats.PlayerStats{}, ErrPlayerNotFound
+	}
+
+	return s.store.PlayerStats(playerID), nil
+}
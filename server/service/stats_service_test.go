@@ -0,0 +1,134 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/stats"
+)
+
+func newStatsServiceForTest(t *testing.T, playerIDs ...string) (*StatsService, *GameService) {
+	t.Helper()
+
+	gameService, _ := newIntegrationGameService()
+	for _, playerID := range playerIDs {
+		addIntegrationPlayer(t, gameService, playerID)
+	}
+
+	statsService := NewStatsService(stats.NewStore(), gameService)
+	gameService.SetGameOutcomeNotifier(statsService.RecordGameOutcome)
+	return statsService, gameService
+}
+
+func TestStatsService_FinishedGameUpdatesStatsAndLeaderboard(t *testing.T) {
+	statsService, gameService := newStatsServiceForTest(t, "p1", "p2")
+	ctx := gameService.context()
+
+	room, err := gameService.CreateMatchRoomWithContext(ctx, "tictactoe", "p1", "p2")
+	if err != nil {
+		t.Fatalf("CreateMatchRoomWithContext() error = %v", err)
+	}
+	moves := []struct {
+		playerID string
+		row, col int
+	}{
+		{"p1", 0, 0},
+		{"p2", 1, 0},
+		{"p1", 0, 1},
+		{"p2", 1, 1},
+		{"p1", 0, 2},
+	}
+	for _, move := range moves {
+		if _, err := gameService.HandleTicTacToeMove(room.RoomID, move.playerID, move.row, move.col); err != nil {
+			t.Fatalf("HandleTicTacToeMove(%s) error = %v", move.playerID, err)
+		}
+	}
+
+	deadline := time.Now().Add(250 * time.Millisecond)
+	var winner stats.PlayerStats
+	for time.Now().Before(deadline) {
+		winner, err = statsService.PlayerStatsWithContext(ctx, "p1")
+		if err != nil {
+			t.Fatalf("PlayerStatsWithContext() error = %v", err)
+		}
+		if winner.Overall.Games > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if winner.Overall.Wins != 1 || winner.Overall.WinRate != 1 {
+		t.Fatalf("p1 overall = %+v, want one win", winner.Overall)
+	}
+	if winner.CurrentStreak.Result != stats.ResultWin || winner.CurrentStreak.Length != 1 {
+		t.Fatalf("p1 streak = %+v, want win streak of 1", winner.CurrentStreak)
+	}
+	if len(winner.RecentGames) != 1 || winner.RecentGames[0].OpponentID != "p2" || winner.RecentGames[0].Moves != 5 {
+		t.Fatalf("p1 recent games = %+v, want one 5-move game against p2", winner.RecentGames)
+	}
+
+	deadline = time.Now().Add(250 * time.Millisecond)
+	var leaderboard []stats.LeaderboardEntry
+	for time.Now().Before(deadline) {
+		leaderboard, err = statsService.LeaderboardWithContext(ctx, "tictactoe", 0)
+		if err != nil {
+			t.Fatalf("LeaderboardWithContext() error = %v", err)
+		}
+		if len(leaderboard) == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if len(leaderboard) != 2 || leaderboard[0].PlayerID != "p1" || leaderboard[1].Losses != 1 {
+		t.Fatalf("leaderboard = %+v, want p1 ahead of p2", leaderboard)
+	}
+	if chess, _ := statsService.LeaderboardWithContext(ctx, "chess", 0); len(chess) != 0 {
+		t.Fatalf("chess leaderboard = %+v, want empty", chess)
+	}
+}
+
+func TestStatsService_RecordGameOutcome_TracksAILevelsAndOpenings(t *testing.T) {
+	statsService, gameService := newStatsServiceForTest(t, "p1")
+	ctx := gameService.context()
+
+	outcomes := []game.GameOutcome{
+		{GameType: "chess", WinnerID: "p1", PGNMoves: []string{"e4", "e5", "Nf3", "Nc6", "Bb5"}},
+		{GameType: "chess", WinnerID: "p1", PGNMoves: []string{"e4", "e5", "Nf3", "Nc6", "Bc4"}},
+		{GameType: "chess", WinnerID: "ai", PGNMoves: []string{"d4", "d5"}},
+	}
+	for _, outcome := range outcomes {
+		outcome.PlayerIDs = map[string]string{"white": "p1", "black": "ai"}
+		outcome.AIPlayerID = "ai"
+		outcome.AILevel = 5
+		statsService.RecordGameOutcome(outcome)
+	}
+
+	playerStats, err := statsService.PlayerStatsWithContext(ctx, "p1")
+	if err != nil {
+		t.Fatalf("PlayerStatsWithContext() error = %v", err)
+	}
+	if len(playerStats.VsAI) != 1 || playerStats.VsAI[0].Level != 5 || playerStats.VsAI[0].Wins != 2 || playerStats.VsAI[0].Losses != 1 {
+		t.Fatalf("vs AI = %+v, want 2-1 against level 5", playerStats.VsAI)
+	}
+	if playerStats.FavoriteOpenings[0].Opening != "e4 e5 Nf3 Nc6" || playerStats.FavoriteOpenings[0].Games != 2 {
+		t.Fatalf("favorite openings = %+v, want e4 e5 Nf3 Nc6 twice", playerStats.FavoriteOpenings)
+	}
+	if playerStats.LongestWinStreak != 2 || playerStats.CurrentStreak.Result != stats.ResultLoss {
+		t.Fatalf("streaks = longest %d current %+v, want 2 and a loss", playerStats.LongestWinStreak, playerStats.CurrentStreak)
+	}
+	if statsService.store.HasPlayer("ai") {
+		t.Fatalf("AI player has stats, want human players only")
+	}
+}
+
+func TestStatsService_PlayerStats_UnknownPlayer(t *testing.T) {
+	statsService, gameService := newStatsServiceForTest(t)
+
+	if _, err := statsService.PlayerStatsWithContext(gameService.context(), "ghost"); err != ErrPlayerNotFound {
+		t.Fatalf("PlayerStatsWithContext() error = %v, want %v", err, ErrPlayerNotFound)
+	}
+	if _, err := statsService.LeaderboardWithContext(gameService.context(), "checkers", 0); err != ErrUnsupportedGameType {
+		t.Fatalf("LeaderboardWithContext() error = %v, want %v", err, ErrUnsupportedGameType)
+	}
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/tsaqiffatih/mini-game/game"
+	"github.com/tsaqiffatih/mini-game/internal/observability"
+	"github.com/tsaqiffatih/mini-game/tournament"
+)
+
+var (
+	ErrTournamentNotFound     = errors.New("Tournament not found")
+	ErrTournamentNameRequired = errors.New("Tournament name is required")
+	ErrUnsupportedGameType    = errors.New("Unsupported game type")
+)
+
+type TournamentRepository interface {
+	GetByID(ctx context.Context, tournamentID string) (*tournament.Tournament, error)
+	Save(ctx context.Context, t *tournament.Tournament) error
+	List(ctx context.Context) ([]*tournament.Tournament, error)
+}
+
+// TournamentGameStartedEvent tells one player that their next tournament
+// game is ready in RoomID.
+type TournamentGameStartedEvent struct {
+	TournamentID string
+	Round        int
+	Board        int
+	RoomID       string
+	GameType     string
+	PlayerID     string
+	OpponentID   string
+	Color        string
+}
+
+// TournamentService runs tournaments on top of GameService: it creates a
+// room for every pairing and records results from the outcomes of those
+// rooms.
+type TournamentService struct {
+	tournaments  TournamentRepository
+	games        *GameService
+	roomIndex    map[string]string // room ID -> tournament ID
+	gameNotifier func(context.Context, TournamentGameStartedEvent)
+	mu           sync.RWMutex
+}
+
+func NewTournamentService(tournaments TournamentRepository, games *GameService) *TournamentService {
+	return &TournamentService{
+		tournaments: tournaments,
+		games:       games,
+		roomIndex:   make(map[string]string),
+	}
+}
+
+func (s *TournamentService) SetGameStartedNotifier(notifier func(context.Context, TournamentGameStartedEvent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gameNotifier = notifier
+}
+
+func (s *TournamentService) CreateTournamentWithContext(
+	ctx context.Context,
+	name string,
+	creatorID string,
+	format tournament.Format,
+	gameType string,
+	rounds int,
+) (tournament.Snapshot, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "tournament.create")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	if name == "" {
+		spanErr = ErrTournamentNameRequired
+		return tournament.Snapshot{}, ErrTournamentNameRequired
+	}
+	if gameType != "chess" && gameType != "tictactoe" {
+		spanErr = ErrUnsupportedGameType
+		return tournament.Snapshot{}, ErrUnsupportedGameType
+	}
+	if _, err := s.games.playerManager.GetPlayer(creatorID); err != nil {
+		spanErr = ErrPlayerNotFound
+		return tournament.Snapshot{}, ErrPlayerNotFound
+	}
+
+	t, err := tournament.New(generateRandomRoomCode(), name, creatorID, format, gameType, rounds)
+	if err != nil {
+		spanErr = err
+		return tournament.Snapshot{}, err
+	}
+	if err := s.tournaments.Save(ctx, t); err != nil {
+		spanErr = err
+		return tournament.Snapshot{}, err
+	}
+
+	observability.Logger().InfoContext(ctx, "tournament created",
+		"room_id", "",
+		"player_id", creatorID,
+		"event_type", "tournament_created",
+		"tournament_id", t.ID,
+		"format", string(format),
+		"game_type", gameType,
+	)
+	return t.Snapshot(), nil
+}
+
+func (s *TournamentService) ListTournamentsWithContext(ctx context.Context) ([]tournament.Snapshot, error) {
+	tournaments, err := s.tournaments.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]tournament.Snapshot, 0, len(tournaments))
+	for _, t := range tournaments {
+		snapshots = append(snapshots, t.Snapshot())
+	}
+	return snapshots, nil
+}
+
+func (s *TournamentService) TournamentSnapshotWithContext(ctx context.Context, tournamentID string) (tournament.Snapshot, error) {
+	t, err := s.getTournament(ctx, tournamentID)
+	if err != nil {
+		return tournament.Snapshot{}, err
+	}
+	return t.Snapshot(), nil
+}
+
+func (s *TournamentService) RegisterPlayerWithContext(ctx context.Context, tournamentID string, playerID string) (tournament.Snapshot, error) {
+	t, err := s.getTournament(ctx, tournamentID)
+	if err != nil {
+		return tournament.Snapshot{}, err
+	}
+	if _, err := s.games.playerManager.GetPlayer(playerID); err != nil {
+		return tournament.Snapshot{}, ErrPlayerNotFound
+	}
+
+	if err := t.Register(playerID); err != nil {
+		return tournament.Snapshot{}, err
+	}
+
+	observability.Logger().InfoContext(ctx, "tournament player registered",
+		"room_id", "",
+		"player_id", playerID,
+		"event_type", "tournament_player_registered",
+		"tournament_id", tournamentID,
+	)
+	return t.Snapshot(), nil
+}
+
+// StartTournamentWithContext closes registration, pairs round one and
+// creates its rooms. Only the creator of the tournament may start it.
+func (s *TournamentService) StartTournamentWithContext(ctx context.Context, tournamentID string, playerID string) (tournament.Snapshot, error) {
+	ctx, endSpan := observability.StartSpan(ctx, "tournament.start")
+	var spanErr error
+	defer func() { endSpan(spanErr) }()
+
+	t, err := s.getTournament(ctx, tournamentID)
+	if err != nil {
+		spanErr = err
+		return tournament.Snapshot{}, err
+	}
+	if t.CreatorID != playerID {
+		spanErr = tournament.ErrNotCreator
+		return tournament.Snapshot{}, tournament.ErrNotCreator
+	}
+
+	pairings, err := t.Start()
+	if err != nil {
+		spanErr = err
+		return tournament.Snapshot{}, err
+	}
+
+	observability.Logger().InfoContext(ctx, "tournament started",
+		"room_id", "",
+		"player_id", playerID,
+		"event_type", "tournament_started",
+		"tournament_id", tournamentID,
+	)
+	s.startPairings(ctx, t, pairings)
+	return t.Snapshot(), nil
+}
+
+// RecordResultWithContext stores a result by hand, for forfeits or games
+// played outside the server. Only the creator of the tournament may do so.
+func (s *TournamentService) RecordResultWithContext(
+	ctx context.Context,
+	tournamentID string,
+	playerID string,
+	round int,
+	board int,
+	result tournament.Result,
+) (tournament.Snapshot, error) {
+	t, err := s.getTournament(ctx, tournamentID)
+	if err != nil {
+		return tournament.Snapshot{}, err
+	}
+	if t.CreatorID != playerID {
+		return tournament.Snapshot{}, tournament.ErrNotCreator
+	}
+
+	if err := s.recordResult(ctx, t, round, board, result); err != nil {
+		return tournament.Snapshot{}, err
+	}
+	return t.Snapshot(), nil
+}
+
+// HandleGameOutcome records the result of a finished tournament room. Games
+// replayed in the same room after its automatic reset are ignored.
+func (s *TournamentService) HandleGameOutcome(outcome game.GameOutcome) {
+	ctx := s.games.context()
+
+	s.mu.RLock()
+	tournamentID, ok := s.roomIndex[outcome.RoomID]
+	s.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	t, err := s.getTournament(ctx, tournamentID)
+	if err != nil {
+		return
+	}
+	pairing, ok := t.PairingForRoom(outcome.RoomID)
+	if !ok || pairing.Result != tournament.ResultPending {
+		return
+	}
+
+	result := tournament.ResultDraw
+	switch {
+	case outcome.Draw:
+	case outcome.WinnerID == pairing.WhiteID:
+		result = tournament.ResultWhiteWins
+	case outcome.WinnerID == pairing.BlackID:
+		result = tournament.ResultBlackWins
+	default:
+		return
+	}
+
+	if err := s.recordResult(ctx, t, pairing.Round, pairing.Board, result); err != nil {
+		observability.Logger().WarnContext(ctx, "tournament result not recorded",
+			"room_id", outcome.RoomID,
+			"player_id", "",
+			"event_type", "tournament_result_error",
+			"tournament_id", tournamentID,
+			"error", err,
+		)
+	}
+}
+
+func (s *TournamentService) recordResult(ctx context.Context, t *tournament.Tournament, round int, board int, result tournament.Result) error {
+	pairing, next, err := t.RecordResult(round, board, result)
+	if err != nil {
+		return err
+	}
+
+	observability.Logger().InfoContext(ctx, "tournament result recorded",
+		"room_id", pairing.RoomID,
+		"player_id", "",
+		"event_type", "tournament_result",
+		"tournament_id", t.ID,
+		"round", round,
+		"board", board,
+		"result", string(result),
+	)
+
+	if next != nil {
+		s.startPairings(ctx, t, next)
+	} else if t.Status() == tournament.StatusFinished {
+		observability.Logger().InfoContext(ctx, "tournament finished",
+			"room_id", "",
+			"player_id", "",
+			"event_type", "tournament_finished",
+			"tournament_id", t.ID,
+		)
+	}
+	return nil
+}
+
+// startPairings creates a room for every game of a new round and tells both
+// players where to go. A pairing whose room cannot be created keeps an empty
+// room ID; its result has to be recorded by hand.
+func (s *TournamentService) startPairings(ctx context.Context, t *tournament.Tournament, pairings []tournament.Pairing) {
+	for _, pairing := range pairings {
+		if pairing.IsBye() {
+			continue
+		}
+
+		snapshot, err := s.games.CreateMatchRoomWithContext(ctx, t.GameType, pairing.WhiteID, pairing.BlackID)
+		if err != nil {
+			observability.Logger().WarnContext(ctx, "tournament room creation failed",
+				"room_id", "",
+				"player_id", "",
+				"event_type", "tournament_room_error",
+				"tournament_id", t.ID,
+				"round", pairing.Round,
+				"board", pairing.Board,
+				"error", err,
+			)
+			continue
+		}
+
+		s.mu.Lock()
+		s.roomIndex[snapshot.RoomID] = t.ID
+		notifier := s.gameNotifier
+		s.mu.Unlock()
+
+		if err := t.SetRoomID(pairing.Round, pairing.Board, snapshot.RoomID); err != nil {
+			continue
+		}
+		if notifier == nil {
+			continue
+		}
+
+		for _, player := range snapshot.Players {
+			event := TournamentGameStartedEvent{
+				TournamentID: t.ID,
+				Round:        pairing.Round,
+				Board:        pairing.Board,
+				RoomID:       snapshot.RoomID,
+				GameType:     t.GameType,
+				PlayerID:     player.ID,
+				OpponentID:   pairing.BlackID,
+				Color:        player.Mark,
+			}
+			if player.ID == pairing.BlackID {
+				event.OpponentID = pairing.WhiteID
+			}
+			notifier(ctx, event)
+		}
+	}
+}
+
+func (s *TournamentService) getTournament(ctx context.Context, tournamentID string) (*tournament.Tournament, error) {
+	t, err := s.tournaments.GetByID(ctx, tournamentID)
+	if err != nil {
+		return nil, ErrTournamentNotFound
+	}
+	return t, nil
+}
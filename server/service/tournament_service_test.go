@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tsaqiffatih/mini-game/infrastructure"
+	"github.com/tsaqiffatih/mini-game/tournament"
+)
+
+func newTournamentServiceForTest(t *testing.T, playerIDs ...string) (*TournamentService, *GameService) {
+	t.Helper()
+
+	gameService, _ := newIntegrationGameService()
+	for _, playerID := range playerIDs {
+		addIntegrationPlayer(t, gameService, playerID)
+	}
+
+	tournamentService := NewTournamentService(infrastructure.NewMemoryTournamentRepository(), gameService)
+	gameService.SetGameOutcomeNotifier(tournamentService.HandleGameOutcome)
+	return tournamentService, gameService
+}
+
+func TestTournamentService_Start_CreatesRoomsAndNotifiesPlayers(t *testing.T) {
+	tournamentService, gameService := newTournamentServiceForTest(t, "p1", "p2")
+
+	var mu sync.Mutex
+	var events []TournamentGameStartedEvent
+	tournamentService.SetGameStartedNotifier(func(_ context.Context, event TournamentGameStartedEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+
+	created, err := tournamentService.CreateTournamentWithContext(gameService.context(), "Friday", "p1", tournament.FormatRoundRobin, "tictactoe", 0)
+	if err != nil {
+		t.Fatalf("CreateTournamentWithContext() error = %v", err)
+	}
+	for _, playerID := range []string{"p1", "p2"} {
+		if _, err := tournamentService.RegisterPlayerWithContext(gameService.context(), created.ID, playerID); err != nil {
+			t.Fatalf("RegisterPlayerWithContext(%q) error = %v", playerID, err)
+		}
+	}
+
+	started, err := tournamentService.StartTournamentWithContext(gameService.context(), created.ID, "p1")
+	if err != nil {
+		t.Fatalf("StartTournamentWithContext() error = %v", err)
+	}
+
+	pairing := started.Rounds[0][0]
+	if pairing.RoomID == "" {
+		t.Fatalf("pairing room ID is empty")
+	}
+	room, err := gameService.RoomSnapshot(pairing.RoomID)
+	if err != nil {
+		t.Fatalf("RoomSnapshot() error = %v", err)
+	}
+	if len(room.Players) != 2 || room.RoomState != "PLAYING" {
+		t.Fatalf("room = %d players in %q, want 2 players playing", len(room.Players), room.RoomState)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 {
+		t.Fatalf("game started events = %d, want one per player", len(events))
+	}
+	for _, event := range events {
+		if event.RoomID != pairing.RoomID || event.OpponentID == event.PlayerID {
+			t.Fatalf("event = %+v, want room %s with the other player as opponent", event, pairing.RoomID)
+		}
+	}
+}
+
+func TestTournamentService_FinishedRoomRecordsResult(t *testing.T) {
+	tournamentService, gameService := newTournamentServiceForTest(t, "p1", "p2")
+	ctx := gameService.context()
+
+	created, err := tournamentService.CreateTournamentWithContext(ctx, "Friday", "p1", tournament.FormatSingleElimination, "tictactoe", 0)
+	if err != nil {
+		t.Fatalf("CreateTournamentWithContext() error = %v", err)
+	}
+	for _, playerID := range []string{"p1", "p2"} {
+		if _, err := tournamentService.RegisterPlayerWithContext(ctx, created.ID, playerID); err != nil {
+			t.Fatalf("RegisterPlayerWithContext(%q) error = %v", playerID, err)
+		}
+	}
+	started, err := tournamentService.StartTournamentWithContext(ctx, created.ID, "p1")
+	if err != nil {
+		t.Fatalf("StartTournamentWithContext() error = %v", err)
+	}
+
+	pairing := started.Rounds[0][0]
+	moves := []struct {
+		playerID string
+		row, col int
+	}{
+		{pairing.WhiteID, 0, 0},
+		{pairing.BlackID, 1, 0},
+		{pairing.WhiteID, 0, 1},
+		{pairing.BlackID, 1, 1},
+		{pairing.WhiteID, 0, 2},
+	}
+	for _, move := range moves {
+		if _, err := gameService.HandleTicTacToeMove(pairing.RoomID, move.playerID, move.row, move.col); err != nil {
+			t.Fatalf("HandleTicTacToeMove(%s) error = %v", move.playerID, err)
+		}
+	}
+
+	deadline := time.Now().Add(250 * time.Millisecond)
+	var snapshot tournament.Snapshot
+	for time.Now().Before(deadline) {
+		snapshot, err = tournamentService.TournamentSnapshotWithContext(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("TournamentSnapshotWithContext() error = %v", err)
+		}
+		if snapshot.Status == tournament.StatusFinished {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if snapshot.Status != tournament.StatusFinished {
+		t.Fatalf("status = %q, want %q", snapshot.Status, tournament.StatusFinished)
+	}
+	if snapshot.Rounds[0][0].Result != tournament.ResultWhiteWins || snapshot.WinnerID != pairing.WhiteID {
+		t.Fatalf("result = %q winner = %q, want white win by %s", snapshot.Rounds[0][0].Result, snapshot.WinnerID, pairing.WhiteID)
+	}
+}
+
+func TestTournamentService_RegisterPlayer_UnknownPlayer(t *testing.T) {
+	tournamentService, gameService := newTournamentServiceForTest(t, "p1")
+
+	created, err := tournamentService.CreateTournamentWithContext(gameService.context(), "Friday", "p1", tournament.FormatSwiss, "chess", 3)
+	if err != nil {
+		t.Fatalf("CreateTournamentWithContext() error = %v", err)
+	}
+	if _, err := tournamentService.RegisterPlayerWithContext(gameService.context(), created.ID, "ghost"); err != ErrPlayerNotFound {
+		t.Fatalf("RegisterPlayerWithContext() error = %v, want %v", err, ErrPlayerNotFound)
+	}
+}
+
+func TestTournamentService_OnlyCreatorStartsAndRecordsResults(t *testing.T) {
+	tournamentService, gameService := newTournamentServiceForTest(t, "p1", "p2", "p3")
+	ctx := gameService.context()
+
+	if _, err := tournamentService.CreateTournamentWithContext(ctx, "Friday", "ghost", tournament.FormatSwiss, "chess", 0); err != ErrPlayerNotFound {
+		t.Fatalf("CreateTournamentWithContext(unknown creator) error = %v, want %v", err, ErrPlayerNotFound)
+	}
+	created, err := tournamentService.CreateTournamentWithContext(ctx, "Friday", "p1", tournament.FormatRoundRobin, "tictactoe", 0)
+	if err != nil {
+		t.Fatalf("CreateTournamentWithContext() error = %v", err)
+	}
+	for _, playerID := range []string{"p2", "p3"} {
+		if _, err := tournamentService.RegisterPlayerWithContext(ctx, created.ID, playerID); err != nil {
+			t.Fatalf("RegisterPlayerWithContext(%q) error = %v", playerID, err)
+		}
+	}
+
+	if _, err := tournamentService.StartTournamentWithContext(ctx, created.ID, "p2"); !errors.Is(err, tournament.ErrNotCreator) {
+		t.Fatalf("StartTournamentWithContext(p2) error = %v, want %v", err, tournament.ErrNotCreator)
+	}
+	if _, err := tournamentService.StartTournamentWithContext(ctx, created.ID, "p1"); err != nil {
+		t.Fatalf("StartTournamentWithContext(p1) error = %v", err)
+	}
+
+	if _, err := tournamentService.RecordResultWithContext(ctx, created.ID, "p2", 1, 1, tournament.ResultBlackWins); !errors.Is(err, tournament.ErrNotCreator) {
+		t.Fatalf("RecordResultWithContext(p2) error = %v, want %v", err, tournament.ErrNotCreator)
+	}
+	snapshot, err := tournamentService.RecordResultWithContext(ctx, created.ID, "p1", 1, 1, tournament.ResultBlackWins)
+	if err != nil {
+		t.Fatalf("RecordResultWithContext(p1) error = %v", err)
+	}
+	if snapshot.CreatorID != "p1" || snapshot.Rounds[0][0].Result != tournament.ResultBlackWins {
+		t.Fatalf("snapshot = creator %q result %q, want p1's result recorded", snapshot.CreatorID, snapshot.Rounds[0][0].Result)
+	}
+}
@@ -0,0 +1,281 @@
+package stats
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Result string
+
+const (
+	ResultWin  Result = "win"
+	ResultLoss Result = "loss"
+	ResultDraw Result = "draw"
+)
+
+const (
+	// OpeningPlies is how many chess plies name an opening.
+	OpeningPlies = 4
+	// RecentGamesLimit is how many games PlayerStats keeps in RecentGames.
+	RecentGamesLimit = 10
+	// FavoriteOpeningsLimit is how many openings PlayerStats reports.
+	FavoriteOpeningsLimit = 3
+)
+
+// GameRecord is one finished game from the point of view of one human
+// player.
+type GameRecord struct {
+	RoomID       string
+	GameType     string
+	PlayerID     string
+	OpponentID   string
+	OpponentIsAI bool
+	AILevel      int
+	Result       Result
+	Moves        int
+	Duration     time.Duration
+	Opening      string
+	FinishedAt   time.Time
+}
+
+type Record struct {
+	Games   int
+	Wins    int
+	Losses  int
+	Draws   int
+	WinRate float64
+}
+
+type Streak struct {
+	Result Result
+	Length int
+}
+
+type AILevelStats struct {
+	Level int
+	Record
+}
+
+type OpeningStats struct {
+	Opening string
+	Record
+}
+
+type PlayerStats struct {
+	PlayerID         string
+	Overall          Record
+	ByGame           map[string]Record
+	CurrentStreak    Streak
+	LongestWinStreak int
+	VsAI             []AILevelStats
+	FavoriteOpenings []OpeningStats
+	AverageMoves     float64
+	AverageDuration  time.Duration
+	RecentGames      []GameRecord
+}
+
+type LeaderboardEntry struct {
+	Rank     int
+	PlayerID string
+	// Points counts a win as one point and a draw as half a point.
+	Points float64
+	Record
+}
+
+// Store keeps every recorded game in memory, indexed by player.
+type Store struct {
+	games map[string][]GameRecord
+	mu    sync.RWMutex
+}
+
+func NewStore() *Store {
+	return &Store{
+		games: make(map[string][]GameRecord),
+	}
+}
+
+func (s *Store) Add(record GameRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.games[record.PlayerID] = append(s.games[record.PlayerID], record)
+}
+
+// HasPlayer reports whether playerID has at least one recorded game.
+func (s *Store) HasPlayer(playerID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.games[playerID]) > 0
+}
+
+func (s *Store) PlayerStats(playerID string) PlayerStats {
+	s.mu.RLock()
+	games := append([]GameRecord(nil), s.games[playerID]...)
+	s.mu.RUnlock()
+
+	stats := PlayerStats{
+		PlayerID:         playerID,
+		ByGame:           make(map[string]Record),
+		VsAI:             []AILevelStats{},
+		FavoriteOpenings: []OpeningStats{},
+		RecentGames:      []GameRecord{},
+	}
+
+	aiLevels := make(map[int]*Record)
+	openings := make(map[string]*Record)
+	var totalMoves int
+	var totalDuration time.Duration
+	var winStreak int
+	for _, game := range games {
+		stats.Overall.add(game.Result)
+
+		byGame := stats.ByGame[game.GameType]
+		byGame.add(game.Result)
+		stats.ByGame[game.GameType] = byGame
+
+		if game.OpponentIsAI {
+			if aiLevels[game.AILevel] == nil {
+				aiLevels[game.AILevel] = &Record{}
+			}
+			aiLevels[game.AILevel].add(game.Result)
+		}
+		if game.Opening != "" {
+			if openings[game.Opening] == nil {
+				openings[game.Opening] = &Record{}
+			}
+			openings[game.Opening].add(game.Result)
+		}
+
+		if stats.CurrentStreak.Result == game.Result {
+			stats.CurrentStreak.Length++
+		} else {
+			stats.CurrentStreak = Streak{Result: game.Result, Length: 1}
+		}
+		if game.Result == ResultWin {
+			winStreak++
+			if winStreak > stats.LongestWinStreak {
+				stats.LongestWinStreak = winStreak
+			}
+		} else {
+			winStreak = 0
+		}
+
+		totalMoves += game.Moves
+		totalDuration += game.Duration
+	}
+
+	if len(games) > 0 {
+		stats.AverageMoves = float64(totalMoves) / float64(len(games))
+		stats.AverageDuration = totalDuration / time.Duration(len(games))
+	}
+
+	for level, record := range aiLevels {
+		stats.VsAI = append(stats.VsAI, AILevelStats{Level: level, Record: *record})
+	}
+	sort.Slice(stats.VsAI, func(i, j int) bool {
+		return stats.VsAI[i].Level < stats.VsAI[j].Level
+	})
+
+	for opening, record := range openings {
+		stats.FavoriteOpenings = append(stats.FavoriteOpenings, OpeningStats{Opening: opening, Record: *record})
+	}
+	sort.Slice(stats.FavoriteOpenings, func(i, j int) bool {
+		left, right := stats.FavoriteOpenings[i], stats.FavoriteOpenings[j]
+		if left.Games != right.Games {
+			return left.Games > right.Games
+		}
+		return left.Opening < right.Opening
+	})
+	if len(stats.FavoriteOpenings) > FavoriteOpeningsLimit {
+		stats.FavoriteOpenings = stats.FavoriteOpenings[:FavoriteOpeningsLimit]
+	}
+
+	for i := len(games) - 1; i >= 0 && len(stats.RecentGames) < RecentGamesLimit; i-- {
+		stats.RecentGames = append(stats.RecentGames, games[i])
+	}
+	return stats
+}
+
+// Record sums the results of playerID in gameType. An empty gameType sums
+// all games.
+func (s *Store) Record(playerID string, gameType string) Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var record Record
+	for _, game := range s.games[playerID] {
+		if gameType != "" && game.GameType != gameType {
+			continue
+		}
+		record.add(game.Result)
+	}
+	return record
+}
+
+// Leaderboard ranks players of gameType by points, then win rate, then
+// fewer games played. An empty gameType ranks all games together.
+func (s *Store) Leaderboard(gameType string, limit int) []LeaderboardEntry {
+	s.mu.RLock()
+	entries := make([]LeaderboardEntry, 0, len(s.games))
+	for playerID, games := range s.games {
+		entry := LeaderboardEntry{PlayerID: playerID}
+		for _, game := range games {
+			if gameType != "" && game.GameType != gameType {
+				continue
+			}
+			entry.add(game.Result)
+		}
+		if entry.Games == 0 {
+			continue
+		}
+		entry.Points = float64(entry.Wins) + float64(entry.Draws)/2
+		entries = append(entries, entry)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		left, right := entries[i], entries[j]
+		if left.Points != right.Points {
+			return left.Points > right.Points
+		}
+		if left.WinRate != right.WinRate {
+			return left.WinRate > right.WinRate
+		}
+		if left.Games != right.Games {
+			return left.Games < right.Games
+		}
+		return left.PlayerID < right.PlayerID
+	})
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries
+}
+
+// OpeningName names a chess opening by its first OpeningPlies SAN moves.
+func OpeningName(pgnMoves []string) string {
+	if len(pgnMoves) > OpeningPlies {
+		pgnMoves = pgnMoves[:OpeningPlies]
+	}
+	return strings.Join(pgnMoves, " ")
+}
+
+func (r *Record) add(result Result) {
+	r.Games++
+	switch result {
+	case ResultWin:
+		r.Wins++
+	case ResultLoss:
+		r.Losses++
+	case ResultDraw:
+		r.Draws++
+	}
+	r.WinRate = float64(r.Wins) / float64(r.Games)
+}
@@ -12,19 +12,28 @@ const (
 	StatusEnded   GameStatus = "ended"
 )
 
+type MoveRecord struct {
+	Mark string
+	Row  int
+	Col  int
+}
+
 type TictactoeGameState struct {
-	Board  [3][3]string
-	Turn   string
-	Winner string
-	Status GameStatus
+	Board     [3][3]string
+	Turn      string
+	Winner    string
+	Status    GameStatus
+	History   []MoveRecord
+	firstTurn string
 }
 
 // NewGameState membuat state awal game
 func NewGameState(firstTurn string) *TictactoeGameState {
 	return &TictactoeGameState{
-		Board:  [3][3]string{},
-		Turn:   firstTurn,
-		Status: StatusWaiting,
+		Board:     [3][3]string{},
+		Turn:      firstTurn,
+		Status:    StatusWaiting,
+		firstTurn: firstTurn,
 	}
 }
 
@@ -47,6 +56,7 @@ func (gs *TictactoeGameState) ApplyMove(player string, row, col int) error {
 	}
 
 	gs.Board[row][col] = player
+	gs.History = append(gs.History, MoveRecord{Mark: player, Row: row, Col: col})
 
 	if gs.checkWinner(player) {
 		gs.Winner = player
@@ -70,6 +80,38 @@ func (gs *TictactoeGameState) Reset(firstTurn string) {
 	gs.Turn = firstTurn
 	gs.Winner = ""
 	gs.Status = StatusActive
+	gs.History = nil
+	gs.firstTurn = firstTurn
+}
+
+// LastMoveIndexBy mengembalikan index langkah terakhir milik mark, atau -1
+func (gs *TictactoeGameState) LastMoveIndexBy(mark string) int {
+	for i := len(gs.History) - 1; i >= 0; i-- {
+		if gs.History[i].Mark == mark {
+			return i
+		}
+	}
+	return -1
+}
+
+// RollbackTo menyimpan n langkah pertama lalu memutar ulang dari papan kosong
+func (gs *TictactoeGameState) RollbackTo(n int) error {
+	if n < 0 || n > len(gs.History) {
+		return errors.New("invalid rollback move")
+	}
+
+	kept := append([]MoveRecord(nil), gs.History[:n]...)
+	firstTurn := gs.firstTurn
+	if firstTurn == "" {
+		firstTurn = "X"
+	}
+	gs.Reset(firstTurn)
+	for _, move := range kept {
+		if err := gs.ApplyMove(move.Mark, move.Row, move.Col); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // ===== INTERNAL PURE LOGIC =====
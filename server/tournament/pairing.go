@@ -0,0 +1,241 @@
+package tournament
+
+import "sort"
+
+// roundRobinSchedule builds every round up front with the circle method. With
+// an odd field one player sits out each round and receives a bye.
+func roundRobinSchedule(players []string) [][]Pairing {
+	ids := append([]string(nil), players...)
+	if len(ids)%2 == 1 {
+		ids = append(ids, "")
+	}
+
+	n := len(ids)
+	rounds := make([][]Pairing, 0, n-1)
+	for round := 0; round < n-1; round++ {
+		pairings := make([]Pairing, 0, n/2)
+		var byes []Pairing
+		for i := 0; i < n/2; i++ {
+			white, black := ids[i], ids[n-1-i]
+			// Swap colours every other board, and every other round on the
+			// first board, so the fixed player does not always play white.
+			if (i == 0 && round%2 == 1) || (i > 0 && i%2 == 1) {
+				white, black = black, white
+			}
+
+			switch {
+			case white == "":
+				byes = append(byes, Pairing{WhiteID: black})
+			case black == "":
+				byes = append(byes, Pairing{WhiteID: white})
+			default:
+				pairings = append(pairings, Pairing{WhiteID: white, BlackID: black})
+			}
+		}
+		rounds = append(rounds, append(pairings, byes...))
+
+		last := ids[n-1]
+		copy(ids[2:], ids[1:n-1])
+		ids[1] = last
+	}
+	return rounds
+}
+
+// maxPairingSteps bounds the search for a rematch-free Swiss round. The
+// search backtracks and can take exponential time on a crowded history, and
+// it runs holding the tournament lock.
+const maxPairingSteps = 10000
+
+// swissPairings pairs the next Swiss round Dutch-style: players are ranked by
+// score and seed, and each score group is split in half with the top half
+// playing the bottom half. Rematches are avoided by trying the next candidate
+// in the group, then lower groups; they are only allowed when no rematch-free
+// pairing is found within maxPairingSteps.
+func swissPairings(players []string, rounds [][]Pairing) []Pairing {
+	history := collectHistory(players, rounds)
+	seeds := seedIndex(players)
+
+	ordered := append([]string(nil), players...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		left, right := history[ordered[i]], history[ordered[j]]
+		if left.points != right.points {
+			return left.points > right.points
+		}
+		return seeds[ordered[i]] < seeds[ordered[j]]
+	})
+
+	var byes []Pairing
+	if len(ordered)%2 == 1 {
+		byeIndex := len(ordered) - 1
+		for i := len(ordered) - 1; i >= 0; i-- {
+			if history[ordered[i]].byes == 0 {
+				byeIndex = i
+				break
+			}
+		}
+		byes = append(byes, Pairing{WhiteID: ordered[byeIndex]})
+		ordered = append(ordered[:byeIndex], ordered[byeIndex+1:]...)
+	}
+
+	steps := maxPairingSteps
+	pairs, ok := pairDutch(ordered, history, false, &steps)
+	if !ok {
+		// With rematches allowed the first candidate always fits, so this
+		// pass does not backtrack.
+		steps = maxPairingSteps
+		pairs, _ = pairDutch(ordered, history, true, &steps)
+	}
+
+	pairings := make([]Pairing, 0, len(pairs)+len(byes))
+	for _, pair := range pairs {
+		pairings = append(pairings, orientPairing(pair[0], pair[1], history))
+	}
+	return append(pairings, byes...)
+}
+
+// pairDutch pairs ordered from the top, backtracking when the rest cannot be
+// paired. Every opponent tried costs one of steps; the search gives up when
+// they run out.
+func pairDutch(ordered []string, history map[string]*playerHistory, allowRematch bool, steps *int) ([][2]string, bool) {
+	if len(ordered) == 0 {
+		return nil, true
+	}
+
+	top := ordered[0]
+	rest := ordered[1:]
+	for _, index := range dutchCandidates(top, rest, history) {
+		opponent := rest[index]
+		if !allowRematch && history[top].opponents[opponent] {
+			continue
+		}
+		if *steps <= 0 {
+			return nil, false
+		}
+		*steps--
+
+		remaining := make([]string, 0, len(rest)-1)
+		remaining = append(remaining, rest[:index]...)
+		remaining = append(remaining, rest[index+1:]...)
+		if pairs, ok := pairDutch(remaining, history, allowRematch, steps); ok {
+			return append([][2]string{{top, opponent}}, pairs...), true
+		}
+	}
+	return nil, false
+}
+
+// dutchCandidates orders the possible opponents of top: the matching player
+// in the bottom half of top's score group first, then the rest of the bottom
+// half, the top half, and finally the lower score groups.
+func dutchCandidates(top string, rest []string, history map[string]*playerHistory) []int {
+	groupSize := 1
+	for groupSize-1 < len(rest) && history[rest[groupSize-1]].points == history[top].points {
+		groupSize++
+	}
+
+	candidates := make([]int, 0, len(rest))
+	half := groupSize / 2
+	for i := half - 1; i < groupSize-1; i++ {
+		if i >= 0 {
+			candidates = append(candidates, i)
+		}
+	}
+	for i := 0; i < half-1; i++ {
+		candidates = append(candidates, i)
+	}
+	for i := groupSize - 1; i < len(rest); i++ {
+		candidates = append(candidates, i)
+	}
+	return candidates
+}
+
+// orientPairing gives white to the player who has had it less often, and
+// alternates from the last game when both are balanced.
+func orientPairing(first string, second string, history map[string]*playerHistory) Pairing {
+	left, right := history[first], history[second]
+	switch {
+	case left.colorBalance > right.colorBalance:
+		return Pairing{WhiteID: second, BlackID: first}
+	case left.colorBalance < right.colorBalance:
+		return Pairing{WhiteID: first, BlackID: second}
+	case left.lastColor == "white":
+		return Pairing{WhiteID: second, BlackID: first}
+	default:
+		return Pairing{WhiteID: first, BlackID: second}
+	}
+}
+
+// eliminationPairings seeds the first round into a standard bracket (1 v N,
+// 2 v N-1, ...) with byes for the top seeds, and afterwards pairs the winners
+// of neighbouring boards. A drawn game is won by the higher seed.
+func eliminationPairings(players []string, rounds [][]Pairing) []Pairing {
+	seeds := seedIndex(players)
+
+	if len(rounds) == 0 {
+		size := 1
+		for size < len(players) {
+			size *= 2
+		}
+
+		order := bracketOrder(size)
+		pairings := make([]Pairing, 0, size/2)
+		for i := 0; i < len(order); i += 2 {
+			high, low := order[i], order[i+1]
+			if low > len(players) {
+				pairings = append(pairings, Pairing{WhiteID: players[high-1]})
+				continue
+			}
+			pairings = append(pairings, Pairing{WhiteID: players[high-1], BlackID: players[low-1]})
+		}
+		return pairings
+	}
+
+	previous := rounds[len(rounds)-1]
+	if len(previous) < 2 {
+		return nil
+	}
+
+	pairings := make([]Pairing, 0, len(previous)/2)
+	for i := 0; i+1 < len(previous); i += 2 {
+		first := pairingWinner(previous[i], seeds)
+		second := pairingWinner(previous[i+1], seeds)
+		if seeds[second] < seeds[first] {
+			first, second = second, first
+		}
+		pairings = append(pairings, Pairing{WhiteID: first, BlackID: second})
+	}
+	return pairings
+}
+
+func pairingWinner(pairing Pairing, seeds map[string]int) string {
+	switch pairing.Result {
+	case ResultBlackWins:
+		return pairing.BlackID
+	case ResultDraw:
+		if seeds[pairing.BlackID] < seeds[pairing.WhiteID] {
+			return pairing.BlackID
+		}
+	}
+	return pairing.WhiteID
+}
+
+// bracketOrder returns 1-based seeds in bracket order so that the top two
+// seeds can only meet in the final.
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, len(order)*2)
+		for _, seed := range order {
+			next = append(next, seed, len(order)*2+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+func seedIndex(players []string) map[string]int {
+	seeds := make(map[string]int, len(players))
+	for i, playerID := range players {
+		seeds[playerID] = i
+	}
+	return seeds
+}
@@ -0,0 +1,135 @@
+package tournament
+
+import "sort"
+
+type Standing struct {
+	Rank            int
+	PlayerID        string
+	Points          float64
+	Buchholz        float64
+	SonnebornBerger float64
+	Wins            int
+	Draws           int
+	Losses          int
+	Byes            int
+}
+
+type playerHistory struct {
+	points       float64
+	wins         int
+	draws        int
+	losses       int
+	byes         int
+	opponents    map[string]bool
+	colorBalance int
+	lastColor    string
+	// results holds the score against each opponent, for Sonneborn-Berger.
+	results map[string]float64
+}
+
+func collectHistory(players []string, rounds [][]Pairing) map[string]*playerHistory {
+	history := make(map[string]*playerHistory, len(players))
+	for _, playerID := range players {
+		history[playerID] = &playerHistory{
+			opponents: make(map[string]bool),
+			results:   make(map[string]float64),
+		}
+	}
+
+	for _, round := range rounds {
+		for _, pairing := range round {
+			white, ok := history[pairing.WhiteID]
+			if !ok {
+				continue
+			}
+			if pairing.IsBye() {
+				if pairing.Result == ResultBye {
+					white.points++
+					white.byes++
+				}
+				continue
+			}
+
+			black, ok := history[pairing.BlackID]
+			if !ok {
+				continue
+			}
+			white.opponents[pairing.BlackID] = true
+			black.opponents[pairing.WhiteID] = true
+			white.colorBalance++
+			black.colorBalance--
+			white.lastColor = "white"
+			black.lastColor = "black"
+
+			var whiteScore float64
+			switch pairing.Result {
+			case ResultWhiteWins:
+				whiteScore = 1
+				white.wins++
+				black.losses++
+			case ResultBlackWins:
+				white.losses++
+				black.wins++
+			case ResultDraw:
+				whiteScore = 0.5
+				white.draws++
+				black.draws++
+			default:
+				continue
+			}
+
+			white.points += whiteScore
+			black.points += 1 - whiteScore
+			white.results[pairing.BlackID] += whiteScore
+			black.results[pairing.WhiteID] += 1 - whiteScore
+		}
+	}
+	return history
+}
+
+// computeStandings ranks players by points, then Buchholz (sum of the
+// opponents' points), then Sonneborn-Berger (opponents' points weighted by
+// the score made against them), then seed. Byes count as a win without an
+// opponent and add nothing to the tiebreaks.
+func computeStandings(players []string, rounds [][]Pairing) []Standing {
+	history := collectHistory(players, rounds)
+	seeds := seedIndex(players)
+
+	standings := make([]Standing, 0, len(players))
+	for _, playerID := range players {
+		player := history[playerID]
+		standing := Standing{
+			PlayerID: playerID,
+			Points:   player.points,
+			Wins:     player.wins,
+			Draws:    player.draws,
+			Losses:   player.losses,
+			Byes:     player.byes,
+		}
+		for opponentID := range player.opponents {
+			opponentPoints := history[opponentID].points
+			standing.Buchholz += opponentPoints
+			standing.SonnebornBerger += opponentPoints * player.results[opponentID]
+		}
+		standings = append(standings, standing)
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		left, right := standings[i], standings[j]
+		if left.Points != right.Points {
+			return left.Points > right.Points
+		}
+		if left.Buchholz != right.Buchholz {
+			return left.Buchholz > right.Buchholz
+		}
+		if left.SonnebornBerger != right.SonnebornBerger {
+			return left.SonnebornBerger > right.SonnebornBerger
+		}
+		return seeds[left.PlayerID] < seeds[right.PlayerID]
+	})
+
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+	return standings
+}
@@ -0,0 +1,353 @@
+package tournament
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatRoundRobin        Format = "round_robin"
+	FormatSwiss             Format = "swiss"
+	FormatSingleElimination Format = "single_elimination"
+)
+
+type Status string
+
+const (
+	StatusRegistering Status = "REGISTERING"
+	StatusRunning     Status = "RUNNING"
+	StatusFinished    Status = "FINISHED"
+)
+
+type Result string
+
+const (
+	ResultPending   Result = ""
+	ResultWhiteWins Result = "white"
+	ResultBlackWins Result = "black"
+	ResultDraw      Result = "draw"
+	ResultBye       Result = "bye"
+)
+
+var (
+	ErrInvalidFormat       = errors.New("invalid tournament format")
+	ErrInvalidResult       = errors.New("invalid tournament result")
+	ErrRegistrationClosed  = errors.New("tournament registration is closed")
+	ErrAlreadyRegistered   = errors.New("player already registered")
+	ErrNotEnoughPlayers    = errors.New("tournament needs at least two players")
+	ErrTournamentNotActive = errors.New("tournament is not running")
+	ErrPairingNotFound     = errors.New("tournament pairing not found")
+	ErrResultAlreadySet    = errors.New("tournament pairing already has a result")
+	ErrNotCreator          = errors.New("only the tournament creator can do that")
+	ErrTooManyRounds       = errors.New("a swiss tournament cannot have more rounds than opponents per player")
+)
+
+// Pairing is one game of a round. BlackID is empty for a bye, which is
+// recorded as ResultBye as soon as the round is paired.
+type Pairing struct {
+	Round   int
+	Board   int
+	WhiteID string
+	BlackID string
+	RoomID  string
+	Result  Result
+}
+
+func (p Pairing) IsBye() bool {
+	return p.BlackID == ""
+}
+
+type Snapshot struct {
+	ID           string
+	Name         string
+	CreatorID    string
+	Format       Format
+	GameType     string
+	Status       Status
+	Players      []string
+	CurrentRound int
+	TotalRounds  int
+	Rounds       [][]Pairing
+	Standings    []Standing
+	// WinnerID is set once the tournament is finished: the final winner in
+	// single elimination, otherwise the top of the standings.
+	WinnerID  string
+	CreatedAt time.Time
+}
+
+// Tournament owns registration, pairings and results of one event. All
+// methods are safe for concurrent use; rooms for the pairings are created by
+// the caller.
+type Tournament struct {
+	ID   string
+	Name string
+	// CreatorID is the player who created the event. Only they may start it
+	// and record results by hand.
+	CreatorID string
+	Format    Format
+	GameType  string
+	CreatedAt time.Time
+
+	status          Status
+	players         []string
+	totalRounds     int
+	rounds          [][]Pairing
+	roundRobin      [][]Pairing
+	requestedRounds int
+	mu              sync.RWMutex
+}
+
+// New creates a tournament open for registration. rounds is only used by
+// Swiss events; zero picks enough rounds to separate the field.
+func New(id string, name string, creatorID string, format Format, gameType string, rounds int) (*Tournament, error) {
+	switch format {
+	case FormatRoundRobin, FormatSwiss, FormatSingleElimination:
+	default:
+		return nil, ErrInvalidFormat
+	}
+	if rounds < 0 {
+		return nil, errors.New("rounds must not be negative")
+	}
+
+	return &Tournament{
+		ID:              id,
+		Name:            name,
+		CreatorID:       creatorID,
+		Format:          format,
+		GameType:        gameType,
+		CreatedAt:       time.Now().UTC(),
+		status:          StatusRegistering,
+		requestedRounds: rounds,
+	}, nil
+}
+
+// Register adds a player. Registration order is the seeding order.
+func (t *Tournament) Register(playerID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.status != StatusRegistering {
+		return ErrRegistrationClosed
+	}
+	for _, registered := range t.players {
+		if registered == playerID {
+			return ErrAlreadyRegistered
+		}
+	}
+
+	t.players = append(t.players, playerID)
+	return nil
+}
+
+// Start closes registration and pairs the first round.
+func (t *Tournament) Start() ([]Pairing, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.status != StatusRegistering {
+		return nil, ErrRegistrationClosed
+	}
+	if len(t.players) < 2 {
+		return nil, ErrNotEnoughPlayers
+	}
+
+	switch t.Format {
+	case FormatRoundRobin:
+		t.roundRobin = roundRobinSchedule(t.players)
+		t.totalRounds = len(t.roundRobin)
+	case FormatSwiss:
+		// Each round needs a new opponent for everyone, so a field of n
+		// players supports at most n-1 rounds.
+		if t.requestedRounds > len(t.players)-1 {
+			return nil, ErrTooManyRounds
+		}
+		t.totalRounds = t.requestedRounds
+		if t.totalRounds == 0 {
+			t.totalRounds = int(math.Ceil(math.Log2(float64(len(t.players)))))
+		}
+	case FormatSingleElimination:
+		t.totalRounds = int(math.Ceil(math.Log2(float64(len(t.players)))))
+	}
+
+	t.status = StatusRunning
+	return t.pairNextRoundLocked(), nil
+}
+
+// SetRoomID attaches the room created for a pairing.
+func (t *Tournament) SetRoomID(round int, board int, roomID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pairing, err := t.pairingLocked(round, board)
+	if err != nil {
+		return err
+	}
+	pairing.RoomID = roomID
+	return nil
+}
+
+// RecordResult stores the result of a pairing. When it completes the round,
+// the next round is paired and returned; the tournament finishes instead when
+// no rounds are left. Every round is therefore returned exactly once, even
+// when results for the same round arrive concurrently.
+func (t *Tournament) RecordResult(round int, board int, result Result) (Pairing, []Pairing, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.status != StatusRunning {
+		return Pairing{}, nil, ErrTournamentNotActive
+	}
+	switch result {
+	case ResultWhiteWins, ResultBlackWins, ResultDraw:
+	default:
+		return Pairing{}, nil, ErrInvalidResult
+	}
+
+	pairing, err := t.pairingLocked(round, board)
+	if err != nil {
+		return Pairing{}, nil, err
+	}
+	if pairing.Result != ResultPending {
+		return *pairing, nil, ErrResultAlreadySet
+	}
+	pairing.Result = result
+
+	if round != len(t.rounds) || !t.roundCompleteLocked() {
+		return *pairing, nil, nil
+	}
+	return *pairing, t.pairNextRoundLocked(), nil
+}
+
+// PairingForRoom finds the pairing played in roomID.
+func (t *Tournament) PairingForRoom(roomID string) (Pairing, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, round := range t.rounds {
+		for _, pairing := range round {
+			if pairing.RoomID == roomID {
+				return pairing, true
+			}
+		}
+	}
+	return Pairing{}, false
+}
+
+func (t *Tournament) Status() Status {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.status
+}
+
+func (t *Tournament) Standings() []Standing {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return computeStandings(t.players, t.rounds)
+}
+
+func (t *Tournament) Snapshot() Snapshot {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	rounds := make([][]Pairing, 0, len(t.rounds))
+	for _, round := range t.rounds {
+		rounds = append(rounds, append([]Pairing(nil), round...))
+	}
+
+	standings := computeStandings(t.players, t.rounds)
+	return Snapshot{
+		ID:           t.ID,
+		Name:         t.Name,
+		CreatorID:    t.CreatorID,
+		Format:       t.Format,
+		GameType:     t.GameType,
+		Status:       t.status,
+		Players:      append([]string(nil), t.players...),
+		CurrentRound: len(t.rounds),
+		TotalRounds:  t.totalRounds,
+		Rounds:       rounds,
+		Standings:    standings,
+		WinnerID:     t.winnerLocked(standings),
+		CreatedAt:    t.CreatedAt,
+	}
+}
+
+func (t *Tournament) winnerLocked(standings []Standing) string {
+	if t.status != StatusFinished || len(standings) == 0 {
+		return ""
+	}
+	if t.Format == FormatSingleElimination && len(t.rounds) > 0 {
+		final := t.rounds[len(t.rounds)-1]
+		return pairingWinner(final[0], seedIndex(t.players))
+	}
+	return standings[0].PlayerID
+}
+
+func (t *Tournament) pairingLocked(round int, board int) (*Pairing, error) {
+	if round < 1 || round > len(t.rounds) {
+		return nil, ErrPairingNotFound
+	}
+	pairings := t.rounds[round-1]
+	if board < 1 || board > len(pairings) {
+		return nil, ErrPairingNotFound
+	}
+	return &pairings[board-1], nil
+}
+
+func (t *Tournament) roundCompleteLocked() bool {
+	if len(t.rounds) == 0 {
+		return false
+	}
+	for _, pairing := range t.rounds[len(t.rounds)-1] {
+		if pairing.Result == ResultPending {
+			return false
+		}
+	}
+	return true
+}
+
+// pairNextRoundLocked appends the next round, or finishes the tournament and
+// returns nil when the event is decided.
+func (t *Tournament) pairNextRoundLocked() []Pairing {
+	round := len(t.rounds) + 1
+
+	var pairings []Pairing
+	switch t.Format {
+	case FormatRoundRobin:
+		if round <= len(t.roundRobin) {
+			pairings = append([]Pairing(nil), t.roundRobin[round-1]...)
+		}
+	case FormatSwiss:
+		if round <= t.totalRounds {
+			pairings = swissPairings(t.players, t.rounds)
+		}
+	case FormatSingleElimination:
+		pairings = eliminationPairings(t.players, t.rounds)
+	}
+
+	if len(pairings) == 0 {
+		t.status = StatusFinished
+		return nil
+	}
+
+	for i := range pairings {
+		pairings[i].Round = round
+		pairings[i].Board = i + 1
+		if pairings[i].IsBye() {
+			pairings[i].Result = ResultBye
+		}
+	}
+	t.rounds = append(t.rounds, pairings)
+
+	// A round made only of byes needs no games; keep pairing.
+	if t.roundCompleteLocked() {
+		return t.pairNextRoundLocked()
+	}
+	return append([]Pairing(nil), pairings...)
+}
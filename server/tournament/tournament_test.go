@@ -0,0 +1,226 @@
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func newStartedTournamentForTest(t *testing.T, format Format, rounds int, playerCount int) (*Tournament, []Pairing) {
+	t.Helper()
+
+	tournament, err := New("t1", "Friday", "p1", format, "chess", rounds)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for i := 1; i <= playerCount; i++ {
+		if err := tournament.Register(fmt.Sprintf("p%d", i)); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+
+	pairings, err := tournament.Start()
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	return tournament, pairings
+}
+
+func playRoundForTest(t *testing.T, tournament *Tournament, pairings []Pairing, result Result) []Pairing {
+	t.Helper()
+
+	var next []Pairing
+	for _, pairing := range pairings {
+		if pairing.IsBye() {
+			continue
+		}
+		_, paired, err := tournament.RecordResult(pairing.Round, pairing.Board, result)
+		if err != nil {
+			t.Fatalf("RecordResult(%d, %d) error = %v", pairing.Round, pairing.Board, err)
+		}
+		if paired != nil {
+			next = paired
+		}
+	}
+	return next
+}
+
+func TestTournament_RoundRobin_EveryPlayerMeetsEveryOtherOnce(t *testing.T) {
+	tournament, pairings := newStartedTournamentForTest(t, FormatRoundRobin, 0, 5)
+
+	met := make(map[string]int)
+	byes := make(map[string]int)
+	for pairings != nil {
+		for _, pairing := range pairings {
+			if pairing.IsBye() {
+				byes[pairing.WhiteID]++
+				continue
+			}
+			met[pairing.WhiteID+"-"+pairing.BlackID]++
+			met[pairing.BlackID+"-"+pairing.WhiteID]++
+		}
+		pairings = playRoundForTest(t, tournament, pairings, ResultDraw)
+	}
+
+	snapshot := tournament.Snapshot()
+	if snapshot.Status != StatusFinished {
+		t.Fatalf("status = %q, want %q", snapshot.Status, StatusFinished)
+	}
+	if snapshot.CurrentRound != 5 {
+		t.Fatalf("rounds = %d, want 5 for five players", snapshot.CurrentRound)
+	}
+	for i := 1; i <= 5; i++ {
+		player := fmt.Sprintf("p%d", i)
+		if byes[player] != 1 {
+			t.Fatalf("byes for %s = %d, want 1", player, byes[player])
+		}
+		for j := i + 1; j <= 5; j++ {
+			opponent := fmt.Sprintf("p%d", j)
+			if met[player+"-"+opponent] != 1 {
+				t.Fatalf("%s met %s %d times, want 1", player, opponent, met[player+"-"+opponent])
+			}
+		}
+	}
+}
+
+func TestTournament_Swiss_PairsTopHalfAgainstBottomHalfWithoutRematches(t *testing.T) {
+	tournament, round1 := newStartedTournamentForTest(t, FormatSwiss, 3, 6)
+
+	if len(round1) != 3 || round1[0].WhiteID != "p1" || round1[0].BlackID != "p4" {
+		t.Fatalf("round 1 = %+v, want p1 v p4 on board 1", round1)
+	}
+
+	round2 := playRoundForTest(t, tournament, round1, ResultWhiteWins)
+	played := make(map[string]bool)
+	for _, pairing := range round1 {
+		played[pairing.WhiteID+"-"+pairing.BlackID] = true
+		played[pairing.BlackID+"-"+pairing.WhiteID] = true
+	}
+	for _, pairing := range round2 {
+		if played[pairing.WhiteID+"-"+pairing.BlackID] {
+			t.Fatalf("round 2 pairing %s v %s is a rematch", pairing.WhiteID, pairing.BlackID)
+		}
+	}
+
+	// The three winners are in the top score group and must be paired
+	// among themselves before anyone floats down.
+	winners := map[string]bool{"p1": true, "p2": true, "p3": true}
+	if !winners[round2[0].WhiteID] || !winners[round2[0].BlackID] {
+		t.Fatalf("round 2 board 1 = %s v %s, want two round 1 winners", round2[0].WhiteID, round2[0].BlackID)
+	}
+}
+
+func TestTournament_Swiss_OddFieldGivesByeToLowestRanked(t *testing.T) {
+	_, round1 := newStartedTournamentForTest(t, FormatSwiss, 2, 5)
+
+	bye := round1[len(round1)-1]
+	if !bye.IsBye() || bye.WhiteID != "p5" || bye.Result != ResultBye {
+		t.Fatalf("last pairing = %+v, want bye for p5", bye)
+	}
+}
+
+func TestTournament_SingleElimination_AdvancesWinnersAndFinishes(t *testing.T) {
+	tournament, round1 := newStartedTournamentForTest(t, FormatSingleElimination, 0, 3)
+
+	if len(round1) != 2 || !round1[0].IsBye() || round1[0].WhiteID != "p1" {
+		t.Fatalf("round 1 = %+v, want bye for top seed", round1)
+	}
+	if round1[1].WhiteID != "p2" || round1[1].BlackID != "p3" {
+		t.Fatalf("round 1 board 2 = %+v, want p2 v p3", round1[1])
+	}
+
+	final := playRoundForTest(t, tournament, round1, ResultBlackWins)
+	if len(final) != 1 || final[0].WhiteID != "p1" || final[0].BlackID != "p3" {
+		t.Fatalf("final = %+v, want p1 v p3", final)
+	}
+
+	if next := playRoundForTest(t, tournament, final, ResultBlackWins); next != nil {
+		t.Fatalf("pairings after final = %+v, want none", next)
+	}
+	snapshot := tournament.Snapshot()
+	if snapshot.Status != StatusFinished || snapshot.WinnerID != "p3" {
+		t.Fatalf("status = %q winner = %q, want finished with p3", snapshot.Status, snapshot.WinnerID)
+	}
+}
+
+func TestTournament_Standings_BreaksTiesWithBuchholzAndSonnebornBerger(t *testing.T) {
+	rounds := [][]Pairing{
+		{
+			{Round: 1, Board: 1, WhiteID: "a", BlackID: "b", Result: ResultWhiteWins},
+			{Round: 1, Board: 2, WhiteID: "c", BlackID: "d", Result: ResultWhiteWins},
+		},
+		{
+			{Round: 2, Board: 1, WhiteID: "a", BlackID: "c", Result: ResultDraw},
+			{Round: 2, Board: 2, WhiteID: "b", BlackID: "d", Result: ResultWhiteWins},
+		},
+	}
+
+	standings := computeStandings([]string{"a", "b", "c", "d"}, rounds)
+
+	// a and c both have 1.5; a beat b (1 point) while c beat d (0 points).
+	if standings[0].PlayerID != "a" || standings[1].PlayerID != "c" {
+		t.Fatalf("standings = %+v, want a ahead of c", standings)
+	}
+	if standings[0].Buchholz != 2.5 || standings[1].Buchholz != 1.5 {
+		t.Fatalf("buchholz = %v/%v, want 2.5/1.5", standings[0].Buchholz, standings[1].Buchholz)
+	}
+	if standings[0].SonnebornBerger != 1.75 {
+		t.Fatalf("sonneborn-berger = %v, want 1.75", standings[0].SonnebornBerger)
+	}
+}
+
+func TestTournament_RecordResult_RejectsSecondResult(t *testing.T) {
+	tournament, round1 := newStartedTournamentForTest(t, FormatSwiss, 2, 4)
+
+	if _, _, err := tournament.RecordResult(1, 1, ResultDraw); err != nil {
+		t.Fatalf("RecordResult() error = %v", err)
+	}
+	if _, _, err := tournament.RecordResult(1, 1, ResultWhiteWins); !errors.Is(err, ErrResultAlreadySet) {
+		t.Fatalf("second RecordResult() error = %v, want %v", err, ErrResultAlreadySet)
+	}
+	if _, _, err := tournament.RecordResult(1, len(round1)+1, ResultDraw); !errors.Is(err, ErrPairingNotFound) {
+		t.Fatalf("RecordResult() unknown board error = %v, want %v", err, ErrPairingNotFound)
+	}
+	if err := tournament.Register("late"); !errors.Is(err, ErrRegistrationClosed) {
+		t.Fatalf("Register() after start error = %v, want %v", err, ErrRegistrationClosed)
+	}
+}
+
+func TestTournament_Swiss_RejectsMoreRoundsThanOpponents(t *testing.T) {
+	tournament, err := New("t1", "Friday", "p1", FormatSwiss, "chess", 4)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for i := 1; i <= 4; i++ {
+		if err := tournament.Register(fmt.Sprintf("p%d", i)); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+
+	if _, err := tournament.Start(); !errors.Is(err, ErrTooManyRounds) {
+		t.Fatalf("Start() error = %v, want %v", err, ErrTooManyRounds)
+	}
+	if status := tournament.Status(); status != StatusRegistering {
+		t.Fatalf("status = %q, want registration still open", status)
+	}
+}
+
+func TestTournament_Swiss_AllowsRematchWhenSearchGivesUp(t *testing.T) {
+	players := []string{"p1", "p2", "p3", "p4", "p5", "p6"}
+	history := collectHistory(players, nil)
+
+	steps := 1
+	if _, ok := pairDutch(players, history, false, &steps); ok {
+		t.Fatalf("pairDutch() with one step ok = true, want the search to give up")
+	}
+
+	// Four players who have all met leave no rematch-free round.
+	rounds := [][]Pairing{
+		{{WhiteID: "p1", BlackID: "p2", Result: ResultDraw}, {WhiteID: "p3", BlackID: "p4", Result: ResultDraw}},
+		{{WhiteID: "p1", BlackID: "p3", Result: ResultDraw}, {WhiteID: "p2", BlackID: "p4", Result: ResultDraw}},
+		{{WhiteID: "p1", BlackID: "p4", Result: ResultDraw}, {WhiteID: "p2", BlackID: "p3", Result: ResultDraw}},
+	}
+	if pairings := swissPairings(players[:4], rounds); len(pairings) != 2 {
+		t.Fatalf("pairings = %+v, want two rematches", pairings)
+	}
+}